@@ -0,0 +1,152 @@
+// Package classifier suggests categories for transactions with a
+// multinomial naive Bayes model trained on a user's categorized
+// transactions. The features of a transaction are the words of its
+// description, a bucket of its amount, its account and its type, so that
+// e.g. a small "SQ *BLUE BOTTLE" expense on the credit card is recognized as
+// Coffee from earlier purchases there.
+package classifier
+
+import (
+	"cmp"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// amountBuckets are the upper bounds of the amount buckets, roughly
+// logarithmic so that a bucket covers amounts of the same kind.
+var amountBuckets = []float64{5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000}
+
+// Example is a categorized transaction the model learns from.
+type Example struct {
+	Features []string
+	Category string
+}
+
+// Suggestion is a category with the model's estimate of the probability
+// that it is the right one.
+type Suggestion struct {
+	Category   string  `json:"category"`
+	Confidence float64 `json:"confidence" minimum:"0" maximum:"1"`
+}
+
+// Model is a trained classifier. It is safe for concurrent use.
+type Model struct {
+	examples   int
+	vocabulary map[string]bool
+	categories map[string]*categoryStats
+}
+
+type categoryStats struct {
+	examples int
+	// features counts how often each feature occurs in the category's
+	// examples, and total all of their features.
+	features map[string]int
+	total    int
+}
+
+// Features returns the features of a transaction. The amount may be nil.
+func Features(description string, amount *big.Rat, account, typ string) []string {
+	var features []string
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		// Single letters are mostly noise such as the "x" of "x1234".
+		if len([]rune(w)) > 1 {
+			features = append(features, "word:"+w)
+		}
+	}
+	if amount != nil {
+		f, _ := new(big.Rat).Abs(amount).Float64()
+		i, _ := slices.BinarySearch(amountBuckets, f)
+		features = append(features, "amount:"+strconv.Itoa(i))
+	}
+	if account != "" {
+		features = append(features, "account:"+strings.ToLower(account))
+	}
+	if typ != "" {
+		features = append(features, "type:"+typ)
+	}
+	return features
+}
+
+// Train builds a model from examples.
+func Train(examples []Example) *Model {
+	m := &Model{
+		examples:   len(examples),
+		vocabulary: map[string]bool{},
+		categories: map[string]*categoryStats{},
+	}
+	for _, e := range examples {
+		c := m.categories[e.Category]
+		if c == nil {
+			c = &categoryStats{features: map[string]int{}}
+			m.categories[e.Category] = c
+		}
+		c.examples++
+		for _, f := range e.Features {
+			c.features[f]++
+			c.total++
+			m.vocabulary[f] = true
+		}
+	}
+	return m
+}
+
+// Examples returns the number of examples the model was trained on.
+func (m *Model) Examples() int {
+	return m.examples
+}
+
+// KnowsDescription reports whether any of the description words among
+// features occurs in the examples the model was trained on. Suggestions for
+// a description without one rest on the amount, account and type alone.
+func (m *Model) KnowsDescription(features []string) bool {
+	return slices.ContainsFunc(features, func(f string) bool {
+		return strings.HasPrefix(f, "word:") && m.vocabulary[f]
+	})
+}
+
+// Suggest returns up to n categories for a transaction with the given
+// features, most likely first. Features the model has never seen are
+// ignored; the confidences of all categories add up to 1.
+func (m *Model) Suggest(features []string, n int) []Suggestion {
+	if m.examples == 0 || n <= 0 {
+		return nil
+	}
+
+	// Log probabilities with add-one smoothing, normalized below.
+	vocabulary := float64(len(m.vocabulary))
+	suggestions := make([]Suggestion, 0, len(m.categories))
+	scores := make([]float64, 0, len(m.categories))
+	for name, c := range m.categories {
+		score := math.Log(float64(c.examples) / float64(m.examples))
+		for _, f := range features {
+			if !m.vocabulary[f] {
+				continue
+			}
+			score += math.Log(float64(c.features[f]+1) / (float64(c.total) + vocabulary))
+		}
+		suggestions = append(suggestions, Suggestion{Category: name})
+		scores = append(scores, score)
+	}
+
+	top := slices.Max(scores)
+	var sum float64
+	for i, s := range scores {
+		suggestions[i].Confidence = math.Exp(s - top)
+		sum += suggestions[i].Confidence
+	}
+	for i := range suggestions {
+		suggestions[i].Confidence /= sum
+	}
+
+	slices.SortFunc(suggestions, func(a, b Suggestion) int {
+		return cmp.Or(cmp.Compare(b.Confidence, a.Confidence), cmp.Compare(a.Category, b.Category))
+	})
+	return suggestions[:min(n, len(suggestions))]
+}
@@ -0,0 +1,83 @@
+package classifier
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestFeatures(t *testing.T) {
+	got := Features("SQ *BLUE BOTTLE #0042 x1234", big.NewRat(-475, 100), "Amex", "expense")
+	want := []string{"word:sq", "word:blue", "word:bottle", "amount:0", "account:amex", "type:expense"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Features = %v, want %v", got, want)
+	}
+
+	if got := Features("Rent", big.NewRat(1500, 1), "", ""); !reflect.DeepEqual(got, []string{"word:rent", "amount:8"}) {
+		t.Errorf("Features = %v", got)
+	}
+}
+
+func example(description string, amount int64, account, category string) Example {
+	return Example{
+		Features: Features(description, big.NewRat(amount, 1), account, "expense"),
+		Category: category,
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	var examples []Example
+	for range 5 {
+		examples = append(examples,
+			example("SQ *BLUE BOTTLE COFFEE", 5, "Amex", "Coffee"),
+			example("STARBUCKS STORE 1234", 6, "Amex", "Coffee"),
+			example("WHOLE FOODS MARKET", 85, "Checking", "Groceries"),
+			example("TRADER JOE'S", 60, "Checking", "Groceries"),
+			example("SHELL OIL 5744", 45, "Amex", "Fuel"),
+		)
+	}
+	model := Train(examples)
+	if model.Examples() != 25 {
+		t.Errorf("Examples = %d", model.Examples())
+	}
+
+	suggestions := model.Suggest(Features("BLUE BOTTLE SF", big.NewRat(7, 1), "Amex", "expense"), 3)
+	if len(suggestions) != 3 || suggestions[0].Category != "Coffee" {
+		t.Fatalf("suggestions = %v", suggestions)
+	}
+	if suggestions[0].Confidence < 0.8 {
+		t.Errorf("confidence %f for a known merchant", suggestions[0].Confidence)
+	}
+	for i := 1; i < len(suggestions); i++ {
+		if suggestions[i].Confidence > suggestions[i-1].Confidence {
+			t.Errorf("suggestions not ordered: %v", suggestions)
+		}
+	}
+
+	// Unknown words leave only the amount, account and type to go by.
+	suggestions = model.Suggest(Features("ACME HARDWARE", big.NewRat(70, 1), "Checking", "expense"), 10)
+	var sum float64
+	for _, s := range suggestions {
+		sum += s.Confidence
+	}
+	if len(suggestions) != 3 || math.Abs(sum-1) > 1e-9 {
+		t.Errorf("suggestions = %v, confidences add up to %f", suggestions, sum)
+	}
+	if suggestions[0].Category != "Groceries" {
+		t.Errorf("suggestions = %v", suggestions)
+	}
+
+	if !model.KnowsDescription(Features("BLUE BOTTLE SF", nil, "", "")) {
+		t.Error("KnowsDescription = false for a known merchant")
+	}
+	if model.KnowsDescription(Features("ACME HARDWARE", big.NewRat(70, 1), "Checking", "expense")) {
+		t.Error("KnowsDescription = true for an unknown merchant")
+	}
+}
+
+func TestSuggestUntrained(t *testing.T) {
+	if s := Train(nil).Suggest([]string{"word:rent"}, 3); s != nil {
+		t.Errorf("suggestions without examples: %v", s)
+	}
+}
@@ -0,0 +1,17 @@
+package database
+
+import (
+	"context"
+
+	"budgetctl-go/internal/database/gensql"
+)
+
+// CheckSplitTotalsImmediately makes the split total triggers, which are
+// deferred to commit by default, check after each statement for the rest of
+// the database transaction. Use it where an amount change must fail on its
+// own statement, e.g. inside a savepoint. sqlc cannot parse SET CONSTRAINTS,
+// so this is not a generated query.
+func CheckSplitTotalsImmediately(ctx context.Context, db gensql.DBTX) error {
+	_, err := db.Exec(ctx, "SET CONSTRAINTS transaction_splits_total, transactions_split_total IMMEDIATE")
+	return err
+}
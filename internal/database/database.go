@@ -7,6 +7,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/joho/godotenv/autoload"
 
@@ -18,6 +19,8 @@ type Service interface {
 	Health() map[string]string
 	Close()
 	GetQueries() *gensql.Queries
+	WithTx(ctx context.Context, fn func(tx pgx.Tx) error) error
+	StreamTransactionsWithFilters(ctx context.Context, arg gensql.ListTransactionsWithFiltersParams, fn func(gensql.ListTransactionsWithFiltersRow) error) error
 }
 
 type service struct {
@@ -51,6 +54,22 @@ func (s *service) GetQueries() *gensql.Queries {
 	return s.Queries
 }
 
+// WithTx runs fn inside a database transaction. The transaction is committed
+// when fn returns nil and rolled back otherwise; use GetQueries().WithTx(tx)
+// to run generated queries inside it.
+func (s *service) WithTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
 func (s *service) Health() map[string]string {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
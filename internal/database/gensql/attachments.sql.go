@@ -0,0 +1,333 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: attachments.sql
+
+package gensql
+
+import (
+	"context"
+)
+
+const CreateAttachment = `-- name: CreateAttachment :one
+INSERT INTO attachments (
+  user_id, transaction_id, filename, content_type, size, checksum,
+  storage_key, thumbnail_key
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING id, user_id, transaction_id, filename, content_type, size, checksum, storage_key, thumbnail_key, created_at
+`
+
+type CreateAttachmentParams struct {
+	UserID        int64
+	TransactionID int64
+	Filename      string
+	ContentType   string
+	Size          int64
+	Checksum      *string
+	StorageKey    string
+	ThumbnailKey  *string
+}
+
+func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentParams) (Attachment, error) {
+	row := q.db.QueryRow(ctx, CreateAttachment,
+		arg.UserID,
+		arg.TransactionID,
+		arg.Filename,
+		arg.ContentType,
+		arg.Size,
+		arg.Checksum,
+		arg.StorageKey,
+		arg.ThumbnailKey,
+	)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TransactionID,
+		&i.Filename,
+		&i.ContentType,
+		&i.Size,
+		&i.Checksum,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const DeleteAttachment = `-- name: DeleteAttachment :one
+DELETE FROM attachments
+WHERE id = $1 AND transaction_id = $2 AND user_id = $3
+RETURNING id, user_id, transaction_id, filename, content_type, size, checksum, storage_key, thumbnail_key, created_at
+`
+
+type DeleteAttachmentParams struct {
+	ID            int64
+	TransactionID int64
+	UserID        int64
+}
+
+func (q *Queries) DeleteAttachment(ctx context.Context, arg DeleteAttachmentParams) (Attachment, error) {
+	row := q.db.QueryRow(ctx, DeleteAttachment, arg.ID, arg.TransactionID, arg.UserID)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TransactionID,
+		&i.Filename,
+		&i.ContentType,
+		&i.Size,
+		&i.Checksum,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const DeleteStorageDeletion = `-- name: DeleteStorageDeletion :exec
+DELETE FROM storage_deletions
+WHERE storage_key = $1
+`
+
+func (q *Queries) DeleteStorageDeletion(ctx context.Context, storageKey string) error {
+	_, err := q.db.Exec(ctx, DeleteStorageDeletion, storageKey)
+	return err
+}
+
+const FindAttachmentByChecksum = `-- name: FindAttachmentByChecksum :one
+SELECT id, user_id, transaction_id, filename, content_type, size, checksum, storage_key, thumbnail_key, created_at FROM attachments
+WHERE user_id = $1 AND checksum = $2
+ORDER BY id
+LIMIT 1
+`
+
+type FindAttachmentByChecksumParams struct {
+	UserID   int64
+	Checksum *string
+}
+
+// Any attachment of the user with the same content; its stored object is
+// reused instead of uploading the file again.
+func (q *Queries) FindAttachmentByChecksum(ctx context.Context, arg FindAttachmentByChecksumParams) (Attachment, error) {
+	row := q.db.QueryRow(ctx, FindAttachmentByChecksum, arg.UserID, arg.Checksum)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TransactionID,
+		&i.Filename,
+		&i.ContentType,
+		&i.Size,
+		&i.Checksum,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const GetAttachment = `-- name: GetAttachment :one
+SELECT id, user_id, transaction_id, filename, content_type, size, checksum, storage_key, thumbnail_key, created_at FROM attachments
+WHERE id = $1 AND transaction_id = $2 AND user_id = $3
+`
+
+type GetAttachmentParams struct {
+	ID            int64
+	TransactionID int64
+	UserID        int64
+}
+
+func (q *Queries) GetAttachment(ctx context.Context, arg GetAttachmentParams) (Attachment, error) {
+	row := q.db.QueryRow(ctx, GetAttachment, arg.ID, arg.TransactionID, arg.UserID)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TransactionID,
+		&i.Filename,
+		&i.ContentType,
+		&i.Size,
+		&i.Checksum,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const ListAttachments = `-- name: ListAttachments :many
+
+SELECT id, user_id, transaction_id, filename, content_type, size, checksum, storage_key, thumbnail_key, created_at FROM attachments
+WHERE transaction_id = $1 AND user_id = $2
+ORDER BY created_at, id
+`
+
+type ListAttachmentsParams struct {
+	TransactionID int64
+	UserID        int64
+}
+
+// internal/database/queries/attachments.sql
+func (q *Queries) ListAttachments(ctx context.Context, arg ListAttachmentsParams) ([]Attachment, error) {
+	rows, err := q.db.Query(ctx, ListAttachments, arg.TransactionID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.TransactionID,
+			&i.Filename,
+			&i.ContentType,
+			&i.Size,
+			&i.Checksum,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListStorageDeletions = `-- name: ListStorageDeletions :many
+SELECT storage_key FROM storage_deletions
+ORDER BY queued_at
+LIMIT $1
+`
+
+func (q *Queries) ListStorageDeletions(ctx context.Context, limit int32) ([]string, error) {
+	rows, err := q.db.Query(ctx, ListStorageDeletions, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var storage_key string
+		if err := rows.Scan(&storage_key); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const LockStorageKey = `-- name: LockStorageKey :exec
+SELECT pg_advisory_xact_lock(hashtext($1::text))
+`
+
+// Serialises uploads and deletions of the same stored object until the end
+// of the transaction.
+func (q *Queries) LockStorageKey(ctx context.Context, storageKey string) error {
+	_, err := q.db.Exec(ctx, LockStorageKey, storageKey)
+	return err
+}
+
+const MoveAttachments = `-- name: MoveAttachments :exec
+UPDATE attachments
+SET transaction_id = $1
+WHERE id IN (
+  SELECT DISTINCT ON (COALESCE(a.checksum, a.id::text)) a.id
+  FROM attachments a
+  WHERE a.user_id = $2
+    AND a.transaction_id = ANY($3::bigint[])
+    AND NOT EXISTS (
+      SELECT 1 FROM attachments k
+      WHERE k.transaction_id = $1 AND k.checksum = a.checksum
+    )
+  ORDER BY COALESCE(a.checksum, a.id::text), a.id
+)
+`
+
+type MoveAttachmentsParams struct {
+	KeepID       int64
+	UserID       int64
+	DuplicateIds []int64
+}
+
+// Moves the attachments of merged duplicates to the kept transaction,
+// skipping files it already has. The skipped rows are deleted with their
+// transactions.
+func (q *Queries) MoveAttachments(ctx context.Context, arg MoveAttachmentsParams) error {
+	_, err := q.db.Exec(ctx, MoveAttachments, arg.KeepID, arg.UserID, arg.DuplicateIds)
+	return err
+}
+
+const QueueStorageDeletion = `-- name: QueueStorageDeletion :exec
+INSERT INTO storage_deletions (storage_key)
+VALUES ($1)
+ON CONFLICT DO NOTHING
+`
+
+func (q *Queries) QueueStorageDeletion(ctx context.Context, storageKey string) error {
+	_, err := q.db.Exec(ctx, QueueStorageDeletion, storageKey)
+	return err
+}
+
+const RenameAttachment = `-- name: RenameAttachment :one
+UPDATE attachments
+SET filename = $4
+WHERE id = $1 AND transaction_id = $2 AND user_id = $3
+RETURNING id, user_id, transaction_id, filename, content_type, size, checksum, storage_key, thumbnail_key, created_at
+`
+
+type RenameAttachmentParams struct {
+	ID            int64
+	TransactionID int64
+	UserID        int64
+	Filename      string
+}
+
+func (q *Queries) RenameAttachment(ctx context.Context, arg RenameAttachmentParams) (Attachment, error) {
+	row := q.db.QueryRow(ctx, RenameAttachment,
+		arg.ID,
+		arg.TransactionID,
+		arg.UserID,
+		arg.Filename,
+	)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TransactionID,
+		&i.Filename,
+		&i.ContentType,
+		&i.Size,
+		&i.Checksum,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const StorageKeyInUse = `-- name: StorageKeyInUse :one
+SELECT EXISTS (
+  SELECT 1 FROM attachments
+  WHERE storage_key = $1::text OR thumbnail_key = $1::text
+)
+`
+
+func (q *Queries) StorageKeyInUse(ctx context.Context, storageKey string) (bool, error) {
+	row := q.db.QueryRow(ctx, StorageKeyInUse, storageKey)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,337 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: duplicates.sql
+
+package gensql
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const CountDuplicateCandidates = `-- name: CountDuplicateCandidates :one
+SELECT COUNT(*) FROM transactions a
+JOIN transactions b
+  ON b.user_id = a.user_id
+  AND b.id > a.id
+  AND b.account = a.account
+  AND b.type = a.type
+  AND b.amount = a.amount
+  AND b.date BETWEEN a.date - interval '3 days' AND a.date + interval '3 days'
+  AND b.deleted_at IS NULL
+WHERE a.user_id = $1
+  AND a.deleted_at IS NULL
+  AND (a.external_id IS NULL OR b.external_id IS NULL OR a.external_id = b.external_id)
+`
+
+func (q *Queries) CountDuplicateCandidates(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, CountDuplicateCandidates, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const FindNearDuplicate = `-- name: FindNearDuplicate :one
+SELECT id FROM transactions
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND account = $2
+  AND type = $3
+  AND amount = $4
+  AND date BETWEEN $5::timestamptz - interval '3 days' AND $5::timestamptz + interval '3 days'
+  AND (external_id IS NULL OR $6::text IS NULL OR external_id <> $6)
+ORDER BY
+  abs(extract(epoch FROM date - $5::timestamptz)),
+  similarity(description, $7) DESC,
+  id
+LIMIT 1
+`
+
+type FindNearDuplicateParams struct {
+	UserID      int64
+	Account     string
+	Type        string
+	Amount      pgtype.Numeric
+	Date        pgtype.Timestamptz
+	ExternalID  *string
+	Description string
+}
+
+// Finds a transaction on the same account with the same amount booked within
+// three days, preferring the closest date and most similar description.
+func (q *Queries) FindNearDuplicate(ctx context.Context, arg FindNearDuplicateParams) (int64, error) {
+	row := q.db.QueryRow(ctx, FindNearDuplicate,
+		arg.UserID,
+		arg.Account,
+		arg.Type,
+		arg.Amount,
+		arg.Date,
+		arg.ExternalID,
+		arg.Description,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const FindTransactionByExternalID = `-- name: FindTransactionByExternalID :one
+
+SELECT id FROM transactions
+WHERE user_id = $1 AND account = $2 AND external_id = $3 AND deleted_at IS NULL
+ORDER BY id
+LIMIT 1
+`
+
+type FindTransactionByExternalIDParams struct {
+	UserID     int64
+	Account    string
+	ExternalID *string
+}
+
+// internal/database/queries/duplicates.sql
+func (q *Queries) FindTransactionByExternalID(ctx context.Context, arg FindTransactionByExternalIDParams) (int64, error) {
+	row := q.db.QueryRow(ctx, FindTransactionByExternalID, arg.UserID, arg.Account, arg.ExternalID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const ListDuplicateCandidates = `-- name: ListDuplicateCandidates :many
+SELECT
+  a.id, a.user_id, a.amount, a.description, a.category, a.date, a.type, a.currency, a.status, a.account, a.tags, a.notes, a.has_receipt, a.receipt_url, a.created_at, a.updated_at, a.import_batch_id, a.external_id, a.fingerprint, a.transfer_id, a.transfer_direction, a.recurring_id, a.recurring_occurrence, a.deleted_at, a.version, a.reconciliation_id, a.payee_id,
+  b.id, b.user_id, b.amount, b.description, b.category, b.date, b.type, b.currency, b.status, b.account, b.tags, b.notes, b.has_receipt, b.receipt_url, b.created_at, b.updated_at, b.import_batch_id, b.external_id, b.fingerprint, b.transfer_id, b.transfer_direction, b.recurring_id, b.recurring_occurrence, b.deleted_at, b.version, b.reconciliation_id, b.payee_id,
+  (a.fingerprint = b.fingerprint)::boolean AS exact,
+  similarity(a.description, b.description)::float8 AS similarity
+FROM transactions a
+JOIN transactions b
+  ON b.user_id = a.user_id
+  AND b.id > a.id
+  AND b.account = a.account
+  AND b.type = a.type
+  AND b.amount = a.amount
+  AND b.date BETWEEN a.date - interval '3 days' AND a.date + interval '3 days'
+  AND b.deleted_at IS NULL
+WHERE a.user_id = $1
+  AND a.deleted_at IS NULL
+  AND (a.external_id IS NULL OR b.external_id IS NULL OR a.external_id = b.external_id)
+ORDER BY exact DESC, similarity DESC, a.date DESC, a.id, b.id
+LIMIT $2 OFFSET $3
+`
+
+type ListDuplicateCandidatesParams struct {
+	UserID int64
+	Limit  int32
+	Offset int32
+}
+
+type ListDuplicateCandidatesRow struct {
+	Transaction   Transaction
+	Transaction_2 Transaction
+	Exact         bool
+	Similarity    float64
+}
+
+// Pairs of transactions that look like the same booking: same account, type
+// and amount within three days, without conflicting bank references.
+func (q *Queries) ListDuplicateCandidates(ctx context.Context, arg ListDuplicateCandidatesParams) ([]ListDuplicateCandidatesRow, error) {
+	rows, err := q.db.Query(ctx, ListDuplicateCandidates, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDuplicateCandidatesRow
+	for rows.Next() {
+		var i ListDuplicateCandidatesRow
+		if err := rows.Scan(
+			&i.Transaction.ID,
+			&i.Transaction.UserID,
+			&i.Transaction.Amount,
+			&i.Transaction.Description,
+			&i.Transaction.Category,
+			&i.Transaction.Date,
+			&i.Transaction.Type,
+			&i.Transaction.Currency,
+			&i.Transaction.Status,
+			&i.Transaction.Account,
+			&i.Transaction.Tags,
+			&i.Transaction.Notes,
+			&i.Transaction.HasReceipt,
+			&i.Transaction.ReceiptUrl,
+			&i.Transaction.CreatedAt,
+			&i.Transaction.UpdatedAt,
+			&i.Transaction.ImportBatchID,
+			&i.Transaction.ExternalID,
+			&i.Transaction.Fingerprint,
+			&i.Transaction.TransferID,
+			&i.Transaction.TransferDirection,
+			&i.Transaction.RecurringID,
+			&i.Transaction.RecurringOccurrence,
+			&i.Transaction.DeletedAt,
+			&i.Transaction.Version,
+			&i.Transaction.ReconciliationID,
+			&i.Transaction.PayeeID,
+			&i.Transaction_2.ID,
+			&i.Transaction_2.UserID,
+			&i.Transaction_2.Amount,
+			&i.Transaction_2.Description,
+			&i.Transaction_2.Category,
+			&i.Transaction_2.Date,
+			&i.Transaction_2.Type,
+			&i.Transaction_2.Currency,
+			&i.Transaction_2.Status,
+			&i.Transaction_2.Account,
+			&i.Transaction_2.Tags,
+			&i.Transaction_2.Notes,
+			&i.Transaction_2.HasReceipt,
+			&i.Transaction_2.ReceiptUrl,
+			&i.Transaction_2.CreatedAt,
+			&i.Transaction_2.UpdatedAt,
+			&i.Transaction_2.ImportBatchID,
+			&i.Transaction_2.ExternalID,
+			&i.Transaction_2.Fingerprint,
+			&i.Transaction_2.TransferID,
+			&i.Transaction_2.TransferDirection,
+			&i.Transaction_2.RecurringID,
+			&i.Transaction_2.RecurringOccurrence,
+			&i.Transaction_2.DeletedAt,
+			&i.Transaction_2.Version,
+			&i.Transaction_2.ReconciliationID,
+			&i.Transaction_2.PayeeID,
+			&i.Exact,
+			&i.Similarity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const MatchFingerprint = `-- name: MatchFingerprint :one
+SELECT
+  f.fingerprint::text AS fingerprint,
+  count(t.id) AS existing,
+  COALESCE(min(t.id), 0)::bigint AS first_id
+FROM (
+  SELECT transaction_fingerprint(
+    $1::timestamptz,
+    $2::numeric,
+    $3::text,
+    $4::text,
+    $5::text
+  ) AS fingerprint
+) f
+LEFT JOIN transactions t
+  ON t.user_id = $6
+  AND t.fingerprint = f.fingerprint
+  AND t.deleted_at IS NULL
+  AND (t.external_id IS NULL OR $7::text IS NULL OR t.external_id = $7)
+GROUP BY f.fingerprint
+`
+
+type MatchFingerprintParams struct {
+	Date        pgtype.Timestamptz
+	Amount      pgtype.Numeric
+	Type        string
+	Description string
+	Account     string
+	UserID      int64
+	ExternalID  *string
+}
+
+type MatchFingerprintRow struct {
+	Fingerprint string
+	Existing    int64
+	FirstID     int64
+}
+
+// Computes the fingerprint a new transaction would get and counts the
+// existing transactions sharing it. Rows whose bank reference differs are
+// distinct transactions and are not counted.
+func (q *Queries) MatchFingerprint(ctx context.Context, arg MatchFingerprintParams) (MatchFingerprintRow, error) {
+	row := q.db.QueryRow(ctx, MatchFingerprint,
+		arg.Date,
+		arg.Amount,
+		arg.Type,
+		arg.Description,
+		arg.Account,
+		arg.UserID,
+		arg.ExternalID,
+	)
+	var i MatchFingerprintRow
+	err := row.Scan(&i.Fingerprint, &i.Existing, &i.FirstID)
+	return i, err
+}
+
+const MergeTransactionDetails = `-- name: MergeTransactionDetails :one
+UPDATE transactions
+SET
+  tags = $1,
+  notes = $2,
+  external_id = COALESCE(external_id, $3),
+  has_receipt = has_receipt OR $4,
+  receipt_url = COALESCE(receipt_url, $5),
+  updated_at = NOW()
+WHERE id = $6 AND user_id = $7 AND deleted_at IS NULL
+RETURNING id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id
+`
+
+type MergeTransactionDetailsParams struct {
+	Tags       []string
+	Notes      *string
+	ExternalID *string
+	HasReceipt bool
+	ReceiptUrl *string
+	ID         int64
+	UserID     int64
+}
+
+// Stores the tags and notes combined from merged duplicates and fills in
+// the bank reference and receipt when the kept transaction has none.
+func (q *Queries) MergeTransactionDetails(ctx context.Context, arg MergeTransactionDetailsParams) (Transaction, error) {
+	row := q.db.QueryRow(ctx, MergeTransactionDetails,
+		arg.Tags,
+		arg.Notes,
+		arg.ExternalID,
+		arg.HasReceipt,
+		arg.ReceiptUrl,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Amount,
+		&i.Description,
+		&i.Category,
+		&i.Date,
+		&i.Type,
+		&i.Currency,
+		&i.Status,
+		&i.Account,
+		&i.Tags,
+		&i.Notes,
+		&i.HasReceipt,
+		&i.ReceiptUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ImportBatchID,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.TransferID,
+		&i.TransferDirection,
+		&i.RecurringID,
+		&i.RecurringOccurrence,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ReconciliationID,
+		&i.PayeeID,
+	)
+	return i, err
+}
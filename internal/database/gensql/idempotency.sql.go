@@ -0,0 +1,136 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: idempotency.sql
+
+package gensql
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const ClaimIdempotencyKey = `-- name: ClaimIdempotencyKey :one
+
+INSERT INTO idempotency_keys (user_id, key, request_hash)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, key) DO UPDATE
+SET
+  request_hash = EXCLUDED.request_hash,
+  status = NULL,
+  headers = NULL,
+  body = NULL,
+  created_at = NOW()
+WHERE idempotency_keys.created_at < $4
+RETURNING user_id, key, request_hash, status, headers, body, created_at
+`
+
+type ClaimIdempotencyKeyParams struct {
+	UserID      int64
+	Key         string
+	RequestHash string
+	Cutoff      pgtype.Timestamptz
+}
+
+// internal/database/queries/idempotency.sql
+// Claims a key for a new request. A key claimed before the cutoff has
+// expired and is taken over; otherwise no row is returned.
+func (q *Queries) ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.db.QueryRow(ctx, ClaimIdempotencyKey,
+		arg.UserID,
+		arg.Key,
+		arg.RequestHash,
+		arg.Cutoff,
+	)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.UserID,
+		&i.Key,
+		&i.RequestHash,
+		&i.Status,
+		&i.Headers,
+		&i.Body,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const DeleteExpiredIdempotencyKeys = `-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < $1
+`
+
+func (q *Queries) DeleteExpiredIdempotencyKeys(ctx context.Context, createdAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteExpiredIdempotencyKeys, createdAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const GetIdempotencyKey = `-- name: GetIdempotencyKey :one
+SELECT user_id, key, request_hash, status, headers, body, created_at FROM idempotency_keys
+WHERE user_id = $1 AND key = $2
+`
+
+type GetIdempotencyKeyParams struct {
+	UserID int64
+	Key    string
+}
+
+func (q *Queries) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.db.QueryRow(ctx, GetIdempotencyKey, arg.UserID, arg.Key)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.UserID,
+		&i.Key,
+		&i.RequestHash,
+		&i.Status,
+		&i.Headers,
+		&i.Body,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const ReleaseIdempotencyKey = `-- name: ReleaseIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE user_id = $1 AND key = $2
+`
+
+type ReleaseIdempotencyKeyParams struct {
+	UserID int64
+	Key    string
+}
+
+// Frees the key of a request that failed, so that it can be retried.
+func (q *Queries) ReleaseIdempotencyKey(ctx context.Context, arg ReleaseIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, ReleaseIdempotencyKey, arg.UserID, arg.Key)
+	return err
+}
+
+const SaveIdempotentResponse = `-- name: SaveIdempotentResponse :exec
+UPDATE idempotency_keys
+SET status = $3, headers = $4, body = $5
+WHERE user_id = $1 AND key = $2
+`
+
+type SaveIdempotentResponseParams struct {
+	UserID  int64
+	Key     string
+	Status  *int32
+	Headers []byte
+	Body    []byte
+}
+
+func (q *Queries) SaveIdempotentResponse(ctx context.Context, arg SaveIdempotentResponseParams) error {
+	_, err := q.db.Exec(ctx, SaveIdempotentResponse,
+		arg.UserID,
+		arg.Key,
+		arg.Status,
+		arg.Headers,
+		arg.Body,
+	)
+	return err
+}
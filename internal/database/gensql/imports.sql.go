@@ -0,0 +1,144 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: imports.sql
+
+package gensql
+
+import (
+	"context"
+)
+
+const CreateImportBatch = `-- name: CreateImportBatch :one
+
+INSERT INTO import_batches (user_id, source, filename, row_count)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, source, filename, row_count, created_at
+`
+
+type CreateImportBatchParams struct {
+	UserID   int64
+	Source   string
+	Filename string
+	RowCount int32
+}
+
+// internal/database/queries/imports.sql
+func (q *Queries) CreateImportBatch(ctx context.Context, arg CreateImportBatchParams) (ImportBatch, error) {
+	row := q.db.QueryRow(ctx, CreateImportBatch,
+		arg.UserID,
+		arg.Source,
+		arg.Filename,
+		arg.RowCount,
+	)
+	var i ImportBatch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Source,
+		&i.Filename,
+		&i.RowCount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const DeleteImportMapping = `-- name: DeleteImportMapping :exec
+DELETE FROM import_mappings
+WHERE user_id = $1 AND bank = $2
+`
+
+type DeleteImportMappingParams struct {
+	UserID int64
+	Bank   string
+}
+
+func (q *Queries) DeleteImportMapping(ctx context.Context, arg DeleteImportMappingParams) error {
+	_, err := q.db.Exec(ctx, DeleteImportMapping, arg.UserID, arg.Bank)
+	return err
+}
+
+const GetImportMapping = `-- name: GetImportMapping :one
+SELECT id, user_id, bank, options, created_at, updated_at FROM import_mappings
+WHERE user_id = $1 AND bank = $2
+`
+
+type GetImportMappingParams struct {
+	UserID int64
+	Bank   string
+}
+
+func (q *Queries) GetImportMapping(ctx context.Context, arg GetImportMappingParams) (ImportMapping, error) {
+	row := q.db.QueryRow(ctx, GetImportMapping, arg.UserID, arg.Bank)
+	var i ImportMapping
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Bank,
+		&i.Options,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const ListImportMappings = `-- name: ListImportMappings :many
+SELECT id, user_id, bank, options, created_at, updated_at FROM import_mappings
+WHERE user_id = $1
+ORDER BY bank
+`
+
+func (q *Queries) ListImportMappings(ctx context.Context, userID int64) ([]ImportMapping, error) {
+	rows, err := q.db.Query(ctx, ListImportMappings, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ImportMapping
+	for rows.Next() {
+		var i ImportMapping
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Bank,
+			&i.Options,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const UpsertImportMapping = `-- name: UpsertImportMapping :one
+INSERT INTO import_mappings (user_id, bank, options)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, bank) DO UPDATE
+SET options = EXCLUDED.options, updated_at = NOW()
+RETURNING id, user_id, bank, options, created_at, updated_at
+`
+
+type UpsertImportMappingParams struct {
+	UserID  int64
+	Bank    string
+	Options []byte
+}
+
+func (q *Queries) UpsertImportMapping(ctx context.Context, arg UpsertImportMappingParams) (ImportMapping, error) {
+	row := q.db.QueryRow(ctx, UpsertImportMapping, arg.UserID, arg.Bank, arg.Options)
+	var i ImportMapping
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Bank,
+		&i.Options,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
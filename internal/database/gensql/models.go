@@ -8,23 +8,189 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-type Transaction struct {
-	ID          int64
+type Attachment struct {
+	ID            int64
+	UserID        int64
+	TransactionID int64
+	Filename      string
+	ContentType   string
+	Size          int64
+	Checksum      *string
+	StorageKey    string
+	ThumbnailKey  *string
+	CreatedAt     pgtype.Timestamptz
+}
+
+type IdempotencyKey struct {
 	UserID      int64
+	Key         string
+	RequestHash string
+	Status      *int32
+	Headers     []byte
+	Body        []byte
+	CreatedAt   pgtype.Timestamptz
+}
+
+type ImportBatch struct {
+	ID        int64
+	UserID    int64
+	Source    string
+	Filename  string
+	RowCount  int32
+	CreatedAt pgtype.Timestamptz
+}
+
+type ImportMapping struct {
+	ID        int64
+	UserID    int64
+	Bank      string
+	Options   []byte
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+}
+
+type Payee struct {
+	ID              int64
+	UserID          int64
+	Name            string
+	Aliases         []string
+	Patterns        []string
+	DefaultCategory *string
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+}
+
+type Reconciliation struct {
+	ID            int64
+	UserID        int64
+	Account       string
+	Currency      string
+	StatementDate pgtype.Date
+	EndingBalance pgtype.Numeric
+	LockedAt      pgtype.Timestamptz
+	CreatedAt     pgtype.Timestamptz
+}
+
+type RecurringException struct {
+	RecurringID int64
+	Occurrence  pgtype.Date
+	Skip        bool
+	Date        pgtype.Date
 	Amount      pgtype.Numeric
-	Description string
-	Category    string
-	Date        pgtype.Timestamptz
-	Type        string
-	Currency    string
-	Status      string
-	Account     string
-	Tags        []string
+	Description *string
+	Category    *string
 	Notes       *string
-	HasReceipt  bool
-	ReceiptUrl  *string
-	CreatedAt   pgtype.Timestamptz
-	UpdatedAt   pgtype.Timestamptz
+}
+
+type RecurringTransaction struct {
+	ID               int64
+	UserID           int64
+	Rrule            string
+	StartDate        pgtype.Date
+	Amount           pgtype.Numeric
+	Description      string
+	Category         string
+	Type             string
+	Currency         string
+	Account          string
+	Tags             []string
+	Notes            *string
+	Active           bool
+	GeneratedThrough pgtype.Date
+	CreatedAt        pgtype.Timestamptz
+	UpdatedAt        pgtype.Timestamptz
+}
+
+type Rule struct {
+	ID                 int64
+	UserID             int64
+	Name               string
+	Priority           int32
+	Enabled            bool
+	DescriptionPattern *string
+	MinAmount          pgtype.Numeric
+	MaxAmount          pgtype.Numeric
+	Account            *string
+	Currency           *string
+	Type               *string
+	SetCategory        *string
+	AddTags            []string
+	SetNotes           *string
+	SetStatus          *string
+	CreatedAt          pgtype.Timestamptz
+	UpdatedAt          pgtype.Timestamptz
+}
+
+type StorageDeletion struct {
+	StorageKey string
+	QueuedAt   pgtype.Timestamptz
+}
+
+type Transaction struct {
+	ID                  int64
+	UserID              int64
+	Amount              pgtype.Numeric
+	Description         string
+	Category            string
+	Date                pgtype.Timestamptz
+	Type                string
+	Currency            string
+	Status              string
+	Account             string
+	Tags                []string
+	Notes               *string
+	HasReceipt          bool
+	ReceiptUrl          *string
+	CreatedAt           pgtype.Timestamptz
+	UpdatedAt           pgtype.Timestamptz
+	ImportBatchID       *int64
+	ExternalID          *string
+	Fingerprint         string
+	TransferID          *int64
+	TransferDirection   *string
+	RecurringID         *int64
+	RecurringOccurrence pgtype.Date
+	DeletedAt           pgtype.Timestamptz
+	Version             int64
+	ReconciliationID    *int64
+	PayeeID             *int64
+}
+
+type TransactionLine struct {
+	TransactionID int64
+	SplitID       *int64
+	Amount        pgtype.Numeric
+	Category      string
+	Tags          []string
+}
+
+type TransactionRevision struct {
+	ID            int64
+	TransactionID int64
+	UserID        int64
+	Action        string
+	Actor         string
+	ActorID       *int64
+	OldValues     []byte
+	NewValues     []byte
+	CreatedAt     pgtype.Timestamptz
+}
+
+type TransactionSplit struct {
+	ID            int64
+	TransactionID int64
+	Position      int32
+	Amount        pgtype.Numeric
+	Category      string
+	Tags          []string
+	Notes         *string
+}
+
+type Transfer struct {
+	ID        int64
+	UserID    int64
+	Rate      pgtype.Numeric
+	CreatedAt pgtype.Timestamptz
 }
 
 type User struct {
@@ -0,0 +1,475 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: payees.sql
+
+package gensql
+
+import (
+	"context"
+)
+
+const CategorizePayeeTransactions = `-- name: CategorizePayeeTransactions :execrows
+UPDATE transactions
+SET category = $1, updated_at = NOW()
+WHERE user_id = $2 AND payee_id = $3
+  AND category = $4 AND deleted_at IS NULL
+`
+
+type CategorizePayeeTransactionsParams struct {
+	Category      string
+	UserID        int64
+	PayeeID       *int64
+	Uncategorized string
+}
+
+// Gives the payee's uncategorized transactions its default category.
+func (q *Queries) CategorizePayeeTransactions(ctx context.Context, arg CategorizePayeeTransactionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, CategorizePayeeTransactions,
+		arg.Category,
+		arg.UserID,
+		arg.PayeeID,
+		arg.Uncategorized,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const CountPayees = `-- name: CountPayees :one
+SELECT COUNT(*) FROM payees
+WHERE user_id = $1
+`
+
+func (q *Queries) CountPayees(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, CountPayees, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountUnlinkedTransactions = `-- name: CountUnlinkedTransactions :one
+SELECT COUNT(*) FROM transactions
+WHERE user_id = $1 AND id > $2::bigint
+  AND payee_id IS NULL AND deleted_at IS NULL
+`
+
+type CountUnlinkedTransactionsParams struct {
+	UserID int64
+	After  int64
+}
+
+func (q *Queries) CountUnlinkedTransactions(ctx context.Context, arg CountUnlinkedTransactionsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CountUnlinkedTransactions, arg.UserID, arg.After)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CreatePayee = `-- name: CreatePayee :one
+
+INSERT INTO payees (user_id, name, aliases, patterns, default_category)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, name, aliases, patterns, default_category, created_at, updated_at
+`
+
+type CreatePayeeParams struct {
+	UserID          int64
+	Name            string
+	Aliases         []string
+	Patterns        []string
+	DefaultCategory *string
+}
+
+// internal/database/queries/payees.sql
+func (q *Queries) CreatePayee(ctx context.Context, arg CreatePayeeParams) (Payee, error) {
+	row := q.db.QueryRow(ctx, CreatePayee,
+		arg.UserID,
+		arg.Name,
+		arg.Aliases,
+		arg.Patterns,
+		arg.DefaultCategory,
+	)
+	var i Payee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Aliases,
+		&i.Patterns,
+		&i.DefaultCategory,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const DeletePayee = `-- name: DeletePayee :execrows
+DELETE FROM payees
+WHERE id = $1 AND user_id = $2
+`
+
+type DeletePayeeParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) DeletePayee(ctx context.Context, arg DeletePayeeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeletePayee, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const DeletePayees = `-- name: DeletePayees :execrows
+DELETE FROM payees
+WHERE user_id = $1 AND id = ANY($2::bigint[])
+`
+
+type DeletePayeesParams struct {
+	UserID int64
+	Ids    []int64
+}
+
+func (q *Queries) DeletePayees(ctx context.Context, arg DeletePayeesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeletePayees, arg.UserID, arg.Ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const EnsurePayee = `-- name: EnsurePayee :one
+INSERT INTO payees (user_id, name)
+VALUES ($1, $2)
+ON CONFLICT (user_id, lower(name)) DO UPDATE SET name = payees.name
+RETURNING id, user_id, name, aliases, patterns, default_category, created_at, updated_at
+`
+
+type EnsurePayeeParams struct {
+	UserID int64
+	Name   string
+}
+
+// Returns the user's payee with the name, creating it if needed.
+func (q *Queries) EnsurePayee(ctx context.Context, arg EnsurePayeeParams) (Payee, error) {
+	row := q.db.QueryRow(ctx, EnsurePayee, arg.UserID, arg.Name)
+	var i Payee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Aliases,
+		&i.Patterns,
+		&i.DefaultCategory,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetPayee = `-- name: GetPayee :one
+SELECT id, user_id, name, aliases, patterns, default_category, created_at, updated_at FROM payees
+WHERE id = $1 AND user_id = $2
+`
+
+type GetPayeeParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) GetPayee(ctx context.Context, arg GetPayeeParams) (Payee, error) {
+	row := q.db.QueryRow(ctx, GetPayee, arg.ID, arg.UserID)
+	var i Payee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Aliases,
+		&i.Patterns,
+		&i.DefaultCategory,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const LinkTransactionPayee = `-- name: LinkTransactionPayee :exec
+UPDATE transactions
+SET payee_id = $3, category = $4, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type LinkTransactionPayeeParams struct {
+	ID       int64
+	UserID   int64
+	PayeeID  *int64
+	Category string
+}
+
+func (q *Queries) LinkTransactionPayee(ctx context.Context, arg LinkTransactionPayeeParams) error {
+	_, err := q.db.Exec(ctx, LinkTransactionPayee,
+		arg.ID,
+		arg.UserID,
+		arg.PayeeID,
+		arg.Category,
+	)
+	return err
+}
+
+const ListAllPayees = `-- name: ListAllPayees :many
+SELECT id, user_id, name, aliases, patterns, default_category, created_at, updated_at FROM payees
+WHERE user_id = $1
+ORDER BY id
+`
+
+// Payees in the order their patterns are tried.
+func (q *Queries) ListAllPayees(ctx context.Context, userID int64) ([]Payee, error) {
+	rows, err := q.db.Query(ctx, ListAllPayees, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Payee
+	for rows.Next() {
+		var i Payee
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Aliases,
+			&i.Patterns,
+			&i.DefaultCategory,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListPayees = `-- name: ListPayees :many
+SELECT id, user_id, name, aliases, patterns, default_category, created_at, updated_at FROM payees
+WHERE user_id = $1
+ORDER BY lower(name), id
+LIMIT $2 OFFSET $3
+`
+
+type ListPayeesParams struct {
+	UserID int64
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListPayees(ctx context.Context, arg ListPayeesParams) ([]Payee, error) {
+	rows, err := q.db.Query(ctx, ListPayees, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Payee
+	for rows.Next() {
+		var i Payee
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Aliases,
+			&i.Patterns,
+			&i.DefaultCategory,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListUnlinkedTransactions = `-- name: ListUnlinkedTransactions :many
+SELECT id, description, category FROM transactions
+WHERE user_id = $1 AND id > $2::bigint
+  AND payee_id IS NULL AND deleted_at IS NULL
+ORDER BY id
+LIMIT $3
+`
+
+type ListUnlinkedTransactionsParams struct {
+	UserID int64
+	After  int64
+	Limit  int32
+}
+
+type ListUnlinkedTransactionsRow struct {
+	ID          int64
+	Description string
+	Category    string
+}
+
+// Transactions not linked to a payee yet, oldest first.
+func (q *Queries) ListUnlinkedTransactions(ctx context.Context, arg ListUnlinkedTransactionsParams) ([]ListUnlinkedTransactionsRow, error) {
+	rows, err := q.db.Query(ctx, ListUnlinkedTransactions, arg.UserID, arg.After, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnlinkedTransactionsRow
+	for rows.Next() {
+		var i ListUnlinkedTransactionsRow
+		if err := rows.Scan(&i.ID, &i.Description, &i.Category); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const LockPayees = `-- name: LockPayees :many
+SELECT id, user_id, name, aliases, patterns, default_category, created_at, updated_at FROM payees
+WHERE user_id = $1 AND id = ANY($2::bigint[])
+ORDER BY id
+FOR UPDATE
+`
+
+type LockPayeesParams struct {
+	UserID int64
+	Ids    []int64
+}
+
+func (q *Queries) LockPayees(ctx context.Context, arg LockPayeesParams) ([]Payee, error) {
+	rows, err := q.db.Query(ctx, LockPayees, arg.UserID, arg.Ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Payee
+	for rows.Next() {
+		var i Payee
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Aliases,
+			&i.Patterns,
+			&i.DefaultCategory,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ReassignPayeeTransactions = `-- name: ReassignPayeeTransactions :execrows
+UPDATE transactions
+SET payee_id = $1, updated_at = NOW()
+WHERE user_id = $2 AND payee_id = ANY($3::bigint[])
+`
+
+type ReassignPayeeTransactionsParams struct {
+	PayeeID *int64
+	UserID  int64
+	Ids     []int64
+}
+
+// Moves the transactions of merged payees, including deleted ones, to the
+// payee they were merged into.
+func (q *Queries) ReassignPayeeTransactions(ctx context.Context, arg ReassignPayeeTransactionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, ReassignPayeeTransactions, arg.PayeeID, arg.UserID, arg.Ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const SetPayeeDefaultCategory = `-- name: SetPayeeDefaultCategory :one
+UPDATE payees
+SET default_category = $3, updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, aliases, patterns, default_category, created_at, updated_at
+`
+
+type SetPayeeDefaultCategoryParams struct {
+	ID              int64
+	UserID          int64
+	DefaultCategory *string
+}
+
+func (q *Queries) SetPayeeDefaultCategory(ctx context.Context, arg SetPayeeDefaultCategoryParams) (Payee, error) {
+	row := q.db.QueryRow(ctx, SetPayeeDefaultCategory, arg.ID, arg.UserID, arg.DefaultCategory)
+	var i Payee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Aliases,
+		&i.Patterns,
+		&i.DefaultCategory,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const UpdatePayee = `-- name: UpdatePayee :one
+UPDATE payees
+SET
+  name = $3,
+  aliases = $4,
+  patterns = $5,
+  default_category = $6,
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, aliases, patterns, default_category, created_at, updated_at
+`
+
+type UpdatePayeeParams struct {
+	ID              int64
+	UserID          int64
+	Name            string
+	Aliases         []string
+	Patterns        []string
+	DefaultCategory *string
+}
+
+func (q *Queries) UpdatePayee(ctx context.Context, arg UpdatePayeeParams) (Payee, error) {
+	row := q.db.QueryRow(ctx, UpdatePayee,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.Aliases,
+		arg.Patterns,
+		arg.DefaultCategory,
+	)
+	var i Payee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Aliases,
+		&i.Patterns,
+		&i.DefaultCategory,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,456 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: reconciliations.sql
+
+package gensql
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const CloseReconciliation = `-- name: CloseReconciliation :one
+UPDATE reconciliations
+SET locked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND locked_at IS NULL
+RETURNING id, user_id, account, currency, statement_date, ending_balance, locked_at, created_at
+`
+
+type CloseReconciliationParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) CloseReconciliation(ctx context.Context, arg CloseReconciliationParams) (Reconciliation, error) {
+	row := q.db.QueryRow(ctx, CloseReconciliation, arg.ID, arg.UserID)
+	var i Reconciliation
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Account,
+		&i.Currency,
+		&i.StatementDate,
+		&i.EndingBalance,
+		&i.LockedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const CountReconciliations = `-- name: CountReconciliations :one
+SELECT COUNT(*) FROM reconciliations
+WHERE user_id = $1
+  AND ($2::text IS NULL OR account = $2)
+`
+
+type CountReconciliationsParams struct {
+	UserID  int64
+	Account *string
+}
+
+func (q *Queries) CountReconciliations(ctx context.Context, arg CountReconciliationsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CountReconciliations, arg.UserID, arg.Account)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CreateReconciliation = `-- name: CreateReconciliation :one
+
+INSERT INTO reconciliations (user_id, account, currency, statement_date, ending_balance)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, account, currency, statement_date, ending_balance, locked_at, created_at
+`
+
+type CreateReconciliationParams struct {
+	UserID        int64
+	Account       string
+	Currency      string
+	StatementDate pgtype.Date
+	EndingBalance pgtype.Numeric
+}
+
+// internal/database/queries/reconciliations.sql
+func (q *Queries) CreateReconciliation(ctx context.Context, arg CreateReconciliationParams) (Reconciliation, error) {
+	row := q.db.QueryRow(ctx, CreateReconciliation,
+		arg.UserID,
+		arg.Account,
+		arg.Currency,
+		arg.StatementDate,
+		arg.EndingBalance,
+	)
+	var i Reconciliation
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Account,
+		&i.Currency,
+		&i.StatementDate,
+		&i.EndingBalance,
+		&i.LockedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const DeleteReconciliation = `-- name: DeleteReconciliation :execrows
+DELETE FROM reconciliations
+WHERE id = $1 AND user_id = $2 AND locked_at IS NULL
+`
+
+type DeleteReconciliationParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) DeleteReconciliation(ctx context.Context, arg DeleteReconciliationParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteReconciliation, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const GetReconciliation = `-- name: GetReconciliation :one
+SELECT id, user_id, account, currency, statement_date, ending_balance, locked_at, created_at FROM reconciliations
+WHERE id = $1 AND user_id = $2
+`
+
+type GetReconciliationParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) GetReconciliation(ctx context.Context, arg GetReconciliationParams) (Reconciliation, error) {
+	row := q.db.QueryRow(ctx, GetReconciliation, arg.ID, arg.UserID)
+	var i Reconciliation
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Account,
+		&i.Currency,
+		&i.StatementDate,
+		&i.EndingBalance,
+		&i.LockedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const GetReconciliationBalances = `-- name: GetReconciliationBalances :one
+SELECT
+  COALESCE(SUM(CASE WHEN t.type = 'expense' OR t.transfer_direction = 'out' THEN -t.amount ELSE t.amount END)
+    FILTER (WHERE t.status = 'reconciled' AND (t.reconciliation_id IS NULL OR t.reconciliation_id < r.id)), 0)::numeric AS opening_balance,
+  COALESCE(SUM(CASE WHEN t.type = 'expense' OR t.transfer_direction = 'out' THEN -t.amount ELSE t.amount END)
+    FILTER (WHERE (t.status = 'reconciled' AND (t.reconciliation_id IS NULL OR t.reconciliation_id <= r.id))
+      OR (r.locked_at IS NULL AND t.status = 'cleared' AND (t.date AT TIME ZONE 'UTC')::date <= r.statement_date)), 0)::numeric AS cleared_balance,
+  (r.ending_balance - COALESCE(SUM(CASE WHEN t.type = 'expense' OR t.transfer_direction = 'out' THEN -t.amount ELSE t.amount END)
+    FILTER (WHERE (t.status = 'reconciled' AND (t.reconciliation_id IS NULL OR t.reconciliation_id <= r.id))
+      OR (r.locked_at IS NULL AND t.status = 'cleared' AND (t.date AT TIME ZONE 'UTC')::date <= r.statement_date)), 0))::numeric AS difference
+FROM reconciliations r
+LEFT JOIN transactions t
+  ON t.user_id = r.user_id AND t.account = r.account AND t.currency = r.currency AND t.deleted_at IS NULL
+WHERE r.id = $1 AND r.user_id = $2
+GROUP BY r.id
+`
+
+type GetReconciliationBalancesParams struct {
+	ID     int64
+	UserID int64
+}
+
+type GetReconciliationBalancesRow struct {
+	OpeningBalance pgtype.Numeric
+	ClearedBalance pgtype.Numeric
+	Difference     pgtype.Numeric
+}
+
+// The opening balance adds up the transactions reconciled before this
+// statement. The cleared balance adds the transactions this statement
+// reconciled or, while it is open, the cleared ones up to its date.
+func (q *Queries) GetReconciliationBalances(ctx context.Context, arg GetReconciliationBalancesParams) (GetReconciliationBalancesRow, error) {
+	row := q.db.QueryRow(ctx, GetReconciliationBalances, arg.ID, arg.UserID)
+	var i GetReconciliationBalancesRow
+	err := row.Scan(&i.OpeningBalance, &i.ClearedBalance, &i.Difference)
+	return i, err
+}
+
+const ListReconciliationTransactions = `-- name: ListReconciliationTransactions :many
+SELECT t.id, t.user_id, t.amount, t.description, t.category, t.date, t.type, t.currency, t.status, t.account, t.tags, t.notes, t.has_receipt, t.receipt_url, t.created_at, t.updated_at, t.import_batch_id, t.external_id, t.fingerprint, t.transfer_id, t.transfer_direction, t.recurring_id, t.recurring_occurrence, t.deleted_at, t.version, t.reconciliation_id, t.payee_id FROM transactions t
+JOIN reconciliations r ON r.user_id = t.user_id
+WHERE r.id = $1 AND r.user_id = $2 AND t.deleted_at IS NULL
+  AND (
+    t.reconciliation_id = r.id
+    OR (r.locked_at IS NULL AND t.account = r.account AND t.currency = r.currency
+      AND t.status <> 'reconciled' AND (t.date AT TIME ZONE 'UTC')::date <= r.statement_date)
+  )
+ORDER BY t.date, t.id
+`
+
+type ListReconciliationTransactionsParams struct {
+	ID     int64
+	UserID int64
+}
+
+// The transactions a locked statement reconciled or, while it is open, the
+// unreconciled ones of its account up to its date.
+func (q *Queries) ListReconciliationTransactions(ctx context.Context, arg ListReconciliationTransactionsParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, ListReconciliationTransactions, arg.ID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.Description,
+			&i.Category,
+			&i.Date,
+			&i.Type,
+			&i.Currency,
+			&i.Status,
+			&i.Account,
+			&i.Tags,
+			&i.Notes,
+			&i.HasReceipt,
+			&i.ReceiptUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ImportBatchID,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.TransferID,
+			&i.TransferDirection,
+			&i.RecurringID,
+			&i.RecurringOccurrence,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ReconciliationID,
+			&i.PayeeID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListReconciliations = `-- name: ListReconciliations :many
+SELECT id, user_id, account, currency, statement_date, ending_balance, locked_at, created_at FROM reconciliations
+WHERE user_id = $1
+  AND ($2::text IS NULL OR account = $2)
+ORDER BY statement_date DESC, id DESC
+LIMIT $4 OFFSET $3
+`
+
+type ListReconciliationsParams struct {
+	UserID  int64
+	Account *string
+	Offset  int32
+	Limit   int32
+}
+
+func (q *Queries) ListReconciliations(ctx context.Context, arg ListReconciliationsParams) ([]Reconciliation, error) {
+	rows, err := q.db.Query(ctx, ListReconciliations,
+		arg.UserID,
+		arg.Account,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Reconciliation
+	for rows.Next() {
+		var i Reconciliation
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Account,
+			&i.Currency,
+			&i.StatementDate,
+			&i.EndingBalance,
+			&i.LockedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const LockReconciliation = `-- name: LockReconciliation :one
+SELECT id, user_id, account, currency, statement_date, ending_balance, locked_at, created_at FROM reconciliations
+WHERE id = $1 AND user_id = $2
+FOR UPDATE
+`
+
+type LockReconciliationParams struct {
+	ID     int64
+	UserID int64
+}
+
+// Keeps the statement from changing until the end of the transaction.
+func (q *Queries) LockReconciliation(ctx context.Context, arg LockReconciliationParams) (Reconciliation, error) {
+	row := q.db.QueryRow(ctx, LockReconciliation, arg.ID, arg.UserID)
+	var i Reconciliation
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Account,
+		&i.Currency,
+		&i.StatementDate,
+		&i.EndingBalance,
+		&i.LockedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const ReconcileTransactions = `-- name: ReconcileTransactions :execrows
+UPDATE transactions t
+SET status = 'reconciled', reconciliation_id = r.id
+FROM reconciliations r
+WHERE r.id = $1 AND r.user_id = $2 AND r.locked_at IS NULL
+  AND t.user_id = r.user_id AND t.account = r.account AND t.currency = r.currency
+  AND t.deleted_at IS NULL AND t.status = 'cleared'
+  AND (t.date AT TIME ZONE 'UTC')::date <= r.statement_date
+`
+
+type ReconcileTransactionsParams struct {
+	ID     int64
+	UserID int64
+}
+
+// Reconciles the cleared transactions of an open statement.
+func (q *Queries) ReconcileTransactions(ctx context.Context, arg ReconcileTransactionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, ReconcileTransactions, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const SetReconciliationMatches = `-- name: SetReconciliationMatches :many
+UPDATE transactions t
+SET status = $1
+FROM reconciliations r
+WHERE r.id = $2 AND r.user_id = $3 AND r.locked_at IS NULL
+  AND t.user_id = r.user_id AND t.id = ANY($4::bigint[])
+  AND t.account = r.account AND t.currency = r.currency AND t.deleted_at IS NULL
+  AND t.status <> 'reconciled' AND (t.date AT TIME ZONE 'UTC')::date <= r.statement_date
+RETURNING t.id, t.user_id, t.amount, t.description, t.category, t.date, t.type, t.currency, t.status, t.account, t.tags, t.notes, t.has_receipt, t.receipt_url, t.created_at, t.updated_at, t.import_batch_id, t.external_id, t.fingerprint, t.transfer_id, t.transfer_direction, t.recurring_id, t.recurring_occurrence, t.deleted_at, t.version, t.reconciliation_id, t.payee_id
+`
+
+type SetReconciliationMatchesParams struct {
+	Status           string
+	ReconciliationID int64
+	UserID           int64
+	Ids              []int64
+}
+
+// Sets the status of unreconciled transactions of an open statement,
+// cleared when they match it and pending otherwise. Transactions outside
+// the statement are left out.
+func (q *Queries) SetReconciliationMatches(ctx context.Context, arg SetReconciliationMatchesParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, SetReconciliationMatches,
+		arg.Status,
+		arg.ReconciliationID,
+		arg.UserID,
+		arg.Ids,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.Description,
+			&i.Category,
+			&i.Date,
+			&i.Type,
+			&i.Currency,
+			&i.Status,
+			&i.Account,
+			&i.Tags,
+			&i.Notes,
+			&i.HasReceipt,
+			&i.ReceiptUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ImportBatchID,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.TransferID,
+			&i.TransferDirection,
+			&i.RecurringID,
+			&i.RecurringOccurrence,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ReconciliationID,
+			&i.PayeeID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const UpdateReconciliation = `-- name: UpdateReconciliation :one
+UPDATE reconciliations
+SET statement_date = $3, ending_balance = $4
+WHERE id = $1 AND user_id = $2 AND locked_at IS NULL
+RETURNING id, user_id, account, currency, statement_date, ending_balance, locked_at, created_at
+`
+
+type UpdateReconciliationParams struct {
+	ID            int64
+	UserID        int64
+	StatementDate pgtype.Date
+	EndingBalance pgtype.Numeric
+}
+
+// Only open reconciliations can change.
+func (q *Queries) UpdateReconciliation(ctx context.Context, arg UpdateReconciliationParams) (Reconciliation, error) {
+	row := q.db.QueryRow(ctx, UpdateReconciliation,
+		arg.ID,
+		arg.UserID,
+		arg.StatementDate,
+		arg.EndingBalance,
+	)
+	var i Reconciliation
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Account,
+		&i.Currency,
+		&i.StatementDate,
+		&i.EndingBalance,
+		&i.LockedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
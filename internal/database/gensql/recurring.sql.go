@@ -0,0 +1,584 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: recurring.sql
+
+package gensql
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const CreateRecurring = `-- name: CreateRecurring :one
+
+INSERT INTO recurring_transactions (
+  user_id, rrule, start_date, amount, description, category, type,
+  currency, account, tags, notes, active
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING id, user_id, rrule, start_date, amount, description, category, type, currency, account, tags, notes, active, generated_through, created_at, updated_at
+`
+
+type CreateRecurringParams struct {
+	UserID      int64
+	Rrule       string
+	StartDate   pgtype.Date
+	Amount      pgtype.Numeric
+	Description string
+	Category    string
+	Type        string
+	Currency    string
+	Account     string
+	Tags        []string
+	Notes       *string
+	Active      bool
+}
+
+// internal/database/queries/recurring.sql
+func (q *Queries) CreateRecurring(ctx context.Context, arg CreateRecurringParams) (RecurringTransaction, error) {
+	row := q.db.QueryRow(ctx, CreateRecurring,
+		arg.UserID,
+		arg.Rrule,
+		arg.StartDate,
+		arg.Amount,
+		arg.Description,
+		arg.Category,
+		arg.Type,
+		arg.Currency,
+		arg.Account,
+		arg.Tags,
+		arg.Notes,
+		arg.Active,
+	)
+	var i RecurringTransaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Rrule,
+		&i.StartDate,
+		&i.Amount,
+		&i.Description,
+		&i.Category,
+		&i.Type,
+		&i.Currency,
+		&i.Account,
+		&i.Tags,
+		&i.Notes,
+		&i.Active,
+		&i.GeneratedThrough,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const CreateRecurringTransaction = `-- name: CreateRecurringTransaction :execrows
+INSERT INTO transactions (
+  user_id, recurring_id, recurring_occurrence, amount, description,
+  category, type, currency, status, account, tags, notes, date
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, 'pending', $9, $10, $11, $12
+)
+ON CONFLICT (recurring_id, recurring_occurrence) DO NOTHING
+`
+
+type CreateRecurringTransactionParams struct {
+	UserID              int64
+	RecurringID         *int64
+	RecurringOccurrence pgtype.Date
+	Amount              pgtype.Numeric
+	Description         string
+	Category            string
+	Type                string
+	Currency            string
+	Account             string
+	Tags                []string
+	Notes               *string
+	Date                pgtype.Timestamptz
+}
+
+// Creates the transaction for an occurrence unless it already exists.
+func (q *Queries) CreateRecurringTransaction(ctx context.Context, arg CreateRecurringTransactionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, CreateRecurringTransaction,
+		arg.UserID,
+		arg.RecurringID,
+		arg.RecurringOccurrence,
+		arg.Amount,
+		arg.Description,
+		arg.Category,
+		arg.Type,
+		arg.Currency,
+		arg.Account,
+		arg.Tags,
+		arg.Notes,
+		arg.Date,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const DeleteRecurring = `-- name: DeleteRecurring :execrows
+DELETE FROM recurring_transactions
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteRecurringParams struct {
+	ID     int64
+	UserID int64
+}
+
+// Transactions already created from the template are kept.
+func (q *Queries) DeleteRecurring(ctx context.Context, arg DeleteRecurringParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteRecurring, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const DeleteRecurringException = `-- name: DeleteRecurringException :execrows
+DELETE FROM recurring_exceptions
+WHERE recurring_id = $1 AND occurrence = $2
+`
+
+type DeleteRecurringExceptionParams struct {
+	RecurringID int64
+	Occurrence  pgtype.Date
+}
+
+func (q *Queries) DeleteRecurringException(ctx context.Context, arg DeleteRecurringExceptionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteRecurringException, arg.RecurringID, arg.Occurrence)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const GetRecurring = `-- name: GetRecurring :one
+SELECT id, user_id, rrule, start_date, amount, description, category, type, currency, account, tags, notes, active, generated_through, created_at, updated_at FROM recurring_transactions
+WHERE id = $1 AND user_id = $2
+`
+
+type GetRecurringParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) GetRecurring(ctx context.Context, arg GetRecurringParams) (RecurringTransaction, error) {
+	row := q.db.QueryRow(ctx, GetRecurring, arg.ID, arg.UserID)
+	var i RecurringTransaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Rrule,
+		&i.StartDate,
+		&i.Amount,
+		&i.Description,
+		&i.Category,
+		&i.Type,
+		&i.Currency,
+		&i.Account,
+		&i.Tags,
+		&i.Notes,
+		&i.Active,
+		&i.GeneratedThrough,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const ListActiveRecurring = `-- name: ListActiveRecurring :many
+SELECT id, user_id, rrule, start_date, amount, description, category, type, currency, account, tags, notes, active, generated_through, created_at, updated_at FROM recurring_transactions
+WHERE user_id = $1 AND active
+ORDER BY created_at, id
+`
+
+func (q *Queries) ListActiveRecurring(ctx context.Context, userID int64) ([]RecurringTransaction, error) {
+	rows, err := q.db.Query(ctx, ListActiveRecurring, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RecurringTransaction
+	for rows.Next() {
+		var i RecurringTransaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Rrule,
+			&i.StartDate,
+			&i.Amount,
+			&i.Description,
+			&i.Category,
+			&i.Type,
+			&i.Currency,
+			&i.Account,
+			&i.Tags,
+			&i.Notes,
+			&i.Active,
+			&i.GeneratedThrough,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDueRecurringIDs = `-- name: ListDueRecurringIDs :many
+SELECT id FROM recurring_transactions
+WHERE active AND (generated_through IS NULL OR generated_through < $1)
+ORDER BY id
+`
+
+// Active templates of all users that have not been generated up to the date.
+func (q *Queries) ListDueRecurringIDs(ctx context.Context, generatedThrough pgtype.Date) ([]int64, error) {
+	rows, err := q.db.Query(ctx, ListDueRecurringIDs, generatedThrough)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListRecurring = `-- name: ListRecurring :many
+SELECT id, user_id, rrule, start_date, amount, description, category, type, currency, account, tags, notes, active, generated_through, created_at, updated_at FROM recurring_transactions
+WHERE user_id = $1
+ORDER BY created_at, id
+`
+
+func (q *Queries) ListRecurring(ctx context.Context, userID int64) ([]RecurringTransaction, error) {
+	rows, err := q.db.Query(ctx, ListRecurring, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RecurringTransaction
+	for rows.Next() {
+		var i RecurringTransaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Rrule,
+			&i.StartDate,
+			&i.Amount,
+			&i.Description,
+			&i.Category,
+			&i.Type,
+			&i.Currency,
+			&i.Account,
+			&i.Tags,
+			&i.Notes,
+			&i.Active,
+			&i.GeneratedThrough,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListRecurringExceptions = `-- name: ListRecurringExceptions :many
+SELECT e.recurring_id, e.occurrence, e.skip, e.date, e.amount, e.description, e.category, e.notes FROM recurring_exceptions e
+JOIN recurring_transactions r ON r.id = e.recurring_id
+WHERE r.user_id = $1
+  AND e.recurring_id = ANY($2::bigint[])
+  AND e.occurrence BETWEEN $3 AND $4
+ORDER BY e.recurring_id, e.occurrence
+`
+
+type ListRecurringExceptionsParams struct {
+	UserID       int64
+	RecurringIds []int64
+	DateFrom     pgtype.Date
+	DateTo       pgtype.Date
+}
+
+func (q *Queries) ListRecurringExceptions(ctx context.Context, arg ListRecurringExceptionsParams) ([]RecurringException, error) {
+	rows, err := q.db.Query(ctx, ListRecurringExceptions,
+		arg.UserID,
+		arg.RecurringIds,
+		arg.DateFrom,
+		arg.DateTo,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RecurringException
+	for rows.Next() {
+		var i RecurringException
+		if err := rows.Scan(
+			&i.RecurringID,
+			&i.Occurrence,
+			&i.Skip,
+			&i.Date,
+			&i.Amount,
+			&i.Description,
+			&i.Category,
+			&i.Notes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListRecurringTransactions = `-- name: ListRecurringTransactions :many
+SELECT id, recurring_id, recurring_occurrence FROM transactions
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND recurring_id = ANY($2::bigint[])
+  AND recurring_occurrence BETWEEN $3 AND $4
+`
+
+type ListRecurringTransactionsParams struct {
+	UserID       int64
+	RecurringIds []int64
+	DateFrom     pgtype.Date
+	DateTo       pgtype.Date
+}
+
+type ListRecurringTransactionsRow struct {
+	ID                  int64
+	RecurringID         *int64
+	RecurringOccurrence pgtype.Date
+}
+
+// Transactions created for the given templates' occurrences in a range.
+func (q *Queries) ListRecurringTransactions(ctx context.Context, arg ListRecurringTransactionsParams) ([]ListRecurringTransactionsRow, error) {
+	rows, err := q.db.Query(ctx, ListRecurringTransactions,
+		arg.UserID,
+		arg.RecurringIds,
+		arg.DateFrom,
+		arg.DateTo,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRecurringTransactionsRow
+	for rows.Next() {
+		var i ListRecurringTransactionsRow
+		if err := rows.Scan(&i.ID, &i.RecurringID, &i.RecurringOccurrence); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const LockRecurring = `-- name: LockRecurring :one
+SELECT id, user_id, rrule, start_date, amount, description, category, type, currency, account, tags, notes, active, generated_through, created_at, updated_at FROM recurring_transactions
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockRecurring(ctx context.Context, id int64) (RecurringTransaction, error) {
+	row := q.db.QueryRow(ctx, LockRecurring, id)
+	var i RecurringTransaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Rrule,
+		&i.StartDate,
+		&i.Amount,
+		&i.Description,
+		&i.Category,
+		&i.Type,
+		&i.Currency,
+		&i.Account,
+		&i.Tags,
+		&i.Notes,
+		&i.Active,
+		&i.GeneratedThrough,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const SetRecurringGeneratedThrough = `-- name: SetRecurringGeneratedThrough :exec
+UPDATE recurring_transactions
+SET generated_through = $2
+WHERE id = $1
+`
+
+type SetRecurringGeneratedThroughParams struct {
+	ID               int64
+	GeneratedThrough pgtype.Date
+}
+
+func (q *Queries) SetRecurringGeneratedThrough(ctx context.Context, arg SetRecurringGeneratedThroughParams) error {
+	_, err := q.db.Exec(ctx, SetRecurringGeneratedThrough, arg.ID, arg.GeneratedThrough)
+	return err
+}
+
+const UpdateRecurring = `-- name: UpdateRecurring :one
+UPDATE recurring_transactions
+SET
+  rrule = $3,
+  start_date = $4,
+  amount = $5,
+  description = $6,
+  category = $7,
+  type = $8,
+  currency = $9,
+  account = $10,
+  tags = $11,
+  notes = $12,
+  active = $13,
+  generated_through = GREATEST(generated_through, $14),
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, rrule, start_date, amount, description, category, type, currency, account, tags, notes, active, generated_through, created_at, updated_at
+`
+
+type UpdateRecurringParams struct {
+	ID               int64
+	UserID           int64
+	Rrule            string
+	StartDate        pgtype.Date
+	Amount           pgtype.Numeric
+	Description      string
+	Category         string
+	Type             string
+	Currency         string
+	Account          string
+	Tags             []string
+	Notes            *string
+	Active           bool
+	GeneratedThrough pgtype.Date
+}
+
+// generated_through is only moved forward, so occurrences that were already
+// created are never created again.
+func (q *Queries) UpdateRecurring(ctx context.Context, arg UpdateRecurringParams) (RecurringTransaction, error) {
+	row := q.db.QueryRow(ctx, UpdateRecurring,
+		arg.ID,
+		arg.UserID,
+		arg.Rrule,
+		arg.StartDate,
+		arg.Amount,
+		arg.Description,
+		arg.Category,
+		arg.Type,
+		arg.Currency,
+		arg.Account,
+		arg.Tags,
+		arg.Notes,
+		arg.Active,
+		arg.GeneratedThrough,
+	)
+	var i RecurringTransaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Rrule,
+		&i.StartDate,
+		&i.Amount,
+		&i.Description,
+		&i.Category,
+		&i.Type,
+		&i.Currency,
+		&i.Account,
+		&i.Tags,
+		&i.Notes,
+		&i.Active,
+		&i.GeneratedThrough,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const UpsertRecurringException = `-- name: UpsertRecurringException :one
+INSERT INTO recurring_exceptions (
+  recurring_id, occurrence, skip, date, amount, description, category, notes
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+ON CONFLICT (recurring_id, occurrence) DO UPDATE
+SET
+  skip = EXCLUDED.skip,
+  date = EXCLUDED.date,
+  amount = EXCLUDED.amount,
+  description = EXCLUDED.description,
+  category = EXCLUDED.category,
+  notes = EXCLUDED.notes
+RETURNING recurring_id, occurrence, skip, date, amount, description, category, notes
+`
+
+type UpsertRecurringExceptionParams struct {
+	RecurringID int64
+	Occurrence  pgtype.Date
+	Skip        bool
+	Date        pgtype.Date
+	Amount      pgtype.Numeric
+	Description *string
+	Category    *string
+	Notes       *string
+}
+
+func (q *Queries) UpsertRecurringException(ctx context.Context, arg UpsertRecurringExceptionParams) (RecurringException, error) {
+	row := q.db.QueryRow(ctx, UpsertRecurringException,
+		arg.RecurringID,
+		arg.Occurrence,
+		arg.Skip,
+		arg.Date,
+		arg.Amount,
+		arg.Description,
+		arg.Category,
+		arg.Notes,
+	)
+	var i RecurringException
+	err := row.Scan(
+		&i.RecurringID,
+		&i.Occurrence,
+		&i.Skip,
+		&i.Date,
+		&i.Amount,
+		&i.Description,
+		&i.Category,
+		&i.Notes,
+	)
+	return i, err
+}
@@ -0,0 +1,148 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: revisions.sql
+
+package gensql
+
+import (
+	"context"
+)
+
+const ListTransactionRevisions = `-- name: ListTransactionRevisions :many
+
+SELECT id, transaction_id, user_id, action, actor, actor_id, old_values, new_values, created_at FROM transaction_revisions
+WHERE transaction_id = $1 AND user_id = $2
+ORDER BY id
+`
+
+type ListTransactionRevisionsParams struct {
+	TransactionID int64
+	UserID        int64
+}
+
+// internal/database/queries/revisions.sql
+// Oldest first. Revisions of transactions in the trash are listed too.
+func (q *Queries) ListTransactionRevisions(ctx context.Context, arg ListTransactionRevisionsParams) ([]TransactionRevision, error) {
+	rows, err := q.db.Query(ctx, ListTransactionRevisions, arg.TransactionID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TransactionRevision
+	for rows.Next() {
+		var i TransactionRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.UserID,
+			&i.Action,
+			&i.Actor,
+			&i.ActorID,
+			&i.OldValues,
+			&i.NewValues,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const RevertTransaction = `-- name: RevertTransaction :one
+UPDATE transactions t
+SET
+  date = (r.new_values->>'date')::timestamptz,
+  amount = (r.new_values->>'amount')::numeric,
+  description = r.new_values->>'description',
+  category = r.new_values->>'category',
+  type = r.new_values->>'type',
+  currency = r.new_values->>'currency',
+  status = r.new_values->>'status',
+  account = r.new_values->>'account',
+  tags = ARRAY(SELECT jsonb_array_elements_text(r.new_values->'tags')),
+  notes = r.new_values->>'notes',
+  payee_id = CASE
+    WHEN r.new_values->'payee_id' IS NULL THEN t.payee_id
+    ELSE (SELECT p.id FROM payees p WHERE p.id = (r.new_values->>'payee_id')::bigint AND p.user_id = t.user_id)
+  END,
+  updated_at = NOW()
+FROM transaction_revisions r
+WHERE r.id = $1
+  AND r.transaction_id = t.id
+  AND t.id = $2
+  AND t.user_id = $3
+  AND t.deleted_at IS NULL
+RETURNING t.id, t.user_id, t.amount, t.description, t.category, t.date, t.type, t.currency, t.status, t.account, t.tags, t.notes, t.has_receipt, t.receipt_url, t.created_at, t.updated_at, t.import_batch_id, t.external_id, t.fingerprint, t.transfer_id, t.transfer_direction, t.recurring_id, t.recurring_occurrence, t.deleted_at, t.version, t.reconciliation_id, t.payee_id
+`
+
+type RevertTransactionParams struct {
+	RevisionID int64
+	ID         int64
+	UserID     int64
+}
+
+// Sets the recorded fields of a transaction to their values after the given
+// revision. The revert is recorded as a new revision. Revisions recorded
+// before payees were tracked keep the current payee, and a payee deleted
+// since is cleared.
+func (q *Queries) RevertTransaction(ctx context.Context, arg RevertTransactionParams) (Transaction, error) {
+	row := q.db.QueryRow(ctx, RevertTransaction, arg.RevisionID, arg.ID, arg.UserID)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Amount,
+		&i.Description,
+		&i.Category,
+		&i.Date,
+		&i.Type,
+		&i.Currency,
+		&i.Status,
+		&i.Account,
+		&i.Tags,
+		&i.Notes,
+		&i.HasReceipt,
+		&i.ReceiptUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ImportBatchID,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.TransferID,
+		&i.TransferDirection,
+		&i.RecurringID,
+		&i.RecurringOccurrence,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ReconciliationID,
+		&i.PayeeID,
+	)
+	return i, err
+}
+
+const SetRevisionActor = `-- name: SetRevisionActor :exec
+SELECT set_config('budgetctl.actor', $1::text, true)
+`
+
+// Attributes the revisions recorded by the rest of the database transaction
+// to a server job instead of the owner.
+func (q *Queries) SetRevisionActor(ctx context.Context, actor string) error {
+	_, err := q.db.Exec(ctx, SetRevisionActor, actor)
+	return err
+}
+
+const SetRevisionActorID = `-- name: SetRevisionActorID :exec
+SELECT set_config('budgetctl.actor_id', $1::bigint::text, true)
+`
+
+// Credits the revisions recorded by the rest of the database transaction to
+// the user making the request.
+func (q *Queries) SetRevisionActorID(ctx context.Context, actorID int64) error {
+	_, err := q.db.Exec(ctx, SetRevisionActorID, actorID)
+	return err
+}
@@ -0,0 +1,369 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: rules.sql
+
+package gensql
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const ApplyRuleChanges = `-- name: ApplyRuleChanges :one
+UPDATE transactions
+SET
+  category = $3,
+  tags = $4,
+  notes = $5,
+  status = $6,
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id
+`
+
+type ApplyRuleChangesParams struct {
+	ID       int64
+	UserID   int64
+	Category string
+	Tags     []string
+	Notes    *string
+	Status   string
+}
+
+// Saves the fields rules set on an existing transaction.
+func (q *Queries) ApplyRuleChanges(ctx context.Context, arg ApplyRuleChangesParams) (Transaction, error) {
+	row := q.db.QueryRow(ctx, ApplyRuleChanges,
+		arg.ID,
+		arg.UserID,
+		arg.Category,
+		arg.Tags,
+		arg.Notes,
+		arg.Status,
+	)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Amount,
+		&i.Description,
+		&i.Category,
+		&i.Date,
+		&i.Type,
+		&i.Currency,
+		&i.Status,
+		&i.Account,
+		&i.Tags,
+		&i.Notes,
+		&i.HasReceipt,
+		&i.ReceiptUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ImportBatchID,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.TransferID,
+		&i.TransferDirection,
+		&i.RecurringID,
+		&i.RecurringOccurrence,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ReconciliationID,
+		&i.PayeeID,
+	)
+	return i, err
+}
+
+const CreateRule = `-- name: CreateRule :one
+
+INSERT INTO rules (
+  user_id, name, priority, enabled, description_pattern, min_amount,
+  max_amount, account, currency, type, set_category, add_tags, set_notes,
+  set_status
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+RETURNING id, user_id, name, priority, enabled, description_pattern, min_amount, max_amount, account, currency, type, set_category, add_tags, set_notes, set_status, created_at, updated_at
+`
+
+type CreateRuleParams struct {
+	UserID             int64
+	Name               string
+	Priority           int32
+	Enabled            bool
+	DescriptionPattern *string
+	MinAmount          pgtype.Numeric
+	MaxAmount          pgtype.Numeric
+	Account            *string
+	Currency           *string
+	Type               *string
+	SetCategory        *string
+	AddTags            []string
+	SetNotes           *string
+	SetStatus          *string
+}
+
+// internal/database/queries/rules.sql
+func (q *Queries) CreateRule(ctx context.Context, arg CreateRuleParams) (Rule, error) {
+	row := q.db.QueryRow(ctx, CreateRule,
+		arg.UserID,
+		arg.Name,
+		arg.Priority,
+		arg.Enabled,
+		arg.DescriptionPattern,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Account,
+		arg.Currency,
+		arg.Type,
+		arg.SetCategory,
+		arg.AddTags,
+		arg.SetNotes,
+		arg.SetStatus,
+	)
+	var i Rule
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Priority,
+		&i.Enabled,
+		&i.DescriptionPattern,
+		&i.MinAmount,
+		&i.MaxAmount,
+		&i.Account,
+		&i.Currency,
+		&i.Type,
+		&i.SetCategory,
+		&i.AddTags,
+		&i.SetNotes,
+		&i.SetStatus,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const DeleteRule = `-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteRuleParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) DeleteRule(ctx context.Context, arg DeleteRuleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteRule, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const GetRule = `-- name: GetRule :one
+SELECT id, user_id, name, priority, enabled, description_pattern, min_amount, max_amount, account, currency, type, set_category, add_tags, set_notes, set_status, created_at, updated_at FROM rules
+WHERE id = $1 AND user_id = $2
+`
+
+type GetRuleParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) GetRule(ctx context.Context, arg GetRuleParams) (Rule, error) {
+	row := q.db.QueryRow(ctx, GetRule, arg.ID, arg.UserID)
+	var i Rule
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Priority,
+		&i.Enabled,
+		&i.DescriptionPattern,
+		&i.MinAmount,
+		&i.MaxAmount,
+		&i.Account,
+		&i.Currency,
+		&i.Type,
+		&i.SetCategory,
+		&i.AddTags,
+		&i.SetNotes,
+		&i.SetStatus,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const ListEnabledRules = `-- name: ListEnabledRules :many
+SELECT id, user_id, name, priority, enabled, description_pattern, min_amount, max_amount, account, currency, type, set_category, add_tags, set_notes, set_status, created_at, updated_at FROM rules
+WHERE user_id = $1 AND enabled
+ORDER BY priority, id
+`
+
+func (q *Queries) ListEnabledRules(ctx context.Context, userID int64) ([]Rule, error) {
+	rows, err := q.db.Query(ctx, ListEnabledRules, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Rule
+	for rows.Next() {
+		var i Rule
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Priority,
+			&i.Enabled,
+			&i.DescriptionPattern,
+			&i.MinAmount,
+			&i.MaxAmount,
+			&i.Account,
+			&i.Currency,
+			&i.Type,
+			&i.SetCategory,
+			&i.AddTags,
+			&i.SetNotes,
+			&i.SetStatus,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListRules = `-- name: ListRules :many
+SELECT id, user_id, name, priority, enabled, description_pattern, min_amount, max_amount, account, currency, type, set_category, add_tags, set_notes, set_status, created_at, updated_at FROM rules
+WHERE user_id = $1
+ORDER BY priority, id
+`
+
+// Rules in the order they apply: by priority, then by creation.
+func (q *Queries) ListRules(ctx context.Context, userID int64) ([]Rule, error) {
+	rows, err := q.db.Query(ctx, ListRules, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Rule
+	for rows.Next() {
+		var i Rule
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Priority,
+			&i.Enabled,
+			&i.DescriptionPattern,
+			&i.MinAmount,
+			&i.MaxAmount,
+			&i.Account,
+			&i.Currency,
+			&i.Type,
+			&i.SetCategory,
+			&i.AddTags,
+			&i.SetNotes,
+			&i.SetStatus,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const UpdateRule = `-- name: UpdateRule :one
+UPDATE rules
+SET
+  name = $3,
+  priority = $4,
+  enabled = $5,
+  description_pattern = $6,
+  min_amount = $7,
+  max_amount = $8,
+  account = $9,
+  currency = $10,
+  type = $11,
+  set_category = $12,
+  add_tags = $13,
+  set_notes = $14,
+  set_status = $15,
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, priority, enabled, description_pattern, min_amount, max_amount, account, currency, type, set_category, add_tags, set_notes, set_status, created_at, updated_at
+`
+
+type UpdateRuleParams struct {
+	ID                 int64
+	UserID             int64
+	Name               string
+	Priority           int32
+	Enabled            bool
+	DescriptionPattern *string
+	MinAmount          pgtype.Numeric
+	MaxAmount          pgtype.Numeric
+	Account            *string
+	Currency           *string
+	Type               *string
+	SetCategory        *string
+	AddTags            []string
+	SetNotes           *string
+	SetStatus          *string
+}
+
+func (q *Queries) UpdateRule(ctx context.Context, arg UpdateRuleParams) (Rule, error) {
+	row := q.db.QueryRow(ctx, UpdateRule,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.Priority,
+		arg.Enabled,
+		arg.DescriptionPattern,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Account,
+		arg.Currency,
+		arg.Type,
+		arg.SetCategory,
+		arg.AddTags,
+		arg.SetNotes,
+		arg.SetStatus,
+	)
+	var i Rule
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Priority,
+		&i.Enabled,
+		&i.DescriptionPattern,
+		&i.MinAmount,
+		&i.MaxAmount,
+		&i.Account,
+		&i.Currency,
+		&i.Type,
+		&i.SetCategory,
+		&i.AddTags,
+		&i.SetNotes,
+		&i.SetStatus,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
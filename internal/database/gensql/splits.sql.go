@@ -0,0 +1,218 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: splits.sql
+
+package gensql
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const CreateSplit = `-- name: CreateSplit :one
+INSERT INTO transaction_splits (
+  transaction_id, position, amount, category, tags, notes
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING id, transaction_id, position, amount, category, tags, notes
+`
+
+type CreateSplitParams struct {
+	TransactionID int64
+	Position      int32
+	Amount        pgtype.Numeric
+	Category      string
+	Tags          []string
+	Notes         *string
+}
+
+func (q *Queries) CreateSplit(ctx context.Context, arg CreateSplitParams) (TransactionSplit, error) {
+	row := q.db.QueryRow(ctx, CreateSplit,
+		arg.TransactionID,
+		arg.Position,
+		arg.Amount,
+		arg.Category,
+		arg.Tags,
+		arg.Notes,
+	)
+	var i TransactionSplit
+	err := row.Scan(
+		&i.ID,
+		&i.TransactionID,
+		&i.Position,
+		&i.Amount,
+		&i.Category,
+		&i.Tags,
+		&i.Notes,
+	)
+	return i, err
+}
+
+const DeleteSplits = `-- name: DeleteSplits :exec
+DELETE FROM transaction_splits
+WHERE transaction_id = $1
+`
+
+// The split total is checked when the database transaction commits, so the
+// splits can be deleted and recreated in between.
+func (q *Queries) DeleteSplits(ctx context.Context, transactionID int64) error {
+	_, err := q.db.Exec(ctx, DeleteSplits, transactionID)
+	return err
+}
+
+const GetCategoryTotals = `-- name: GetCategoryTotals :many
+SELECT
+  line.category,
+  t.type,
+  t.currency,
+  SUM(line.amount)::numeric AS total,
+  COUNT(*) AS count
+FROM transactions t
+JOIN transaction_lines line ON line.transaction_id = t.id
+WHERE t.user_id = $1
+  AND t.deleted_at IS NULL
+  AND (
+    $2::text IS NULL
+    OR transaction_search_document(t.description, t.notes, t.tags, t.category, t.account) @@ websearch_to_tsquery('simple', $2)
+    OR $2 <% t.description
+    OR t.description ILIKE '%' || $2 || '%'
+  )
+  AND ($3::date IS NULL OR t.date >= $3::date)
+  AND ($4::date IS NULL OR t.date <= $4::date)
+  AND ($5::text[] IS NULL OR line.category = ANY($5::text[]))
+  AND ($6::text IS NULL OR t.type = $6)
+  AND ($7::numeric IS NULL OR line.amount >= $7::numeric)
+  AND ($8::numeric IS NULL OR line.amount <= $8::numeric)
+  AND ($9::text[] IS NULL OR line.tags && $9::text[])
+  AND ($10::text[] IS NULL OR line.category <> ALL($10::text[]))
+  AND ($11::text[] IS NULL OR NOT line.tags && $11::text[])
+  AND ($12::text[] IS NULL OR t.status = ANY($12::text[]))
+  AND ($13::text[] IS NULL OR t.account = ANY($13::text[]))
+  AND ($14::text[] IS NULL OR t.currency = ANY($14::text[]))
+  AND ($15::boolean IS NULL OR t.has_receipt = $15::boolean)
+  AND ($16::timestamptz IS NULL OR t.created_at >= $16::timestamptz)
+  AND ($17::timestamptz IS NULL OR t.updated_at >= $17::timestamptz)
+GROUP BY line.category, t.type, t.currency
+ORDER BY t.type, t.currency, total DESC, line.category
+`
+
+type GetCategoryTotalsParams struct {
+	UserID            int64
+	Search            *string
+	DateFrom          pgtype.Date
+	DateTo            pgtype.Date
+	Categories        []string
+	Type              *string
+	MinAmount         pgtype.Numeric
+	MaxAmount         pgtype.Numeric
+	Tags              []string
+	ExcludeCategories []string
+	ExcludeTags       []string
+	Statuses          []string
+	Accounts          []string
+	Currencies        []string
+	HasReceipt        *bool
+	CreatedSince      pgtype.Timestamptz
+	UpdatedSince      pgtype.Timestamptz
+}
+
+type GetCategoryTotalsRow struct {
+	Category string
+	Type     string
+	Currency string
+	Total    pgtype.Numeric
+	Count    int64
+}
+
+// Totals per category, counting the lines of split transactions instead of
+// their whole amount. Takes the filters of ListTransactionsWithFilters, with
+// category, amount and tag filters applied to each line.
+func (q *Queries) GetCategoryTotals(ctx context.Context, arg GetCategoryTotalsParams) ([]GetCategoryTotalsRow, error) {
+	rows, err := q.db.Query(ctx, GetCategoryTotals,
+		arg.UserID,
+		arg.Search,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Categories,
+		arg.Type,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Tags,
+		arg.ExcludeCategories,
+		arg.ExcludeTags,
+		arg.Statuses,
+		arg.Accounts,
+		arg.Currencies,
+		arg.HasReceipt,
+		arg.CreatedSince,
+		arg.UpdatedSince,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCategoryTotalsRow
+	for rows.Next() {
+		var i GetCategoryTotalsRow
+		if err := rows.Scan(
+			&i.Category,
+			&i.Type,
+			&i.Currency,
+			&i.Total,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListSplits = `-- name: ListSplits :many
+
+SELECT s.id, s.transaction_id, s.position, s.amount, s.category, s.tags, s.notes FROM transaction_splits s
+JOIN transactions t ON t.id = s.transaction_id
+WHERE s.transaction_id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+ORDER BY s.position
+`
+
+type ListSplitsParams struct {
+	TransactionID int64
+	UserID        int64
+}
+
+// internal/database/queries/splits.sql
+func (q *Queries) ListSplits(ctx context.Context, arg ListSplitsParams) ([]TransactionSplit, error) {
+	rows, err := q.db.Query(ctx, ListSplits, arg.TransactionID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TransactionSplit
+	for rows.Next() {
+		var i TransactionSplit
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.Position,
+			&i.Amount,
+			&i.Category,
+			&i.Tags,
+			&i.Notes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
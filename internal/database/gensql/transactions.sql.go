@@ -11,79 +11,217 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const countTransactions = `-- name: CountTransactions :one
+const BulkUpdateTransactions = `-- name: BulkUpdateTransactions :many
+UPDATE transactions
+SET
+  category = COALESCE($1::text, category),
+  status = COALESCE($2::text, status),
+  tags = ARRAY(
+    SELECT tag
+    FROM unnest(tags || COALESCE($3::text[], '{}')) WITH ORDINALITY AS t(tag, position)
+    WHERE tag <> ALL(COALESCE($4::text[], '{}'))
+    GROUP BY tag
+    ORDER BY min(position)
+  ),
+  updated_at = NOW()
+WHERE user_id = $5 AND id = ANY($6::bigint[]) AND deleted_at IS NULL
+RETURNING id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id
+`
+
+type BulkUpdateTransactionsParams struct {
+	Category   *string
+	Status     *string
+	AddTags    []string
+	RemoveTags []string
+	UserID     int64
+	Ids        []int64
+}
+
+// Applies the same change to many transactions. NULL category/status leave the
+// column untouched; added tags are appended in order and removed tags dropped.
+func (q *Queries) BulkUpdateTransactions(ctx context.Context, arg BulkUpdateTransactionsParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, BulkUpdateTransactions,
+		arg.Category,
+		arg.Status,
+		arg.AddTags,
+		arg.RemoveTags,
+		arg.UserID,
+		arg.Ids,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.Description,
+			&i.Category,
+			&i.Date,
+			&i.Type,
+			&i.Currency,
+			&i.Status,
+			&i.Account,
+			&i.Tags,
+			&i.Notes,
+			&i.HasReceipt,
+			&i.ReceiptUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ImportBatchID,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.TransferID,
+			&i.TransferDirection,
+			&i.RecurringID,
+			&i.RecurringOccurrence,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ReconciliationID,
+			&i.PayeeID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const CountTransactions = `-- name: CountTransactions :one
 SELECT COUNT(*) FROM transactions
 WHERE user_id = $1
-  AND ($2::text IS NULL OR description ILIKE '%' || $2 || '%')
+  AND deleted_at IS NULL
+  AND (
+    $2::text IS NULL
+    OR transaction_search_document(description, notes, tags, category, account) @@ websearch_to_tsquery('simple', $2)
+    OR $2 <% description
+    OR description ILIKE '%' || $2 || '%'
+  )
   AND ($3::date IS NULL OR date >= $3::date)
   AND ($4::date IS NULL OR date <= $4::date)
-  AND ($5::text[] IS NULL OR category = ANY($5::text[]))
-  AND ($6::text IS NULL OR type = $6)
-  AND ($7::numeric IS NULL OR amount >= $7::numeric)
-  AND ($8::numeric IS NULL OR amount <= $8::numeric)
-  AND ($9::text[] IS NULL OR tags && $9::text[])
+  AND EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND ($5::text[] IS NULL OR line.category = ANY($5::text[]))
+      AND ($6::numeric IS NULL OR line.amount >= $6::numeric)
+      AND ($7::numeric IS NULL OR line.amount <= $7::numeric)
+      AND ($8::text[] IS NULL OR line.tags && $8::text[])
+  )
+  -- A transaction with any excluded line is excluded whole.
+  AND NOT EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND (line.category = ANY($9::text[])
+        OR line.tags && $10::text[])
+  )
+  AND ($11::text IS NULL OR type = $11)
+  AND ($12::text[] IS NULL OR status = ANY($12::text[]))
+  AND ($13::text[] IS NULL OR account = ANY($13::text[]))
+  AND ($14::text[] IS NULL OR currency = ANY($14::text[]))
+  AND ($15::boolean IS NULL OR has_receipt = $15::boolean)
+  AND ($16::timestamptz IS NULL OR created_at >= $16::timestamptz)
+  AND ($17::timestamptz IS NULL OR updated_at >= $17::timestamptz)
 `
 
 type CountTransactionsParams struct {
-	UserID  int64
-	Column2 string
-	Column3 pgtype.Date
-	Column4 pgtype.Date
-	Column5 []string
-	Column6 string
-	Column7 pgtype.Numeric
-	Column8 pgtype.Numeric
-	Column9 []string
+	UserID            int64
+	Search            *string
+	DateFrom          pgtype.Date
+	DateTo            pgtype.Date
+	Categories        []string
+	MinAmount         pgtype.Numeric
+	MaxAmount         pgtype.Numeric
+	Tags              []string
+	ExcludeCategories []string
+	ExcludeTags       []string
+	Type              *string
+	Statuses          []string
+	Accounts          []string
+	Currencies        []string
+	HasReceipt        *bool
+	CreatedSince      pgtype.Timestamptz
+	UpdatedSince      pgtype.Timestamptz
 }
 
 func (q *Queries) CountTransactions(ctx context.Context, arg CountTransactionsParams) (int64, error) {
-	row := q.db.QueryRow(ctx, countTransactions,
+	row := q.db.QueryRow(ctx, CountTransactions,
 		arg.UserID,
-		arg.Column2,
-		arg.Column3,
-		arg.Column4,
-		arg.Column5,
-		arg.Column6,
-		arg.Column7,
-		arg.Column8,
-		arg.Column9,
+		arg.Search,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Categories,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Tags,
+		arg.ExcludeCategories,
+		arg.ExcludeTags,
+		arg.Type,
+		arg.Statuses,
+		arg.Accounts,
+		arg.Currencies,
+		arg.HasReceipt,
+		arg.CreatedSince,
+		arg.UpdatedSince,
 	)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
-const createTransaction = `-- name: CreateTransaction :one
+const CountTrash = `-- name: CountTrash :one
+SELECT COUNT(*) FROM transactions
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) CountTrash(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, CountTrash, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CreateTransaction = `-- name: CreateTransaction :one
 
 INSERT INTO transactions (
   user_id, amount, description, category, type, currency, status,
-  account, tags, notes, has_receipt, receipt_url, date
+  account, tags, notes, has_receipt, receipt_url, date, import_batch_id,
+  external_id, payee_id
 )
 VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 )
-RETURNING id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at
+RETURNING id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id
 `
 
 type CreateTransactionParams struct {
-	UserID      int64
-	Amount      pgtype.Numeric
-	Description string
-	Category    string
-	Type        string
-	Currency    string
-	Status      string
-	Account     string
-	Tags        []string
-	Notes       *string
-	HasReceipt  bool
-	ReceiptUrl  *string
-	Date        pgtype.Timestamptz
+	UserID        int64
+	Amount        pgtype.Numeric
+	Description   string
+	Category      string
+	Type          string
+	Currency      string
+	Status        string
+	Account       string
+	Tags          []string
+	Notes         *string
+	HasReceipt    bool
+	ReceiptUrl    *string
+	Date          pgtype.Timestamptz
+	ImportBatchID *int64
+	ExternalID    *string
+	PayeeID       *int64
 }
 
 // internal/database/queries/transactions.sql
 func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionParams) (Transaction, error) {
-	row := q.db.QueryRow(ctx, createTransaction,
+	row := q.db.QueryRow(ctx, CreateTransaction,
 		arg.UserID,
 		arg.Amount,
 		arg.Description,
@@ -97,6 +235,9 @@ func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionPa
 		arg.HasReceipt,
 		arg.ReceiptUrl,
 		arg.Date,
+		arg.ImportBatchID,
+		arg.ExternalID,
+		arg.PayeeID,
 	)
 	var i Transaction
 	err := row.Scan(
@@ -116,29 +257,68 @@ func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionPa
 		&i.ReceiptUrl,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ImportBatchID,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.TransferID,
+		&i.TransferDirection,
+		&i.RecurringID,
+		&i.RecurringOccurrence,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ReconciliationID,
+		&i.PayeeID,
 	)
 	return i, err
 }
 
-const deleteTransaction = `-- name: DeleteTransaction :exec
-DELETE FROM transactions
-WHERE id = $1 AND user_id = $2
+const DeleteTransaction = `-- name: DeleteTransaction :execrows
+UPDATE transactions
+SET deleted_at = NOW()
+WHERE transactions.user_id = $1
+  AND transactions.deleted_at IS NULL
+  AND (
+    transactions.id = $2
+    OR transactions.transfer_id = (SELECT t.transfer_id FROM transactions t WHERE t.id = $2 AND t.user_id = $1)
+  )
 `
 
 type DeleteTransactionParams struct {
-	ID     int64
 	UserID int64
+	ID     int64
+}
+
+// Moves the transaction to the trash, along with the other leg of a transfer.
+func (q *Queries) DeleteTransaction(ctx context.Context, arg DeleteTransactionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteTransaction, arg.UserID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-func (q *Queries) DeleteTransaction(ctx context.Context, arg DeleteTransactionParams) error {
-	_, err := q.db.Exec(ctx, deleteTransaction, arg.ID, arg.UserID)
-	return err
+const EmptyTrash = `-- name: EmptyTrash :execrows
+DELETE FROM transactions
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) EmptyTrash(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, EmptyTrash, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const getCategories = `-- name: GetCategories :many
+const GetCategories = `-- name: GetCategories :many
 SELECT DISTINCT category as name, category as id
-FROM transactions
-WHERE user_id = $1
+FROM (
+  SELECT t.category FROM transactions t WHERE t.user_id = $1 AND t.deleted_at IS NULL
+  UNION
+  SELECT s.category FROM transaction_splits s
+  JOIN transactions t ON t.id = s.transaction_id
+  WHERE t.user_id = $1 AND t.deleted_at IS NULL
+) categories
 ORDER BY category
 `
 
@@ -148,7 +328,7 @@ type GetCategoriesRow struct {
 }
 
 func (q *Queries) GetCategories(ctx context.Context, userID int64) ([]GetCategoriesRow, error) {
-	rows, err := q.db.Query(ctx, getCategories, userID)
+	rows, err := q.db.Query(ctx, GetCategories, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -167,11 +347,16 @@ func (q *Queries) GetCategories(ctx context.Context, userID int64) ([]GetCategor
 	return items, nil
 }
 
-const getTags = `-- name: GetTags :many
-SELECT DISTINCT unnest(tags) as name, unnest(tags) as id
-FROM transactions
-WHERE user_id = $1 AND array_length(tags, 1) > 0
-ORDER BY name
+const GetTags = `-- name: GetTags :many
+SELECT DISTINCT tag as name, tag as id
+FROM (
+  SELECT unnest(t.tags) AS tag FROM transactions t WHERE t.user_id = $1 AND t.deleted_at IS NULL
+  UNION
+  SELECT unnest(s.tags) AS tag FROM transaction_splits s
+  JOIN transactions t ON t.id = s.transaction_id
+  WHERE t.user_id = $1 AND t.deleted_at IS NULL
+) tags
+ORDER BY tag
 `
 
 type GetTagsRow struct {
@@ -180,7 +365,7 @@ type GetTagsRow struct {
 }
 
 func (q *Queries) GetTags(ctx context.Context, userID int64) ([]GetTagsRow, error) {
-	rows, err := q.db.Query(ctx, getTags, userID)
+	rows, err := q.db.Query(ctx, GetTags, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -199,35 +384,36 @@ func (q *Queries) GetTags(ctx context.Context, userID int64) ([]GetTagsRow, erro
 	return items, nil
 }
 
-const getTotalIncome = `-- name: GetTotalIncome :one
+const GetTotalIncome = `-- name: GetTotalIncome :one
 SELECT COALESCE(SUM(amount), 0)::numeric
 FROM transactions
-WHERE user_id = $1 AND type = 'income'
+WHERE user_id = $1 AND type = 'income' AND deleted_at IS NULL
 `
 
 func (q *Queries) GetTotalIncome(ctx context.Context, userID int64) (pgtype.Numeric, error) {
-	row := q.db.QueryRow(ctx, getTotalIncome, userID)
+	row := q.db.QueryRow(ctx, GetTotalIncome, userID)
 	var column_1 pgtype.Numeric
 	err := row.Scan(&column_1)
 	return column_1, err
 }
 
-const getTotalSpending = `-- name: GetTotalSpending :one
+const GetTotalSpending = `-- name: GetTotalSpending :one
 SELECT COALESCE(SUM(amount), 0)::numeric
 FROM transactions
-WHERE user_id = $1 AND type = 'expense'
+WHERE user_id = $1 AND type = 'expense' AND deleted_at IS NULL
 `
 
+// Transfers have their own type and are not counted as spending or income.
 func (q *Queries) GetTotalSpending(ctx context.Context, userID int64) (pgtype.Numeric, error) {
-	row := q.db.QueryRow(ctx, getTotalSpending, userID)
+	row := q.db.QueryRow(ctx, GetTotalSpending, userID)
 	var column_1 pgtype.Numeric
 	err := row.Scan(&column_1)
 	return column_1, err
 }
 
-const getTransactionByID = `-- name: GetTransactionByID :one
-SELECT id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at FROM transactions
-WHERE id = $1 AND user_id = $2
+const GetTransactionByID = `-- name: GetTransactionByID :one
+SELECT id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id FROM transactions
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
 type GetTransactionByIDParams struct {
@@ -236,7 +422,7 @@ type GetTransactionByIDParams struct {
 }
 
 func (q *Queries) GetTransactionByID(ctx context.Context, arg GetTransactionByIDParams) (Transaction, error) {
-	row := q.db.QueryRow(ctx, getTransactionByID, arg.ID, arg.UserID)
+	row := q.db.QueryRow(ctx, GetTransactionByID, arg.ID, arg.UserID)
 	var i Transaction
 	err := row.Scan(
 		&i.ID,
@@ -255,13 +441,74 @@ func (q *Queries) GetTransactionByID(ctx context.Context, arg GetTransactionByID
 		&i.ReceiptUrl,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ImportBatchID,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.TransferID,
+		&i.TransferDirection,
+		&i.RecurringID,
+		&i.RecurringOccurrence,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ReconciliationID,
+		&i.PayeeID,
 	)
 	return i, err
 }
 
-const listTransactions = `-- name: ListTransactions :many
-SELECT id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at FROM transactions
-WHERE user_id = $1
+const ListCategorizedTransactions = `-- name: ListCategorizedTransactions :many
+SELECT description, amount, account, type, category FROM transactions
+WHERE user_id = $1 AND deleted_at IS NULL
+  AND type <> 'transfer' AND category <> $2
+ORDER BY date DESC, id DESC
+LIMIT $3
+`
+
+type ListCategorizedTransactionsParams struct {
+	UserID        int64
+	Uncategorized string
+	Limit         int32
+}
+
+type ListCategorizedTransactionsRow struct {
+	Description string
+	Amount      pgtype.Numeric
+	Account     string
+	Type        string
+	Category    string
+}
+
+// The user's most recent categorized transactions, which category
+// suggestions are learned from. Transfers are left out.
+func (q *Queries) ListCategorizedTransactions(ctx context.Context, arg ListCategorizedTransactionsParams) ([]ListCategorizedTransactionsRow, error) {
+	rows, err := q.db.Query(ctx, ListCategorizedTransactions, arg.UserID, arg.Uncategorized, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCategorizedTransactionsRow
+	for rows.Next() {
+		var i ListCategorizedTransactionsRow
+		if err := rows.Scan(
+			&i.Description,
+			&i.Amount,
+			&i.Account,
+			&i.Type,
+			&i.Category,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTransactions = `-- name: ListTransactions :many
+SELECT id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id FROM transactions
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY date DESC
 LIMIT $2 OFFSET $3
 `
@@ -273,7 +520,7 @@ type ListTransactionsParams struct {
 }
 
 func (q *Queries) ListTransactions(ctx context.Context, arg ListTransactionsParams) ([]Transaction, error) {
-	rows, err := q.db.Query(ctx, listTransactions, arg.UserID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, ListTransactions, arg.UserID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -298,6 +545,17 @@ func (q *Queries) ListTransactions(ctx context.Context, arg ListTransactionsPara
 			&i.ReceiptUrl,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ImportBatchID,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.TransferID,
+			&i.TransferDirection,
+			&i.RecurringID,
+			&i.RecurringOccurrence,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ReconciliationID,
+			&i.PayeeID,
 		); err != nil {
 			return nil, err
 		}
@@ -309,53 +567,253 @@ func (q *Queries) ListTransactions(ctx context.Context, arg ListTransactionsPara
 	return items, nil
 }
 
-const listTransactionsWithFilters = `-- name: ListTransactionsWithFilters :many
-SELECT id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at FROM transactions
-WHERE user_id = $1
-  AND ($2::text IS NULL OR description ILIKE '%' || $2 || '%')
+const ListTransactionsWithFilters = `-- name: ListTransactionsWithFilters :many
+SELECT
+  transactions.id, transactions.user_id, transactions.amount, transactions.description, transactions.category, transactions.date, transactions.type, transactions.currency, transactions.status, transactions.account, transactions.tags, transactions.notes, transactions.has_receipt, transactions.receipt_url, transactions.created_at, transactions.updated_at, transactions.import_batch_id, transactions.external_id, transactions.fingerprint, transactions.transfer_id, transactions.transfer_direction, transactions.recurring_id, transactions.recurring_occurrence, transactions.deleted_at, transactions.version, transactions.reconciliation_id, transactions.payee_id,
+  search.relevance,
+  COALESCE(ts_headline('simple', description, websearch_to_tsquery('simple', $1), 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'), '')::text AS description_highlight,
+  COALESCE(ts_headline('simple', notes, websearch_to_tsquery('simple', $1), 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2'), '')::text AS notes_highlight
+FROM transactions
+CROSS JOIN LATERAL (
+  SELECT COALESCE(
+    ts_rank(transaction_search_document(description, notes, tags, category, account), websearch_to_tsquery('simple', $1))
+    + word_similarity($1, description),
+    0
+  )::real AS relevance
+) search
+WHERE user_id = $2
+  AND deleted_at IS NULL
+  AND (
+    $1::text IS NULL
+    OR transaction_search_document(description, notes, tags, category, account) @@ websearch_to_tsquery('simple', $1)
+    OR $1 <% description
+    OR description ILIKE '%' || $1 || '%'
+  )
   AND ($3::date IS NULL OR date >= $3::date)
   AND ($4::date IS NULL OR date <= $4::date)
-  AND ($5::text[] IS NULL OR category = ANY($5::text[]))
-  AND ($6::text IS NULL OR type = $6)
-  AND ($7::numeric IS NULL OR amount >= $7::numeric)
-  AND ($8::numeric IS NULL OR amount <= $8::numeric)
-  AND ($9::text[] IS NULL OR tags && $9::text[])
-ORDER BY date DESC
-LIMIT $10 OFFSET $11
+  AND EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND ($5::text[] IS NULL OR line.category = ANY($5::text[]))
+      AND ($6::numeric IS NULL OR line.amount >= $6::numeric)
+      AND ($7::numeric IS NULL OR line.amount <= $7::numeric)
+      AND ($8::text[] IS NULL OR line.tags && $8::text[])
+  )
+  -- A transaction with any excluded line is excluded whole.
+  AND NOT EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND (line.category = ANY($9::text[])
+        OR line.tags && $10::text[])
+  )
+  AND ($11::text IS NULL OR type = $11)
+  AND ($12::text[] IS NULL OR status = ANY($12::text[]))
+  AND ($13::text[] IS NULL OR account = ANY($13::text[]))
+  AND ($14::text[] IS NULL OR currency = ANY($14::text[]))
+  AND ($15::boolean IS NULL OR has_receipt = $15::boolean)
+  AND ($16::timestamptz IS NULL OR created_at >= $16::timestamptz)
+  AND ($17::timestamptz IS NULL OR updated_at >= $17::timestamptz)
+ORDER BY
+  CASE WHEN ($18::text[])[1] = 'date' THEN date END ASC,
+  CASE WHEN ($18::text[])[1] = '-date' THEN date END DESC,
+  CASE WHEN ($18::text[])[1] = 'amount' THEN amount END ASC,
+  CASE WHEN ($18::text[])[1] = '-amount' THEN amount END DESC,
+  CASE WHEN ($18::text[])[1] = 'description' THEN lower(description) END ASC,
+  CASE WHEN ($18::text[])[1] = '-description' THEN lower(description) END DESC,
+  CASE WHEN ($18::text[])[1] = 'category' THEN lower(category) END ASC,
+  CASE WHEN ($18::text[])[1] = '-category' THEN lower(category) END DESC,
+  CASE WHEN ($18::text[])[1] = 'created_at' THEN created_at END ASC,
+  CASE WHEN ($18::text[])[1] = '-created_at' THEN created_at END DESC,
+  CASE WHEN ($18::text[])[1] = 'updated_at' THEN updated_at END ASC,
+  CASE WHEN ($18::text[])[1] = '-updated_at' THEN updated_at END DESC,
+  CASE WHEN ($18::text[])[1] = 'relevance' THEN search.relevance END ASC,
+  CASE WHEN ($18::text[])[1] = '-relevance' THEN search.relevance END DESC,
+  CASE WHEN ($18::text[])[2] = 'date' THEN date END ASC,
+  CASE WHEN ($18::text[])[2] = '-date' THEN date END DESC,
+  CASE WHEN ($18::text[])[2] = 'amount' THEN amount END ASC,
+  CASE WHEN ($18::text[])[2] = '-amount' THEN amount END DESC,
+  CASE WHEN ($18::text[])[2] = 'description' THEN lower(description) END ASC,
+  CASE WHEN ($18::text[])[2] = '-description' THEN lower(description) END DESC,
+  CASE WHEN ($18::text[])[2] = 'category' THEN lower(category) END ASC,
+  CASE WHEN ($18::text[])[2] = '-category' THEN lower(category) END DESC,
+  CASE WHEN ($18::text[])[2] = 'created_at' THEN created_at END ASC,
+  CASE WHEN ($18::text[])[2] = '-created_at' THEN created_at END DESC,
+  CASE WHEN ($18::text[])[2] = 'updated_at' THEN updated_at END ASC,
+  CASE WHEN ($18::text[])[2] = '-updated_at' THEN updated_at END DESC,
+  CASE WHEN ($18::text[])[2] = 'relevance' THEN search.relevance END ASC,
+  CASE WHEN ($18::text[])[2] = '-relevance' THEN search.relevance END DESC,
+  CASE WHEN ($18::text[])[3] = 'date' THEN date END ASC,
+  CASE WHEN ($18::text[])[3] = '-date' THEN date END DESC,
+  CASE WHEN ($18::text[])[3] = 'amount' THEN amount END ASC,
+  CASE WHEN ($18::text[])[3] = '-amount' THEN amount END DESC,
+  CASE WHEN ($18::text[])[3] = 'description' THEN lower(description) END ASC,
+  CASE WHEN ($18::text[])[3] = '-description' THEN lower(description) END DESC,
+  CASE WHEN ($18::text[])[3] = 'category' THEN lower(category) END ASC,
+  CASE WHEN ($18::text[])[3] = '-category' THEN lower(category) END DESC,
+  CASE WHEN ($18::text[])[3] = 'created_at' THEN created_at END ASC,
+  CASE WHEN ($18::text[])[3] = '-created_at' THEN created_at END DESC,
+  CASE WHEN ($18::text[])[3] = 'updated_at' THEN updated_at END ASC,
+  CASE WHEN ($18::text[])[3] = '-updated_at' THEN updated_at END DESC,
+  CASE WHEN ($18::text[])[3] = 'relevance' THEN search.relevance END ASC,
+  CASE WHEN ($18::text[])[3] = '-relevance' THEN search.relevance END DESC,
+  date DESC,
+  id DESC
+LIMIT $20 OFFSET $19
 `
 
 type ListTransactionsWithFiltersParams struct {
-	UserID  int64
-	Column2 string
-	Column3 pgtype.Date
-	Column4 pgtype.Date
-	Column5 []string
-	Column6 string
-	Column7 pgtype.Numeric
-	Column8 pgtype.Numeric
-	Column9 []string
-	Limit   int32
-	Offset  int32
-}
-
-func (q *Queries) ListTransactionsWithFilters(ctx context.Context, arg ListTransactionsWithFiltersParams) ([]Transaction, error) {
-	rows, err := q.db.Query(ctx, listTransactionsWithFilters,
+	Search            *string
+	UserID            int64
+	DateFrom          pgtype.Date
+	DateTo            pgtype.Date
+	Categories        []string
+	MinAmount         pgtype.Numeric
+	MaxAmount         pgtype.Numeric
+	Tags              []string
+	ExcludeCategories []string
+	ExcludeTags       []string
+	Type              *string
+	Statuses          []string
+	Accounts          []string
+	Currencies        []string
+	HasReceipt        *bool
+	CreatedSince      pgtype.Timestamptz
+	UpdatedSince      pgtype.Timestamptz
+	Sort              []string
+	Offset            int32
+	Limit             int32
+}
+
+type ListTransactionsWithFiltersRow struct {
+	ID                   int64
+	UserID               int64
+	Amount               pgtype.Numeric
+	Description          string
+	Category             string
+	Date                 pgtype.Timestamptz
+	Type                 string
+	Currency             string
+	Status               string
+	Account              string
+	Tags                 []string
+	Notes                *string
+	HasReceipt           bool
+	ReceiptUrl           *string
+	CreatedAt            pgtype.Timestamptz
+	UpdatedAt            pgtype.Timestamptz
+	ImportBatchID        *int64
+	ExternalID           *string
+	Fingerprint          string
+	TransferID           *int64
+	TransferDirection    *string
+	RecurringID          *int64
+	RecurringOccurrence  pgtype.Date
+	DeletedAt            pgtype.Timestamptz
+	Version              int64
+	ReconciliationID     *int64
+	PayeeID              *int64
+	Relevance            float32
+	DescriptionHighlight string
+	NotesHighlight       string
+}
+
+// Sort keys are whitelisted in the API layer; each of the first three
+// entries of sort is either "<field>" (ascending) or "-<field>" (descending).
+// Search combines full-text matching over description, notes, tags, category
+// and account with trigram similarity on description for typo tolerance.
+// Category, amount and tag filters must all match one line of the
+// transaction: a split line, or the transaction itself when it is not split.
+func (q *Queries) ListTransactionsWithFilters(ctx context.Context, arg ListTransactionsWithFiltersParams) ([]ListTransactionsWithFiltersRow, error) {
+	rows, err := q.db.Query(ctx, ListTransactionsWithFilters,
+		arg.Search,
 		arg.UserID,
-		arg.Column2,
-		arg.Column3,
-		arg.Column4,
-		arg.Column5,
-		arg.Column6,
-		arg.Column7,
-		arg.Column8,
-		arg.Column9,
-		arg.Limit,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Categories,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Tags,
+		arg.ExcludeCategories,
+		arg.ExcludeTags,
+		arg.Type,
+		arg.Statuses,
+		arg.Accounts,
+		arg.Currencies,
+		arg.HasReceipt,
+		arg.CreatedSince,
+		arg.UpdatedSince,
+		arg.Sort,
 		arg.Offset,
+		arg.Limit,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	var items []ListTransactionsWithFiltersRow
+	for rows.Next() {
+		var i ListTransactionsWithFiltersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.Description,
+			&i.Category,
+			&i.Date,
+			&i.Type,
+			&i.Currency,
+			&i.Status,
+			&i.Account,
+			&i.Tags,
+			&i.Notes,
+			&i.HasReceipt,
+			&i.ReceiptUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ImportBatchID,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.TransferID,
+			&i.TransferDirection,
+			&i.RecurringID,
+			&i.RecurringOccurrence,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ReconciliationID,
+			&i.PayeeID,
+			&i.Relevance,
+			&i.DescriptionHighlight,
+			&i.NotesHighlight,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTrash = `-- name: ListTrash :many
+SELECT id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id FROM transactions
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC, id DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListTrashParams struct {
+	UserID int64
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListTrash(ctx context.Context, arg ListTrashParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, ListTrash, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 	var items []Transaction
 	for rows.Next() {
 		var i Transaction
@@ -376,6 +834,17 @@ func (q *Queries) ListTransactionsWithFilters(ctx context.Context, arg ListTrans
 			&i.ReceiptUrl,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ImportBatchID,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.TransferID,
+			&i.TransferDirection,
+			&i.RecurringID,
+			&i.RecurringOccurrence,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ReconciliationID,
+			&i.PayeeID,
 		); err != nil {
 			return nil, err
 		}
@@ -387,7 +856,158 @@ func (q *Queries) ListTransactionsWithFilters(ctx context.Context, arg ListTrans
 	return items, nil
 }
 
-const updateTransaction = `-- name: UpdateTransaction :one
+const LockTransaction = `-- name: LockTransaction :one
+SELECT id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id FROM transactions
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+type LockTransactionParams struct {
+	ID     int64
+	UserID int64
+}
+
+// Locks the transaction until the end of the database transaction, so its
+// version can be checked before changing it.
+func (q *Queries) LockTransaction(ctx context.Context, arg LockTransactionParams) (Transaction, error) {
+	row := q.db.QueryRow(ctx, LockTransaction, arg.ID, arg.UserID)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Amount,
+		&i.Description,
+		&i.Category,
+		&i.Date,
+		&i.Type,
+		&i.Currency,
+		&i.Status,
+		&i.Account,
+		&i.Tags,
+		&i.Notes,
+		&i.HasReceipt,
+		&i.ReceiptUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ImportBatchID,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.TransferID,
+		&i.TransferDirection,
+		&i.RecurringID,
+		&i.RecurringOccurrence,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ReconciliationID,
+		&i.PayeeID,
+	)
+	return i, err
+}
+
+const PurgeExpiredTrash = `-- name: PurgeExpiredTrash :execrows
+DELETE FROM transactions
+WHERE deleted_at < $1
+`
+
+// Deletes the transactions of all users that were trashed before the cutoff.
+func (q *Queries) PurgeExpiredTrash(ctx context.Context, deletedAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, PurgeExpiredTrash, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const PurgeTransaction = `-- name: PurgeTransaction :execrows
+DELETE FROM transactions
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+`
+
+type PurgeTransactionParams struct {
+	ID     int64
+	UserID int64
+}
+
+// Deletes a transaction in the trash for good. Deleting a transfer leg
+// deletes the other leg too.
+func (q *Queries) PurgeTransaction(ctx context.Context, arg PurgeTransactionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, PurgeTransaction, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const RestoreTransaction = `-- name: RestoreTransaction :execrows
+UPDATE transactions
+SET deleted_at = NULL
+WHERE transactions.user_id = $1
+  AND transactions.deleted_at IS NOT NULL
+  AND (
+    transactions.id = $2
+    OR transactions.transfer_id = (SELECT t.transfer_id FROM transactions t WHERE t.id = $2 AND t.user_id = $1)
+  )
+`
+
+type RestoreTransactionParams struct {
+	UserID int64
+	ID     int64
+}
+
+// Takes the transaction out of the trash, along with the other leg of a
+// transfer.
+func (q *Queries) RestoreTransaction(ctx context.Context, arg RestoreTransactionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, RestoreTransaction, arg.UserID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const TouchTransaction = `-- name: TouchTransaction :one
+UPDATE transactions
+SET updated_at = NOW()
+WHERE id = $1
+RETURNING id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id
+`
+
+// Gives the transaction a new version after a change to its splits.
+func (q *Queries) TouchTransaction(ctx context.Context, id int64) (Transaction, error) {
+	row := q.db.QueryRow(ctx, TouchTransaction, id)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Amount,
+		&i.Description,
+		&i.Category,
+		&i.Date,
+		&i.Type,
+		&i.Currency,
+		&i.Status,
+		&i.Account,
+		&i.Tags,
+		&i.Notes,
+		&i.HasReceipt,
+		&i.ReceiptUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ImportBatchID,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.TransferID,
+		&i.TransferDirection,
+		&i.RecurringID,
+		&i.RecurringOccurrence,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ReconciliationID,
+		&i.PayeeID,
+	)
+	return i, err
+}
+
+const UpdateTransaction = `-- name: UpdateTransaction :one
 UPDATE transactions
 SET
   amount = $2,
@@ -401,9 +1021,10 @@ SET
   notes = $10,
   has_receipt = $11,
   receipt_url = $12,
+  payee_id = $14,
   updated_at = NOW()
-WHERE id = $1 AND user_id = $13
-RETURNING id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at
+WHERE id = $1 AND user_id = $13 AND deleted_at IS NULL
+RETURNING id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id
 `
 
 type UpdateTransactionParams struct {
@@ -420,10 +1041,11 @@ type UpdateTransactionParams struct {
 	HasReceipt  bool
 	ReceiptUrl  *string
 	UserID      int64
+	PayeeID     *int64
 }
 
 func (q *Queries) UpdateTransaction(ctx context.Context, arg UpdateTransactionParams) (Transaction, error) {
-	row := q.db.QueryRow(ctx, updateTransaction,
+	row := q.db.QueryRow(ctx, UpdateTransaction,
 		arg.ID,
 		arg.Amount,
 		arg.Description,
@@ -437,6 +1059,7 @@ func (q *Queries) UpdateTransaction(ctx context.Context, arg UpdateTransactionPa
 		arg.HasReceipt,
 		arg.ReceiptUrl,
 		arg.UserID,
+		arg.PayeeID,
 	)
 	var i Transaction
 	err := row.Scan(
@@ -456,6 +1079,17 @@ func (q *Queries) UpdateTransaction(ctx context.Context, arg UpdateTransactionPa
 		&i.ReceiptUrl,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ImportBatchID,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.TransferID,
+		&i.TransferDirection,
+		&i.RecurringID,
+		&i.RecurringOccurrence,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ReconciliationID,
+		&i.PayeeID,
 	)
 	return i, err
 }
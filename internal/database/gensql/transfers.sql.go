@@ -0,0 +1,388 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: transfers.sql
+
+package gensql
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const CountTransfers = `-- name: CountTransfers :one
+SELECT COUNT(*) FROM transfers
+WHERE transfers.user_id = $1
+  AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.transfer_id = transfers.id AND t.deleted_at IS NOT NULL)
+`
+
+func (q *Queries) CountTransfers(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, CountTransfers, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CreateTransfer = `-- name: CreateTransfer :one
+
+INSERT INTO transfers (user_id, rate)
+VALUES ($1, $2)
+RETURNING id, user_id, rate, created_at
+`
+
+type CreateTransferParams struct {
+	UserID int64
+	Rate   pgtype.Numeric
+}
+
+// internal/database/queries/transfers.sql
+func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
+	row := q.db.QueryRow(ctx, CreateTransfer, arg.UserID, arg.Rate)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Rate,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const CreateTransferLeg = `-- name: CreateTransferLeg :one
+INSERT INTO transactions (
+  user_id, transfer_id, transfer_direction, type, amount, description,
+  category, currency, status, account, tags, notes, date, import_batch_id,
+  external_id
+)
+VALUES (
+  $1, $2, $3, 'transfer', $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+RETURNING id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id
+`
+
+type CreateTransferLegParams struct {
+	UserID            int64
+	TransferID        *int64
+	TransferDirection *string
+	Amount            pgtype.Numeric
+	Description       string
+	Category          string
+	Currency          string
+	Status            string
+	Account           string
+	Tags              []string
+	Notes             *string
+	Date              pgtype.Timestamptz
+	ImportBatchID     *int64
+	ExternalID        *string
+}
+
+func (q *Queries) CreateTransferLeg(ctx context.Context, arg CreateTransferLegParams) (Transaction, error) {
+	row := q.db.QueryRow(ctx, CreateTransferLeg,
+		arg.UserID,
+		arg.TransferID,
+		arg.TransferDirection,
+		arg.Amount,
+		arg.Description,
+		arg.Category,
+		arg.Currency,
+		arg.Status,
+		arg.Account,
+		arg.Tags,
+		arg.Notes,
+		arg.Date,
+		arg.ImportBatchID,
+		arg.ExternalID,
+	)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Amount,
+		&i.Description,
+		&i.Category,
+		&i.Date,
+		&i.Type,
+		&i.Currency,
+		&i.Status,
+		&i.Account,
+		&i.Tags,
+		&i.Notes,
+		&i.HasReceipt,
+		&i.ReceiptUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ImportBatchID,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.TransferID,
+		&i.TransferDirection,
+		&i.RecurringID,
+		&i.RecurringOccurrence,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ReconciliationID,
+		&i.PayeeID,
+	)
+	return i, err
+}
+
+const DeleteTransfer = `-- name: DeleteTransfer :execrows
+UPDATE transactions
+SET deleted_at = NOW()
+WHERE transfer_id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type DeleteTransferParams struct {
+	TransferID *int64
+	UserID     int64
+}
+
+// Moves both legs to the trash.
+func (q *Queries) DeleteTransfer(ctx context.Context, arg DeleteTransferParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteTransfer, arg.TransferID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const GetAccountBalances = `-- name: GetAccountBalances :many
+SELECT
+  account,
+  currency,
+  SUM(CASE WHEN type = 'expense' OR transfer_direction = 'out' THEN -amount ELSE amount END)::numeric AS balance,
+  COUNT(*) AS count
+FROM transactions
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND (
+    $2::text IS NULL
+    OR transaction_search_document(description, notes, tags, category, account) @@ websearch_to_tsquery('simple', $2)
+    OR $2 <% description
+    OR description ILIKE '%' || $2 || '%'
+  )
+  AND ($3::date IS NULL OR date >= $3::date)
+  AND ($4::date IS NULL OR date <= $4::date)
+  AND EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND ($5::text[] IS NULL OR line.category = ANY($5::text[]))
+      AND ($6::numeric IS NULL OR line.amount >= $6::numeric)
+      AND ($7::numeric IS NULL OR line.amount <= $7::numeric)
+      AND ($8::text[] IS NULL OR line.tags && $8::text[])
+  )
+  -- A transaction with any excluded line is excluded whole.
+  AND NOT EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND (line.category = ANY($9::text[])
+        OR line.tags && $10::text[])
+  )
+  AND ($11::text IS NULL OR type = $11)
+  AND ($12::text[] IS NULL OR status = ANY($12::text[]))
+  AND ($13::text[] IS NULL OR account = ANY($13::text[]))
+  AND ($14::text[] IS NULL OR currency = ANY($14::text[]))
+  AND ($15::boolean IS NULL OR has_receipt = $15::boolean)
+  AND ($16::timestamptz IS NULL OR created_at >= $16::timestamptz)
+  AND ($17::timestamptz IS NULL OR updated_at >= $17::timestamptz)
+GROUP BY account, currency
+ORDER BY account, currency
+`
+
+type GetAccountBalancesParams struct {
+	UserID            int64
+	Search            *string
+	DateFrom          pgtype.Date
+	DateTo            pgtype.Date
+	Categories        []string
+	MinAmount         pgtype.Numeric
+	MaxAmount         pgtype.Numeric
+	Tags              []string
+	ExcludeCategories []string
+	ExcludeTags       []string
+	Type              *string
+	Statuses          []string
+	Accounts          []string
+	Currencies        []string
+	HasReceipt        *bool
+	CreatedSince      pgtype.Timestamptz
+	UpdatedSince      pgtype.Timestamptz
+}
+
+type GetAccountBalancesRow struct {
+	Account  string
+	Currency string
+	Balance  pgtype.Numeric
+	Count    int64
+}
+
+// Income adds to an account and expenses subtract from it; transfers move
+// money out of the source account and into the destination. Takes the
+// filters of ListTransactionsWithFilters.
+func (q *Queries) GetAccountBalances(ctx context.Context, arg GetAccountBalancesParams) ([]GetAccountBalancesRow, error) {
+	rows, err := q.db.Query(ctx, GetAccountBalances,
+		arg.UserID,
+		arg.Search,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Categories,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Tags,
+		arg.ExcludeCategories,
+		arg.ExcludeTags,
+		arg.Type,
+		arg.Statuses,
+		arg.Accounts,
+		arg.Currencies,
+		arg.HasReceipt,
+		arg.CreatedSince,
+		arg.UpdatedSince,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAccountBalancesRow
+	for rows.Next() {
+		var i GetAccountBalancesRow
+		if err := rows.Scan(
+			&i.Account,
+			&i.Currency,
+			&i.Balance,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetTransfer = `-- name: GetTransfer :one
+SELECT id, user_id, rate, created_at FROM transfers
+WHERE transfers.id = $1 AND transfers.user_id = $2
+  AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.transfer_id = transfers.id AND t.deleted_at IS NOT NULL)
+`
+
+type GetTransferParams struct {
+	ID     int64
+	UserID int64
+}
+
+// Transfers whose legs are in the trash are left out, here and below.
+func (q *Queries) GetTransfer(ctx context.Context, arg GetTransferParams) (Transfer, error) {
+	row := q.db.QueryRow(ctx, GetTransfer, arg.ID, arg.UserID)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Rate,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const ListTransferLegs = `-- name: ListTransferLegs :many
+SELECT id, user_id, amount, description, category, date, type, currency, status, account, tags, notes, has_receipt, receipt_url, created_at, updated_at, import_batch_id, external_id, fingerprint, transfer_id, transfer_direction, recurring_id, recurring_occurrence, deleted_at, version, reconciliation_id, payee_id FROM transactions
+WHERE user_id = $1 AND transfer_id = ANY($2::bigint[]) AND deleted_at IS NULL
+ORDER BY transfer_id, transfer_direction DESC
+`
+
+type ListTransferLegsParams struct {
+	UserID      int64
+	TransferIds []int64
+}
+
+// Legs of the given transfers, the source leg of each first.
+func (q *Queries) ListTransferLegs(ctx context.Context, arg ListTransferLegsParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, ListTransferLegs, arg.UserID, arg.TransferIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.Description,
+			&i.Category,
+			&i.Date,
+			&i.Type,
+			&i.Currency,
+			&i.Status,
+			&i.Account,
+			&i.Tags,
+			&i.Notes,
+			&i.HasReceipt,
+			&i.ReceiptUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ImportBatchID,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.TransferID,
+			&i.TransferDirection,
+			&i.RecurringID,
+			&i.RecurringOccurrence,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ReconciliationID,
+			&i.PayeeID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTransfers = `-- name: ListTransfers :many
+SELECT id, user_id, rate, created_at FROM transfers
+WHERE transfers.user_id = $1
+  AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.transfer_id = transfers.id AND t.deleted_at IS NOT NULL)
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListTransfersParams struct {
+	UserID int64
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.Query(ctx, ListTransfers, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transfer
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Rate,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
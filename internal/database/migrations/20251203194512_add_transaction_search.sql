@@ -0,0 +1,13 @@
+-- Add extension "pg_trgm"
+CREATE EXTENSION IF NOT EXISTS "pg_trgm" WITH SCHEMA "public";
+-- Create "transaction_search_document" function
+CREATE FUNCTION "public"."transaction_search_document" ("description" text, "notes" text, "tags" text[], "category" text, "account" text) RETURNS tsvector LANGUAGE sql IMMUTABLE AS $$
+SELECT setweight(to_tsvector('simple', coalesce(description, '')), 'A')
+    || setweight(to_tsvector('simple', coalesce(category, '') || ' ' || coalesce(account, '')), 'B')
+    || setweight(to_tsvector('simple', array_to_string(tags, ' ')), 'B')
+    || setweight(to_tsvector('simple', coalesce(notes, '')), 'C')
+$$;
+-- Create index "idx_transactions_search" to table: "transactions"
+CREATE INDEX "idx_transactions_search" ON "public"."transactions" USING gin ((public.transaction_search_document(description, notes, tags, category, account)));
+-- Create index "idx_transactions_description_trgm" to table: "transactions"
+CREATE INDEX "idx_transactions_description_trgm" ON "public"."transactions" USING gin ("description" gin_trgm_ops);
@@ -0,0 +1,30 @@
+-- Create "import_batches" table
+CREATE TABLE "public"."import_batches" (
+  "id" bigserial NOT NULL,
+  "user_id" bigint NOT NULL,
+  "source" text NOT NULL,
+  "filename" text NOT NULL DEFAULT '',
+  "row_count" integer NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_import_batches_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_import_batches_user" to table: "import_batches"
+CREATE INDEX "idx_import_batches_user" ON "public"."import_batches" ("user_id");
+-- Create "import_mappings" table
+CREATE TABLE "public"."import_mappings" (
+  "id" bigserial NOT NULL,
+  "user_id" bigint NOT NULL,
+  "bank" text NOT NULL,
+  "options" jsonb NOT NULL DEFAULT '{}',
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_import_mappings_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "import_mappings_user_bank_key" to table: "import_mappings"
+CREATE UNIQUE INDEX "import_mappings_user_bank_key" ON "public"."import_mappings" ("user_id", "bank");
+-- Modify "transactions" table
+ALTER TABLE "public"."transactions" ADD COLUMN "import_batch_id" bigint NULL, ADD CONSTRAINT "fk_transactions_import_batch" FOREIGN KEY ("import_batch_id") REFERENCES "public"."import_batches" ("id") ON UPDATE NO ACTION ON DELETE SET NULL;
+-- Create index "idx_transactions_import_batch" to table: "transactions"
+CREATE INDEX "idx_transactions_import_batch" ON "public"."transactions" ("import_batch_id");
@@ -0,0 +1,2 @@
+-- Modify "transactions" table
+ALTER TABLE "public"."transactions" ADD COLUMN "external_id" text NULL;
@@ -0,0 +1,16 @@
+-- Create "transaction_fingerprint" function
+CREATE FUNCTION "public"."transaction_fingerprint" ("date" timestamptz, "amount" numeric, "type" text, "description" text, "account" text) RETURNS text LANGUAGE sql IMMUTABLE AS $$
+SELECT md5(concat_ws('|',
+    to_char(date AT TIME ZONE 'UTC', 'YYYY-MM-DD'),
+    trim_scale(amount)::text,
+    type,
+    btrim(regexp_replace(lower(description), '[^[:alnum:]]+', ' ', 'g')),
+    lower(btrim(account))
+))
+$$;
+-- Modify "transactions" table
+ALTER TABLE "public"."transactions" ADD COLUMN "fingerprint" text NOT NULL GENERATED ALWAYS AS (public.transaction_fingerprint(date, amount, type, description, account)) STORED;
+-- Create index "idx_transactions_fingerprint" to table: "transactions"
+CREATE INDEX "idx_transactions_fingerprint" ON "public"."transactions" ("user_id", "fingerprint");
+-- Create index "idx_transactions_external_id" to table: "transactions"
+CREATE INDEX "idx_transactions_external_id" ON "public"."transactions" ("user_id", "account", "external_id") WHERE (external_id IS NOT NULL);
@@ -0,0 +1,2 @@
+-- Modify "transactions" table
+ALTER TABLE "public"."transactions" ADD COLUMN "receipt_key" text NULL, ADD COLUMN "receipt_content_type" text NULL, ADD COLUMN "receipt_size" bigint NULL, ADD COLUMN "receipt_thumbnail_key" text NULL;
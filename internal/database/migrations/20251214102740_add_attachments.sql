@@ -0,0 +1,73 @@
+-- Create "attachments" table
+CREATE TABLE "public"."attachments" (
+  "id" bigserial NOT NULL,
+  "user_id" bigint NOT NULL,
+  "transaction_id" bigint NOT NULL,
+  "filename" text NOT NULL,
+  "content_type" text NOT NULL,
+  "size" bigint NOT NULL,
+  "checksum" text NULL,
+  "storage_key" text NOT NULL,
+  "thumbnail_key" text NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_attachments_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_attachments_transaction" FOREIGN KEY ("transaction_id") REFERENCES "public"."transactions" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_attachments_transaction" to table: "attachments"
+CREATE INDEX "idx_attachments_transaction" ON "public"."attachments" ("transaction_id");
+-- Create index "attachments_transaction_checksum_key" to table: "attachments"
+CREATE UNIQUE INDEX "attachments_transaction_checksum_key" ON "public"."attachments" ("transaction_id", "checksum");
+-- Create index "idx_attachments_checksum" to table: "attachments"
+CREATE INDEX "idx_attachments_checksum" ON "public"."attachments" ("user_id", "checksum");
+-- Create index "idx_attachments_storage_key" to table: "attachments"
+CREATE INDEX "idx_attachments_storage_key" ON "public"."attachments" ("storage_key");
+-- Create "storage_deletions" table
+CREATE TABLE "public"."storage_deletions" (
+  "storage_key" text NOT NULL,
+  "queued_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("storage_key")
+);
+-- Create "queue_attachment_deletion" function
+CREATE FUNCTION "public"."queue_attachment_deletion" () RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  INSERT INTO storage_deletions (storage_key)
+  SELECT key FROM unnest(ARRAY[OLD.storage_key, OLD.thumbnail_key]) AS key
+  WHERE key IS NOT NULL
+  ON CONFLICT DO NOTHING;
+  RETURN OLD;
+END
+$$;
+-- Create trigger "attachments_queue_deletion"
+CREATE TRIGGER "attachments_queue_deletion" AFTER DELETE ON "public"."attachments" FOR EACH ROW EXECUTE FUNCTION "public"."queue_attachment_deletion"();
+-- Create "derive_has_receipt" function
+CREATE FUNCTION "public"."derive_has_receipt" () RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  NEW.has_receipt := NEW.receipt_url IS NOT NULL
+    OR EXISTS (SELECT 1 FROM attachments WHERE transaction_id = NEW.id);
+  RETURN NEW;
+END
+$$;
+-- Create trigger "transactions_derive_has_receipt"
+CREATE TRIGGER "transactions_derive_has_receipt" BEFORE INSERT OR UPDATE OF "has_receipt", "receipt_url" ON "public"."transactions" FOR EACH ROW EXECUTE FUNCTION "public"."derive_has_receipt"();
+-- Create "sync_has_receipt" function
+CREATE FUNCTION "public"."sync_has_receipt" () RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  -- Assigning has_receipt fires derive_has_receipt, which recomputes it.
+  UPDATE transactions SET has_receipt = has_receipt
+  WHERE id IN (OLD.transaction_id, NEW.transaction_id);
+  RETURN NULL;
+END
+$$;
+-- Create trigger "attachments_sync_has_receipt"
+CREATE TRIGGER "attachments_sync_has_receipt" AFTER INSERT OR DELETE OR UPDATE OF "transaction_id" ON "public"."attachments" FOR EACH ROW EXECUTE FUNCTION "public"."sync_has_receipt"();
+-- Move receipts uploaded to transactions into attachments
+INSERT INTO "public"."attachments" ("user_id", "transaction_id", "filename", "content_type", "size", "storage_key", "thumbnail_key", "created_at")
+SELECT "user_id", "id", 'receipt' || coalesce(substring("receipt_key" from '\.[a-z]+$'), ''), coalesce("receipt_content_type", 'application/octet-stream'), coalesce("receipt_size", 0), "receipt_key", "receipt_thumbnail_key", "updated_at"
+FROM "public"."transactions" WHERE "receipt_key" IS NOT NULL;
+-- The URL of uploaded receipts pointed at the removed receipt endpoint
+UPDATE "public"."transactions" SET "receipt_url" = NULL WHERE "receipt_key" IS NOT NULL;
+-- Derive "has_receipt" for every transaction
+UPDATE "public"."transactions" SET "has_receipt" = "has_receipt";
+-- Modify "transactions" table
+ALTER TABLE "public"."transactions" DROP COLUMN "receipt_key", DROP COLUMN "receipt_content_type", DROP COLUMN "receipt_size", DROP COLUMN "receipt_thumbnail_key";
@@ -0,0 +1,65 @@
+-- Create "transaction_splits" table
+CREATE TABLE "public"."transaction_splits" (
+  "id" bigserial NOT NULL,
+  "transaction_id" bigint NOT NULL,
+  "position" integer NOT NULL,
+  "amount" numeric(10,2) NOT NULL,
+  "category" text NOT NULL,
+  "tags" text[] NOT NULL DEFAULT '{}',
+  "notes" text NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_transaction_splits_transaction" FOREIGN KEY ("transaction_id") REFERENCES "public"."transactions" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "transaction_splits_transaction_position_key" to table: "transaction_splits"
+CREATE UNIQUE INDEX "transaction_splits_transaction_position_key" ON "public"."transaction_splits" ("transaction_id", "position");
+-- Create index "idx_transaction_splits_category" to table: "transaction_splits"
+CREATE INDEX "idx_transaction_splits_category" ON "public"."transaction_splits" ("category");
+-- Create "check_split_total" function
+CREATE FUNCTION "public"."check_split_total" ("tx_id" bigint) RETURNS void LANGUAGE plpgsql AS $$
+DECLARE
+  tx_amount numeric;
+  split_total numeric;
+BEGIN
+  SELECT amount INTO tx_amount FROM transactions WHERE id = tx_id;
+  IF NOT FOUND THEN
+    -- Deleted together with its splits.
+    RETURN;
+  END IF;
+  SELECT sum(amount) INTO split_total FROM transaction_splits WHERE transaction_id = tx_id;
+  IF split_total IS NOT NULL AND split_total <> tx_amount THEN
+    RAISE EXCEPTION 'split amounts of transaction % add up to %, not %', tx_id, split_total, tx_amount
+      USING ERRCODE = 'check_violation', CONSTRAINT = 'transaction_splits_total';
+  END IF;
+END
+$$;
+-- Create "enforce_split_total" function
+CREATE FUNCTION "public"."enforce_split_total" () RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  IF TG_TABLE_NAME = 'transactions' THEN
+    PERFORM check_split_total(NEW.id);
+    RETURN NULL;
+  END IF;
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    PERFORM check_split_total(OLD.transaction_id);
+  END IF;
+  IF TG_OP = 'INSERT' OR (TG_OP = 'UPDATE' AND NEW.transaction_id <> OLD.transaction_id) THEN
+    PERFORM check_split_total(NEW.transaction_id);
+  END IF;
+  RETURN NULL;
+END
+$$;
+-- Create trigger "transaction_splits_total"; deferred so that all splits of
+-- a transaction can be replaced within one database transaction
+CREATE CONSTRAINT TRIGGER "transaction_splits_total" AFTER INSERT OR UPDATE OR DELETE ON "public"."transaction_splits" DEFERRABLE INITIALLY DEFERRED FOR EACH ROW EXECUTE FUNCTION "public"."enforce_split_total"();
+-- Create trigger "transactions_split_total"
+CREATE CONSTRAINT TRIGGER "transactions_split_total" AFTER UPDATE OF "amount" ON "public"."transactions" DEFERRABLE INITIALLY DEFERRED FOR EACH ROW EXECUTE FUNCTION "public"."enforce_split_total"();
+-- Create "transaction_lines" view: one line per split of split transactions,
+-- otherwise the transaction itself
+CREATE VIEW "public"."transaction_lines" AS
+SELECT "t"."id" AS "transaction_id", NULL::bigint AS "split_id", "t"."amount", "t"."category", "t"."tags"
+FROM "public"."transactions" "t"
+WHERE NOT EXISTS (SELECT 1 FROM "public"."transaction_splits" "s" WHERE "s"."transaction_id" = "t"."id")
+UNION ALL
+SELECT "s"."transaction_id", "s"."id", "s"."amount", "s"."category", "t"."tags" || "s"."tags"
+FROM "public"."transaction_splits" "s"
+JOIN "public"."transactions" "t" ON "t"."id" = "s"."transaction_id";
@@ -0,0 +1,69 @@
+-- Create "transfers" table
+CREATE TABLE "public"."transfers" (
+  "id" bigserial NOT NULL,
+  "user_id" bigint NOT NULL,
+  "rate" numeric NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_transfers_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "transfers_rate_check" CHECK (rate > 0)
+);
+-- Modify "transactions" table
+ALTER TABLE "public"."transactions" ADD COLUMN "transfer_id" bigint NULL, ADD COLUMN "transfer_direction" text NULL, ADD CONSTRAINT "fk_transactions_transfer" FOREIGN KEY ("transfer_id") REFERENCES "public"."transfers" ("id") ON UPDATE NO ACTION ON DELETE CASCADE, ADD CONSTRAINT "transactions_transfer_direction_check" CHECK (transfer_direction IN ('out', 'in')), ADD CONSTRAINT "transactions_transfer_check" CHECK (((type = 'transfer') = (transfer_id IS NOT NULL)) AND ((transfer_id IS NULL) = (transfer_direction IS NULL)));
+-- Create index "transactions_transfer_direction_key" to table: "transactions"
+CREATE UNIQUE INDEX "transactions_transfer_direction_key" ON "public"."transactions" ("transfer_id", "transfer_direction");
+-- Create "delete_transfer" function
+CREATE FUNCTION "public"."delete_transfer" () RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  -- Deleting the transfer cascades to the other leg.
+  DELETE FROM transfers WHERE id = OLD.transfer_id;
+  RETURN NULL;
+END
+$$;
+-- Create trigger "transactions_delete_transfer"
+CREATE TRIGGER "transactions_delete_transfer" AFTER DELETE ON "public"."transactions" FOR EACH ROW WHEN (OLD.transfer_id IS NOT NULL) EXECUTE FUNCTION "public"."delete_transfer"();
+-- Create "sync_transfer_leg" function
+CREATE FUNCTION "public"."sync_transfer_leg" () RETURNS trigger LANGUAGE plpgsql AS $$
+DECLARE
+  other transactions%ROWTYPE;
+  new_amount numeric := NULL;
+BEGIN
+  -- Only edits made directly to a leg are copied, not the copy itself.
+  IF pg_trigger_depth() > 1 THEN
+    RETURN NULL;
+  END IF;
+  SELECT * INTO other FROM transactions
+  WHERE transfer_id = NEW.transfer_id AND id <> NEW.id;
+  IF NOT FOUND THEN
+    RETURN NULL;
+  END IF;
+
+  -- A new source amount is converted at the transfer's rate; a new
+  -- destination amount in another currency changes the rate instead.
+  IF NEW.amount <> OLD.amount THEN
+    IF other.currency = NEW.currency THEN
+      new_amount := NEW.amount;
+    ELSIF NEW.transfer_direction = 'out' THEN
+      SELECT round(NEW.amount * rate, 2) INTO new_amount FROM transfers WHERE id = NEW.transfer_id;
+    ELSIF other.amount <> 0 THEN
+      UPDATE transfers SET rate = NEW.amount / other.amount WHERE id = NEW.transfer_id;
+    END IF;
+  END IF;
+
+  -- Account, currency, category and status stay per leg.
+  UPDATE transactions
+  SET
+    description = NEW.description,
+    date = NEW.date,
+    notes = NEW.notes,
+    tags = NEW.tags,
+    amount = COALESCE(new_amount, amount),
+    updated_at = NOW()
+  WHERE id = other.id
+    AND (description, date, notes, tags, amount) IS DISTINCT FROM
+        (NEW.description, NEW.date, NEW.notes, NEW.tags, COALESCE(new_amount, other.amount));
+  RETURN NULL;
+END
+$$;
+-- Create trigger "transactions_sync_transfer_leg"
+CREATE TRIGGER "transactions_sync_transfer_leg" AFTER UPDATE OF "description", "date", "notes", "tags", "amount" ON "public"."transactions" FOR EACH ROW WHEN (NEW.transfer_id IS NOT NULL) EXECUTE FUNCTION "public"."sync_transfer_leg"();
@@ -0,0 +1,40 @@
+-- Create "recurring_transactions" table
+CREATE TABLE "public"."recurring_transactions" (
+  "id" bigserial NOT NULL,
+  "user_id" bigint NOT NULL,
+  "rrule" text NOT NULL,
+  "start_date" date NOT NULL,
+  "amount" numeric(10,2) NOT NULL,
+  "description" text NOT NULL,
+  "category" text NOT NULL,
+  "type" text NOT NULL DEFAULT 'expense',
+  "currency" text NOT NULL DEFAULT 'USD',
+  "account" text NOT NULL DEFAULT '',
+  "tags" text[] NOT NULL DEFAULT '{}',
+  "notes" text NULL,
+  "active" boolean NOT NULL DEFAULT true,
+  "generated_through" date NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_recurring_transactions_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_recurring_transactions_user" to table: "recurring_transactions"
+CREATE INDEX "idx_recurring_transactions_user" ON "public"."recurring_transactions" ("user_id");
+-- Create "recurring_exceptions" table
+CREATE TABLE "public"."recurring_exceptions" (
+  "recurring_id" bigint NOT NULL,
+  "occurrence" date NOT NULL,
+  "skip" boolean NOT NULL DEFAULT false,
+  "date" date NULL,
+  "amount" numeric(10,2) NULL,
+  "description" text NULL,
+  "category" text NULL,
+  "notes" text NULL,
+  PRIMARY KEY ("recurring_id", "occurrence"),
+  CONSTRAINT "fk_recurring_exceptions_recurring" FOREIGN KEY ("recurring_id") REFERENCES "public"."recurring_transactions" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Modify "transactions" table
+ALTER TABLE "public"."transactions" ADD COLUMN "recurring_id" bigint NULL, ADD COLUMN "recurring_occurrence" date NULL, ADD CONSTRAINT "fk_transactions_recurring" FOREIGN KEY ("recurring_id") REFERENCES "public"."recurring_transactions" ("id") ON UPDATE NO ACTION ON DELETE SET NULL;
+-- Create index "transactions_recurring_occurrence_key" to table: "transactions"
+CREATE UNIQUE INDEX "transactions_recurring_occurrence_key" ON "public"."transactions" ("recurring_id", "recurring_occurrence");
@@ -0,0 +1,4 @@
+-- Modify "transactions" table
+ALTER TABLE "public"."transactions" ADD COLUMN "deleted_at" timestamptz NULL;
+-- Create index "idx_transactions_trash" to table: "transactions"
+CREATE INDEX "idx_transactions_trash" ON "public"."transactions" ("user_id", "deleted_at") WHERE (deleted_at IS NOT NULL);
@@ -0,0 +1,76 @@
+-- Create "transaction_revisions" table
+CREATE TABLE "public"."transaction_revisions" (
+  "id" bigserial NOT NULL,
+  "transaction_id" bigint NOT NULL,
+  "user_id" bigint NOT NULL,
+  "action" text NOT NULL,
+  "actor" text NOT NULL,
+  "actor_id" bigint NULL,
+  "old_values" jsonb NULL,
+  "new_values" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_transaction_revisions_transaction" FOREIGN KEY ("transaction_id") REFERENCES "public"."transactions" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_transaction_revisions_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_transaction_revisions_actor" FOREIGN KEY ("actor_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT "transaction_revisions_action_check" CHECK (action IN ('create', 'update', 'delete', 'restore'))
+);
+-- Create index "idx_transaction_revisions_transaction" to table: "transaction_revisions"
+CREATE INDEX "idx_transaction_revisions_transaction" ON "public"."transaction_revisions" ("transaction_id", "id");
+-- Create "transaction_revision_values" function: the fields of a transaction
+-- that revisions record and revert
+CREATE FUNCTION "public"."transaction_revision_values" ("t" "public"."transactions") RETURNS jsonb LANGUAGE sql IMMUTABLE AS $$
+  SELECT jsonb_build_object(
+    'date', t.date,
+    'amount', t.amount,
+    'description', t.description,
+    'category', t.category,
+    'type', t.type,
+    'currency', t.currency,
+    'status', t.status,
+    'account', t.account,
+    'tags', t.tags,
+    'notes', t.notes
+  )
+$$;
+-- Create "record_transaction_revision" function
+CREATE FUNCTION "public"."record_transaction_revision" () RETURNS trigger LANGUAGE plpgsql AS $$
+DECLARE
+  -- Set with set_config('budgetctl.actor', ..., true) by changes the server
+  -- makes on its own; everything else is done by the owner.
+  actor text := COALESCE(NULLIF(current_setting('budgetctl.actor', true), ''), 'user');
+  action text;
+  old_values jsonb;
+  new_values jsonb := transaction_revision_values(NEW);
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    action := 'create';
+  ELSE
+    old_values := transaction_revision_values(OLD);
+    IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+      action := 'delete';
+    ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+      action := 'restore';
+    ELSIF old_values = new_values THEN
+      RETURN NULL;
+    ELSE
+      action := 'update';
+    END IF;
+  END IF;
+
+  INSERT INTO transaction_revisions (transaction_id, user_id, action, actor, actor_id, old_values, new_values)
+  VALUES (NEW.id, NEW.user_id, action, actor, CASE WHEN actor = 'user' THEN NEW.user_id END, old_values, new_values);
+  RETURN NULL;
+END
+$$;
+-- Create trigger "transactions_record_revision"
+CREATE TRIGGER "transactions_record_revision" AFTER INSERT OR UPDATE ON "public"."transactions" FOR EACH ROW EXECUTE FUNCTION "public"."record_transaction_revision"();
+-- Create "reject_revision_change" function
+CREATE FUNCTION "public"."reject_revision_change" () RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  RAISE EXCEPTION 'transaction revisions are append-only';
+END
+$$;
+-- Create trigger "transaction_revisions_append_only"; rows are only deleted
+-- along with their transaction
+CREATE TRIGGER "transaction_revisions_append_only" BEFORE UPDATE ON "public"."transaction_revisions" FOR EACH ROW EXECUTE FUNCTION "public"."reject_revision_change"();
@@ -0,0 +1,11 @@
+-- Modify "transactions" table
+ALTER TABLE "public"."transactions" ADD COLUMN "version" bigint NOT NULL DEFAULT 1;
+-- Create "bump_transaction_version" function
+CREATE FUNCTION "public"."bump_transaction_version" () RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  NEW.version := OLD.version + 1;
+  RETURN NEW;
+END
+$$;
+-- Create trigger "transactions_bump_version"
+CREATE TRIGGER "transactions_bump_version" BEFORE UPDATE ON "public"."transactions" FOR EACH ROW EXECUTE FUNCTION "public"."bump_transaction_version"();
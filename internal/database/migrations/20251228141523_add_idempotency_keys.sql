@@ -0,0 +1,14 @@
+-- Create "idempotency_keys" table
+CREATE TABLE "public"."idempotency_keys" (
+  "user_id" bigint NOT NULL,
+  "key" text NOT NULL,
+  "request_hash" text NOT NULL,
+  "status" integer NULL,
+  "headers" jsonb NULL,
+  "body" bytea NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("user_id", "key"),
+  CONSTRAINT "fk_idempotency_keys_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_idempotency_keys_created_at" to table: "idempotency_keys"
+CREATE INDEX "idx_idempotency_keys_created_at" ON "public"."idempotency_keys" ("created_at");
@@ -0,0 +1,43 @@
+-- Create "reconciliations" table
+CREATE TABLE "public"."reconciliations" (
+  "id" bigserial NOT NULL,
+  "user_id" bigint NOT NULL,
+  "account" text NOT NULL,
+  "currency" text NOT NULL DEFAULT 'USD',
+  "statement_date" date NOT NULL,
+  "ending_balance" numeric NOT NULL,
+  "locked_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_reconciliations_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_reconciliations_account" to table: "reconciliations"
+CREATE INDEX "idx_reconciliations_account" ON "public"."reconciliations" ("user_id", "account", "currency");
+-- Create index "reconciliations_open_key" to table: "reconciliations"
+CREATE UNIQUE INDEX "reconciliations_open_key" ON "public"."reconciliations" ("user_id", "account", "currency") WHERE (locked_at IS NULL);
+-- Modify "transactions" table
+ALTER TABLE "public"."transactions" ADD COLUMN "reconciliation_id" bigint NULL, ADD CONSTRAINT "fk_transactions_reconciliation" FOREIGN KEY ("reconciliation_id") REFERENCES "public"."reconciliations" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION;
+-- Create index "idx_transactions_reconciliation" to table: "transactions"
+CREATE INDEX "idx_transactions_reconciliation" ON "public"."transactions" ("reconciliation_id");
+-- Create "protect_reconciled_transaction" function
+CREATE FUNCTION "public"."protect_reconciled_transaction" () RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  -- Reconciled transactions make up locked statement balances, so nothing
+  -- that counts toward a balance may change. Category, tags, description
+  -- and notes can still be edited.
+  IF NEW.date IS DISTINCT FROM OLD.date
+    OR NEW.amount IS DISTINCT FROM OLD.amount
+    OR NEW.type IS DISTINCT FROM OLD.type
+    OR NEW.currency IS DISTINCT FROM OLD.currency
+    OR NEW.account IS DISTINCT FROM OLD.account
+    OR NEW.status IS DISTINCT FROM OLD.status
+    OR NEW.deleted_at IS DISTINCT FROM OLD.deleted_at
+    OR NEW.reconciliation_id IS DISTINCT FROM OLD.reconciliation_id THEN
+    RAISE EXCEPTION 'transaction % is reconciled; its date, amount, type, currency, account and status can no longer change and it cannot be deleted', OLD.id
+      USING ERRCODE = 'check_violation', CONSTRAINT = 'transactions_reconciled';
+  END IF;
+  RETURN NEW;
+END
+$$;
+-- Create trigger "transactions_protect_reconciled"
+CREATE TRIGGER "transactions_protect_reconciled" BEFORE UPDATE ON "public"."transactions" FOR EACH ROW WHEN (OLD.status = 'reconciled') EXECUTE FUNCTION "public"."protect_reconciled_transaction"();
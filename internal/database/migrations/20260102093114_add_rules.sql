@@ -0,0 +1,26 @@
+-- Create "rules" table
+CREATE TABLE "public"."rules" (
+  "id" bigserial NOT NULL,
+  "user_id" bigint NOT NULL,
+  "name" text NOT NULL,
+  "priority" integer NOT NULL DEFAULT 0,
+  "enabled" boolean NOT NULL DEFAULT true,
+  "description_pattern" text NULL,
+  "min_amount" numeric NULL,
+  "max_amount" numeric NULL,
+  "account" text NULL,
+  "currency" text NULL,
+  "type" text NULL,
+  "set_category" text NULL,
+  "add_tags" text[] NOT NULL DEFAULT '{}',
+  "set_notes" text NULL,
+  "set_status" text NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_rules_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "rules_amount_range_check" CHECK ((min_amount IS NULL) OR (max_amount IS NULL) OR (min_amount <= max_amount)),
+  CONSTRAINT "rules_type_check" CHECK (type IN ('income', 'expense', 'transfer'))
+);
+-- Create index "idx_rules_user_priority" to table: "rules"
+CREATE INDEX "idx_rules_user_priority" ON "public"."rules" ("user_id", "priority", "id");
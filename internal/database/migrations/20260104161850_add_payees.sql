@@ -0,0 +1,19 @@
+-- Create "payees" table
+CREATE TABLE "public"."payees" (
+  "id" bigserial NOT NULL,
+  "user_id" bigint NOT NULL,
+  "name" text NOT NULL,
+  "aliases" text[] NOT NULL DEFAULT '{}',
+  "patterns" text[] NOT NULL DEFAULT '{}',
+  "default_category" text NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_payees_user" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "payees_name_key" to table: "payees"
+CREATE UNIQUE INDEX "payees_name_key" ON "public"."payees" ("user_id", (lower(name)));
+-- Modify "transactions" table
+ALTER TABLE "public"."transactions" ADD COLUMN "payee_id" bigint NULL, ADD CONSTRAINT "fk_transactions_payee" FOREIGN KEY ("payee_id") REFERENCES "public"."payees" ("id") ON UPDATE NO ACTION ON DELETE SET NULL;
+-- Create index "idx_transactions_payee" to table: "transactions"
+CREATE INDEX "idx_transactions_payee" ON "public"."transactions" ("payee_id");
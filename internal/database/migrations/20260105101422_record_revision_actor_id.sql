@@ -0,0 +1,35 @@
+-- Modify "record_transaction_revision" function: credit user changes to the
+-- user that made them rather than to the owner of the transaction
+CREATE OR REPLACE FUNCTION "public"."record_transaction_revision" () RETURNS trigger LANGUAGE plpgsql AS $$
+DECLARE
+  -- Set with set_config('budgetctl.actor', ..., true) by changes the server
+  -- makes on its own; everything else is done by a user.
+  actor text := COALESCE(NULLIF(current_setting('budgetctl.actor', true), ''), 'user');
+  -- Set with set_config('budgetctl.actor_id', ..., true) by the handlers
+  -- to the user making the request. Unknown for changes made outside the
+  -- server.
+  actor_id bigint := NULLIF(current_setting('budgetctl.actor_id', true), '')::bigint;
+  action text;
+  old_values jsonb;
+  new_values jsonb := transaction_revision_values(NEW);
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    action := 'create';
+  ELSE
+    old_values := transaction_revision_values(OLD);
+    IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+      action := 'delete';
+    ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+      action := 'restore';
+    ELSIF old_values = new_values THEN
+      RETURN NULL;
+    ELSE
+      action := 'update';
+    END IF;
+  END IF;
+
+  INSERT INTO transaction_revisions (transaction_id, user_id, action, actor, actor_id, old_values, new_values)
+  VALUES (NEW.id, NEW.user_id, action, actor, CASE WHEN actor = 'user' THEN actor_id END, old_values, new_values);
+  RETURN NULL;
+END
+$$;
@@ -0,0 +1,29 @@
+-- Modify "protect_reconciled_transaction" function: protect the
+-- transactions of locked reconciliations rather than every transaction
+-- whose status says reconciled
+CREATE OR REPLACE FUNCTION "public"."protect_reconciled_transaction" () RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM reconciliations WHERE id = OLD.reconciliation_id AND locked_at IS NOT NULL) THEN
+    RETURN NEW;
+  END IF;
+  -- Reconciled transactions make up locked statement balances, so nothing
+  -- that counts toward a balance may change. Category, tags, description
+  -- and notes can still be edited.
+  IF NEW.date IS DISTINCT FROM OLD.date
+    OR NEW.amount IS DISTINCT FROM OLD.amount
+    OR NEW.type IS DISTINCT FROM OLD.type
+    OR NEW.currency IS DISTINCT FROM OLD.currency
+    OR NEW.account IS DISTINCT FROM OLD.account
+    OR NEW.status IS DISTINCT FROM OLD.status
+    OR NEW.deleted_at IS DISTINCT FROM OLD.deleted_at
+    OR NEW.reconciliation_id IS DISTINCT FROM OLD.reconciliation_id THEN
+    RAISE EXCEPTION 'transaction % is reconciled; its date, amount, type, currency, account and status can no longer change and it cannot be deleted', OLD.id
+      USING ERRCODE = 'check_violation', CONSTRAINT = 'transactions_reconciled';
+  END IF;
+  RETURN NEW;
+END
+$$;
+-- Drop trigger "transactions_protect_reconciled"
+DROP TRIGGER "transactions_protect_reconciled" ON "public"."transactions";
+-- Create trigger "transactions_protect_reconciled"
+CREATE TRIGGER "transactions_protect_reconciled" BEFORE UPDATE ON "public"."transactions" FOR EACH ROW WHEN (OLD.reconciliation_id IS NOT NULL) EXECUTE FUNCTION "public"."protect_reconciled_transaction"();
@@ -0,0 +1,30 @@
+-- Modify "transaction_revision_values" function: record the payee, so that
+-- reverting a transaction restores it
+CREATE OR REPLACE FUNCTION "public"."transaction_revision_values" ("t" "public"."transactions") RETURNS jsonb LANGUAGE sql IMMUTABLE AS $$
+  SELECT jsonb_build_object(
+    'date', t.date,
+    'amount', t.amount,
+    'description', t.description,
+    'category', t.category,
+    'type', t.type,
+    'currency', t.currency,
+    'status', t.status,
+    'account', t.account,
+    'tags', t.tags,
+    'notes', t.notes,
+    'payee_id', t.payee_id
+  )
+$$;
+-- Modify "reject_revision_change" function: let the foreign keys cascade
+-- deletes from transactions and users, which run it from a nested trigger
+CREATE OR REPLACE FUNCTION "public"."reject_revision_change" () RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  IF TG_OP = 'DELETE' AND pg_trigger_depth() > 1 THEN
+    RETURN OLD;
+  END IF;
+  RAISE EXCEPTION 'transaction revisions are append-only';
+END
+$$;
+-- Create trigger "transaction_revisions_no_delete"; rows are only deleted
+-- along with their transaction
+CREATE TRIGGER "transaction_revisions_no_delete" BEFORE DELETE ON "public"."transaction_revisions" FOR EACH ROW EXECUTE FUNCTION "public"."reject_revision_change"();
@@ -0,0 +1,82 @@
+-- internal/database/queries/attachments.sql
+
+-- name: ListAttachments :many
+SELECT * FROM attachments
+WHERE transaction_id = $1 AND user_id = $2
+ORDER BY created_at, id;
+
+-- name: GetAttachment :one
+SELECT * FROM attachments
+WHERE id = $1 AND transaction_id = $2 AND user_id = $3;
+
+-- name: FindAttachmentByChecksum :one
+-- Any attachment of the user with the same content; its stored object is
+-- reused instead of uploading the file again.
+SELECT * FROM attachments
+WHERE user_id = $1 AND checksum = $2
+ORDER BY id
+LIMIT 1;
+
+-- name: CreateAttachment :one
+INSERT INTO attachments (
+  user_id, transaction_id, filename, content_type, size, checksum,
+  storage_key, thumbnail_key
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: RenameAttachment :one
+UPDATE attachments
+SET filename = $4
+WHERE id = $1 AND transaction_id = $2 AND user_id = $3
+RETURNING *;
+
+-- name: DeleteAttachment :one
+DELETE FROM attachments
+WHERE id = $1 AND transaction_id = $2 AND user_id = $3
+RETURNING *;
+
+-- name: MoveAttachments :exec
+-- Moves the attachments of merged duplicates to the kept transaction,
+-- skipping files it already has. The skipped rows are deleted with their
+-- transactions.
+UPDATE attachments
+SET transaction_id = sqlc.arg(keep_id)
+WHERE id IN (
+  SELECT DISTINCT ON (COALESCE(a.checksum, a.id::text)) a.id
+  FROM attachments a
+  WHERE a.user_id = sqlc.arg(user_id)
+    AND a.transaction_id = ANY(sqlc.arg(duplicate_ids)::bigint[])
+    AND NOT EXISTS (
+      SELECT 1 FROM attachments k
+      WHERE k.transaction_id = sqlc.arg(keep_id) AND k.checksum = a.checksum
+    )
+  ORDER BY COALESCE(a.checksum, a.id::text), a.id
+);
+
+-- name: LockStorageKey :exec
+-- Serialises uploads and deletions of the same stored object until the end
+-- of the transaction.
+SELECT pg_advisory_xact_lock(hashtext(sqlc.arg(storage_key)::text));
+
+-- name: StorageKeyInUse :one
+SELECT EXISTS (
+  SELECT 1 FROM attachments
+  WHERE storage_key = sqlc.arg(storage_key)::text OR thumbnail_key = sqlc.arg(storage_key)::text
+);
+
+-- name: QueueStorageDeletion :exec
+INSERT INTO storage_deletions (storage_key)
+VALUES ($1)
+ON CONFLICT DO NOTHING;
+
+-- name: ListStorageDeletions :many
+SELECT storage_key FROM storage_deletions
+ORDER BY queued_at
+LIMIT $1;
+
+-- name: DeleteStorageDeletion :exec
+DELETE FROM storage_deletions
+WHERE storage_key = $1;
@@ -0,0 +1,99 @@
+-- internal/database/queries/duplicates.sql
+
+-- name: FindTransactionByExternalID :one
+SELECT id FROM transactions
+WHERE user_id = $1 AND account = $2 AND external_id = $3 AND deleted_at IS NULL
+ORDER BY id
+LIMIT 1;
+
+-- name: MatchFingerprint :one
+-- Computes the fingerprint a new transaction would get and counts the
+-- existing transactions sharing it. Rows whose bank reference differs are
+-- distinct transactions and are not counted.
+SELECT
+  f.fingerprint::text AS fingerprint,
+  count(t.id) AS existing,
+  COALESCE(min(t.id), 0)::bigint AS first_id
+FROM (
+  SELECT transaction_fingerprint(
+    sqlc.arg('date')::timestamptz,
+    sqlc.arg('amount')::numeric,
+    sqlc.arg('type')::text,
+    sqlc.arg('description')::text,
+    sqlc.arg('account')::text
+  ) AS fingerprint
+) f
+LEFT JOIN transactions t
+  ON t.user_id = sqlc.arg('user_id')
+  AND t.fingerprint = f.fingerprint
+  AND t.deleted_at IS NULL
+  AND (t.external_id IS NULL OR sqlc.narg('external_id')::text IS NULL OR t.external_id = sqlc.narg('external_id'))
+GROUP BY f.fingerprint;
+
+-- name: FindNearDuplicate :one
+-- Finds a transaction on the same account with the same amount booked within
+-- three days, preferring the closest date and most similar description.
+SELECT id FROM transactions
+WHERE user_id = sqlc.arg('user_id')
+  AND deleted_at IS NULL
+  AND account = sqlc.arg('account')
+  AND type = sqlc.arg('type')
+  AND amount = sqlc.arg('amount')
+  AND date BETWEEN sqlc.arg('date')::timestamptz - interval '3 days' AND sqlc.arg('date')::timestamptz + interval '3 days'
+  AND (external_id IS NULL OR sqlc.narg('external_id')::text IS NULL OR external_id <> sqlc.narg('external_id'))
+ORDER BY
+  abs(extract(epoch FROM date - sqlc.arg('date')::timestamptz)),
+  similarity(description, sqlc.arg('description')) DESC,
+  id
+LIMIT 1;
+
+-- name: ListDuplicateCandidates :many
+-- Pairs of transactions that look like the same booking: same account, type
+-- and amount within three days, without conflicting bank references.
+SELECT
+  sqlc.embed(a),
+  sqlc.embed(b),
+  (a.fingerprint = b.fingerprint)::boolean AS exact,
+  similarity(a.description, b.description)::float8 AS similarity
+FROM transactions a
+JOIN transactions b
+  ON b.user_id = a.user_id
+  AND b.id > a.id
+  AND b.account = a.account
+  AND b.type = a.type
+  AND b.amount = a.amount
+  AND b.date BETWEEN a.date - interval '3 days' AND a.date + interval '3 days'
+  AND b.deleted_at IS NULL
+WHERE a.user_id = $1
+  AND a.deleted_at IS NULL
+  AND (a.external_id IS NULL OR b.external_id IS NULL OR a.external_id = b.external_id)
+ORDER BY exact DESC, similarity DESC, a.date DESC, a.id, b.id
+LIMIT $2 OFFSET $3;
+
+-- name: CountDuplicateCandidates :one
+SELECT COUNT(*) FROM transactions a
+JOIN transactions b
+  ON b.user_id = a.user_id
+  AND b.id > a.id
+  AND b.account = a.account
+  AND b.type = a.type
+  AND b.amount = a.amount
+  AND b.date BETWEEN a.date - interval '3 days' AND a.date + interval '3 days'
+  AND b.deleted_at IS NULL
+WHERE a.user_id = $1
+  AND a.deleted_at IS NULL
+  AND (a.external_id IS NULL OR b.external_id IS NULL OR a.external_id = b.external_id);
+
+-- name: MergeTransactionDetails :one
+-- Stores the tags and notes combined from merged duplicates and fills in
+-- the bank reference and receipt when the kept transaction has none.
+UPDATE transactions
+SET
+  tags = sqlc.arg('tags'),
+  notes = sqlc.narg('notes'),
+  external_id = COALESCE(external_id, sqlc.narg('external_id')),
+  has_receipt = has_receipt OR sqlc.arg('has_receipt'),
+  receipt_url = COALESCE(receipt_url, sqlc.narg('receipt_url')),
+  updated_at = NOW()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
+RETURNING *;
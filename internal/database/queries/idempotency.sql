@@ -0,0 +1,34 @@
+-- internal/database/queries/idempotency.sql
+
+-- name: ClaimIdempotencyKey :one
+-- Claims a key for a new request. A key claimed before the cutoff has
+-- expired and is taken over; otherwise no row is returned.
+INSERT INTO idempotency_keys (user_id, key, request_hash)
+VALUES (sqlc.arg(user_id), sqlc.arg(key), sqlc.arg(request_hash))
+ON CONFLICT (user_id, key) DO UPDATE
+SET
+  request_hash = EXCLUDED.request_hash,
+  status = NULL,
+  headers = NULL,
+  body = NULL,
+  created_at = NOW()
+WHERE idempotency_keys.created_at < sqlc.arg(cutoff)
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE user_id = $1 AND key = $2;
+
+-- name: SaveIdempotentResponse :exec
+UPDATE idempotency_keys
+SET status = $3, headers = $4, body = $5
+WHERE user_id = $1 AND key = $2;
+
+-- name: ReleaseIdempotencyKey :exec
+-- Frees the key of a request that failed, so that it can be retried.
+DELETE FROM idempotency_keys
+WHERE user_id = $1 AND key = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < $1;
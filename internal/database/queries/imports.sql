@@ -0,0 +1,26 @@
+-- internal/database/queries/imports.sql
+
+-- name: CreateImportBatch :one
+INSERT INTO import_batches (user_id, source, filename, row_count)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListImportMappings :many
+SELECT * FROM import_mappings
+WHERE user_id = $1
+ORDER BY bank;
+
+-- name: GetImportMapping :one
+SELECT * FROM import_mappings
+WHERE user_id = $1 AND bank = $2;
+
+-- name: UpsertImportMapping :one
+INSERT INTO import_mappings (user_id, bank, options)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, bank) DO UPDATE
+SET options = EXCLUDED.options, updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteImportMapping :exec
+DELETE FROM import_mappings
+WHERE user_id = $1 AND bank = $2;
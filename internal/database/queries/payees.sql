@@ -0,0 +1,96 @@
+-- internal/database/queries/payees.sql
+
+-- name: CreatePayee :one
+INSERT INTO payees (user_id, name, aliases, patterns, default_category)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: EnsurePayee :one
+-- Returns the user's payee with the name, creating it if needed.
+INSERT INTO payees (user_id, name)
+VALUES ($1, $2)
+ON CONFLICT (user_id, lower(name)) DO UPDATE SET name = payees.name
+RETURNING *;
+
+-- name: GetPayee :one
+SELECT * FROM payees
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListPayees :many
+SELECT * FROM payees
+WHERE user_id = $1
+ORDER BY lower(name), id
+LIMIT $2 OFFSET $3;
+
+-- name: CountPayees :one
+SELECT COUNT(*) FROM payees
+WHERE user_id = $1;
+
+-- name: ListAllPayees :many
+-- Payees in the order their patterns are tried.
+SELECT * FROM payees
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: LockPayees :many
+SELECT * FROM payees
+WHERE user_id = sqlc.arg(user_id) AND id = ANY(sqlc.arg(ids)::bigint[])
+ORDER BY id
+FOR UPDATE;
+
+-- name: UpdatePayee :one
+UPDATE payees
+SET
+  name = $3,
+  aliases = $4,
+  patterns = $5,
+  default_category = $6,
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: SetPayeeDefaultCategory :one
+UPDATE payees
+SET default_category = $3, updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeletePayee :execrows
+DELETE FROM payees
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeletePayees :execrows
+DELETE FROM payees
+WHERE user_id = sqlc.arg(user_id) AND id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: ReassignPayeeTransactions :execrows
+-- Moves the transactions of merged payees, including deleted ones, to the
+-- payee they were merged into.
+UPDATE transactions
+SET payee_id = sqlc.arg(payee_id), updated_at = NOW()
+WHERE user_id = sqlc.arg(user_id) AND payee_id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: CategorizePayeeTransactions :execrows
+-- Gives the payee's uncategorized transactions its default category.
+UPDATE transactions
+SET category = sqlc.arg(category), updated_at = NOW()
+WHERE user_id = sqlc.arg(user_id) AND payee_id = sqlc.arg(payee_id)
+  AND category = sqlc.arg(uncategorized) AND deleted_at IS NULL;
+
+-- name: ListUnlinkedTransactions :many
+-- Transactions not linked to a payee yet, oldest first.
+SELECT id, description, category FROM transactions
+WHERE user_id = sqlc.arg(user_id) AND id > sqlc.arg(after)::bigint
+  AND payee_id IS NULL AND deleted_at IS NULL
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+-- name: CountUnlinkedTransactions :one
+SELECT COUNT(*) FROM transactions
+WHERE user_id = sqlc.arg(user_id) AND id > sqlc.arg(after)::bigint
+  AND payee_id IS NULL AND deleted_at IS NULL;
+
+-- name: LinkTransactionPayee :exec
+UPDATE transactions
+SET payee_id = $3, category = $4, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
@@ -0,0 +1,100 @@
+-- internal/database/queries/reconciliations.sql
+
+-- name: CreateReconciliation :one
+INSERT INTO reconciliations (user_id, account, currency, statement_date, ending_balance)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetReconciliation :one
+SELECT * FROM reconciliations
+WHERE id = $1 AND user_id = $2;
+
+-- name: LockReconciliation :one
+-- Keeps the statement from changing until the end of the transaction.
+SELECT * FROM reconciliations
+WHERE id = $1 AND user_id = $2
+FOR UPDATE;
+
+-- name: ListReconciliations :many
+SELECT * FROM reconciliations
+WHERE user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(account)::text IS NULL OR account = sqlc.narg(account))
+ORDER BY statement_date DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountReconciliations :one
+SELECT COUNT(*) FROM reconciliations
+WHERE user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(account)::text IS NULL OR account = sqlc.narg(account));
+
+-- name: UpdateReconciliation :one
+-- Only open reconciliations can change.
+UPDATE reconciliations
+SET statement_date = $3, ending_balance = $4
+WHERE id = $1 AND user_id = $2 AND locked_at IS NULL
+RETURNING *;
+
+-- name: DeleteReconciliation :execrows
+DELETE FROM reconciliations
+WHERE id = $1 AND user_id = $2 AND locked_at IS NULL;
+
+-- name: GetReconciliationBalances :one
+-- The opening balance adds up the transactions reconciled before this
+-- statement. The cleared balance adds the transactions this statement
+-- reconciled or, while it is open, the cleared ones up to its date.
+SELECT
+  COALESCE(SUM(CASE WHEN t.type = 'expense' OR t.transfer_direction = 'out' THEN -t.amount ELSE t.amount END)
+    FILTER (WHERE t.status = 'reconciled' AND (t.reconciliation_id IS NULL OR t.reconciliation_id < r.id)), 0)::numeric AS opening_balance,
+  COALESCE(SUM(CASE WHEN t.type = 'expense' OR t.transfer_direction = 'out' THEN -t.amount ELSE t.amount END)
+    FILTER (WHERE (t.status = 'reconciled' AND (t.reconciliation_id IS NULL OR t.reconciliation_id <= r.id))
+      OR (r.locked_at IS NULL AND t.status = 'cleared' AND (t.date AT TIME ZONE 'UTC')::date <= r.statement_date)), 0)::numeric AS cleared_balance,
+  (r.ending_balance - COALESCE(SUM(CASE WHEN t.type = 'expense' OR t.transfer_direction = 'out' THEN -t.amount ELSE t.amount END)
+    FILTER (WHERE (t.status = 'reconciled' AND (t.reconciliation_id IS NULL OR t.reconciliation_id <= r.id))
+      OR (r.locked_at IS NULL AND t.status = 'cleared' AND (t.date AT TIME ZONE 'UTC')::date <= r.statement_date)), 0))::numeric AS difference
+FROM reconciliations r
+LEFT JOIN transactions t
+  ON t.user_id = r.user_id AND t.account = r.account AND t.currency = r.currency AND t.deleted_at IS NULL
+WHERE r.id = $1 AND r.user_id = $2
+GROUP BY r.id;
+
+-- name: ListReconciliationTransactions :many
+-- The transactions a locked statement reconciled or, while it is open, the
+-- unreconciled ones of its account up to its date.
+SELECT t.* FROM transactions t
+JOIN reconciliations r ON r.user_id = t.user_id
+WHERE r.id = $1 AND r.user_id = $2 AND t.deleted_at IS NULL
+  AND (
+    t.reconciliation_id = r.id
+    OR (r.locked_at IS NULL AND t.account = r.account AND t.currency = r.currency
+      AND t.status <> 'reconciled' AND (t.date AT TIME ZONE 'UTC')::date <= r.statement_date)
+  )
+ORDER BY t.date, t.id;
+
+-- name: SetReconciliationMatches :many
+-- Sets the status of unreconciled transactions of an open statement,
+-- cleared when they match it and pending otherwise. Transactions outside
+-- the statement are left out.
+UPDATE transactions t
+SET status = sqlc.arg(status)
+FROM reconciliations r
+WHERE r.id = sqlc.arg(reconciliation_id) AND r.user_id = sqlc.arg(user_id) AND r.locked_at IS NULL
+  AND t.user_id = r.user_id AND t.id = ANY(sqlc.arg(ids)::bigint[])
+  AND t.account = r.account AND t.currency = r.currency AND t.deleted_at IS NULL
+  AND t.status <> 'reconciled' AND (t.date AT TIME ZONE 'UTC')::date <= r.statement_date
+RETURNING t.*;
+
+-- name: ReconcileTransactions :execrows
+-- Reconciles the cleared transactions of an open statement.
+UPDATE transactions t
+SET status = 'reconciled', reconciliation_id = r.id
+FROM reconciliations r
+WHERE r.id = $1 AND r.user_id = $2 AND r.locked_at IS NULL
+  AND t.user_id = r.user_id AND t.account = r.account AND t.currency = r.currency
+  AND t.deleted_at IS NULL AND t.status = 'cleared'
+  AND (t.date AT TIME ZONE 'UTC')::date <= r.statement_date;
+
+-- name: CloseReconciliation :one
+UPDATE reconciliations
+SET locked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND locked_at IS NULL
+RETURNING *;
@@ -0,0 +1,115 @@
+-- internal/database/queries/recurring.sql
+
+-- name: CreateRecurring :one
+INSERT INTO recurring_transactions (
+  user_id, rrule, start_date, amount, description, category, type,
+  currency, account, tags, notes, active
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING *;
+
+-- name: GetRecurring :one
+SELECT * FROM recurring_transactions
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListRecurring :many
+SELECT * FROM recurring_transactions
+WHERE user_id = $1
+ORDER BY created_at, id;
+
+-- name: ListActiveRecurring :many
+SELECT * FROM recurring_transactions
+WHERE user_id = $1 AND active
+ORDER BY created_at, id;
+
+-- name: UpdateRecurring :one
+-- generated_through is only moved forward, so occurrences that were already
+-- created are never created again.
+UPDATE recurring_transactions
+SET
+  rrule = $3,
+  start_date = $4,
+  amount = $5,
+  description = $6,
+  category = $7,
+  type = $8,
+  currency = $9,
+  account = $10,
+  tags = $11,
+  notes = $12,
+  active = $13,
+  generated_through = GREATEST(generated_through, sqlc.narg(generated_through)),
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteRecurring :execrows
+-- Transactions already created from the template are kept.
+DELETE FROM recurring_transactions
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListDueRecurringIDs :many
+-- Active templates of all users that have not been generated up to the date.
+SELECT id FROM recurring_transactions
+WHERE active AND (generated_through IS NULL OR generated_through < $1)
+ORDER BY id;
+
+-- name: LockRecurring :one
+SELECT * FROM recurring_transactions
+WHERE id = $1
+FOR UPDATE;
+
+-- name: SetRecurringGeneratedThrough :exec
+UPDATE recurring_transactions
+SET generated_through = $2
+WHERE id = $1;
+
+-- name: ListRecurringExceptions :many
+SELECT e.* FROM recurring_exceptions e
+JOIN recurring_transactions r ON r.id = e.recurring_id
+WHERE r.user_id = sqlc.arg(user_id)
+  AND e.recurring_id = ANY(sqlc.arg(recurring_ids)::bigint[])
+  AND e.occurrence BETWEEN sqlc.arg(date_from) AND sqlc.arg(date_to)
+ORDER BY e.recurring_id, e.occurrence;
+
+-- name: UpsertRecurringException :one
+INSERT INTO recurring_exceptions (
+  recurring_id, occurrence, skip, date, amount, description, category, notes
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+ON CONFLICT (recurring_id, occurrence) DO UPDATE
+SET
+  skip = EXCLUDED.skip,
+  date = EXCLUDED.date,
+  amount = EXCLUDED.amount,
+  description = EXCLUDED.description,
+  category = EXCLUDED.category,
+  notes = EXCLUDED.notes
+RETURNING *;
+
+-- name: DeleteRecurringException :execrows
+DELETE FROM recurring_exceptions
+WHERE recurring_id = $1 AND occurrence = $2;
+
+-- name: ListRecurringTransactions :many
+-- Transactions created for the given templates' occurrences in a range.
+SELECT id, recurring_id, recurring_occurrence FROM transactions
+WHERE user_id = sqlc.arg(user_id)
+  AND deleted_at IS NULL
+  AND recurring_id = ANY(sqlc.arg(recurring_ids)::bigint[])
+  AND recurring_occurrence BETWEEN sqlc.arg(date_from) AND sqlc.arg(date_to);
+
+-- name: CreateRecurringTransaction :execrows
+-- Creates the transaction for an occurrence unless it already exists.
+INSERT INTO transactions (
+  user_id, recurring_id, recurring_occurrence, amount, description,
+  category, type, currency, status, account, tags, notes, date
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, 'pending', $9, $10, $11, $12
+)
+ON CONFLICT (recurring_id, recurring_occurrence) DO NOTHING;
@@ -0,0 +1,47 @@
+-- internal/database/queries/revisions.sql
+
+-- name: ListTransactionRevisions :many
+-- Oldest first. Revisions of transactions in the trash are listed too.
+SELECT * FROM transaction_revisions
+WHERE transaction_id = $1 AND user_id = $2
+ORDER BY id;
+
+-- name: RevertTransaction :one
+-- Sets the recorded fields of a transaction to their values after the given
+-- revision. The revert is recorded as a new revision. Revisions recorded
+-- before payees were tracked keep the current payee, and a payee deleted
+-- since is cleared.
+UPDATE transactions t
+SET
+  date = (r.new_values->>'date')::timestamptz,
+  amount = (r.new_values->>'amount')::numeric,
+  description = r.new_values->>'description',
+  category = r.new_values->>'category',
+  type = r.new_values->>'type',
+  currency = r.new_values->>'currency',
+  status = r.new_values->>'status',
+  account = r.new_values->>'account',
+  tags = ARRAY(SELECT jsonb_array_elements_text(r.new_values->'tags')),
+  notes = r.new_values->>'notes',
+  payee_id = CASE
+    WHEN r.new_values->'payee_id' IS NULL THEN t.payee_id
+    ELSE (SELECT p.id FROM payees p WHERE p.id = (r.new_values->>'payee_id')::bigint AND p.user_id = t.user_id)
+  END,
+  updated_at = NOW()
+FROM transaction_revisions r
+WHERE r.id = sqlc.arg(revision_id)
+  AND r.transaction_id = t.id
+  AND t.id = sqlc.arg(id)
+  AND t.user_id = sqlc.arg(user_id)
+  AND t.deleted_at IS NULL
+RETURNING t.*;
+
+-- name: SetRevisionActor :exec
+-- Attributes the revisions recorded by the rest of the database transaction
+-- to a server job instead of the owner.
+SELECT set_config('budgetctl.actor', sqlc.arg(actor)::text, true);
+
+-- name: SetRevisionActorID :exec
+-- Credits the revisions recorded by the rest of the database transaction to
+-- the user making the request.
+SELECT set_config('budgetctl.actor_id', sqlc.arg(actor_id)::bigint::text, true);
@@ -0,0 +1,63 @@
+-- internal/database/queries/rules.sql
+
+-- name: CreateRule :one
+INSERT INTO rules (
+  user_id, name, priority, enabled, description_pattern, min_amount,
+  max_amount, account, currency, type, set_category, add_tags, set_notes,
+  set_status
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+RETURNING *;
+
+-- name: GetRule :one
+SELECT * FROM rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListRules :many
+-- Rules in the order they apply: by priority, then by creation.
+SELECT * FROM rules
+WHERE user_id = $1
+ORDER BY priority, id;
+
+-- name: ListEnabledRules :many
+SELECT * FROM rules
+WHERE user_id = $1 AND enabled
+ORDER BY priority, id;
+
+-- name: UpdateRule :one
+UPDATE rules
+SET
+  name = $3,
+  priority = $4,
+  enabled = $5,
+  description_pattern = $6,
+  min_amount = $7,
+  max_amount = $8,
+  account = $9,
+  currency = $10,
+  type = $11,
+  set_category = $12,
+  add_tags = $13,
+  set_notes = $14,
+  set_status = $15,
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: ApplyRuleChanges :one
+-- Saves the fields rules set on an existing transaction.
+UPDATE transactions
+SET
+  category = $3,
+  tags = $4,
+  notes = $5,
+  status = $6,
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING *;
@@ -0,0 +1,61 @@
+-- internal/database/queries/splits.sql
+
+-- name: ListSplits :many
+SELECT s.* FROM transaction_splits s
+JOIN transactions t ON t.id = s.transaction_id
+WHERE s.transaction_id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+ORDER BY s.position;
+
+-- name: DeleteSplits :exec
+-- The split total is checked when the database transaction commits, so the
+-- splits can be deleted and recreated in between.
+DELETE FROM transaction_splits
+WHERE transaction_id = $1;
+
+-- name: CreateSplit :one
+INSERT INTO transaction_splits (
+  transaction_id, position, amount, category, tags, notes
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetCategoryTotals :many
+-- Totals per category, counting the lines of split transactions instead of
+-- their whole amount. Takes the filters of ListTransactionsWithFilters, with
+-- category, amount and tag filters applied to each line.
+SELECT
+  line.category,
+  t.type,
+  t.currency,
+  SUM(line.amount)::numeric AS total,
+  COUNT(*) AS count
+FROM transactions t
+JOIN transaction_lines line ON line.transaction_id = t.id
+WHERE t.user_id = sqlc.arg(user_id)
+  AND t.deleted_at IS NULL
+  AND (
+    sqlc.narg(search)::text IS NULL
+    OR transaction_search_document(t.description, t.notes, t.tags, t.category, t.account) @@ websearch_to_tsquery('simple', sqlc.narg(search))
+    OR sqlc.narg(search) <% t.description
+    OR t.description ILIKE '%' || sqlc.narg(search) || '%'
+  )
+  AND (sqlc.narg(date_from)::date IS NULL OR t.date >= sqlc.narg(date_from)::date)
+  AND (sqlc.narg(date_to)::date IS NULL OR t.date <= sqlc.narg(date_to)::date)
+  AND (sqlc.narg(categories)::text[] IS NULL OR line.category = ANY(sqlc.narg(categories)::text[]))
+  AND (sqlc.narg(type)::text IS NULL OR t.type = sqlc.narg(type))
+  AND (sqlc.narg(min_amount)::numeric IS NULL OR line.amount >= sqlc.narg(min_amount)::numeric)
+  AND (sqlc.narg(max_amount)::numeric IS NULL OR line.amount <= sqlc.narg(max_amount)::numeric)
+  AND (sqlc.narg(tags)::text[] IS NULL OR line.tags && sqlc.narg(tags)::text[])
+  AND (sqlc.narg(exclude_categories)::text[] IS NULL OR line.category <> ALL(sqlc.narg(exclude_categories)::text[]))
+  AND (sqlc.narg(exclude_tags)::text[] IS NULL OR NOT line.tags && sqlc.narg(exclude_tags)::text[])
+  AND (sqlc.narg(statuses)::text[] IS NULL OR t.status = ANY(sqlc.narg(statuses)::text[]))
+  AND (sqlc.narg(accounts)::text[] IS NULL OR t.account = ANY(sqlc.narg(accounts)::text[]))
+  AND (sqlc.narg(currencies)::text[] IS NULL OR t.currency = ANY(sqlc.narg(currencies)::text[]))
+  AND (sqlc.narg(has_receipt)::boolean IS NULL OR t.has_receipt = sqlc.narg(has_receipt)::boolean)
+  AND (sqlc.narg(created_since)::timestamptz IS NULL OR t.created_at >= sqlc.narg(created_since)::timestamptz)
+  AND (sqlc.narg(updated_since)::timestamptz IS NULL OR t.updated_at >= sqlc.narg(updated_since)::timestamptz)
+GROUP BY line.category, t.type, t.currency
+ORDER BY t.type, t.currency, total DESC, line.category;
+
@@ -3,16 +3,17 @@
 -- name: CreateTransaction :one
 INSERT INTO transactions (
   user_id, amount, description, category, type, currency, status,
-  account, tags, notes, has_receipt, receipt_url, date
+  account, tags, notes, has_receipt, receipt_url, date, import_batch_id,
+  external_id, payee_id
 )
 VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 )
 RETURNING *;
 
 -- name: GetTransactionByID :one
 SELECT * FROM transactions
-WHERE id = $1 AND user_id = $2;
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
 
 -- name: UpdateTransaction :one
 UPDATE transactions
@@ -28,64 +29,269 @@ SET
   notes = $10,
   has_receipt = $11,
   receipt_url = $12,
+  payee_id = $14,
   updated_at = NOW()
-WHERE id = $1 AND user_id = $13
+WHERE id = $1 AND user_id = $13 AND deleted_at IS NULL
 RETURNING *;
 
--- name: DeleteTransaction :exec
+-- name: DeleteTransaction :execrows
+-- Moves the transaction to the trash, along with the other leg of a transfer.
+UPDATE transactions
+SET deleted_at = NOW()
+WHERE transactions.user_id = sqlc.arg(user_id)
+  AND transactions.deleted_at IS NULL
+  AND (
+    transactions.id = sqlc.arg(id)
+    OR transactions.transfer_id = (SELECT t.transfer_id FROM transactions t WHERE t.id = sqlc.arg(id) AND t.user_id = sqlc.arg(user_id))
+  );
+
+-- name: ListTrash :many
+SELECT * FROM transactions
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC, id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountTrash :one
+SELECT COUNT(*) FROM transactions
+WHERE user_id = $1 AND deleted_at IS NOT NULL;
+
+-- name: RestoreTransaction :execrows
+-- Takes the transaction out of the trash, along with the other leg of a
+-- transfer.
+UPDATE transactions
+SET deleted_at = NULL
+WHERE transactions.user_id = sqlc.arg(user_id)
+  AND transactions.deleted_at IS NOT NULL
+  AND (
+    transactions.id = sqlc.arg(id)
+    OR transactions.transfer_id = (SELECT t.transfer_id FROM transactions t WHERE t.id = sqlc.arg(id) AND t.user_id = sqlc.arg(user_id))
+  );
+
+-- name: PurgeTransaction :execrows
+-- Deletes a transaction in the trash for good. Deleting a transfer leg
+-- deletes the other leg too.
+DELETE FROM transactions
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
+
+-- name: EmptyTrash :execrows
 DELETE FROM transactions
-WHERE id = $1 AND user_id = $2;
+WHERE user_id = $1 AND deleted_at IS NOT NULL;
+
+-- name: PurgeExpiredTrash :execrows
+-- Deletes the transactions of all users that were trashed before the cutoff.
+DELETE FROM transactions
+WHERE deleted_at < $1;
 
 -- name: ListTransactions :many
 SELECT * FROM transactions
-WHERE user_id = $1
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY date DESC
 LIMIT $2 OFFSET $3;
 
 -- name: ListTransactionsWithFilters :many
-SELECT * FROM transactions
-WHERE user_id = $1
-  AND ($2::text IS NULL OR description ILIKE '%' || $2 || '%')
-  AND ($3::date IS NULL OR date >= $3::date)
-  AND ($4::date IS NULL OR date <= $4::date)
-  AND ($5::text[] IS NULL OR category = ANY($5::text[]))
-  AND ($6::text IS NULL OR type = $6)
-  AND ($7::numeric IS NULL OR amount >= $7::numeric)
-  AND ($8::numeric IS NULL OR amount <= $8::numeric)
-  AND ($9::text[] IS NULL OR tags && $9::text[])
-ORDER BY date DESC
-LIMIT $10 OFFSET $11;
+-- Sort keys are whitelisted in the API layer; each of the first three
+-- entries of sort is either "<field>" (ascending) or "-<field>" (descending).
+-- Search combines full-text matching over description, notes, tags, category
+-- and account with trigram similarity on description for typo tolerance.
+-- Category, amount and tag filters must all match one line of the
+-- transaction: a split line, or the transaction itself when it is not split.
+SELECT
+  transactions.*,
+  search.relevance,
+  COALESCE(ts_headline('simple', description, websearch_to_tsquery('simple', sqlc.narg(search)), 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'), '')::text AS description_highlight,
+  COALESCE(ts_headline('simple', notes, websearch_to_tsquery('simple', sqlc.narg(search)), 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2'), '')::text AS notes_highlight
+FROM transactions
+CROSS JOIN LATERAL (
+  SELECT COALESCE(
+    ts_rank(transaction_search_document(description, notes, tags, category, account), websearch_to_tsquery('simple', sqlc.narg(search)))
+    + word_similarity(sqlc.narg(search), description),
+    0
+  )::real AS relevance
+) search
+WHERE user_id = sqlc.arg(user_id)
+  AND deleted_at IS NULL
+  AND (
+    sqlc.narg(search)::text IS NULL
+    OR transaction_search_document(description, notes, tags, category, account) @@ websearch_to_tsquery('simple', sqlc.narg(search))
+    OR sqlc.narg(search) <% description
+    OR description ILIKE '%' || sqlc.narg(search) || '%'
+  )
+  AND (sqlc.narg(date_from)::date IS NULL OR date >= sqlc.narg(date_from)::date)
+  AND (sqlc.narg(date_to)::date IS NULL OR date <= sqlc.narg(date_to)::date)
+  AND EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND (sqlc.narg(categories)::text[] IS NULL OR line.category = ANY(sqlc.narg(categories)::text[]))
+      AND (sqlc.narg(min_amount)::numeric IS NULL OR line.amount >= sqlc.narg(min_amount)::numeric)
+      AND (sqlc.narg(max_amount)::numeric IS NULL OR line.amount <= sqlc.narg(max_amount)::numeric)
+      AND (sqlc.narg(tags)::text[] IS NULL OR line.tags && sqlc.narg(tags)::text[])
+  )
+  -- A transaction with any excluded line is excluded whole.
+  AND NOT EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND (line.category = ANY(sqlc.narg(exclude_categories)::text[])
+        OR line.tags && sqlc.narg(exclude_tags)::text[])
+  )
+  AND (sqlc.narg(type)::text IS NULL OR type = sqlc.narg(type))
+  AND (sqlc.narg(statuses)::text[] IS NULL OR status = ANY(sqlc.narg(statuses)::text[]))
+  AND (sqlc.narg(accounts)::text[] IS NULL OR account = ANY(sqlc.narg(accounts)::text[]))
+  AND (sqlc.narg(currencies)::text[] IS NULL OR currency = ANY(sqlc.narg(currencies)::text[]))
+  AND (sqlc.narg(has_receipt)::boolean IS NULL OR has_receipt = sqlc.narg(has_receipt)::boolean)
+  AND (sqlc.narg(created_since)::timestamptz IS NULL OR created_at >= sqlc.narg(created_since)::timestamptz)
+  AND (sqlc.narg(updated_since)::timestamptz IS NULL OR updated_at >= sqlc.narg(updated_since)::timestamptz)
+ORDER BY
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = 'date' THEN date END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = '-date' THEN date END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = 'amount' THEN amount END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = '-amount' THEN amount END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = 'description' THEN lower(description) END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = '-description' THEN lower(description) END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = 'category' THEN lower(category) END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = '-category' THEN lower(category) END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = 'created_at' THEN created_at END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = '-created_at' THEN created_at END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = 'updated_at' THEN updated_at END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = '-updated_at' THEN updated_at END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = 'relevance' THEN search.relevance END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[1] = '-relevance' THEN search.relevance END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = 'date' THEN date END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = '-date' THEN date END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = 'amount' THEN amount END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = '-amount' THEN amount END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = 'description' THEN lower(description) END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = '-description' THEN lower(description) END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = 'category' THEN lower(category) END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = '-category' THEN lower(category) END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = 'created_at' THEN created_at END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = '-created_at' THEN created_at END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = 'updated_at' THEN updated_at END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = '-updated_at' THEN updated_at END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = 'relevance' THEN search.relevance END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[2] = '-relevance' THEN search.relevance END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = 'date' THEN date END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = '-date' THEN date END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = 'amount' THEN amount END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = '-amount' THEN amount END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = 'description' THEN lower(description) END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = '-description' THEN lower(description) END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = 'category' THEN lower(category) END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = '-category' THEN lower(category) END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = 'created_at' THEN created_at END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = '-created_at' THEN created_at END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = 'updated_at' THEN updated_at END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = '-updated_at' THEN updated_at END DESC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = 'relevance' THEN search.relevance END ASC,
+  CASE WHEN (sqlc.arg(sort)::text[])[3] = '-relevance' THEN search.relevance END DESC,
+  date DESC,
+  id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: CountTransactions :one
 SELECT COUNT(*) FROM transactions
-WHERE user_id = $1
-  AND ($2::text IS NULL OR description ILIKE '%' || $2 || '%')
-  AND ($3::date IS NULL OR date >= $3::date)
-  AND ($4::date IS NULL OR date <= $4::date)
-  AND ($5::text[] IS NULL OR category = ANY($5::text[]))
-  AND ($6::text IS NULL OR type = $6)
-  AND ($7::numeric IS NULL OR amount >= $7::numeric)
-  AND ($8::numeric IS NULL OR amount <= $8::numeric)
-  AND ($9::text[] IS NULL OR tags && $9::text[]);
+WHERE user_id = sqlc.arg(user_id)
+  AND deleted_at IS NULL
+  AND (
+    sqlc.narg(search)::text IS NULL
+    OR transaction_search_document(description, notes, tags, category, account) @@ websearch_to_tsquery('simple', sqlc.narg(search))
+    OR sqlc.narg(search) <% description
+    OR description ILIKE '%' || sqlc.narg(search) || '%'
+  )
+  AND (sqlc.narg(date_from)::date IS NULL OR date >= sqlc.narg(date_from)::date)
+  AND (sqlc.narg(date_to)::date IS NULL OR date <= sqlc.narg(date_to)::date)
+  AND EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND (sqlc.narg(categories)::text[] IS NULL OR line.category = ANY(sqlc.narg(categories)::text[]))
+      AND (sqlc.narg(min_amount)::numeric IS NULL OR line.amount >= sqlc.narg(min_amount)::numeric)
+      AND (sqlc.narg(max_amount)::numeric IS NULL OR line.amount <= sqlc.narg(max_amount)::numeric)
+      AND (sqlc.narg(tags)::text[] IS NULL OR line.tags && sqlc.narg(tags)::text[])
+  )
+  -- A transaction with any excluded line is excluded whole.
+  AND NOT EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND (line.category = ANY(sqlc.narg(exclude_categories)::text[])
+        OR line.tags && sqlc.narg(exclude_tags)::text[])
+  )
+  AND (sqlc.narg(type)::text IS NULL OR type = sqlc.narg(type))
+  AND (sqlc.narg(statuses)::text[] IS NULL OR status = ANY(sqlc.narg(statuses)::text[]))
+  AND (sqlc.narg(accounts)::text[] IS NULL OR account = ANY(sqlc.narg(accounts)::text[]))
+  AND (sqlc.narg(currencies)::text[] IS NULL OR currency = ANY(sqlc.narg(currencies)::text[]))
+  AND (sqlc.narg(has_receipt)::boolean IS NULL OR has_receipt = sqlc.narg(has_receipt)::boolean)
+  AND (sqlc.narg(created_since)::timestamptz IS NULL OR created_at >= sqlc.narg(created_since)::timestamptz)
+  AND (sqlc.narg(updated_since)::timestamptz IS NULL OR updated_at >= sqlc.narg(updated_since)::timestamptz);
 
 -- name: GetTotalSpending :one
+-- Transfers have their own type and are not counted as spending or income.
 SELECT COALESCE(SUM(amount), 0)::numeric
 FROM transactions
-WHERE user_id = $1 AND type = 'expense';
+WHERE user_id = $1 AND type = 'expense' AND deleted_at IS NULL;
 
 -- name: GetTotalIncome :one
 SELECT COALESCE(SUM(amount), 0)::numeric
 FROM transactions
-WHERE user_id = $1 AND type = 'income';
+WHERE user_id = $1 AND type = 'income' AND deleted_at IS NULL;
 
 -- name: GetCategories :many
 SELECT DISTINCT category as name, category as id
-FROM transactions
-WHERE user_id = $1
+FROM (
+  SELECT t.category FROM transactions t WHERE t.user_id = $1 AND t.deleted_at IS NULL
+  UNION
+  SELECT s.category FROM transaction_splits s
+  JOIN transactions t ON t.id = s.transaction_id
+  WHERE t.user_id = $1 AND t.deleted_at IS NULL
+) categories
 ORDER BY category;
 
 -- name: GetTags :many
-SELECT DISTINCT unnest(tags) as name, unnest(tags) as id
-FROM transactions
-WHERE user_id = $1 AND array_length(tags, 1) > 0
-ORDER BY name;
+SELECT DISTINCT tag as name, tag as id
+FROM (
+  SELECT unnest(t.tags) AS tag FROM transactions t WHERE t.user_id = $1 AND t.deleted_at IS NULL
+  UNION
+  SELECT unnest(s.tags) AS tag FROM transaction_splits s
+  JOIN transactions t ON t.id = s.transaction_id
+  WHERE t.user_id = $1 AND t.deleted_at IS NULL
+) tags
+ORDER BY tag;
+
+-- name: BulkUpdateTransactions :many
+-- Applies the same change to many transactions. NULL category/status leave the
+-- column untouched; added tags are appended in order and removed tags dropped.
+UPDATE transactions
+SET
+  category = COALESCE(sqlc.narg(category)::text, category),
+  status = COALESCE(sqlc.narg(status)::text, status),
+  tags = ARRAY(
+    SELECT tag
+    FROM unnest(tags || COALESCE(sqlc.arg(add_tags)::text[], '{}')) WITH ORDINALITY AS t(tag, position)
+    WHERE tag <> ALL(COALESCE(sqlc.arg(remove_tags)::text[], '{}'))
+    GROUP BY tag
+    ORDER BY min(position)
+  ),
+  updated_at = NOW()
+WHERE user_id = sqlc.arg(user_id) AND id = ANY(sqlc.arg(ids)::bigint[]) AND deleted_at IS NULL
+RETURNING *;
+
+-- name: LockTransaction :one
+-- Locks the transaction until the end of the database transaction, so its
+-- version can be checked before changing it.
+SELECT * FROM transactions
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: TouchTransaction :one
+-- Gives the transaction a new version after a change to its splits.
+UPDATE transactions
+SET updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListCategorizedTransactions :many
+-- The user's most recent categorized transactions, which category
+-- suggestions are learned from. Transfers are left out.
+SELECT description, amount, account, type, category FROM transactions
+WHERE user_id = sqlc.arg(user_id) AND deleted_at IS NULL
+  AND type <> 'transfer' AND category <> sqlc.arg(uncategorized)
+ORDER BY date DESC, id DESC
+LIMIT sqlc.arg('limit');
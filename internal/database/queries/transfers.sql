@@ -0,0 +1,92 @@
+-- internal/database/queries/transfers.sql
+
+-- name: CreateTransfer :one
+INSERT INTO transfers (user_id, rate)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: CreateTransferLeg :one
+INSERT INTO transactions (
+  user_id, transfer_id, transfer_direction, type, amount, description,
+  category, currency, status, account, tags, notes, date, import_batch_id,
+  external_id
+)
+VALUES (
+  $1, $2, $3, 'transfer', $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+RETURNING *;
+
+-- name: GetTransfer :one
+-- Transfers whose legs are in the trash are left out, here and below.
+SELECT * FROM transfers
+WHERE transfers.id = $1 AND transfers.user_id = $2
+  AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.transfer_id = transfers.id AND t.deleted_at IS NOT NULL);
+
+-- name: ListTransfers :many
+SELECT * FROM transfers
+WHERE transfers.user_id = $1
+  AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.transfer_id = transfers.id AND t.deleted_at IS NOT NULL)
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountTransfers :one
+SELECT COUNT(*) FROM transfers
+WHERE transfers.user_id = $1
+  AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.transfer_id = transfers.id AND t.deleted_at IS NOT NULL);
+
+-- name: ListTransferLegs :many
+-- Legs of the given transfers, the source leg of each first.
+SELECT * FROM transactions
+WHERE user_id = sqlc.arg(user_id) AND transfer_id = ANY(sqlc.arg(transfer_ids)::bigint[]) AND deleted_at IS NULL
+ORDER BY transfer_id, transfer_direction DESC;
+
+-- name: DeleteTransfer :execrows
+-- Moves both legs to the trash.
+UPDATE transactions
+SET deleted_at = NOW()
+WHERE transfer_id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: GetAccountBalances :many
+-- Income adds to an account and expenses subtract from it; transfers move
+-- money out of the source account and into the destination. Takes the
+-- filters of ListTransactionsWithFilters.
+SELECT
+  account,
+  currency,
+  SUM(CASE WHEN type = 'expense' OR transfer_direction = 'out' THEN -amount ELSE amount END)::numeric AS balance,
+  COUNT(*) AS count
+FROM transactions
+WHERE user_id = sqlc.arg(user_id)
+  AND deleted_at IS NULL
+  AND (
+    sqlc.narg(search)::text IS NULL
+    OR transaction_search_document(description, notes, tags, category, account) @@ websearch_to_tsquery('simple', sqlc.narg(search))
+    OR sqlc.narg(search) <% description
+    OR description ILIKE '%' || sqlc.narg(search) || '%'
+  )
+  AND (sqlc.narg(date_from)::date IS NULL OR date >= sqlc.narg(date_from)::date)
+  AND (sqlc.narg(date_to)::date IS NULL OR date <= sqlc.narg(date_to)::date)
+  AND EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND (sqlc.narg(categories)::text[] IS NULL OR line.category = ANY(sqlc.narg(categories)::text[]))
+      AND (sqlc.narg(min_amount)::numeric IS NULL OR line.amount >= sqlc.narg(min_amount)::numeric)
+      AND (sqlc.narg(max_amount)::numeric IS NULL OR line.amount <= sqlc.narg(max_amount)::numeric)
+      AND (sqlc.narg(tags)::text[] IS NULL OR line.tags && sqlc.narg(tags)::text[])
+  )
+  -- A transaction with any excluded line is excluded whole.
+  AND NOT EXISTS (
+    SELECT 1 FROM transaction_lines line
+    WHERE line.transaction_id = transactions.id
+      AND (line.category = ANY(sqlc.narg(exclude_categories)::text[])
+        OR line.tags && sqlc.narg(exclude_tags)::text[])
+  )
+  AND (sqlc.narg(type)::text IS NULL OR type = sqlc.narg(type))
+  AND (sqlc.narg(statuses)::text[] IS NULL OR status = ANY(sqlc.narg(statuses)::text[]))
+  AND (sqlc.narg(accounts)::text[] IS NULL OR account = ANY(sqlc.narg(accounts)::text[]))
+  AND (sqlc.narg(currencies)::text[] IS NULL OR currency = ANY(sqlc.narg(currencies)::text[]))
+  AND (sqlc.narg(has_receipt)::boolean IS NULL OR has_receipt = sqlc.narg(has_receipt)::boolean)
+  AND (sqlc.narg(created_since)::timestamptz IS NULL OR created_at >= sqlc.narg(created_since)::timestamptz)
+  AND (sqlc.narg(updated_since)::timestamptz IS NULL OR updated_at >= sqlc.narg(updated_since)::timestamptz)
+GROUP BY account, currency
+ORDER BY account, currency;
@@ -96,6 +96,8 @@ table "transactions" {
     null = true
     type = text
   }
+  // Derived from receipt_url and attachments by the derive_has_receipt and
+  // sync_has_receipt triggers created in the add_attachments migration.
   column "has_receipt" {
     null    = false
     type    = boolean
@@ -120,6 +122,76 @@ table "transactions" {
     type    = timestamptz
     default = sql("now()")
   }
+  column "import_batch_id" {
+    null = true
+    type = bigint
+  }
+  // Identifier assigned by the bank, e.g. the OFX FITID
+  column "external_id" {
+    null = true
+    type = text
+  }
+  // Hash of date, amount, type, normalized description and account used to
+  // detect duplicate imports; transaction_fingerprint() is created in the
+  // add_transaction_fingerprint migration.
+  column "fingerprint" {
+    null = false
+    type = text
+    as {
+      expr = "transaction_fingerprint(date, amount, type, description, account)"
+      type = STORED
+    }
+  }
+  // Set on both legs of a transfer, which have type "transfer". Deleting
+  // either leg deletes the transfer and the other leg, and edits are copied
+  // between legs by the sync_transfer_leg trigger created in the
+  // add_transfers migration.
+  column "transfer_id" {
+    null = true
+    type = bigint
+  }
+  // "out" for the leg on the source account, "in" for the destination
+  column "transfer_direction" {
+    null = true
+    type = text
+  }
+  // Template and scheduled date of transactions created from a recurring
+  // template; unique so an occurrence is never created twice
+  column "recurring_id" {
+    null = true
+    type = bigint
+  }
+  column "recurring_occurrence" {
+    null = true
+    type = date
+  }
+  // Set while the transaction is in the trash; trashed rows are purged
+  // after the retention period
+  column "deleted_at" {
+    null = true
+    type = timestamptz
+  }
+  // Incremented on every update by the transactions_bump_version trigger
+  // created in the add_transaction_version migration; served as the ETag
+  column "version" {
+    null    = false
+    type    = bigint
+    default = 1
+  }
+  // Statement the transaction was reconciled in. Once the statement is
+  // locked, the transaction can no longer change in ways that affect the
+  // balance, enforced by the transactions_protect_reconciled trigger
+  // (protect_locked_reconciliations migration).
+  column "reconciliation_id" {
+    null = true
+    type = bigint
+  }
+  // Merchant or person the transaction is with, matched from the
+  // description when the transaction is created
+  column "payee_id" {
+    null = true
+    type = bigint
+  }
 
   primary_key {
     columns = [column.id]
@@ -131,7 +203,894 @@ table "transactions" {
     on_delete   = CASCADE
   }
 
+  foreign_key "fk_transactions_import_batch" {
+    columns     = [column.import_batch_id]
+    ref_columns = [table.import_batches.column.id]
+    on_delete   = SET_NULL
+  }
+
+  foreign_key "fk_transactions_transfer" {
+    columns     = [column.transfer_id]
+    ref_columns = [table.transfers.column.id]
+    on_delete   = CASCADE
+  }
+
+  foreign_key "fk_transactions_recurring" {
+    columns     = [column.recurring_id]
+    ref_columns = [table.recurring_transactions.column.id]
+    on_delete   = SET_NULL
+  }
+
+  foreign_key "fk_transactions_reconciliation" {
+    columns     = [column.reconciliation_id]
+    ref_columns = [table.reconciliations.column.id]
+    on_delete   = NO_ACTION
+  }
+
+  foreign_key "fk_transactions_payee" {
+    columns     = [column.payee_id]
+    ref_columns = [table.payees.column.id]
+    on_delete   = SET_NULL
+  }
+
+  check "transactions_transfer_direction_check" {
+    expr = "transfer_direction IN ('out', 'in')"
+  }
+
+  check "transactions_transfer_check" {
+    expr = "((type = 'transfer') = (transfer_id IS NOT NULL)) AND ((transfer_id IS NULL) = (transfer_direction IS NULL))"
+  }
+
   index "idx_transactions_user" {
     columns = [column.user_id]
   }
+
+  index "idx_transactions_import_batch" {
+    columns = [column.import_batch_id]
+  }
+
+  index "transactions_transfer_direction_key" {
+    unique  = true
+    columns = [column.transfer_id, column.transfer_direction]
+  }
+
+  index "transactions_recurring_occurrence_key" {
+    unique  = true
+    columns = [column.recurring_id, column.recurring_occurrence]
+  }
+
+  index "idx_transactions_fingerprint" {
+    columns = [column.user_id, column.fingerprint]
+  }
+
+  index "idx_transactions_external_id" {
+    columns = [column.user_id, column.account, column.external_id]
+    where   = "external_id IS NOT NULL"
+  }
+
+  index "idx_transactions_reconciliation" {
+    columns = [column.reconciliation_id]
+  }
+
+  index "idx_transactions_payee" {
+    columns = [column.payee_id]
+  }
+
+  index "idx_transactions_trash" {
+    columns = [column.user_id, column.deleted_at]
+    where   = "deleted_at IS NOT NULL"
+  }
+
+  // transaction_search_document() and the pg_trgm extension are created
+  // in the add_transaction_search migration.
+  index "idx_transactions_search" {
+    type = GIN
+    on {
+      expr = "transaction_search_document(description, notes, tags, category, account)"
+    }
+  }
+
+  index "idx_transactions_description_trgm" {
+    type = GIN
+    on {
+      column = column.description
+      ops    = gin_trgm_ops
+    }
+  }
+}
+
+// 3. Import Batches (one row per committed file import)
+table "import_batches" {
+  schema = schema.public
+  column "id" {
+    null = false
+    type = bigserial
+  }
+  column "user_id" {
+    null = false
+    type = bigint
+  }
+  column "source" {
+    null = false
+    type = text
+  }
+  column "filename" {
+    null    = false
+    type    = text
+    default = sql("''")
+  }
+  column "row_count" {
+    null    = false
+    type    = integer
+    default = 0
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "fk_import_batches_user" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_delete   = CASCADE
+  }
+
+  index "idx_import_batches_user" {
+    columns = [column.user_id]
+  }
+}
+
+// 4. Import Mappings (saved CSV options per bank)
+table "import_mappings" {
+  schema = schema.public
+  column "id" {
+    null = false
+    type = bigserial
+  }
+  column "user_id" {
+    null = false
+    type = bigint
+  }
+  column "bank" {
+    null = false
+    type = text
+  }
+  column "options" {
+    null    = false
+    type    = jsonb
+    default = sql("'{}'::jsonb")
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  column "updated_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "fk_import_mappings_user" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_delete   = CASCADE
+  }
+
+  index "import_mappings_user_bank_key" {
+    unique  = true
+    columns = [column.user_id, column.bank]
+  }
+}
+
+// 5. Attachments (files stored per transaction; identical files of a user
+// share one stored object, addressed by checksum)
+table "attachments" {
+  schema = schema.public
+  column "id" {
+    null = false
+    type = bigserial
+  }
+  column "user_id" {
+    null = false
+    type = bigint
+  }
+  column "transaction_id" {
+    null = false
+    type = bigint
+  }
+  column "filename" {
+    null = false
+    type = text
+  }
+  column "content_type" {
+    null = false
+    type = text
+  }
+  column "size" {
+    null = false
+    type = bigint
+  }
+  // SHA-256 of the file, hex encoded; NULL for receipts uploaded before
+  // attachments existed
+  column "checksum" {
+    null = true
+    type = text
+  }
+  column "storage_key" {
+    null = false
+    type = text
+  }
+  column "thumbnail_key" {
+    null = true
+    type = text
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "fk_attachments_user" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_delete   = CASCADE
+  }
+
+  foreign_key "fk_attachments_transaction" {
+    columns     = [column.transaction_id]
+    ref_columns = [table.transactions.column.id]
+    on_delete   = CASCADE
+  }
+
+  index "idx_attachments_transaction" {
+    columns = [column.transaction_id]
+  }
+
+  index "attachments_transaction_checksum_key" {
+    unique  = true
+    columns = [column.transaction_id, column.checksum]
+  }
+
+  index "idx_attachments_checksum" {
+    columns = [column.user_id, column.checksum]
+  }
+
+  index "idx_attachments_storage_key" {
+    columns = [column.storage_key]
+  }
+}
+
+// 6. Storage Deletions (stored objects whose last attachment was deleted;
+// filled by the queue_attachment_deletion trigger and emptied by the server)
+table "storage_deletions" {
+  schema = schema.public
+  column "storage_key" {
+    null = false
+    type = text
+  }
+  column "queued_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.storage_key]
+  }
+}
+
+// 7. Transaction Splits (lines dividing a transaction across categories; the
+// deferred transaction_splits_total and transactions_split_total triggers
+// require their amounts to add up to the transaction amount, and the
+// transaction_lines view lists split lines in place of their transaction)
+table "transaction_splits" {
+  schema = schema.public
+  column "id" {
+    null = false
+    type = bigserial
+  }
+  column "transaction_id" {
+    null = false
+    type = bigint
+  }
+  column "position" {
+    null = false
+    type = integer
+  }
+  column "amount" {
+    null = false
+    type = numeric(10, 2)
+  }
+  column "category" {
+    null = false
+    type = text
+  }
+  column "tags" {
+    null    = false
+    type    = sql("text[]")
+    default = sql("'{}'::text[]")
+  }
+  column "notes" {
+    null = true
+    type = text
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "fk_transaction_splits_transaction" {
+    columns     = [column.transaction_id]
+    ref_columns = [table.transactions.column.id]
+    on_delete   = CASCADE
+  }
+
+  index "transaction_splits_transaction_position_key" {
+    unique  = true
+    columns = [column.transaction_id, column.position]
+  }
+
+  index "idx_transaction_splits_category" {
+    columns = [column.category]
+  }
+}
+
+// 8. Transfers (links the two legs of a transfer between accounts)
+table "transfers" {
+  schema = schema.public
+  column "id" {
+    null = false
+    type = bigserial
+  }
+  column "user_id" {
+    null = false
+    type = bigint
+  }
+  // Destination amount per unit of the source amount; 1 unless the legs
+  // have different currencies
+  column "rate" {
+    null = false
+    type = numeric
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "fk_transfers_user" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_delete   = CASCADE
+  }
+
+  check "transfers_rate_check" {
+    expr = "rate > 0"
+  }
+}
+
+// 9. Recurring Transactions (templates created as pending transactions on
+// the dates of an RFC 5545 RRULE)
+table "recurring_transactions" {
+  schema = schema.public
+  column "id" {
+    null = false
+    type = bigserial
+  }
+  column "user_id" {
+    null = false
+    type = bigint
+  }
+  column "rrule" {
+    null = false
+    type = text
+  }
+  column "start_date" {
+    null = false
+    type = date
+  }
+  column "amount" {
+    null = false
+    type = numeric(10, 2)
+  }
+  column "description" {
+    null = false
+    type = text
+  }
+  column "category" {
+    null = false
+    type = text
+  }
+  column "type" {
+    null    = false
+    type    = text
+    default = "expense"
+  }
+  column "currency" {
+    null    = false
+    type    = text
+    default = "USD"
+  }
+  column "account" {
+    null    = false
+    type    = text
+    default = ""
+  }
+  column "tags" {
+    null    = false
+    type    = sql("text[]")
+    default = sql("'{}'::text[]")
+  }
+  column "notes" {
+    null = true
+    type = text
+  }
+  column "active" {
+    null    = false
+    type    = boolean
+    default = true
+  }
+  // Last date up to which occurrences have been created
+  column "generated_through" {
+    null = true
+    type = date
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  column "updated_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "fk_recurring_transactions_user" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_delete   = CASCADE
+  }
+
+  index "idx_recurring_transactions_user" {
+    columns = [column.user_id]
+  }
+}
+
+// 10. Recurring Exceptions (a single occurrence of a template skipped or
+// created with different values)
+table "recurring_exceptions" {
+  schema = schema.public
+  column "recurring_id" {
+    null = false
+    type = bigint
+  }
+  column "occurrence" {
+    null = false
+    type = date
+  }
+  column "skip" {
+    null    = false
+    type    = boolean
+    default = false
+  }
+  column "date" {
+    null = true
+    type = date
+  }
+  column "amount" {
+    null = true
+    type = numeric(10, 2)
+  }
+  column "description" {
+    null = true
+    type = text
+  }
+  column "category" {
+    null = true
+    type = text
+  }
+  column "notes" {
+    null = true
+    type = text
+  }
+
+  primary_key {
+    columns = [column.recurring_id, column.occurrence]
+  }
+
+  foreign_key "fk_recurring_exceptions_recurring" {
+    columns     = [column.recurring_id]
+    ref_columns = [table.recurring_transactions.column.id]
+    on_delete   = CASCADE
+  }
+}
+
+// 11. Transaction Revisions (append-only history of every create, update,
+// delete and restore of a transaction, written by the
+// transactions_record_revision trigger created in the
+// add_transaction_revisions migration)
+table "transaction_revisions" {
+  schema = schema.public
+  column "id" {
+    null = false
+    type = bigserial
+  }
+  column "transaction_id" {
+    null = false
+    type = bigint
+  }
+  column "user_id" {
+    null = false
+    type = bigint
+  }
+  column "action" {
+    null = false
+    type = text
+  }
+  // "user" for changes made by a user, otherwise the server job that
+  // made it, e.g. "recurring"
+  column "actor" {
+    null = false
+    type = text
+  }
+  // The user that made a "user" change; NULL for server jobs and for
+  // changes made outside the server
+  column "actor_id" {
+    null = true
+    type = bigint
+  }
+  // Values of the fields listed by transaction_revision_values() before and
+  // after the change; old_values is NULL for a create
+  column "old_values" {
+    null = true
+    type = jsonb
+  }
+  column "new_values" {
+    null = false
+    type = jsonb
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "fk_transaction_revisions_transaction" {
+    columns     = [column.transaction_id]
+    ref_columns = [table.transactions.column.id]
+    on_delete   = CASCADE
+  }
+
+  foreign_key "fk_transaction_revisions_user" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_delete   = CASCADE
+  }
+
+  foreign_key "fk_transaction_revisions_actor" {
+    columns     = [column.actor_id]
+    ref_columns = [table.users.column.id]
+    on_delete   = SET_NULL
+  }
+
+  check "transaction_revisions_action_check" {
+    expr = "action IN ('create', 'update', 'delete', 'restore')"
+  }
+
+  index "idx_transaction_revisions_transaction" {
+    columns = [column.transaction_id, column.id]
+  }
+}
+
+// 12. Idempotency Keys (responses to POST requests sent with an
+// Idempotency-Key header, replayed when the request is retried)
+table "idempotency_keys" {
+  schema = schema.public
+  column "user_id" {
+    null = false
+    type = bigint
+  }
+  column "key" {
+    null = false
+    type = text
+  }
+  // SHA-256 of the method, URL and body of the request, hex encoded
+  column "request_hash" {
+    null = false
+    type = text
+  }
+  // Status, headers and body of the response; NULL while the first request
+  // is still running
+  column "status" {
+    null = true
+    type = integer
+  }
+  column "headers" {
+    null = true
+    type = jsonb
+  }
+  column "body" {
+    null = true
+    type = bytea
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.user_id, column.key]
+  }
+
+  foreign_key "fk_idempotency_keys_user" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_delete   = CASCADE
+  }
+
+  index "idx_idempotency_keys_created_at" {
+    columns = [column.created_at]
+  }
+}
+
+// 13. Reconciliations (a bank statement of one account and currency matched
+// against its cleared transactions; locking it reconciles them)
+table "reconciliations" {
+  schema = schema.public
+  column "id" {
+    null = false
+    type = bigserial
+  }
+  column "user_id" {
+    null = false
+    type = bigint
+  }
+  column "account" {
+    null = false
+    type = text
+  }
+  column "currency" {
+    null    = false
+    type    = text
+    default = "USD"
+  }
+  column "statement_date" {
+    null = false
+    type = date
+  }
+  column "ending_balance" {
+    null = false
+    type = numeric
+  }
+  // NULL while the reconciliation is open; an account has at most one open
+  // reconciliation per currency
+  column "locked_at" {
+    null = true
+    type = timestamptz
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "fk_reconciliations_user" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_delete   = CASCADE
+  }
+
+  index "idx_reconciliations_account" {
+    columns = [column.user_id, column.account, column.currency]
+  }
+
+  index "reconciliations_open_key" {
+    unique  = true
+    columns = [column.user_id, column.account, column.currency]
+    where   = "locked_at IS NULL"
+  }
+}
+
+// 14. Rules (user-defined conditions on new and existing transactions that
+// set their category, tags, notes or status, applied in priority order)
+table "rules" {
+  schema = schema.public
+  column "id" {
+    null = false
+    type = bigserial
+  }
+  column "user_id" {
+    null = false
+    type = bigint
+  }
+  column "name" {
+    null = false
+    type = text
+  }
+  // Lower priorities apply first
+  column "priority" {
+    null    = false
+    type    = integer
+    default = 0
+  }
+  column "enabled" {
+    null    = false
+    type    = boolean
+    default = true
+  }
+  // Conditions; NULL ones are not checked. The description pattern is an
+  // RE2 regular expression.
+  column "description_pattern" {
+    null = true
+    type = text
+  }
+  column "min_amount" {
+    null = true
+    type = numeric
+  }
+  column "max_amount" {
+    null = true
+    type = numeric
+  }
+  column "account" {
+    null = true
+    type = text
+  }
+  column "currency" {
+    null = true
+    type = text
+  }
+  column "type" {
+    null = true
+    type = text
+  }
+  // Actions; NULL ones leave the field alone
+  column "set_category" {
+    null = true
+    type = text
+  }
+  column "add_tags" {
+    null    = false
+    type    = sql("text[]")
+    default = sql("'{}'::text[]")
+  }
+  column "set_notes" {
+    null = true
+    type = text
+  }
+  column "set_status" {
+    null = true
+    type = text
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  column "updated_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "fk_rules_user" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_delete   = CASCADE
+  }
+
+  check "rules_amount_range_check" {
+    expr = "(min_amount IS NULL) OR (max_amount IS NULL) OR (min_amount <= max_amount)"
+  }
+
+  check "rules_type_check" {
+    expr = "type IN ('income', 'expense', 'transfer')"
+  }
+
+  index "idx_rules_user_priority" {
+    columns = [column.user_id, column.priority, column.id]
+  }
+}
+
+// 15. Payees (merchants and people transactions are with; descriptions are
+// matched by pattern, or by their normalized form against the name and
+// aliases)
+table "payees" {
+  schema = schema.public
+  column "id" {
+    null = false
+    type = bigserial
+  }
+  column "user_id" {
+    null = false
+    type = bigint
+  }
+  // Canonical name, unique per user ignoring case
+  column "name" {
+    null = false
+    type = text
+  }
+  // Other names whose normalized descriptions belong to the payee
+  column "aliases" {
+    null    = false
+    type    = sql("text[]")
+    default = sql("'{}'::text[]")
+  }
+  // RE2 regular expressions matched against raw descriptions
+  column "patterns" {
+    null    = false
+    type    = sql("text[]")
+    default = sql("'{}'::text[]")
+  }
+  // Category given to uncategorized transactions linked to the payee
+  column "default_category" {
+    null = true
+    type = text
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  column "updated_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "fk_payees_user" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_delete   = CASCADE
+  }
+
+  index "payees_name_key" {
+    unique = true
+    on {
+      column = column.user_id
+    }
+    on {
+      expr = "lower(name)"
+    }
+  }
 }
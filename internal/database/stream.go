@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"reflect"
+
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// StreamTransactionsWithFilters runs the ListTransactionsWithFilters query and
+// calls fn for each row as it is read from the connection, so large results
+// are never held in memory. Iteration stops at the first error returned by fn.
+func (s *service) StreamTransactionsWithFilters(ctx context.Context, arg gensql.ListTransactionsWithFiltersParams, fn func(gensql.ListTransactionsWithFiltersRow) error) error {
+	return streamQuery(ctx, s.db, gensql.ListTransactionsWithFilters, arg, fn)
+}
+
+// streamQuery runs a generated query with the arguments in its params struct
+// and scans each row into a generated row struct.
+func streamQuery[P, R any](ctx context.Context, db gensql.DBTX, query string, params P, fn func(R) error) error {
+	rows, err := db.Query(ctx, query, queryArgs(params)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row, err := pgx.RowToStructByPos[R](rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// queryArgs returns the fields of a generated params struct in order. sqlc
+// declares them in the order of the query's parameters, $1 first.
+func queryArgs(params any) []any {
+	v := reflect.ValueOf(params)
+	args := make([]any, v.NumField())
+	for i := range args {
+		args[i] = v.Field(i).Interface()
+	}
+	return args
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"budgetctl-go/internal/database/gensql"
+)
+
+func TestQueryArgs(t *testing.T) {
+	search := "coffee"
+	args := queryArgs(gensql.ListTransactionsWithFiltersParams{Search: &search, UserID: 7, Limit: 20})
+
+	// Every parameter of the query needs an argument.
+	highest := 0
+	for _, m := range regexp.MustCompile(`\$(\d+)`).FindAllStringSubmatch(gensql.ListTransactionsWithFilters, -1) {
+		n, _ := strconv.Atoi(m[1])
+		highest = max(highest, n)
+	}
+	if len(args) != highest {
+		t.Fatalf("%d arguments for %d parameters", len(args), highest)
+	}
+	if !reflect.DeepEqual(args[0], &search) || args[1] != int64(7) || args[len(args)-1] != int32(20) {
+		t.Errorf("arguments out of order: %v", args)
+	}
+}
@@ -0,0 +1,37 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type csvWriter struct {
+	e      *Exporter
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, e *Exporter) (*csvWriter, error) {
+	cw := &csvWriter{e: e, w: csv.NewWriter(w), record: make([]string, len(e.columns))}
+	cw.w.Comma = e.delimiter
+
+	for i, col := range e.columns {
+		cw.record[i] = col.name
+	}
+	if err := cw.w.Write(cw.record); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(r *Row) error {
+	for i, col := range cw.e.columns {
+		cw.record[i] = cw.e.text(col.kind, col.value(r))
+	}
+	return cw.w.Write(cw.record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
@@ -0,0 +1,341 @@
+// Package exporter writes transactions as CSV, XLSX, NDJSON or as Beancount
+// and hledger journals. Rows are encoded one at a time as they are read from
+// the database, so exports of any size use constant memory.
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/importer"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Supported export formats.
+const (
+	FormatCSV       = "csv"
+	FormatXLSX      = "xlsx"
+	FormatNDJSON    = "ndjson"
+	FormatBeancount = "beancount"
+	FormatHledger   = "hledger"
+)
+
+// Row is the record type written by exports.
+type Row = gensql.ListTransactionsWithFiltersRow
+
+// DefaultColumns are exported when no columns are requested.
+var DefaultColumns = []string{"date", "description", "amount", "type", "category", "account", "currency", "status", "tags", "notes"}
+
+type valueKind int
+
+const (
+	kindString valueKind = iota
+	kindInt
+	kindDecimal
+	kindDate
+	kindDateTime
+	kindBool
+	kindList
+)
+
+type column struct {
+	name  string
+	kind  valueKind
+	value func(r *Row) any
+}
+
+// columns lists every exportable column. Values are string, *string, int64,
+// *int64, bool, []string, pgtype.Numeric or pgtype.Timestamptz.
+var columns = map[string]column{
+	"id":              {kind: kindInt, value: func(r *Row) any { return r.ID }},
+	"date":            {kind: kindDate, value: func(r *Row) any { return r.Date }},
+	"description":     {kind: kindString, value: func(r *Row) any { return r.Description }},
+	"amount":          {kind: kindDecimal, value: func(r *Row) any { return r.Amount }},
+	"signed_amount":   {kind: kindDecimal, value: signedAmount},
+	"type":            {kind: kindString, value: func(r *Row) any { return r.Type }},
+	"category":        {kind: kindString, value: func(r *Row) any { return r.Category }},
+	"account":         {kind: kindString, value: func(r *Row) any { return r.Account }},
+	"currency":        {kind: kindString, value: func(r *Row) any { return r.Currency }},
+	"status":          {kind: kindString, value: func(r *Row) any { return r.Status }},
+	"tags":            {kind: kindList, value: func(r *Row) any { return r.Tags }},
+	"notes":           {kind: kindString, value: func(r *Row) any { return r.Notes }},
+	"has_receipt":     {kind: kindBool, value: func(r *Row) any { return r.HasReceipt }},
+	"receipt_url":     {kind: kindString, value: func(r *Row) any { return r.ReceiptUrl }},
+	"external_id":     {kind: kindString, value: func(r *Row) any { return r.ExternalID }},
+	"import_batch_id": {kind: kindInt, value: func(r *Row) any { return r.ImportBatchID }},
+	"created_at":      {kind: kindDateTime, value: func(r *Row) any { return r.CreatedAt }},
+	"updated_at":      {kind: kindDateTime, value: func(r *Row) any { return r.UpdatedAt }},
+}
+
+// ColumnNames returns the names accepted in Options.Columns, sorted.
+func ColumnNames() []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// signedAmount returns the amount as negative for expenses and outgoing
+// transfers, which is easier to sum in a spreadsheet.
+func signedAmount(r *Row) any {
+	if !outflow(r) || !r.Amount.Valid || r.Amount.Int == nil {
+		return r.Amount
+	}
+	n := r.Amount
+	n.Int = new(big.Int).Neg(r.Amount.Int)
+	return n
+}
+
+// outflow reports whether r takes money out of its account.
+func outflow(r *Row) bool {
+	return r.Type == "expense" || (r.TransferDirection != nil && *r.TransferDirection == "out")
+}
+
+// localeDateLayouts holds the conventional short date layout of common
+// locales, looked up by full tag and then by base language.
+var localeDateLayouts = map[string]string{
+	"en-US": "01/02/2006",
+	"en-CA": "2006-01-02",
+	"en":    "02/01/2006",
+	"de":    "02.01.2006",
+	"fr":    "02/01/2006",
+	"es":    "02/01/2006",
+	"it":    "02/01/2006",
+	"pt":    "02/01/2006",
+	"nl":    "02-01-2006",
+	"pl":    "02.01.2006",
+	"cs":    "02.01.2006",
+	"ru":    "02.01.2006",
+	"sv":    "2006-01-02",
+	"da":    "02.01.2006",
+	"fi":    "2.1.2006",
+	"ja":    "2006/01/02",
+	"zh":    "2006/01/02",
+	"ko":    "2006.01.02",
+}
+
+// Options configure an export.
+type Options struct {
+	// Columns to export, in order. Defaults to DefaultColumns. Journals
+	// always contain every field they can represent.
+	Columns []string
+	// Locale is a BCP 47 tag such as de-DE. It selects the decimal and
+	// grouping separators and the date layout of CSV exports and the date
+	// format of XLSX exports. Without a locale numbers are written as 1234.50
+	// and dates as YYYY-MM-DD.
+	Locale string
+	// DateFormat overrides the locale's date layout, e.g. DD.MM.YYYY.
+	DateFormat string
+	// Delimiter is the CSV field separator. Defaults to a comma, or a
+	// semicolon for locales that use a decimal comma.
+	Delimiter string
+}
+
+// Exporter is a validated export configuration.
+type Exporter struct {
+	format     string
+	columns    []column
+	printer    *message.Printer
+	dateLayout string
+	timeLayout string
+	delimiter  rune
+}
+
+// Writer encodes rows to the underlying output.
+type Writer interface {
+	Write(r *Row) error
+	// Close writes any trailing data. It does not close the output.
+	Close() error
+}
+
+// New validates the format and options.
+func New(format string, opts Options) (*Exporter, error) {
+	e := &Exporter{format: format, timeLayout: time.RFC3339}
+
+	switch format {
+	case FormatCSV, FormatXLSX, FormatNDJSON, FormatBeancount, FormatHledger:
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	names := opts.Columns
+	if len(names) == 0 {
+		names = DefaultColumns
+	}
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		col, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		seen[name] = true
+		col.name = name
+		e.columns = append(e.columns, col)
+	}
+
+	e.dateLayout = importer.DateLayout("")
+	decimalComma := false
+	if opts.Locale != "" {
+		tag, err := language.Parse(opts.Locale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid locale %q", opts.Locale)
+		}
+		e.printer = message.NewPrinter(tag)
+		decimalComma = strings.Contains(e.printer.Sprint(number.Decimal(1.5)), ",")
+
+		base, _ := tag.Base()
+		if layout, ok := localeDateLayouts[tag.String()]; ok {
+			e.dateLayout = layout
+		} else if layout, ok := localeDateLayouts[base.String()]; ok {
+			e.dateLayout = layout
+		}
+		e.timeLayout = e.dateLayout + " 15:04:05"
+	}
+	if opts.DateFormat != "" {
+		e.dateLayout = importer.DateLayout(opts.DateFormat)
+		e.timeLayout = e.dateLayout + " 15:04:05"
+	}
+
+	e.delimiter = ','
+	if decimalComma {
+		e.delimiter = ';'
+	}
+	if opts.Delimiter != "" {
+		delim := []rune(strings.ReplaceAll(opts.Delimiter, `\t`, "\t"))
+		if len(delim) != 1 {
+			return nil, fmt.Errorf("delimiter must be a single character, got %q", opts.Delimiter)
+		}
+		e.delimiter = delim[0]
+	}
+
+	return e, nil
+}
+
+// ContentType returns the MIME type of the export.
+func (e *Exporter) ContentType() string {
+	switch e.format {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatBeancount, FormatHledger:
+		return "text/plain; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Extension returns the file extension of the export, without a dot.
+func (e *Exporter) Extension() string {
+	if e.format == FormatHledger {
+		return "journal"
+	}
+	return e.format
+}
+
+// Chronological reports whether rows must be written in date order.
+func (e *Exporter) Chronological() bool {
+	return e.format == FormatBeancount || e.format == FormatHledger
+}
+
+// Start writes the file header to w and returns a Writer for the rows.
+func (e *Exporter) Start(w io.Writer) (Writer, error) {
+	switch e.format {
+	case FormatXLSX:
+		return newXLSXWriter(w, e)
+	case FormatNDJSON:
+		return newNDJSONWriter(w, e), nil
+	case FormatBeancount, FormatHledger:
+		return newLedgerWriter(w, e), nil
+	}
+	return newCSVWriter(w, e)
+}
+
+// text formats a value for CSV using the configured locale and date layout.
+func (e *Exporter) text(kind valueKind, v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case *string:
+		if v == nil {
+			return ""
+		}
+		return *v
+	case int64:
+		return fmt.Sprint(v)
+	case *int64:
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(*v)
+	case bool:
+		return fmt.Sprint(v)
+	case []string:
+		return strings.Join(v, ";")
+	case pgtype.Numeric:
+		return e.decimal(v)
+	case pgtype.Timestamptz:
+		if !v.Valid {
+			return ""
+		}
+		if kind == kindDate {
+			return v.Time.UTC().Format(e.dateLayout)
+		}
+		return v.Time.UTC().Format(e.timeLayout)
+	}
+	return fmt.Sprint(v)
+}
+
+// decimal formats n with its own scale, localised when a locale is set.
+func (e *Exporter) decimal(n pgtype.Numeric) string {
+	plain := decimalString(n)
+	if e.printer == nil || plain == "" {
+		return plain
+	}
+
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return plain
+	}
+	scale := 0
+	if n.Exp < 0 {
+		scale = int(-n.Exp)
+	}
+	return e.printer.Sprint(number.Decimal(f.Float64, number.Scale(scale)))
+}
+
+// decimalString returns n in plain notation such as -1234.50.
+func decimalString(n pgtype.Numeric) string {
+	if !n.Valid || n.NaN || n.Int == nil {
+		return ""
+	}
+
+	digits := new(big.Int).Abs(n.Int).String()
+	if n.Exp > 0 {
+		digits += strings.Repeat("0", int(n.Exp))
+	} else if n.Exp < 0 {
+		scale := int(-n.Exp)
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+	if n.Int.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
@@ -0,0 +1,187 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func testRows() []Row {
+	notes := "team lunch; split later"
+	var amount, small pgtype.Numeric
+	amount.Scan("1234.50")
+	small.Scan("4.20")
+
+	return []Row{
+		{
+			ID:          1,
+			Date:        pgtype.Timestamptz{Time: time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC), Valid: true},
+			Description: "Bistro \"Zur Post\"",
+			Amount:      amount,
+			Type:        "expense",
+			Category:    "Food",
+			Tags:        []string{"work", "lunch"},
+			Notes:       &notes,
+		},
+		{
+			ID:          2,
+			Date:        pgtype.Timestamptz{Time: time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC), Valid: true},
+			Description: "Refund",
+			Amount:      small,
+			Type:        "income",
+			Category:    "Other",
+		},
+	}
+}
+
+func export(t *testing.T, format string, opts Options) []byte {
+	t.Helper()
+
+	e, err := New(format, opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	var buf bytes.Buffer
+	w, err := e.Start(&buf)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	for _, r := range testRows() {
+		if err := w.Write(&r); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVLocaleFormatting(t *testing.T) {
+	got := string(export(t, FormatCSV, Options{
+		Columns: []string{"date", "description", "signed_amount", "tags"},
+		Locale:  "de-DE",
+	}))
+
+	want := "date;description;signed_amount;tags\n" +
+		"03.01.2025;\"Bistro \"\"Zur Post\"\"\";-1.234,50;\"work;lunch\"\n" +
+		"04.01.2025;Refund;4,20;\n"
+	if got != want {
+		t.Errorf("unexpected CSV:\n%s", got)
+	}
+}
+
+func TestCSVDefaults(t *testing.T) {
+	got := string(export(t, FormatCSV, Options{Columns: []string{"id", "date", "amount", "created_at"}}))
+
+	want := "id,date,amount,created_at\n1,2025-01-03,1234.50,\n2,2025-01-04,4.20,\n"
+	if got != want {
+		t.Errorf("unexpected CSV:\n%s", got)
+	}
+}
+
+func TestNDJSONKeepsColumnOrderAndTypes(t *testing.T) {
+	out := export(t, FormatNDJSON, Options{Columns: []string{"description", "amount", "tags", "notes"}, Locale: "de-DE"})
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if want := `{"description":"Bistro \"Zur Post\"","amount":1234.50,"tags":["work","lunch"],"notes":"team lunch; split later"}`; lines[0] != want {
+		t.Errorf("line 1 = %s", lines[0])
+	}
+	var second map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("line 2 is not JSON: %v", err)
+	}
+	if second["notes"] != nil || second["amount"] != 4.2 {
+		t.Errorf("line 2 = %s", lines[1])
+	}
+}
+
+func TestXLSXIsAValidWorkbook(t *testing.T) {
+	out := export(t, FormatXLSX, Options{Columns: []string{"date", "description", "amount"}, Locale: "en-GB"})
+
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("not a zip file: %v", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(body)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	if !strings.Contains(files["xl/styles.xml"], `formatCode="dd/mm/yyyy"`) {
+		t.Errorf("date format not localised: %s", files["xl/styles.xml"])
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr" s="4"><is><t xml:space="preserve">date</t></is></c>`,
+		`<c r="A2" t="n" s="1"><v>45660.5</v></c>`,
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">Bistro &#34;Zur Post&#34;</t></is></c>`,
+		`<c r="C3" t="n" s="3"><v>4.20</v></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %s", want)
+		}
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	tests := []struct {
+		format string
+		opts   Options
+	}{
+		{"pdf", Options{}},
+		{FormatCSV, Options{Columns: []string{"date", "fingerprint"}}},
+		{FormatCSV, Options{Columns: []string{"date", "date"}}},
+		{FormatCSV, Options{Locale: "not a locale"}},
+		{FormatCSV, Options{Delimiter: ";;"}},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.format, tt.opts); err == nil {
+			t.Errorf("New(%q, %+v) succeeded, want error", tt.format, tt.opts)
+		}
+	}
+}
+
+func TestColumnLetters(t *testing.T) {
+	for col, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnLetters(col); got != want {
+			t.Errorf("columnLetters(%d) = %s, want %s", col, got, want)
+		}
+	}
+}
+
+func TestSignedAmountOfTransfers(t *testing.T) {
+	var amount pgtype.Numeric
+	amount.Scan("50.00")
+	transfer := int64(7)
+
+	for direction, sign := range map[string]int{"out": -1, "in": 1} {
+		r := Row{Type: "transfer", Amount: amount, TransferID: &transfer, TransferDirection: &direction}
+		if got := signedAmount(&r).(pgtype.Numeric); got.Int.Sign() != sign {
+			t.Errorf("%s leg: signed amount %v", direction, got.Int)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"budgetctl-go/internal/importer"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ledgerWriter writes a Beancount or hledger journal. Accounts are declared
+// the first time they are used, with their original name as metadata when it
+// does not survive conversion to a journal account, so that importing the
+// journal restores it. Rows must be written in date order, since Beancount
+// rejects postings dated before their account's open directive.
+type ledgerWriter struct {
+	w         *bufio.Writer
+	beancount bool
+
+	// accounts maps root and name to the journal account; used detects two
+	// names that would share one account.
+	accounts    map[[2]string]string
+	used        map[string]bool
+	commodities map[string]bool
+}
+
+func newLedgerWriter(w io.Writer, e *Exporter) *ledgerWriter {
+	return &ledgerWriter{
+		w:           bufio.NewWriter(w),
+		beancount:   e.format == FormatBeancount,
+		accounts:    map[[2]string]string{},
+		used:        map[string]bool{},
+		commodities: map[string]bool{},
+	}
+}
+
+func (lw *ledgerWriter) Write(r *Row) error {
+	date := r.Date.Time.UTC().Format("2006-01-02")
+	// Each transfer leg is balanced against an equity account, through
+	// which the two legs offset each other.
+	root := "Income"
+	switch {
+	case r.Type == "expense":
+		root = "Expenses"
+	case r.TransferID != nil:
+		root = "Equity"
+	}
+	account := lw.declare(date, "Assets", r.Account)
+	category := lw.declare(date, root, r.Category)
+	if r.Currency != "" && !lw.commodities[r.Currency] {
+		lw.commodities[r.Currency] = true
+		if lw.beancount {
+			fmt.Fprintf(lw.w, "%s commodity %s\n\n", date, r.Currency)
+		} else {
+			fmt.Fprintf(lw.w, "commodity 1000.00 %s\n\n", r.Currency)
+		}
+	}
+
+	flag := "*"
+	if r.Status == "pending" {
+		flag = "!"
+	}
+	meta := lw.metadata(r)
+	tags := r.Tags
+	if !nativeTags(tags) {
+		tags = nil
+		encoded, err := json.Marshal(r.Tags)
+		if err != nil {
+			return err
+		}
+		meta = append(meta, [2]string{importer.LedgerMetaTags, string(encoded)})
+	}
+
+	if lw.beancount {
+		fmt.Fprintf(lw.w, "%s %s %s", date, flag, beancountString(r.Description))
+		for _, tag := range tags {
+			lw.w.WriteString(" #" + tag)
+		}
+		lw.w.WriteByte('\n')
+		for _, m := range meta {
+			fmt.Fprintf(lw.w, "  %s: %s\n", m[0], beancountString(m[1]))
+		}
+	} else {
+		description := r.Description
+		// hledger ends the description at a comment and reads a leading
+		// status mark or parenthesised code, so such descriptions are kept
+		// whole in the metadata.
+		if strings.ContainsAny(description, ";\n") || strings.TrimSpace(description) != description || strings.IndexAny(description, "*!(") == 0 {
+			description = strings.Join(strings.FieldsFunc(description, func(c rune) bool { return c == ';' || c == '\n' }), "")
+			meta = append([][2]string{{importer.LedgerMetaDescription, r.Description}}, meta...)
+		}
+		fmt.Fprintf(lw.w, "%s %s %s", date, flag, strings.TrimLeft(description, "*!( "))
+		for i, tag := range tags {
+			if i == 0 {
+				lw.w.WriteString("  ; ")
+			} else {
+				lw.w.WriteString(", ")
+			}
+			lw.w.WriteString(tag + ":")
+		}
+		lw.w.WriteByte('\n')
+		for _, m := range meta {
+			for _, line := range strings.Split(m[1], "\n") {
+				fmt.Fprintf(lw.w, "    ; %s: %s\n", m[0], line)
+			}
+		}
+	}
+
+	amount := decimalString(r.Amount)
+	negated := decimalString(negate(r.Amount))
+	indent := "    "
+	if lw.beancount {
+		indent = "  "
+	}
+	if outflow(r) {
+		lw.posting(indent, category, amount, r.Currency)
+		lw.posting(indent, account, negated, r.Currency)
+	} else {
+		lw.posting(indent, account, amount, r.Currency)
+		lw.posting(indent, category, negated, r.Currency)
+	}
+	_, err := lw.w.WriteString("\n")
+	return err
+}
+
+func (lw *ledgerWriter) Close() error {
+	return lw.w.Flush()
+}
+
+// metadata returns the fields of r that have no place in a journal entry.
+// Reconciled and other statuses beyond pending and cleared are kept here, as
+// journals only distinguish the two, and so is the transfer a leg belongs
+// to.
+func (lw *ledgerWriter) metadata(r *Row) [][2]string {
+	var meta [][2]string
+	if r.ExternalID != nil {
+		meta = append(meta, [2]string{importer.LedgerMetaExternalID, *r.ExternalID})
+	}
+	if r.Status != "pending" && r.Status != "cleared" {
+		meta = append(meta, [2]string{importer.LedgerMetaStatus, r.Status})
+	}
+	if r.Notes != nil {
+		meta = append(meta, [2]string{importer.LedgerMetaNotes, *r.Notes})
+	}
+	if r.TransferID != nil && r.TransferDirection != nil {
+		meta = append(meta,
+			[2]string{importer.LedgerMetaTransferID, strconv.FormatInt(*r.TransferID, 10)},
+			[2]string{importer.LedgerMetaTransferDirection, *r.TransferDirection},
+		)
+	}
+	return meta
+}
+
+// declare returns the journal account for name under root, writing its
+// declaration on first use. Names that convert to an account already taken
+// by another name get a numeric suffix.
+func (lw *ledgerWriter) declare(date, root, name string) string {
+	key := [2]string{root, name}
+	if account, ok := lw.accounts[key]; ok {
+		return account
+	}
+
+	base := importer.LedgerAccount(root, name)
+	account := base
+	for n := 2; lw.used[account]; n++ {
+		account = base + "-" + strconv.Itoa(n)
+	}
+	lw.accounts[key] = account
+	lw.used[account] = true
+
+	renamed := account != root+":"+name
+	if lw.beancount {
+		fmt.Fprintf(lw.w, "%s open %s\n", date, account)
+		if renamed {
+			fmt.Fprintf(lw.w, "  %s: %s\n", importer.LedgerMetaName, beancountString(name))
+		}
+	} else {
+		fmt.Fprintf(lw.w, "account %s", account)
+		if renamed {
+			fmt.Fprintf(lw.w, "  ; %s: %s", importer.LedgerMetaName, name)
+		}
+		lw.w.WriteByte('\n')
+	}
+	lw.w.WriteByte('\n')
+	return account
+}
+
+func (lw *ledgerWriter) posting(indent, account, amount, currency string) {
+	fmt.Fprintf(lw.w, "%s%-40s  %12s", indent, account, amount)
+	if currency != "" {
+		lw.w.WriteString(" " + currency)
+	}
+	lw.w.WriteByte('\n')
+}
+
+// nativeTags reports whether every tag can be written as a journal tag.
+func nativeTags(tags []string) bool {
+	for _, tag := range tags {
+		if !importer.LedgerTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// beancountString quotes s as a Beancount string.
+func beancountString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func negate(n pgtype.Numeric) pgtype.Numeric {
+	if n.Int != nil {
+		n.Int = new(big.Int).Neg(n.Int)
+	}
+	return n
+}
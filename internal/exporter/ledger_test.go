@@ -0,0 +1,141 @@
+package exporter
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"budgetctl-go/internal/importer"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func ledgerRows() []Row {
+	notes := "Split with Sam.\n\"Receipt\" in the drawer; see photo"
+	ref := "FIT-0042"
+	var lunch, salary, coffee, savings pgtype.Numeric
+	lunch.Scan("23.40")
+	salary.Scan("3100.00")
+	coffee.Scan("3.10")
+	savings.Scan("500.00")
+	transfer := int64(9)
+	out, in := "out", "in"
+
+	date := func(day int) pgtype.Timestamptz {
+		return pgtype.Timestamptz{Time: time.Date(2025, 2, day, 0, 0, 0, 0, time.UTC), Valid: true}
+	}
+	return []Row{
+		{
+			Date: date(1), Description: "Salary", Amount: salary, Type: "income",
+			Category: "Salary", Account: "Checking", Currency: "EUR", Status: "reconciled",
+			Tags: []string{}, ExternalID: &ref,
+		},
+		{
+			Date: date(3), Description: "Bistro \"Zur Post\"; table 4", Amount: lunch, Type: "expense",
+			Category: "food & drink", Account: "Joint account", Currency: "EUR", Status: "cleared",
+			Tags: []string{"work", "team lunch"}, Notes: &notes,
+		},
+		{
+			Date: date(4), Description: "(Coffee)", Amount: coffee, Type: "expense",
+			Category: "Food & Drink", Account: "", Currency: "USD", Status: "pending",
+			Tags: []string{"trip"},
+		},
+		{
+			Date: date(5), Description: "Savings", Amount: savings, Type: "transfer",
+			Category: "Transfer", Account: "Checking", Currency: "EUR", Status: "cleared",
+			Tags: []string{}, TransferID: &transfer, TransferDirection: &out,
+		},
+		{
+			Date: date(5), Description: "Savings", Amount: savings, Type: "transfer",
+			Category: "Transfer", Account: "Savings", Currency: "EUR", Status: "cleared",
+			Tags: []string{}, TransferID: &transfer, TransferDirection: &in,
+		},
+	}
+}
+
+func TestLedgerRoundTrip(t *testing.T) {
+	tests := []struct {
+		format string
+		parse  func(io.Reader) ([]importer.Row, error)
+	}{
+		{FormatBeancount, importer.ParseBeancount},
+		{FormatHledger, importer.ParseHledger},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			e, err := New(tt.format, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			w, err := e.Start(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ledgerRows()
+			for _, r := range want {
+				if err := w.Write(&r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := tt.parse(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("parse export: %v\n%s", err, buf.String())
+			}
+			if len(rows) != len(want) {
+				t.Fatalf("got %d rows, want %d\n%s", len(rows), len(want), buf.String())
+			}
+			for i, row := range rows {
+				got, w := row.Transaction, want[i]
+				if !row.Valid() {
+					t.Errorf("row %d: %v", i, row.Errors)
+				}
+				if got.Date != w.Date || decimalString(got.Amount) != decimalString(w.Amount) ||
+					got.Description != w.Description || got.Type != w.Type || got.Category != w.Category ||
+					got.Account != w.Account || got.Currency != w.Currency || got.Status != w.Status ||
+					!reflect.DeepEqual(got.Tags, w.Tags) || !reflect.DeepEqual(got.Notes, w.Notes) ||
+					!reflect.DeepEqual(got.ExternalID, w.ExternalID) {
+					t.Errorf("row %d did not survive the round trip:\ngot  %+v\nwant %+v\n%s", i, got, w, buf.String())
+				}
+				if w.TransferID != nil && (row.Transfer != "9" || row.TransferDirection != *w.TransferDirection) {
+					t.Errorf("row %d: transfer %q %q, want 9 %s", i, row.Transfer, row.TransferDirection, *w.TransferDirection)
+				}
+			}
+		})
+	}
+}
+
+func TestBeancountOutput(t *testing.T) {
+	e, err := New(FormatBeancount, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, _ := e.Start(&buf)
+	r := ledgerRows()[1]
+	if err := w.Write(&r); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	for _, want := range []string{
+		"2025-02-03 open Assets:Joint-account\n  name: \"Joint account\"\n",
+		"2025-02-03 open Expenses:Food-drink\n  name: \"food & drink\"\n",
+		"2025-02-03 commodity EUR\n",
+		"2025-02-03 * \"Bistro \\\"Zur Post\\\"; table 4\"\n",
+		"  tags: \"[\\\"work\\\",\\\"team lunch\\\"]\"\n",
+		"  Expenses:Food-drink                              23.40 EUR\n",
+		"  Assets:Joint-account                            -23.40 EUR\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ndjsonWriter writes one JSON object per line with keys in column order.
+// Values keep their JSON types; the locale and date format do not apply.
+type ndjsonWriter struct {
+	e *Exporter
+	w *bufio.Writer
+}
+
+func newNDJSONWriter(w io.Writer, e *Exporter) *ndjsonWriter {
+	return &ndjsonWriter{e: e, w: bufio.NewWriter(w)}
+}
+
+func (nw *ndjsonWriter) Write(r *Row) error {
+	nw.w.WriteByte('{')
+	for i, col := range nw.e.columns {
+		if i > 0 {
+			nw.w.WriteByte(',')
+		}
+		key, _ := json.Marshal(col.name)
+		nw.w.Write(key)
+		nw.w.WriteByte(':')
+
+		value, err := jsonValue(col.value(r))
+		if err != nil {
+			return err
+		}
+		nw.w.Write(value)
+	}
+	_, err := nw.w.WriteString("}\n")
+	return err
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nw.w.Flush()
+}
+
+func jsonValue(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case pgtype.Numeric:
+		if s := decimalString(v); s != "" {
+			return []byte(s), nil
+		}
+		return []byte("null"), nil
+	case pgtype.Timestamptz:
+		if !v.Valid {
+			return []byte("null"), nil
+		}
+		return json.Marshal(v.Time.UTC().Format(time.RFC3339))
+	case []string:
+		if v == nil {
+			v = []string{}
+		}
+	}
+	return json.Marshal(v)
+}
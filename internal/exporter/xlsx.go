@@ -0,0 +1,206 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// The static parts of a single-sheet workbook. Cells use inline strings, so no
+// shared string table has to be built before the sheet can be written.
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Transactions" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+
+	// Cell styles: 1 date, 2 date-time, 3 amount (#,##0.00), 4 bold header.
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts count="2"><numFmt numFmtId="164" formatCode="%s"/><numFmt numFmtId="165" formatCode="%s"/></numFmts><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="5"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews><sheetData>`
+
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+const (
+	styleDate     = 1
+	styleDateTime = 2
+	styleAmount   = 3
+	styleHeader   = 4
+)
+
+// excelEpoch is day zero of Excel's 1900 date system, accounting for its
+// fictitious 29 February 1900.
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// excelFormat converts Go layout elements to Excel number format codes.
+var excelFormat = strings.NewReplacer(
+	"2006", "yyyy",
+	"Jan", "mmm",
+	"01", "mm",
+	"02", "dd",
+	"06", "yy",
+	"15", "hh",
+	"04", "mm",
+	"05", "ss",
+	"1", "m",
+	"2", "d",
+)
+
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	e     *Exporter
+	row   int
+}
+
+func newXLSXWriter(w io.Writer, e *Exporter) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+
+	var styles strings.Builder
+	xml.EscapeText(&styles, []byte(excelFormat.Replace(e.dateLayout)))
+	dateFormat := styles.String()
+	styles.Reset()
+	xml.EscapeText(&styles, []byte(excelFormat.Replace(e.timeLayoutForXLSX())))
+	timeFormat := styles.String()
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", fmt.Sprintf(xlsxStyles, dateFormat, timeFormat)},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	// The sheet is the last entry, so it can be written row by row.
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw := &xlsxWriter{zip: zw, sheet: bufio.NewWriter(f), e: e}
+	xw.sheet.WriteString(xlsxSheetStart)
+
+	xw.startRow()
+	for i, col := range e.columns {
+		xw.inlineString(i, col.name, styleHeader)
+	}
+	xw.sheet.WriteString("</row>")
+	return xw, nil
+}
+
+// timeLayoutForXLSX returns the date-time layout; RFC 3339 has no Excel
+// equivalent, so ISO dates with a space-separated time are used instead.
+func (e *Exporter) timeLayoutForXLSX() string {
+	if e.timeLayout == time.RFC3339 {
+		return e.dateLayout + " 15:04:05"
+	}
+	return e.timeLayout
+}
+
+func (xw *xlsxWriter) Write(r *Row) error {
+	xw.startRow()
+	for i, col := range xw.e.columns {
+		switch v := col.value(r).(type) {
+		case pgtype.Numeric:
+			style := 0
+			if col.kind == kindDecimal {
+				style = styleAmount
+			}
+			xw.number(i, decimalString(v), style)
+		case pgtype.Timestamptz:
+			if !v.Valid {
+				continue
+			}
+			style := styleDateTime
+			if col.kind == kindDate {
+				style = styleDate
+			}
+			serial := v.Time.UTC().Sub(excelEpoch).Hours() / 24
+			xw.number(i, strconv.FormatFloat(serial, 'f', -1, 64), style)
+		case int64:
+			xw.number(i, strconv.FormatInt(v, 10), 0)
+		case *int64:
+			if v != nil {
+				xw.number(i, strconv.FormatInt(*v, 10), 0)
+			}
+		case bool:
+			xw.cell(i, "b", 0, "<v>"+map[bool]string{true: "1", false: "0"}[v]+"</v>")
+		default:
+			if s := xw.e.text(col.kind, v); s != "" {
+				xw.inlineString(i, s, 0)
+			}
+		}
+	}
+	_, err := xw.sheet.WriteString("</row>")
+	return err
+}
+
+func (xw *xlsxWriter) Close() error {
+	xw.sheet.WriteString(xlsxSheetEnd)
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zip.Close()
+}
+
+func (xw *xlsxWriter) startRow() {
+	xw.row++
+	fmt.Fprintf(xw.sheet, `<row r="%d">`, xw.row)
+}
+
+func (xw *xlsxWriter) number(col int, value string, style int) {
+	if value == "" {
+		return
+	}
+	xw.cell(col, "n", style, "<v>"+value+"</v>")
+}
+
+func (xw *xlsxWriter) inlineString(col int, value string, style int) {
+	var b strings.Builder
+	b.WriteString(`<is><t xml:space="preserve">`)
+	xml.EscapeText(&b, []byte(value))
+	b.WriteString(`</t></is>`)
+	xw.cell(col, "inlineStr", style, b.String())
+}
+
+func (xw *xlsxWriter) cell(col int, typ string, style int, content string) {
+	fmt.Fprintf(xw.sheet, `<c r="%s%d" t="%s"`, columnLetters(col), xw.row, typ)
+	if style != 0 {
+		fmt.Fprintf(xw.sheet, ` s="%d"`, style)
+	}
+	xw.sheet.WriteString(">" + content + "</c>")
+}
+
+// columnLetters returns the spreadsheet name of a zero-based column index.
+func columnLetters(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
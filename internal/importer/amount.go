@@ -0,0 +1,126 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// parseAmount parses a formatted number such as "-1.234,56", "(12.00)",
+// "12.00-", "$ 1,200" or "12,00 EUR" and returns its absolute value and
+// whether it was negative. The number may carry one sign and a currency code
+// or symbol before or after it; letters, symbols or signs inside the number
+// are an error. An empty thousands separator means none is expected.
+func parseAmount(s, decimalSep, thousandsSep string) (pgtype.Numeric, bool, error) {
+	raw := s
+	invalid := fmt.Errorf("invalid amount %q", raw)
+	s = strings.TrimSpace(s)
+	if decimalSep == "" {
+		decimalSep = "."
+	}
+
+	signs := 0
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		signs++
+		s = s[1 : len(s)-1]
+	}
+	if thousandsSep != "" {
+		s = strings.ReplaceAll(s, thousandsSep, "")
+	}
+	s = strings.ReplaceAll(s, decimalSep, ".")
+
+	isDigit := func(r rune) bool { return r >= '0' && r <= '9' || r == '.' }
+	first := strings.IndexFunc(s, isDigit)
+	if first < 0 {
+		return pgtype.Numeric{}, false, invalid
+	}
+	last := strings.LastIndexFunc(s, isDigit)
+	prefix, number, suffix := s[:first], s[first:last+1], s[last+1:]
+
+	// Between its first and last digit the number only holds digits, the
+	// decimal point and spaces or apostrophes grouping thousands.
+	var b strings.Builder
+	for _, r := range number {
+		switch {
+		case isDigit(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r), r == '\'':
+		default:
+			return pgtype.Numeric{}, false, invalid
+		}
+	}
+
+	negative := signs == 1
+	currencies := 0
+	for _, affix := range []string{prefix, suffix} {
+		inCurrency := false
+		for _, r := range affix {
+			currency := unicode.IsLetter(r) || unicode.Is(unicode.Sc, r)
+			switch {
+			case currency && !inCurrency:
+				currencies++
+			case currency:
+			case r == '-' || r == '−':
+				signs++
+				negative = true
+			case r == '+':
+				signs++
+			case unicode.IsSpace(r):
+			default:
+				return pgtype.Numeric{}, false, invalid
+			}
+			inCurrency = currency
+		}
+	}
+	if signs > 1 || currencies > 1 {
+		return pgtype.Numeric{}, false, invalid
+	}
+
+	digits := b.String()
+	if strings.Count(digits, ".") > 1 || strings.HasPrefix(digits, ".") || strings.HasSuffix(digits, ".") {
+		return pgtype.Numeric{}, false, invalid
+	}
+
+	var n pgtype.Numeric
+	if err := n.Scan(digits); err != nil {
+		return pgtype.Numeric{}, false, invalid
+	}
+	return n, negative, nil
+}
+
+// dateTokens maps human-friendly date format tokens to Go layout elements.
+var dateTokens = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MMM", "Jan",
+	"MM", "01",
+	"M", "1",
+	"DD", "02",
+	"D", "2",
+	"HH", "15",
+	"mm", "04",
+	"ss", "05",
+)
+
+// DateLayout converts a format such as "DD/MM/YYYY" into a Go time layout.
+// Formats that already are Go layouts (they mention 2006) are used as is.
+func DateLayout(format string) string {
+	if format == "" {
+		return "2006-01-02"
+	}
+	if strings.Contains(format, "2006") {
+		return format
+	}
+	return dateTokens.Replace(format)
+}
+
+func parseDate(s, layout string) (pgtype.Timestamptz, error) {
+	t, err := time.ParseInLocation(layout, strings.TrimSpace(s), time.UTC)
+	if err != nil {
+		return pgtype.Timestamptz{}, fmt.Errorf("invalid date %q, expected layout %q", s, layout)
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}, nil
+}
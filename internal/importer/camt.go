@@ -0,0 +1,301 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// camtAccount is the Acct element of a camt.053 statement.
+type camtAccount struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+}
+
+// camtDate holds either a date or a date-time, as allowed for BookgDt and ValDt.
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// camtParty covers both the pre-2019 (Dbtr>Nm) and the later (Dbtr>Pty>Nm) layouts.
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+func (p camtParty) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.PartyName
+}
+
+type camtTxDetails struct {
+	EndToEndID string    `xml:"Refs>EndToEndId"`
+	AcctSvcRef string    `xml:"Refs>AcctSvcrRef"`
+	Debtor     camtParty `xml:"RltdPties>Dbtr"`
+	Creditor   camtParty `xml:"RltdPties>Cdtr"`
+	Ustrd      []string  `xml:"RmtInf>Ustrd"`
+	StrdRef    string    `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	AddtlInfo  string    `xml:"AddtlTxInf"`
+}
+
+type camtEntry struct {
+	NtryRef string `xml:"NtryRef"`
+	Amount  struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	CdtDbtInd string `xml:"CdtDbtInd"`
+	// Sts is a plain code up to camt.053.001.08 and a Cd element afterwards.
+	Status struct {
+		Value string `xml:",chardata"`
+		Code  string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookingDate camtDate        `xml:"BookgDt"`
+	ValueDate   camtDate        `xml:"ValDt"`
+	AcctSvcRef  string          `xml:"AcctSvcrRef"`
+	Details     []camtTxDetails `xml:"NtryDtls>TxDtls"`
+	AddtlInfo   string          `xml:"AddtlNtryInf"`
+}
+
+// ParseCAMT053 reads an ISO 20022 camt.053 bank-to-customer statement and
+// returns one Row per Ntry element. The counterparty becomes the description,
+// remittance information and a differing value date go into the notes, and the
+// bank's entry reference is kept as the external ID.
+func ParseCAMT053(r io.Reader) ([]Row, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(label)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported encoding %q", label)
+		}
+		return enc.NewDecoder().Reader(input), nil
+	}
+
+	var (
+		rows      []Row
+		account   camtAccount
+		statement string
+		entries   int
+		inStmt    bool
+		sawDoc    bool
+	)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid camt.053 file: %w", err)
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "BkToCstmrStmt":
+				sawDoc = true
+			case "Stmt":
+				inStmt, account, statement, entries = true, camtAccount{}, "", 0
+			case "Id":
+				if inStmt && statement == "" {
+					if err := dec.DecodeElement(&statement, &el); err != nil {
+						return nil, fmt.Errorf("invalid camt.053 file: %w", err)
+					}
+				}
+			case "Acct":
+				if inStmt {
+					if err := dec.DecodeElement(&account, &el); err != nil {
+						return nil, fmt.Errorf("invalid camt.053 file: %w", err)
+					}
+				}
+			case "Ntry":
+				line, _ := dec.InputPos()
+				var entry camtEntry
+				if err := dec.DecodeElement(&entry, &el); err != nil {
+					return nil, fmt.Errorf("line %d: invalid entry: %w", line, err)
+				}
+				entries++
+				rows = append(rows, camtRow(line, entry, account, statement, entries))
+			default:
+				if inStmt {
+					// Balances, summaries and other statement children are not needed.
+					if err := dec.Skip(); err != nil {
+						return nil, fmt.Errorf("invalid camt.053 file: %w", err)
+					}
+				}
+			}
+		case xml.EndElement:
+			if el.Name.Local == "Stmt" {
+				inStmt = false
+			}
+		}
+	}
+
+	if !sawDoc {
+		return nil, errors.New("not a camt.053 file: missing BkToCstmrStmt element")
+	}
+	if len(rows) == 0 {
+		return nil, ErrEmptyFile
+	}
+	return rows, nil
+}
+
+func camtRow(line int, e camtEntry, account camtAccount, statement string, n int) Row {
+	row := Row{Line: line}
+	t := &row.Transaction
+
+	t.Account = account.IBAN
+	if t.Account == "" {
+		t.Account = account.Other
+	}
+	t.Currency = e.Amount.Currency
+	if t.Currency == "" {
+		t.Currency = account.Currency
+	}
+
+	status := e.Status.Code
+	if status == "" {
+		status = strings.TrimSpace(e.Status.Value)
+	}
+	switch status {
+	case "BOOK":
+		t.Status = "cleared"
+	case "PDNG", "INFO":
+		t.Status = "pending"
+	}
+
+	switch e.CdtDbtInd {
+	case "CRDT":
+		t.Type = "income"
+	case "DBIT":
+		t.Type = "expense"
+	default:
+		// Reported by validate as an invalid type.
+		t.Type = e.CdtDbtInd
+	}
+
+	if v := strings.TrimSpace(e.Amount.Value); v != "" {
+		amount, _, err := parseAmount(v, ".", "")
+		if err != nil {
+			row.fieldError("amount", err)
+		} else {
+			t.Amount = amount
+		}
+	}
+
+	booking, err := camtParseDate(e.BookingDate)
+	if err != nil {
+		row.fieldError("date", err)
+	}
+	t.Date = booking
+	value, err := camtParseDate(e.ValueDate)
+	if err != nil {
+		row.addError("invalid value date: %v", err)
+	}
+
+	var (
+		tx        camtTxDetails
+		remitInfo []string
+	)
+	if len(e.Details) > 0 {
+		tx = e.Details[0]
+	}
+	for _, d := range e.Details {
+		remitInfo = append(remitInfo, d.Ustrd...)
+		if d.StrdRef != "" {
+			remitInfo = append(remitInfo, d.StrdRef)
+		}
+	}
+	remittance := strings.Join(trimAll(remitInfo), " ")
+
+	counterparty := tx.Creditor.name()
+	if t.Type == "income" {
+		counterparty = tx.Debtor.name()
+	}
+
+	info := strings.TrimSpace(e.AddtlInfo)
+	if info == "" {
+		info = strings.TrimSpace(tx.AddtlInfo)
+	}
+
+	var notes []string
+	switch {
+	case counterparty != "":
+		t.Description = counterparty
+		notes = append(notes, remittance)
+	case remittance != "":
+		t.Description = remittance
+	default:
+		t.Description = info
+		info = ""
+	}
+	if info != "" && info != t.Description {
+		notes = append(notes, info)
+	}
+	if value.Valid && booking.Valid && !value.Time.Equal(booking.Time) {
+		notes = append(notes, "Value date: "+value.Time.Format("2006-01-02"))
+	}
+	if s := strings.Join(trimAll(notes), "\n"); s != "" {
+		t.Notes = &s
+	}
+
+	t.ExternalID = firstReference(
+		e.AcctSvcRef,
+		tx.AcctSvcRef,
+		e.NtryRef,
+		tx.EndToEndID,
+	)
+	if t.ExternalID == nil && statement != "" {
+		t.ExternalID = optionalString(fmt.Sprintf("%s/%d", statement, n))
+	}
+
+	row.validate()
+	return row
+}
+
+func camtParseDate(d camtDate) (pgtype.Timestamptz, error) {
+	switch {
+	case d.Date != "":
+		return parseDate(d.Date, "2006-01-02")
+	case d.DateTime != "":
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+			if t, err := time.ParseInLocation(layout, strings.TrimSpace(d.DateTime), time.UTC); err == nil {
+				return pgtype.Timestamptz{Time: t, Valid: true}, nil
+			}
+		}
+		return pgtype.Timestamptz{}, fmt.Errorf("invalid date %q", d.DateTime)
+	}
+	return pgtype.Timestamptz{}, nil
+}
+
+// firstReference returns the first usable bank reference. NOTPROVIDED is the
+// ISO 20022 placeholder for a missing end-to-end ID.
+func firstReference(refs ...string) *string {
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if ref != "" && !strings.EqualFold(ref, "NOTPROVIDED") && !strings.EqualFold(ref, "NONREF") {
+			return &ref
+		}
+	}
+	return nil
+}
+
+// trimAll trims each string and drops the empty ones.
+func trimAll(list []string) []string {
+	out := list[:0:0]
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
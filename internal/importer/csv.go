@@ -0,0 +1,310 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// CSVColumns maps transaction fields to CSV columns. Each value is either a
+// header name (matched case-insensitively) or a 1-based column number.
+type CSVColumns struct {
+	Date        string `json:"date" doc:"Booking date column"`
+	Amount      string `json:"amount,omitempty" doc:"Signed amount column; negative values are expenses"`
+	Debit       string `json:"debit,omitempty" doc:"Money-out column, used instead of amount"`
+	Credit      string `json:"credit,omitempty" doc:"Money-in column, used instead of amount"`
+	Description string `json:"description" doc:"Description or payee column"`
+	Category    string `json:"category,omitempty"`
+	Type        string `json:"type,omitempty" doc:"Column holding income/expense or credit/debit, overriding the amount sign"`
+	Currency    string `json:"currency,omitempty"`
+	Account     string `json:"account,omitempty"`
+	Status      string `json:"status,omitempty"`
+	Tags        string `json:"tags,omitempty"`
+	Notes       string `json:"notes,omitempty"`
+}
+
+// CSVOptions describes how to read a bank's CSV export. Options are saved per
+// bank so the same export can be imported again without re-entering them.
+type CSVOptions struct {
+	Delimiter          string     `json:"delimiter,omitempty" doc:"Field delimiter, defaults to a comma. Use \\t for tab"`
+	Encoding           string     `json:"encoding,omitempty" doc:"Text encoding such as utf-8, utf-16le, windows-1252 or iso-8859-2. Defaults to utf-8"`
+	NoHeader           bool       `json:"no_header,omitempty" doc:"The file has no header row; columns must be given by number"`
+	SkipRows           int        `json:"skip_rows,omitempty" minimum:"0" doc:"Lines to skip before the header, e.g. bank preamble"`
+	DateFormat         string     `json:"date_format,omitempty" doc:"Date format such as DD/MM/YYYY or a Go layout. Defaults to YYYY-MM-DD"`
+	DecimalSeparator   string     `json:"decimal_separator,omitempty" doc:"Decimal separator, defaults to ."`
+	ThousandsSeparator string     `json:"thousands_separator,omitempty" doc:"Thousands separator, if any"`
+	InvertSign         bool       `json:"invert_sign,omitempty" doc:"Treat positive amounts as expenses, as in many credit card exports"`
+	TagSeparator       string     `json:"tag_separator,omitempty" doc:"Separator for multiple tags in the tags column, defaults to ;"`
+	DefaultCurrency    string     `json:"default_currency,omitempty" doc:"Currency for rows without a currency column"`
+	DefaultAccount     string     `json:"default_account,omitempty" doc:"Account for rows without an account column"`
+	Columns            CSVColumns `json:"columns"`
+}
+
+// ParseCSV reads a CSV export using opts and returns one Row per record.
+// Problems with individual records are reported on the row; an error is
+// returned only when the file as a whole cannot be read.
+func ParseCSV(r io.Reader, opts CSVOptions) ([]Row, error) {
+	decoded, err := decodeReader(r, opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(decoded)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if opts.Delimiter != "" {
+		delim := strings.ReplaceAll(opts.Delimiter, `\t`, "\t")
+		comma, size := utf8.DecodeRuneInString(delim)
+		if size != len(delim) {
+			return nil, fmt.Errorf("delimiter must be a single character, got %q", opts.Delimiter)
+		}
+		reader.Comma = comma
+	}
+
+	for i := 0; i < opts.SkipRows; i++ {
+		if _, err := reader.Read(); errors.Is(err, io.EOF) {
+			return nil, ErrEmptyFile
+		}
+	}
+
+	var header []string
+	if !opts.NoHeader {
+		header, err = reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil, ErrEmptyFile
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading header: %w", err)
+		}
+	}
+
+	cols, err := resolveColumns(opts.Columns, header)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := DateLayout(opts.DateFormat)
+	tagSep := opts.TagSeparator
+	if tagSep == "" {
+		tagSep = ";"
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			row := Row{Line: parseErr.StartLine}
+			row.addError("%v", parseErr.Err)
+			rows = append(rows, row)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isBlank(record) {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := Row{Line: line}
+
+		parseCSVRecord(&row, record, cols, opts, layout, tagSep)
+		row.validate()
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrEmptyFile
+	}
+	return rows, nil
+}
+
+func parseCSVRecord(row *Row, record []string, cols map[string]int, opts CSVOptions, layout, tagSep string) {
+	get := func(field string) string {
+		idx, ok := cols[field]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+
+	t := &row.Transaction
+	t.Description = get("description")
+	t.Category = get("category")
+	t.Notes = optionalString(get("notes"))
+	t.Status = get("status")
+
+	t.Currency = get("currency")
+	if t.Currency == "" {
+		t.Currency = opts.DefaultCurrency
+	}
+	t.Account = get("account")
+	if t.Account == "" {
+		t.Account = opts.DefaultAccount
+	}
+
+	for _, tag := range strings.Split(get("tags"), tagSep) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+
+	if v := get("date"); v != "" {
+		date, err := parseDate(v, layout)
+		if err != nil {
+			row.fieldError("date", err)
+		} else {
+			t.Date = date
+		}
+	}
+
+	// Amount: either a signed amount column or separate debit/credit columns.
+	negative := false
+	if _, ok := cols["amount"]; ok {
+		if v := get("amount"); v != "" {
+			amount, neg, err := parseAmount(v, opts.DecimalSeparator, opts.ThousandsSeparator)
+			if err != nil {
+				row.fieldError("amount", err)
+			} else {
+				t.Amount, negative = amount, neg != opts.InvertSign
+			}
+		}
+	} else {
+		debit, credit := get("debit"), get("credit")
+		switch {
+		case debit != "" && credit != "":
+			row.fieldError("amount", errors.New("both debit and credit are set"))
+		case debit != "":
+			amount, _, err := parseAmount(debit, opts.DecimalSeparator, opts.ThousandsSeparator)
+			if err != nil {
+				row.fieldError("amount", err)
+			} else {
+				t.Amount, negative = amount, true
+			}
+		case credit != "":
+			amount, _, err := parseAmount(credit, opts.DecimalSeparator, opts.ThousandsSeparator)
+			if err != nil {
+				row.fieldError("amount", err)
+			} else {
+				t.Amount = amount
+			}
+		}
+	}
+
+	t.Type = "income"
+	if negative {
+		t.Type = "expense"
+	}
+	if v := get("type"); v != "" {
+		typ, ok := normalizeType(v)
+		if !ok {
+			row.addError("unknown type %q", v)
+		} else {
+			t.Type = typ
+		}
+	}
+}
+
+// normalizeType maps common bank spellings of the transaction direction.
+func normalizeType(v string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "income", "credit", "cr", "c", "in", "deposit":
+		return "income", true
+	case "expense", "debit", "dr", "d", "out", "withdrawal", "payment":
+		return "expense", true
+	}
+	return "", false
+}
+
+// resolveColumns turns the configured column references into record indexes.
+func resolveColumns(c CSVColumns, header []string) (map[string]int, error) {
+	refs := map[string]string{
+		"date":        c.Date,
+		"amount":      c.Amount,
+		"debit":       c.Debit,
+		"credit":      c.Credit,
+		"description": c.Description,
+		"category":    c.Category,
+		"type":        c.Type,
+		"currency":    c.Currency,
+		"account":     c.Account,
+		"status":      c.Status,
+		"tags":        c.Tags,
+		"notes":       c.Notes,
+	}
+
+	if c.Date == "" || c.Description == "" {
+		return nil, errors.New("columns.date and columns.description are required")
+	}
+	if c.Amount == "" && c.Debit == "" && c.Credit == "" {
+		return nil, errors.New("columns.amount or columns.debit/columns.credit is required")
+	}
+
+	cols := map[string]int{}
+	for field, ref := range refs {
+		if ref == "" {
+			continue
+		}
+		idx, err := columnIndex(ref, header)
+		if err != nil {
+			return nil, fmt.Errorf("columns.%s: %w", field, err)
+		}
+		cols[field] = idx
+	}
+	return cols, nil
+}
+
+func columnIndex(ref string, header []string) (int, error) {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(ref)) {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(ref); err == nil && n >= 1 {
+		return n - 1, nil
+	}
+	return 0, fmt.Errorf("column %q not found", ref)
+}
+
+// decodeReader wraps r so that it yields UTF-8, honouring a byte order mark
+// when present.
+func decodeReader(r io.Reader, name string) (io.Reader, error) {
+	if name == "" || strings.EqualFold(name, "utf-8") || strings.EqualFold(name, "utf8") {
+		return transform.NewReader(r, unicode.BOMOverride(unicode.UTF8.NewDecoder())), nil
+	}
+
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported encoding %q", name)
+	}
+	return transform.NewReader(r, unicode.BOMOverride(enc.NewDecoder())), nil
+}
+
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,151 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestParseCSVSignedAmount(t *testing.T) {
+	input := "Date,Payee,Amount,Tags\n" +
+		"2025-01-03,Coffee Shop,-4.50,food;morning\n" +
+		"2025-01-04,Salary,\"2,500.00\",\n" +
+		"not-a-date,Broken,abc,\n"
+
+	rows, err := ParseCSV(strings.NewReader(input), CSVOptions{
+		ThousandsSeparator: ",",
+		DefaultAccount:     "checking",
+		Columns: CSVColumns{
+			Date:        "date",
+			Description: "Payee",
+			Amount:      "Amount",
+			Tags:        "Tags",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+
+	coffee := rows[0].Transaction
+	if !rows[0].Valid() || coffee.Type != "expense" || coffee.Description != "Coffee Shop" {
+		t.Fatalf("unexpected first row: %+v", rows[0])
+	}
+	if coffee.Amount.Int.Int64() != 450 || coffee.Amount.Exp != -2 {
+		t.Fatalf("expected amount 4.50, got %v e%d", coffee.Amount.Int, coffee.Amount.Exp)
+	}
+	if len(coffee.Tags) != 2 || coffee.Tags[1] != "morning" {
+		t.Fatalf("unexpected tags: %v", coffee.Tags)
+	}
+	if coffee.Account != "checking" || coffee.Category != DefaultCategory {
+		t.Fatalf("expected defaults to be applied, got account %q category %q", coffee.Account, coffee.Category)
+	}
+
+	salary := rows[1].Transaction
+	if salary.Type != "income" || salary.Amount.Int.Int64() != 250000 {
+		t.Fatalf("unexpected salary row: %+v", rows[1])
+	}
+
+	if rows[2].Valid() || len(rows[2].Errors) != 2 || rows[2].Line != 4 {
+		t.Fatalf("expected date and amount errors on line 4, got %+v", rows[2])
+	}
+}
+
+func TestParseCSVEuropeanBankExport(t *testing.T) {
+	input := "Kontoauszug Girokonto\n" +
+		"Buchungstag;Empfänger;Soll;Haben\n" +
+		"31.01.2025;Bäckerei Müller;3,20;\n" +
+		"01.02.2025;Gehalt;;1.800,00\n"
+
+	encoded, err := charmap.Windows1252.NewEncoder().String(input)
+	if err != nil {
+		t.Fatalf("failed to encode fixture: %v", err)
+	}
+
+	rows, err := ParseCSV(bytes.NewReader([]byte(encoded)), CSVOptions{
+		Delimiter:          ";",
+		Encoding:           "windows-1252",
+		SkipRows:           1,
+		DateFormat:         "DD.MM.YYYY",
+		DecimalSeparator:   ",",
+		ThousandsSeparator: ".",
+		DefaultCurrency:    "EUR",
+		Columns: CSVColumns{
+			Date:        "Buchungstag",
+			Description: "Empfänger",
+			Debit:       "Soll",
+			Credit:      "Haben",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+
+	bakery := rows[0]
+	if !bakery.Valid() || bakery.Transaction.Description != "Bäckerei Müller" || bakery.Transaction.Type != "expense" {
+		t.Fatalf("unexpected bakery row: %+v", bakery)
+	}
+	if !bakery.Transaction.Date.Time.Equal(time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected date: %v", bakery.Transaction.Date.Time)
+	}
+	if bakery.Transaction.Currency != "EUR" {
+		t.Fatalf("expected EUR, got %q", bakery.Transaction.Currency)
+	}
+
+	salary := rows[1].Transaction
+	if salary.Type != "income" || salary.Amount.Int.Int64() != 180000 {
+		t.Fatalf("unexpected salary row: %+v", rows[1])
+	}
+}
+
+func TestParseCSVMissingColumn(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader("Date,Amount\n2025-01-01,1\n"), CSVOptions{
+		Columns: CSVColumns{Date: "Date", Amount: "Amount", Description: "Memo"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Memo") {
+		t.Fatalf("expected missing column error, got %v", err)
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in       string
+		want     string
+		negative bool
+	}{
+		{"-4.50", "4.50", true},
+		{"(12.00)", "12.00", true},
+		{"12.00-", "12.00", true},
+		{"$ 1,200", "1200", false},
+		{"$-1,200.50", "1200.50", true},
+		{"USD 12", "12", false},
+		{"+12", "12", false},
+		{"1,200.50 EUR", "1200.50", false},
+		{"1'234.5", "1234.5", false},
+	}
+	for _, tt := range tests {
+		n, negative, err := parseAmount(tt.in, ".", ",")
+		if err != nil {
+			t.Errorf("parseAmount(%q): %v", tt.in, err)
+			continue
+		}
+		got, _ := n.Value()
+		if got != tt.want || negative != tt.negative {
+			t.Errorf("parseAmount(%q) = %v, %v, want %s, %v", tt.in, got, negative, tt.want, tt.negative)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "12e5", "1-2", "1+2", "--12", "-12-", "(-12)", "USD 12 EUR", "12 USD 3", "1.2.3", "12#"} {
+		if _, _, err := parseAmount(in, ".", ","); err == nil {
+			t.Errorf("parseAmount(%q) succeeded, want an error", in)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// checkGolden compares rows against testdata/<name>.golden.json.
+func checkGolden(t *testing.T, name string, rows []Row) {
+	t.Helper()
+
+	got, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal rows: %v", err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match parsed rows:\n%s", path, got)
+	}
+}
+
+func TestParseOFXGolden(t *testing.T) {
+	for _, file := range []string{"checking.ofx", "creditcard.qfx"} {
+		t.Run(file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			rows, err := ParseOFX(f)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkGolden(t, file, rows)
+		})
+	}
+}
+
+func TestParseQIFGolden(t *testing.T) {
+	tests := []struct {
+		file string
+		opts QIFOptions
+	}{
+		{"quicken.qif", QIFOptions{DefaultCurrency: "USD"}},
+		{"cash-dmy.qif", QIFOptions{DateFormat: "DD.MM.YYYY", DefaultCurrency: "EUR", DefaultAccount: "Wallet"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			rows, err := ParseQIF(f, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkGolden(t, tt.file, rows)
+		})
+	}
+}
+
+func TestParseOFXRejectsOtherFiles(t *testing.T) {
+	if _, err := ParseOFX(strings.NewReader("Date,Amount\n2025-01-01,1.00\n")); err == nil {
+		t.Fatal("expected an error for a non-OFX file")
+	}
+
+	empty := "OFXHEADER:100\nDATA:OFXSGML\n\n<OFX>\n<BANKMSGSRSV1>\n<STMTTRNRS>\n<STMTRS>\n<CURDEF>USD\n<BANKTRANLIST>\n</BANKTRANLIST>\n</STMTRS>\n</STMTTRNRS>\n</BANKMSGSRSV1>\n</OFX>\n"
+	if _, err := ParseOFX(strings.NewReader(empty)); !errors.Is(err, ErrEmptyFile) {
+		t.Fatalf("expected ErrEmptyFile, got %v", err)
+	}
+}
+
+func TestParseOFXEmptyTag(t *testing.T) {
+	for _, doc := range []string{"<OFX><>", "<OFX>\n< />", "<OFX>\n<STMTRS>\n</ >"} {
+		_, err := ParseOFX(strings.NewReader(doc))
+		if err == nil || !strings.HasSuffix(err.Error(), ": empty tag") || !strings.HasPrefix(err.Error(), "ofx: line ") {
+			t.Errorf("%q: error %v, want an empty tag error", doc, err)
+		}
+	}
+	if _, err := ParseOFX(strings.NewReader("<OFX>\n<STMTRS>\n< />")); err == nil || err.Error() != "ofx: line 3: empty tag" {
+		t.Errorf("error %v, want ofx: line 3: empty tag", err)
+	}
+}
+
+func TestParseBankStatementsGolden(t *testing.T) {
+	tests := []struct {
+		file  string
+		parse func(io.Reader) ([]Row, error)
+	}{
+		{"camt053.xml", ParseCAMT053},
+		{"mt940-de.sta", func(r io.Reader) ([]Row, error) { return ParseMT940(r, "") }},
+		{"mt940-nl.sta", func(r io.Reader) ([]Row, error) { return ParseMT940(r, "") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			rows, err := tt.parse(f)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkGolden(t, tt.file, rows)
+		})
+	}
+}
+
+func TestParseLedgerGolden(t *testing.T) {
+	tests := []struct {
+		file  string
+		parse func(io.Reader) ([]Row, error)
+	}{
+		{"personal.beancount", ParseBeancount},
+		{"household.journal", ParseHledger},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			rows, err := tt.parse(f)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkGolden(t, tt.file, rows)
+		})
+	}
+}
+
+func TestParseLedgerTransfers(t *testing.T) {
+	journal := `2025-02-05 * Savings
+    ; transfer_id: 9
+    ; transfer_direction: out
+    Equity:Transfer       500.00 EUR
+    Assets:Checking      -500.00 EUR
+
+2025-02-05 * Savings
+    ; transfer_id: 9
+    ; transfer_direction: in
+    Assets:Savings        500.00 EUR
+    Equity:Transfer      -500.00 EUR
+
+2025-02-06 * Half a transfer
+    ; transfer_id: 10
+    Equity:Transfer        20.00 EUR
+    Assets:Checking       -20.00 EUR
+
+2025-02-07 * Uneven
+    ; transfer_id: 11
+    Equity:Transfer        20.00 EUR
+    Assets:Checking       -20.00 EUR
+
+2025-02-07 * Uneven
+    ; transfer_id: 11
+    Assets:Savings         25.00 EUR
+    Equity:Transfer       -25.00 EUR
+`
+	rows, err := ParseHledger(strings.NewReader(journal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("got %d rows", len(rows))
+	}
+	for i, want := range []string{"out", "in"} {
+		r := rows[i]
+		if !r.Valid() || r.Transfer != "9" || r.TransferDirection != want || r.Transaction.Type != "transfer" || r.Transaction.Category != "Transfer" {
+			t.Errorf("leg %d: %+v", i, r)
+		}
+	}
+	if rows[2].Valid() || rows[2].Errors[0] != "transfer 10 needs one outgoing and one incoming leg" {
+		t.Errorf("unpaired leg: errors %q", rows[2].Errors)
+	}
+	for _, r := range rows[3:] {
+		if r.Valid() || r.Errors[0] != "the legs of transfer 11 have different amounts" {
+			t.Errorf("uneven leg: errors %q", r.Errors)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package importer parses bank exports into transaction parameters that can
+// be previewed and then inserted with CreateTransaction.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/numeric"
+)
+
+// DefaultCategory is assigned to imported rows that carry no category.
+const DefaultCategory = "Uncategorized"
+
+// Duplicate classifications reported on imported rows.
+const (
+	// DuplicateExact rows match an existing transaction's bank reference or
+	// fingerprint and are skipped.
+	DuplicateExact = "exact"
+	// DuplicatePossible rows resemble an existing transaction; they are
+	// imported and can be reviewed later.
+	DuplicatePossible = "possible"
+)
+
+// Row is a single parsed record together with any validation problems.
+// Rows with errors are reported in previews but never inserted.
+type Row struct {
+	Line              int                            `json:"line"`
+	Transaction       gensql.CreateTransactionParams `json:"transaction"`
+	Errors            []string                       `json:"errors,omitempty"`
+	Duplicate         string                         `json:"duplicate,omitempty" enum:"exact,possible" doc:"Set when the row duplicates an existing transaction"`
+	DuplicateOf       *int64                         `json:"duplicate_of,omitempty" doc:"ID of the transaction this row duplicates"`
+	SuggestedCategory string                         `json:"suggested_category,omitempty" doc:"Category suggested from earlier transactions; filled in when confident enough"`
+	Confidence        float64                        `json:"confidence,omitempty" doc:"Estimated probability that the suggested category is right"`
+	Transfer          string                         `json:"transfer,omitempty" doc:"Key shared by the two legs of a transfer in the file; they are imported as one transfer"`
+	TransferDirection string                         `json:"transfer_direction,omitempty" enum:"out,in"`
+	NeedsReview       bool                           `json:"needs_review,omitempty" doc:"Set when the suggestion was not confident enough to fill in or the merchant is new; such rows are imported with the needs-review tag"`
+
+	// invalid records fields that already have a parse error, so validate
+	// does not report them a second time as missing.
+	invalid map[string]bool
+}
+
+// Valid reports whether the row can be inserted.
+func (r Row) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+func (r *Row) addError(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+// fieldError records a parse error for a specific field.
+func (r *Row) fieldError(field string, err error) {
+	if r.invalid == nil {
+		r.invalid = map[string]bool{}
+	}
+	r.invalid[field] = true
+	r.addError("%v", err)
+}
+
+// validate checks the fields the transactions table requires.
+func (r *Row) validate() {
+	t := &r.Transaction
+	if !t.Date.Valid && !r.invalid["date"] {
+		r.addError("missing date")
+	}
+	if !t.Amount.Valid && !r.invalid["amount"] {
+		r.addError("missing amount")
+	}
+	if strings.TrimSpace(t.Description) == "" && !r.invalid["description"] {
+		r.addError("missing description")
+	}
+	if t.Category == "" {
+		t.Category = DefaultCategory
+	}
+	if t.Type != "income" && t.Type != "expense" && (t.Type != "transfer" || r.Transfer == "") {
+		r.addError("invalid type %q", t.Type)
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+}
+
+// pairTransfers checks that each transfer in rows has an outgoing and an
+// incoming leg that can be saved together. Both legs of a transfer that
+// cannot are invalid.
+func pairTransfers(rows []Row) {
+	var keys []string
+	legs := map[string][]int{}
+	for i, r := range rows {
+		if r.Transfer == "" {
+			continue
+		}
+		if _, ok := legs[r.Transfer]; !ok {
+			keys = append(keys, r.Transfer)
+		}
+		legs[r.Transfer] = append(legs[r.Transfer], i)
+	}
+
+	for _, key := range keys {
+		idx := legs[key]
+		var problem string
+		switch {
+		case len(idx) != 2 || rows[idx[0]].TransferDirection == rows[idx[1]].TransferDirection:
+			problem = fmt.Sprintf("transfer %s needs one outgoing and one incoming leg", key)
+		case !rows[idx[0]].Valid() || !rows[idx[1]].Valid():
+			for _, i := range idx {
+				if rows[i].Valid() {
+					rows[i].addError("the other leg of transfer %s is invalid", key)
+				}
+			}
+			continue
+		default:
+			a, b := rows[idx[0]].Transaction, rows[idx[1]].Transaction
+			if a.Currency != b.Currency {
+				continue
+			}
+			if a.Account == b.Account {
+				problem = fmt.Sprintf("the legs of transfer %s are in the same account", key)
+			} else if numeric.Rat(a.Amount).Cmp(numeric.Rat(b.Amount)) != 0 {
+				problem = fmt.Sprintf("the legs of transfer %s have different amounts", key)
+			}
+		}
+		if problem == "" {
+			continue
+		}
+		for _, i := range idx {
+			rows[i].addError("%s", problem)
+		}
+	}
+}
+
+// ErrEmptyFile is returned when an import file contains no records.
+var ErrEmptyFile = errors.New("file contains no records")
@@ -0,0 +1,686 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Plain-text journals store a transaction as balanced postings. A transaction
+// maps to a posting to its account under Assets and one to its category under
+// Expenses or Income. Fields without a posting equivalent are kept as
+// transaction metadata under these keys.
+const (
+	LedgerMetaDescription = "description"
+	LedgerMetaNotes       = "notes"
+	LedgerMetaStatus      = "status"
+	LedgerMetaTags        = "tags"
+	LedgerMetaExternalID  = "external_id"
+	// LedgerMetaTransferID and LedgerMetaTransferDirection mark the legs of
+	// a transfer, balanced against an Equity account, so that importing the
+	// journal pairs them again.
+	LedgerMetaTransferID        = "transfer_id"
+	LedgerMetaTransferDirection = "transfer_direction"
+	// LedgerMetaName on an open or account directive holds the account or
+	// category name the journal account was derived from.
+	LedgerMetaName = "name"
+)
+
+// ledgerUnassigned names the journal account of an empty account or category.
+const ledgerUnassigned = "Unassigned"
+
+// LedgerAccount returns the journal account for an account or category name
+// under root, e.g. "Expenses:Food-Drink" for "food & drink". Components are
+// split at colons and reduced to letters, digits and dashes with a capital
+// first letter, which both Beancount and hledger accept.
+func LedgerAccount(root, name string) string {
+	var parts []string
+	for _, part := range strings.Split(name, ":") {
+		if c := ledgerComponent(part); c != "" {
+			parts = append(parts, c)
+		}
+	}
+	if len(parts) == 0 {
+		parts = []string{ledgerUnassigned}
+	}
+	return root + ":" + strings.Join(parts, ":")
+}
+
+func ledgerComponent(s string) string {
+	var b strings.Builder
+	gap := false
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			gap = true
+			continue
+		}
+		if gap && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		gap = false
+		b.WriteRune(r)
+	}
+
+	c := b.String()
+	first, size := utf8.DecodeRuneInString(c)
+	if c == "" || unicode.IsUpper(first) || unicode.IsDigit(first) {
+		return c
+	}
+	if upper := unicode.ToUpper(first); upper != first {
+		return string(upper) + c[size:]
+	}
+	return "X" + c
+}
+
+// LedgerTag reports whether tag can be written as a native journal tag.
+// Other tags are kept in the tags metadata instead.
+func LedgerTag(tag string) bool {
+	return ledgerTagPattern.MatchString(tag)
+}
+
+var ledgerTagPattern = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+
+type ledgerPosting struct {
+	account string
+	amount  string
+}
+
+// ledgerTxn holds the raw parts of a journal transaction.
+type ledgerTxn struct {
+	line        int
+	date        string
+	flag        string
+	payee       string
+	description string
+	tags        []string
+	meta        map[string][]string
+	postings    []ledgerPosting
+}
+
+func (t *ledgerTxn) addMeta(key, value string) {
+	if t.meta == nil {
+		t.meta = map[string][]string{}
+	}
+	t.meta[key] = append(t.meta[key], value)
+}
+
+func (t *ledgerTxn) addTag(tag string) {
+	if tag != "" && !slices.Contains(t.tags, tag) {
+		t.tags = append(t.tags, tag)
+	}
+}
+
+type ledgerLine struct {
+	no   int
+	text string
+}
+
+// readLedgerLines returns the lines of a journal. With joinStrings, a line
+// ending inside a quoted string is joined with the following lines, since
+// Beancount strings may span lines.
+func readLedgerLines(r io.Reader, joinStrings bool) ([]ledgerLine, error) {
+	decoded, err := decodeReader(r, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []ledgerLine
+	scanner := bufio.NewScanner(decoded)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	open := false
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if open {
+			last := &lines[len(lines)-1]
+			last.text += "\n" + text
+			open = inQuotedString(last.text)
+			continue
+		}
+		lines = append(lines, ledgerLine{no: lineNo, text: text})
+		open = joinStrings && inQuotedString(text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// inQuotedString reports whether s ends inside a double-quoted string.
+func inQuotedString(s string) bool {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == ';':
+			return false
+		}
+	}
+	return quoted
+}
+
+// readQuoted reads the string starting at the quote s[i] and returns it
+// unescaped with the index after the closing quote.
+func readQuoted(s string, i int) (string, int) {
+	var b strings.Builder
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\') {
+				i++
+			}
+			b.WriteByte(s[i])
+		case '"':
+			return b.String(), i + 1
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), i
+}
+
+var (
+	beancountDated   = regexp.MustCompile(`(?s)^(\d{4}-\d{2}-\d{2})\s+(\S+)(.*)$`)
+	beancountMeta    = regexp.MustCompile(`(?s)^([a-z][A-Za-z0-9_-]*):\s*(.*)$`)
+	beancountPosting = regexp.MustCompile(`^(?:[*!&#?%PSTCURM]\s+)?([A-Z][^\s:]*(?::\S+)+)\s*(.*)$`)
+)
+
+// ParseBeancount reads a Beancount ledger and returns one Row per
+// transaction. Account and category names are taken from the name metadata
+// of open directives, or else from the account path below its root, e.g.
+// "Checking" for Assets:Checking. See ledgerRow for how postings map to rows.
+func ParseBeancount(r io.Reader) ([]Row, error) {
+	lines, err := readLedgerLines(r, true)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	var (
+		txns   []*ledgerTxn
+		cur    *ledgerTxn
+		opened string
+		pushed []string
+	)
+	for _, l := range lines {
+		trimmed := strings.TrimSpace(l.text)
+		if trimmed == "" || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+
+		if l.text[0] == ' ' || l.text[0] == '\t' {
+			m := beancountMeta.FindStringSubmatch(trimmed)
+			switch {
+			case cur != nil && m != nil:
+				// Metadata after the first posting belongs to the posting.
+				if len(cur.postings) == 0 {
+					cur.addMeta(m[1], beancountValue(m[2]))
+				}
+			case cur != nil:
+				if p := beancountPosting.FindStringSubmatch(trimmed); p != nil {
+					cur.postings = append(cur.postings, ledgerPosting{account: p[1], amount: ledgerAmountText(p[2])})
+				}
+			case opened != "" && m != nil && m[1] == LedgerMetaName:
+				names[opened] = beancountValue(m[2])
+			}
+			continue
+		}
+
+		cur, opened = nil, ""
+		keyword, rest, _ := strings.Cut(trimmed, " ")
+		switch keyword {
+		case "pushtag":
+			pushed = append(pushed, strings.TrimPrefix(strings.TrimSpace(rest), "#"))
+			continue
+		case "poptag":
+			tag := strings.TrimPrefix(strings.TrimSpace(rest), "#")
+			if i := slices.Index(pushed, tag); i >= 0 {
+				pushed = slices.Delete(pushed, i, i+1)
+			}
+			continue
+		}
+
+		m := beancountDated.FindStringSubmatch(l.text)
+		if m == nil {
+			continue
+		}
+		switch directive := m[2]; {
+		case directive == "open":
+			if fields := strings.Fields(m[3]); len(fields) > 0 {
+				opened = fields[0]
+			}
+		case directive == "txn" || len(directive) == 1 && strings.Contains("*!&#?%PSTCURM", directive):
+			cur = &ledgerTxn{line: l.no, date: m[1], flag: directive}
+			parseBeancountHeader(cur, m[3])
+			for _, tag := range pushed {
+				cur.addTag(tag)
+			}
+			txns = append(txns, cur)
+		}
+	}
+
+	if len(txns) == 0 {
+		return nil, ErrEmptyFile
+	}
+	rows := make([]Row, 0, len(txns))
+	for _, t := range txns {
+		rows = append(rows, ledgerRow(t, names, "."))
+	}
+	pairTransfers(rows)
+	return rows, nil
+}
+
+// parseBeancountHeader reads the payee, narration, tags and links following
+// the flag of a transaction.
+func parseBeancountHeader(t *ledgerTxn, s string) {
+	var strs []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			var str string
+			str, i = readQuoted(s, i)
+			strs = append(strs, str)
+		case c == '#' || c == '^':
+			end := strings.IndexAny(s[i:], " \t")
+			if end < 0 {
+				end = len(s) - i
+			}
+			if c == '#' {
+				t.addTag(s[i+1 : i+end])
+			}
+			i += end
+		default:
+			// A comment or anything unexpected ends the header.
+			i = len(s)
+		}
+	}
+
+	switch len(strs) {
+	case 0:
+	case 1:
+		t.description = strs[0]
+	default:
+		t.payee, t.description = strs[0], strs[1]
+	}
+}
+
+// beancountValue returns a metadata value, unquoting strings.
+func beancountValue(v string) string {
+	v = strings.TrimSpace(v)
+	if strings.HasPrefix(v, `"`) {
+		s, _ := readQuoted(v, 0)
+		return s
+	}
+	if i := strings.Index(v, ";"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	return v
+}
+
+// ledgerAmountText strips the comment, cost, price and balance assertion from
+// the amount part of a posting.
+func ledgerAmountText(s string) string {
+	if i := strings.IndexAny(s, ";{@="); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+var (
+	hledgerDated   = regexp.MustCompile(`^(\d{4}[-/.]\d{1,2}[-/.]\d{1,2})(?:=\S+)?\s*([*!])?\s*(?:\(([^)]*)\))?\s*(.*)$`)
+	hledgerPosting = regexp.MustCompile(`^(?:[*!]\s*)?(\S(?:[^\t]*?\S)?)(?:(?:\t|  )\s*(.*))?$`)
+	hledgerTag     = regexp.MustCompile(`(?:^|[\s,])([^\s:,]+):`)
+)
+
+// ParseHledger reads an hledger (or Ledger) journal and returns one Row per
+// transaction. Account and category names are taken from the name tag of
+// account directives, or else from the account path below its root.
+// Transaction tags become tags; the description, notes, status, tags and
+// external_id tags hold the fields written by exports and run to the end of
+// their line. Periodic and automated transactions are skipped.
+func ParseHledger(r io.Reader) ([]Row, error) {
+	lines, err := readLedgerLines(r, false)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	decimalMark := "."
+	var (
+		txns      []*ledgerTxn
+		cur       *ledgerTxn
+		declared  string
+		inComment bool
+	)
+	for _, l := range lines {
+		trimmed := strings.TrimSpace(l.text)
+		if inComment {
+			inComment = trimmed != "end comment"
+			continue
+		}
+		if trimmed == "" {
+			cur, declared = nil, ""
+			continue
+		}
+
+		if l.text[0] == ' ' || l.text[0] == '\t' {
+			comment, isComment := strings.CutPrefix(trimmed, ";")
+			switch {
+			case cur != nil && isComment:
+				// Comments after the first posting belong to the posting.
+				if len(cur.postings) == 0 {
+					parseHledgerComment(cur, comment)
+				}
+			case cur != nil:
+				if p := hledgerPosting.FindStringSubmatch(trimmed); p != nil && !strings.ContainsAny(p[1][:1], "([") {
+					cur.postings = append(cur.postings, ledgerPosting{account: p[1], amount: ledgerAmountText(p[2])})
+				}
+			case declared != "" && isComment:
+				if name, ok := hledgerName(comment); ok {
+					names[declared] = name
+				}
+			}
+			continue
+		}
+
+		cur, declared = nil, ""
+		switch {
+		case trimmed == "comment":
+			inComment = true
+		case strings.HasPrefix(trimmed, "account "):
+			account, comment, _ := strings.Cut(strings.TrimPrefix(trimmed, "account "), ";")
+			declared = strings.TrimSpace(account)
+			if name, ok := hledgerName(comment); ok {
+				names[declared] = name
+			}
+		case strings.HasPrefix(trimmed, "decimal-mark "):
+			decimalMark = strings.TrimSpace(strings.TrimPrefix(trimmed, "decimal-mark "))
+		default:
+			m := hledgerDated.FindStringSubmatch(trimmed)
+			if m == nil {
+				continue
+			}
+			cur = &ledgerTxn{line: l.no, date: m[1], flag: m[2]}
+			description, comment, hasComment := strings.Cut(m[4], ";")
+			cur.description = strings.TrimSpace(description)
+			if hasComment {
+				parseHledgerComment(cur, comment)
+			}
+			txns = append(txns, cur)
+		}
+	}
+
+	if len(txns) == 0 {
+		return nil, ErrEmptyFile
+	}
+	rows := make([]Row, 0, len(txns))
+	for _, t := range txns {
+		rows = append(rows, ledgerRow(t, names, decimalMark))
+	}
+	pairTransfers(rows)
+	return rows, nil
+}
+
+// parseHledgerComment reads the tags in a transaction comment. A comment
+// starting with one of the metadata keys is taken whole as its value.
+func parseHledgerComment(t *ledgerTxn, comment string) {
+	comment = strings.TrimLeft(comment, " \t")
+	if key, value, ok := strings.Cut(comment, ":"); ok {
+		switch key {
+		case LedgerMetaDescription, LedgerMetaNotes, LedgerMetaStatus, LedgerMetaTags, LedgerMetaExternalID,
+			LedgerMetaTransferID, LedgerMetaTransferDirection:
+			t.addMeta(key, strings.TrimPrefix(value, " "))
+			return
+		}
+	}
+	for _, m := range hledgerTag.FindAllStringSubmatch(comment, -1) {
+		t.addTag(m[1])
+	}
+}
+
+// hledgerName returns the value of a name tag in an account directive comment.
+func hledgerName(comment string) (string, bool) {
+	value, ok := strings.CutPrefix(strings.TrimSpace(comment), LedgerMetaName+":")
+	return strings.TrimPrefix(value, " "), ok
+}
+
+// ledgerAmount is a posting amount.
+type ledgerAmount struct {
+	value    *big.Rat
+	scale    int
+	currency string
+}
+
+// ledgerCommodities maps currency symbols to ISO codes.
+var ledgerCommodities = map[string]string{"$": "USD", "€": "EUR", "£": "GBP", "¥": "JPY"}
+
+// parseLedgerAmount parses amounts such as "-12.50 EUR", "EUR 12.50",
+// "$-12.50" or "12.50 \"Gift card\"".
+func parseLedgerAmount(s, decimalMark string) (ledgerAmount, error) {
+	var number, commodity strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"':
+			str, next := readQuoted(s, i)
+			commodity.WriteString(str)
+			i = next
+			continue
+		case unicode.IsDigit(r) || r == '.' || r == ',' || r == '-' || r == '+':
+			number.WriteRune(r)
+		case unicode.IsSpace(r):
+		default:
+			commodity.WriteRune(r)
+		}
+		i += size
+	}
+
+	thousands := ","
+	if decimalMark == "," {
+		thousands = "."
+	}
+	n, negative, err := parseAmount(number.String(), decimalMark, thousands)
+	if err != nil {
+		return ledgerAmount{}, fmt.Errorf("invalid amount %q", s)
+	}
+
+	a := ledgerAmount{value: new(big.Rat).SetInt(n.Int), currency: commodity.String()}
+	if code, ok := ledgerCommodities[a.currency]; ok {
+		a.currency = code
+	}
+	if n.Exp < 0 {
+		a.scale = int(-n.Exp)
+		a.value.Quo(a.value, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(a.scale)), nil)))
+	} else if n.Exp > 0 {
+		a.value.Mul(a.value, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n.Exp)), nil)))
+	}
+	if negative {
+		a.value.Neg(a.value)
+	}
+	return a, nil
+}
+
+// ledgerRow converts a journal transaction. Postings to Assets or Liabilities
+// accounts give the account and the amount: a net outflow is an expense and a
+// net inflow income. The category is the largest other posting; the names of
+// further categories are added as tags. A transaction between two asset
+// accounts is categorised as Transfer. One posting may omit its amount.
+func ledgerRow(t *ledgerTxn, names map[string]string, decimalMark string) Row {
+	row := Row{Line: t.line}
+	tr := &row.Transaction
+
+	date, err := time.ParseInLocation("2006-1-2", strings.NewReplacer("/", "-", ".", "-").Replace(t.date), time.UTC)
+	if err != nil {
+		row.fieldError("date", fmt.Errorf("invalid date %q", t.date))
+	} else {
+		tr.Date = pgtype.Timestamptz{Time: date, Valid: true}
+	}
+
+	tr.Description = t.description
+	notes := t.meta[LedgerMetaNotes]
+	if t.payee != "" {
+		tr.Description = t.payee
+		if t.description != "" {
+			notes = append([]string{t.description}, notes...)
+		}
+	}
+	if v := t.meta[LedgerMetaDescription]; len(v) > 0 {
+		tr.Description = v[0]
+	}
+	if len(notes) > 0 {
+		tr.Notes = optionalString(strings.Join(notes, "\n"))
+	}
+	if v := t.meta[LedgerMetaExternalID]; len(v) > 0 {
+		tr.ExternalID = optionalString(v[0])
+	}
+
+	tr.Status = "pending"
+	if t.flag == "*" || t.flag == "txn" {
+		tr.Status = "cleared"
+	}
+	if v := t.meta[LedgerMetaStatus]; len(v) > 0 && v[0] != "" {
+		tr.Status = v[0]
+	}
+
+	tr.Tags = slices.Clone(t.tags)
+	if v := t.meta[LedgerMetaTags]; len(v) > 0 {
+		if err := json.Unmarshal([]byte(v[0]), &tr.Tags); err != nil {
+			row.addError("invalid tags metadata %q", v[0])
+		}
+	}
+
+	tr.Type = "income"
+	ledgerPostings(&row, t.postings, names, decimalMark)
+	if v := t.meta[LedgerMetaTransferID]; len(v) > 0 && v[0] != "" {
+		ledgerTransferLeg(&row, v[0], t.meta[LedgerMetaTransferDirection])
+	}
+	row.validate()
+	return row
+}
+
+// ledgerTransferLeg marks row as a leg of the transfer with the given key.
+// Money leaving the account makes it the outgoing leg.
+func ledgerTransferLeg(row *Row, key string, direction []string) {
+	tr := &row.Transaction
+	row.Transfer = key
+	row.TransferDirection = "in"
+	if tr.Type == "expense" {
+		row.TransferDirection = "out"
+	}
+	if len(direction) > 0 && direction[0] != row.TransferDirection {
+		row.addError("transfer direction %q does not match the postings", direction[0])
+	}
+	if tr.Amount.Valid && tr.Amount.Int.Sign() == 0 {
+		row.addError("transfer amount must be greater than zero")
+	}
+	tr.Type = "transfer"
+}
+
+// ledgerPostings sets the amount, currency, account and category of row.
+func ledgerPostings(row *Row, postings []ledgerPosting, names map[string]string, decimalMark string) {
+	tr := &row.Transaction
+	amounts := make([]ledgerAmount, len(postings))
+	elided := -1
+	sum, scale := new(big.Rat), 0
+	var currencies []string
+	for i, p := range postings {
+		if p.amount == "" {
+			if elided >= 0 {
+				row.fieldError("amount", fmt.Errorf("more than one posting without an amount"))
+				return
+			}
+			elided = i
+			continue
+		}
+		a, err := parseLedgerAmount(p.amount, decimalMark)
+		if err != nil {
+			row.fieldError("amount", err)
+			return
+		}
+		amounts[i] = a
+		sum.Add(sum, a.value)
+		scale = max(scale, a.scale)
+		if !slices.Contains(currencies, a.currency) {
+			currencies = append(currencies, a.currency)
+		}
+	}
+	if len(currencies) > 1 {
+		row.fieldError("amount", fmt.Errorf("postings in several commodities (%s) are not supported", strings.Join(currencies, ", ")))
+		return
+	}
+	if len(currencies) == 1 {
+		tr.Currency = currencies[0]
+	}
+	if elided >= 0 {
+		amounts[elided] = ledgerAmount{value: new(big.Rat).Neg(sum), scale: scale}
+	}
+
+	var balance, other []int
+	for i, p := range postings {
+		root, _, _ := strings.Cut(p.account, ":")
+		switch strings.ToLower(root) {
+		case "assets", "liabilities":
+			balance = append(balance, i)
+		default:
+			other = append(other, i)
+		}
+	}
+	if len(balance) == 0 {
+		row.fieldError("amount", fmt.Errorf("no Assets or Liabilities posting"))
+		return
+	}
+
+	total := new(big.Rat)
+	for _, i := range balance {
+		total.Add(total, amounts[i].value)
+	}
+	tr.Account = ledgerName(postings[balance[0]].account, names)
+	if len(other) == 0 {
+		total = amounts[balance[0]].value
+		tr.Category = "Transfer"
+	} else {
+		largest := other[0]
+		for _, i := range other[1:] {
+			if new(big.Rat).Abs(amounts[i].value).Cmp(new(big.Rat).Abs(amounts[largest].value)) > 0 {
+				largest = i
+			}
+		}
+		tr.Category = ledgerName(postings[largest].account, names)
+		for _, i := range other {
+			if name := ledgerName(postings[i].account, names); i != largest && name != "" && !slices.Contains(tr.Tags, name) {
+				tr.Tags = append(tr.Tags, name)
+			}
+		}
+	}
+
+	if total.Sign() < 0 {
+		tr.Type = "expense"
+	}
+	if err := tr.Amount.Scan(new(big.Rat).Abs(total).FloatString(scale)); err != nil {
+		row.fieldError("amount", err)
+	}
+}
+
+// ledgerName returns the name an account was declared with, or else its
+// path below the root.
+func ledgerName(account string, names map[string]string) string {
+	if name, ok := names[account]; ok {
+		return name
+	}
+	_, name, _ := strings.Cut(account, ":")
+	return name
+}
@@ -0,0 +1,323 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// mt940Field is a tag such as :61: together with its (possibly multi-line) value.
+type mt940Field struct {
+	tag   string
+	value string
+	line  int
+}
+
+var (
+	mt940FieldStart = regexp.MustCompile(`^:(\d{2}[A-Z]?):(.*)$`)
+
+	// :61: value date, optional entry date, debit/credit mark, optional funds
+	// code, amount, transaction type, customer reference, optional bank
+	// reference and optional supplementary details on the next line.
+	mt940Statement = regexp.MustCompile(`(?s)^(\d{6})(\d{4})?(R?[DC])([A-Z])?(\d+,\d*)([NSF][A-Z0-9]{3})([^\n]*?)(?://([^\n]*))?(?:\n(.*))?$`)
+
+	// German banks structure :86: as ?NN subfields after a three digit code.
+	mt940GermanInfo = regexp.MustCompile(`^\d{3}\?`)
+	mt940Subfield   = regexp.MustCompile(`\?(\d{2})`)
+
+	// Dutch and other SEPA banks use /TAG/value/ pairs.
+	mt940SlashTag = regexp.MustCompile(`/(TRTP|IBAN|BIC|NAME|REMI|EREF|MARF|CSID|RTRN|CNTP|PURP|ULTC|ULTD|ORDP|BENM|ADDR)/`)
+)
+
+// mt940Info is the counterparty and remittance information of a :86: field.
+type mt940Info struct {
+	name        string
+	remittance  string
+	bookingText string
+	endToEndID  string
+}
+
+// ParseMT940 reads a SWIFT MT940 customer statement and returns one Row per
+// :61: statement line. The following :86: field supplies the counterparty
+// and remittance information, in the German ?NN, the SEPA /TAG/ or plain
+// text layout. The bank reference is kept as the external ID.
+func ParseMT940(r io.Reader, encoding string) ([]Row, error) {
+	decoded, err := decodeReader(r, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := mt940Fields(decoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("not an MT940 file: no :20: or :61: fields found")
+	}
+
+	var (
+		rows                []Row
+		account, currency   string
+		reference, sequence string
+		entries             int
+	)
+	for i, f := range fields {
+		switch f.tag {
+		case "20":
+			reference, sequence, entries = f.value, "", 0
+		case "25":
+			account = f.value
+		case "28C", "28":
+			sequence = f.value
+		case "60F", "60M":
+			// D/C mark, YYMMDD, then the currency code.
+			if len(f.value) >= 10 {
+				currency = f.value[7:10]
+			}
+		case "61":
+			var info *mt940Info
+			if i+1 < len(fields) && fields[i+1].tag == "86" {
+				info = parseMT940Info(fields[i+1].value)
+			}
+			entries++
+			fallbackID := fmt.Sprintf("%s/%d", reference, entries)
+			if sequence != "" {
+				fallbackID = fmt.Sprintf("%s/%s/%d", reference, sequence, entries)
+			}
+			rows = append(rows, mt940Row(f, info, account, currency, fallbackID))
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrEmptyFile
+	}
+	return rows, nil
+}
+
+// mt940Fields splits the message into fields, dropping SWIFT block headers
+// and the "-" message terminators.
+func mt940Fields(r io.Reader) ([]mt940Field, error) {
+	var fields []mt940Field
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+		if i := strings.Index(line, "{4:"); i >= 0 {
+			line = line[i+3:]
+		} else if strings.HasPrefix(line, "{") {
+			continue
+		}
+		if line == "" || line == "-" || line == "-}" {
+			continue
+		}
+
+		if m := mt940FieldStart.FindStringSubmatch(line); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], value: m[2], line: lineNo})
+			continue
+		}
+		if len(fields) == 0 {
+			// Preamble lines some banks write before the first field.
+			continue
+		}
+		fields[len(fields)-1].value += "\n" + line
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func mt940Row(f mt940Field, info *mt940Info, account, currency, fallbackID string) Row {
+	row := Row{Line: f.line}
+	t := &row.Transaction
+	t.Account = account
+	t.Currency = currency
+	t.Status = "cleared"
+
+	m := mt940Statement.FindStringSubmatch(f.value)
+	if m == nil {
+		row.fieldError("date", fmt.Errorf("invalid :61: statement line %q", f.value))
+		row.invalid["amount"] = true
+		row.invalid["description"] = true
+		t.Type = "expense"
+		row.validate()
+		return row
+	}
+
+	value, err := parseDate(m[1], "060102")
+	if err != nil {
+		row.fieldError("date", err)
+	}
+	t.Date = value
+	if m[2] != "" && value.Valid {
+		booking, err := mt940EntryDate(value.Time, m[2])
+		if err != nil {
+			row.fieldError("date", err)
+		} else {
+			t.Date = booking
+		}
+	}
+
+	// RD and RC reverse a previous debit or credit.
+	switch m[3] {
+	case "C", "RD":
+		t.Type = "income"
+	default:
+		t.Type = "expense"
+	}
+
+	amount, _, err := parseAmount(m[5], ",", "")
+	if err != nil {
+		row.fieldError("amount", err)
+	} else {
+		t.Amount = amount
+	}
+
+	customerRef, bankRef, details := strings.TrimSpace(m[7]), strings.TrimSpace(m[8]), strings.TrimSpace(m[9])
+	if info == nil {
+		info = &mt940Info{}
+	}
+
+	var notes []string
+	switch {
+	case info.name != "":
+		t.Description = info.name
+		notes = append(notes, info.remittance)
+	case info.remittance != "":
+		t.Description = info.remittance
+	case info.bookingText != "":
+		t.Description = info.bookingText
+	default:
+		t.Description = details
+		details = ""
+	}
+	if details != "" && details != t.Description {
+		notes = append(notes, details)
+	}
+	if value.Valid && t.Date.Valid && !value.Time.Equal(t.Date.Time) {
+		notes = append(notes, "Value date: "+value.Time.Format("2006-01-02"))
+	}
+	if s := strings.Join(trimAll(notes), "\n"); s != "" {
+		t.Notes = &s
+	}
+
+	t.ExternalID = firstReference(bankRef, customerRef, info.endToEndID)
+	if t.ExternalID == nil {
+		t.ExternalID = &fallbackID
+	}
+
+	row.validate()
+	return row
+}
+
+// mt940EntryDate combines the MMDD entry date with the year of the value
+// date, allowing for entries booked across a year boundary.
+func mt940EntryDate(value time.Time, mmdd string) (pgtype.Timestamptz, error) {
+	month, _ := strconv.Atoi(mmdd[:2])
+	day, _ := strconv.Atoi(mmdd[2:])
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return pgtype.Timestamptz{}, fmt.Errorf("invalid entry date %q", mmdd)
+	}
+
+	year := value.Year()
+	switch {
+	case value.Month() == time.December && month == 1:
+		year++
+	case value.Month() == time.January && month == 12:
+		year--
+	}
+	return pgtype.Timestamptz{Time: time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), Valid: true}, nil
+}
+
+// parseMT940Info extracts counterparty and remittance details from a :86: field.
+func parseMT940Info(v string) *mt940Info {
+	switch {
+	case mt940GermanInfo.MatchString(v):
+		return parseGermanInfo(v)
+	case mt940SlashTag.MatchString(v):
+		return parseSlashInfo(v)
+	}
+	return &mt940Info{remittance: strings.Join(strings.Fields(v), " ")}
+}
+
+// parseGermanInfo reads the ?NN subfields used by German banks: ?00 booking
+// text, ?20-?29 and ?60-?63 remittance, ?32-?33 counterparty name.
+func parseGermanInfo(v string) *mt940Info {
+	v = strings.ReplaceAll(v, "\n", "")
+	info := &mt940Info{}
+
+	var remittance, name []string
+	locs := mt940Subfield.FindAllStringSubmatchIndex(v, -1)
+	for i, loc := range locs {
+		end := len(v)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		code, _ := strconv.Atoi(v[loc[2]:loc[3]])
+		// Subfields have a fixed width and may split words, so they are
+		// joined without a separator.
+		text := v[loc[1]:end]
+
+		switch {
+		case code == 0:
+			info.bookingText = strings.TrimSpace(text)
+		case code >= 20 && code <= 29, code >= 60 && code <= 63:
+			// SEPA keywords such as EREF+ split the remittance into parts.
+			if ref, ok := strings.CutPrefix(strings.TrimSpace(text), "EREF+"); ok {
+				info.endToEndID = ref
+				continue
+			}
+			remittance = append(remittance, strings.TrimPrefix(text, "SVWZ+"))
+		case code == 32, code == 33:
+			name = append(name, text)
+		}
+	}
+
+	info.name = strings.Join(strings.Fields(strings.Join(name, "")), " ")
+	info.remittance = strings.Join(strings.Fields(strings.Join(remittance, "")), " ")
+	return info
+}
+
+// parseSlashInfo reads /TAG/value/ pairs such as /NAME/ and /REMI/.
+func parseSlashInfo(v string) *mt940Info {
+	v = strings.ReplaceAll(v, "\n", "")
+	info := &mt940Info{}
+
+	locs := mt940SlashTag.FindAllStringSubmatchIndex(v, -1)
+	for i, loc := range locs {
+		end := len(v)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		tag := v[loc[2]:loc[3]]
+		value := strings.Trim(v[loc[1]:end], "/ ")
+
+		switch tag {
+		case "NAME":
+			if info.name == "" {
+				info.name = value
+			}
+		case "CNTP":
+			// /CNTP/IBAN/BIC/Name/City/
+			if parts := strings.Split(value, "/"); len(parts) >= 3 && info.name == "" {
+				info.name = strings.TrimSpace(parts[2])
+			}
+		case "REMI":
+			// Unstructured remittance is often wrapped as /REMI/USTD//text/.
+			value = strings.TrimPrefix(value, "USTD//")
+			info.remittance = strings.TrimSpace(value)
+		case "EREF":
+			info.endToEndID = value
+		case "TRTP":
+			info.bookingText = value
+		}
+	}
+	return info
+}
@@ -0,0 +1,293 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// ofxNode is an element of an OFX document. Leaf elements carry a value;
+// aggregates carry children. OFX 1.x (SGML) leaves have no closing tags, so
+// both versions are parsed into the same tree.
+type ofxNode struct {
+	name     string
+	value    string
+	line     int
+	children []*ofxNode
+}
+
+func (n *ofxNode) child(name string) *ofxNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// text returns the value of the leaf found by following path from n.
+func (n *ofxNode) text(path ...string) string {
+	node := n
+	for _, name := range path {
+		if node = node.child(name); node == nil {
+			return ""
+		}
+	}
+	return node.value
+}
+
+// findAll returns every descendant of n called name.
+func (n *ofxNode) findAll(name string) []*ofxNode {
+	var out []*ofxNode
+	for _, c := range n.children {
+		if c.name == name {
+			out = append(out, c)
+			continue
+		}
+		out = append(out, c.findAll(name)...)
+	}
+	return out
+}
+
+// ParseOFX reads an OFX 1.x (SGML) or 2.x (XML) file, including Quicken's
+// QFX variant, and returns one Row per STMTTRN of every bank and credit card
+// statement it contains. The currency and account come from the statement.
+func ParseOFX(r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = decodeOFX(data)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parseOFXTree(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	statements := append(root.findAll("STMTRS"), root.findAll("CCSTMTRS")...)
+	for _, stmt := range statements {
+		currency := stmt.text("CURDEF")
+		account := stmt.text("BANKACCTFROM", "ACCTID")
+		if account == "" {
+			account = stmt.text("CCACCTFROM", "ACCTID")
+		}
+
+		list := stmt.child("BANKTRANLIST")
+		if list == nil {
+			continue
+		}
+		for _, trn := range list.findAll("STMTTRN") {
+			rows = append(rows, ofxRow(trn, currency, account))
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrEmptyFile
+	}
+	return rows, nil
+}
+
+func ofxRow(trn *ofxNode, currency, account string) Row {
+	row := Row{Line: trn.line}
+	t := &row.Transaction
+	t.Currency = currency
+	t.Account = account
+	t.ExternalID = optionalString(trn.text("FITID"))
+
+	t.Description = trn.text("NAME")
+	if t.Description == "" {
+		t.Description = trn.text("PAYEE", "NAME")
+	}
+	memo := trn.text("MEMO")
+	if t.Description == "" {
+		t.Description = memo
+	} else if memo != "" && memo != t.Description {
+		t.Notes = &memo
+	}
+
+	if v := trn.text("DTPOSTED"); v != "" {
+		date, err := parseOFXDate(v)
+		if err != nil {
+			row.fieldError("date", err)
+		} else {
+			t.Date = date
+		}
+	}
+
+	t.Type = "income"
+	if v := trn.text("TRNAMT"); v != "" {
+		amount, negative, err := parseAmount(v, ".", "")
+		if err != nil {
+			row.fieldError("amount", err)
+		} else {
+			t.Amount = amount
+			if negative {
+				t.Type = "expense"
+			}
+		}
+	}
+
+	row.validate()
+	return row
+}
+
+var ofxDatePattern = regexp.MustCompile(`^(\d{8})(\d{6})?(?:\.\d+)?(?:\[([+-]?\d+(?:\.\d+)?)(?::[A-Za-z]+)?\])?$`)
+
+// parseOFXDate parses OFX datetimes such as 20250115, 20250115120000.000 and
+// 20250115120000[-5:EST]. Times without an offset are taken as UTC.
+func parseOFXDate(v string) (pgtype.Timestamptz, error) {
+	m := ofxDatePattern.FindStringSubmatch(strings.TrimSpace(v))
+	if m == nil {
+		return pgtype.Timestamptz{}, fmt.Errorf("invalid date %q", v)
+	}
+
+	layout, value := "20060102", m[1]
+	if m[2] != "" {
+		layout, value = "20060102150405", m[1]+m[2]
+	}
+
+	loc := time.UTC
+	if m[3] != "" {
+		hours, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			return pgtype.Timestamptz{}, fmt.Errorf("invalid date %q", v)
+		}
+		loc = time.FixedZone("", int(hours*3600))
+	}
+
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return pgtype.Timestamptz{}, fmt.Errorf("invalid date %q", v)
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}, nil
+}
+
+var (
+	ofxSGMLCharset = regexp.MustCompile(`(?m)^\s*CHARSET:\s*(\S+)`)
+	ofxXMLEncoding = regexp.MustCompile(`<\?xml[^>]*encoding="([^"]+)"`)
+)
+
+// decodeOFX converts the document to UTF-8 based on its declared character set.
+func decodeOFX(data []byte) ([]byte, error) {
+	header := data
+	if i := bytes.Index(data, []byte("<")); i > 0 {
+		header = data[:i]
+	}
+
+	if m := ofxSGMLCharset.FindSubmatch(header); m != nil {
+		switch strings.ToUpper(string(m[1])) {
+		case "1252":
+			return charmap.Windows1252.NewDecoder().Bytes(data)
+		case "ISO-8859-1", "8859-1":
+			return charmap.ISO8859_1.NewDecoder().Bytes(data)
+		}
+		return data, nil
+	}
+
+	if m := ofxXMLEncoding.FindSubmatch(data); m != nil {
+		name := strings.ToLower(string(m[1]))
+		if name != "utf-8" && name != "us-ascii" {
+			enc, err := htmlindex.Get(name)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported encoding %q", name)
+			}
+			return enc.NewDecoder().Bytes(data)
+		}
+	}
+	return data, nil
+}
+
+// parseOFXTree builds the element tree starting at the <OFX> root. Leaf
+// elements are closed implicitly when their value is read; explicit closing
+// tags pop back to the matching aggregate.
+func parseOFXTree(doc string) (*ofxNode, error) {
+	start := strings.Index(strings.ToUpper(doc), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("not an OFX file: missing <OFX> element")
+	}
+
+	line := 1 + strings.Count(doc[:start], "\n")
+	doc = doc[start:]
+
+	root := &ofxNode{name: "#root"}
+	stack := []*ofxNode{root}
+	for len(doc) > 0 {
+		open := strings.IndexByte(doc, '<')
+		if open < 0 {
+			break
+		}
+
+		if text := strings.TrimSpace(doc[:open]); text != "" {
+			top := stack[len(stack)-1]
+			if top != root && top.value == "" && len(top.children) == 0 {
+				top.value = html.UnescapeString(text)
+				stack = stack[:len(stack)-1]
+			}
+		}
+		line += strings.Count(doc[:open], "\n")
+		doc = doc[open:]
+
+		if strings.HasPrefix(doc, "<!--") {
+			end := strings.Index(doc, "-->")
+			if end < 0 {
+				return nil, fmt.Errorf("ofx: line %d: unterminated comment", line)
+			}
+			line += strings.Count(doc[:end], "\n")
+			doc = doc[end+3:]
+			continue
+		}
+
+		end := strings.IndexByte(doc, '>')
+		if end < 0 {
+			return nil, fmt.Errorf("ofx: line %d: unterminated tag", line)
+		}
+		tag := strings.TrimSpace(doc[1:end])
+		doc = doc[end+1:]
+		if strings.Trim(tag, "/ \t\r\n") == "" {
+			return nil, fmt.Errorf("ofx: line %d: empty tag", line)
+		}
+
+		switch {
+		case strings.HasPrefix(tag, "?"), strings.HasPrefix(tag, "!"):
+			// Processing instructions and declarations.
+		case strings.HasPrefix(tag, "/"):
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		default:
+			selfClosing := strings.HasSuffix(tag, "/")
+			name := strings.ToUpper(strings.Fields(strings.TrimSuffix(tag, "/"))[0])
+			node := &ofxNode{name: name, line: line}
+			top := stack[len(stack)-1]
+			top.children = append(top.children, node)
+			if !selfClosing {
+				stack = append(stack, node)
+			}
+		}
+	}
+
+	ofx := root.child("OFX")
+	if ofx == nil {
+		return nil, errors.New("not an OFX file: missing <OFX> element")
+	}
+	return ofx, nil
+}
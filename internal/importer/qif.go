@@ -0,0 +1,306 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// QIFOptions describes how to read a QIF export.
+type QIFOptions struct {
+	DateFormat      string `json:"date_format,omitempty" doc:"Date format such as DD/MM/YYYY or a Go layout. Defaults to M/D/YYYY, also accepting two-digit years"`
+	Encoding        string `json:"encoding,omitempty" doc:"Text encoding, defaults to utf-8. Quicken usually writes windows-1252"`
+	DefaultCurrency string `json:"default_currency,omitempty" doc:"Currency for the imported transactions; QIF files carry none"`
+	DefaultAccount  string `json:"default_account,omitempty" doc:"Account for transactions outside an !Account block"`
+}
+
+// qifSections lists the transaction sections that are imported. Investment,
+// category and memorized-transaction lists are skipped.
+var qifSections = map[string]bool{
+	"bank":  true,
+	"ccard": true,
+	"cash":  true,
+}
+
+// qifSplit is one S/E/$ group of a split transaction.
+type qifSplit struct {
+	category string
+	memo     string
+	amount   string
+}
+
+// qifRecord holds the raw fields of a transaction up to its ^ terminator.
+type qifRecord struct {
+	line     int
+	fields   map[byte]string
+	splits   []qifSplit
+	hasField bool
+}
+
+// ParseQIF reads the !Type:Bank, !Type:CCard and !Type:Cash sections of a
+// QIF export and returns one Row per transaction. Account names are taken
+// from !Account blocks when present.
+//
+// Categories use QIF's "Category:Subcategory/Class" notation: the top-level
+// category becomes the transaction category and subcategories and the class
+// become tags. Split lines are listed in the notes and their categories are
+// added as tags; the category of a split transaction without an L line is
+// that of its largest split.
+func ParseQIF(r io.Reader, opts QIFOptions) ([]Row, error) {
+	decoded, err := decodeReader(r, opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	layouts := []string{"1/2/2006", "1/2/06"}
+	if opts.DateFormat != "" {
+		layouts = []string{DateLayout(opts.DateFormat)}
+	}
+
+	var (
+		rows    []Row
+		section string
+		account = opts.DefaultAccount
+		rec     = qifRecord{fields: map[byte]string{}}
+		// accountBlock is set while reading the fields of an !Account entry.
+		accountBlock bool
+		accountName  string
+	)
+
+	scanner := bufio.NewScanner(decoded)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if line[0] == '!' {
+			header := strings.ToLower(strings.TrimSpace(line[1:]))
+			accountBlock, accountName = header == "account", ""
+			switch {
+			case accountBlock:
+			case strings.HasPrefix(header, "type:"):
+				section = strings.TrimSpace(strings.TrimPrefix(header, "type:"))
+			case strings.HasPrefix(header, "option:"), strings.HasPrefix(header, "clear:"):
+				// Quicken switches that do not affect parsing.
+			default:
+				section = header
+			}
+			rec = qifRecord{fields: map[byte]string{}}
+			continue
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+
+		if accountBlock {
+			switch code {
+			case 'N':
+				accountName = value
+			case '^':
+				accountBlock = false
+				if accountName != "" {
+					account = accountName
+				}
+			}
+			continue
+		}
+
+		if !qifSections[section] {
+			continue
+		}
+
+		if code == '^' {
+			if rec.hasField {
+				rows = append(rows, qifRow(rec, layouts, account, opts.DefaultCurrency))
+			}
+			rec = qifRecord{fields: map[byte]string{}}
+			continue
+		}
+
+		if !rec.hasField {
+			rec.line = lineNo
+			rec.hasField = true
+		}
+
+		switch code {
+		case 'S':
+			rec.splits = append(rec.splits, qifSplit{category: value})
+		case 'E', '$':
+			if len(rec.splits) == 0 {
+				rec.splits = append(rec.splits, qifSplit{})
+			}
+			split := &rec.splits[len(rec.splits)-1]
+			if code == 'E' {
+				split.memo = value
+			} else {
+				split.amount = value
+			}
+		case 'A':
+			// Address lines; the payee is enough for a description.
+		default:
+			rec.fields[code] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// A final record without a ^ terminator is still a record.
+	if rec.hasField && qifSections[section] && !accountBlock {
+		rows = append(rows, qifRow(rec, layouts, account, opts.DefaultCurrency))
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrEmptyFile
+	}
+	return rows, nil
+}
+
+func qifRow(rec qifRecord, layouts []string, account, currency string) Row {
+	row := Row{Line: rec.line}
+	t := &row.Transaction
+	t.Account = account
+	t.Currency = currency
+	t.Status = qifStatus(rec.fields['C'])
+
+	t.Description = rec.fields['P']
+	memo := rec.fields['M']
+	if t.Description == "" {
+		t.Description = memo
+		memo = ""
+	}
+	if t.Description == "" && rec.fields['N'] != "" {
+		t.Description = "Check " + rec.fields['N']
+	}
+
+	if v := rec.fields['D']; v != "" {
+		date, err := parseQIFDate(v, layouts)
+		if err != nil {
+			row.fieldError("date", err)
+		} else {
+			t.Date = date
+		}
+	}
+
+	amount := rec.fields['T']
+	if amount == "" {
+		amount = rec.fields['U']
+	}
+	t.Type = "income"
+	if amount != "" {
+		n, negative, err := parseAmount(amount, ".", ",")
+		if err != nil {
+			row.fieldError("amount", err)
+		} else {
+			t.Amount = n
+			if negative {
+				t.Type = "expense"
+			}
+		}
+	}
+
+	category := rec.fields['L']
+	if category == "" {
+		category = largestSplit(rec.splits)
+	}
+	t.Category, t.Tags = qifCategory(category, nil)
+
+	var notes []string
+	if memo != "" {
+		notes = append(notes, memo)
+	}
+	for _, s := range rec.splits {
+		_, t.Tags = qifCategory(s.category, t.Tags)
+		notes = append(notes, formatQIFSplit(s))
+	}
+	if len(notes) > 0 {
+		t.Notes = optionalString(strings.Join(notes, "\n"))
+	}
+
+	row.validate()
+	return row
+}
+
+// parseQIFDate accepts Quicken's date quirks: an apostrophe before the year
+// ("1/15'25") and space-padded fields ("1/ 5/25").
+func parseQIFDate(v string, layouts []string) (pgtype.Timestamptz, error) {
+	normalized := strings.ReplaceAll(strings.ReplaceAll(v, "'", "/"), " ", "")
+	for _, layout := range layouts {
+		if date, err := parseDate(normalized, layout); err == nil {
+			return date, nil
+		}
+	}
+	return pgtype.Timestamptz{}, fmt.Errorf("invalid date %q", v)
+}
+
+// qifCategory splits "Category:Subcategory/Class" into the top-level category
+// and appends the subcategories and class to tags. Transfers, written as
+// [Account], are categorised as Transfer.
+func qifCategory(v string, tags []string) (string, []string) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", tags
+	}
+	if strings.HasPrefix(v, "[") {
+		return "Transfer", tags
+	}
+
+	path, class, _ := strings.Cut(v, "/")
+	parts := strings.Split(path, ":")
+	for _, tag := range append(parts[1:], class) {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return strings.TrimSpace(parts[0]), tags
+}
+
+// largestSplit returns the category of the split with the largest absolute amount.
+func largestSplit(splits []qifSplit) string {
+	var (
+		best     string
+		bestSize float64
+	)
+	for _, s := range splits {
+		n, _, err := parseAmount(s.amount, ".", ",")
+		if err != nil {
+			continue
+		}
+		f, err := n.Float64Value()
+		if err != nil || !f.Valid {
+			continue
+		}
+		if best == "" || f.Float64 > bestSize {
+			best, bestSize = s.category, f.Float64
+		}
+	}
+	return best
+}
+
+func formatQIFSplit(s qifSplit) string {
+	category := s.category
+	if category == "" {
+		category = DefaultCategory
+	}
+	line := category + ": " + s.amount
+	if s.memo != "" {
+		line += " (" + s.memo + ")"
+	}
+	return line
+}
+
+// qifStatus maps the cleared flag: * or c for cleared, X or R for reconciled.
+func qifStatus(flag string) string {
+	switch strings.ToLower(flag) {
+	case "*", "c":
+		return "cleared"
+	case "x", "r":
+		return "reconciled"
+	}
+	return ""
+}
@@ -0,0 +1,27 @@
+// Package numeric converts Postgres numeric values for exact arithmetic.
+// Amounts are never converted to float64.
+package numeric
+
+import (
+	"math/big"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Rat returns n as an exact fraction, or nil if it is NULL or not a finite
+// number.
+func Rat(n pgtype.Numeric) *big.Rat {
+	if !n.Valid || n.NaN || n.InfinityModifier != pgtype.Finite || n.Int == nil {
+		return nil
+	}
+	r := new(big.Rat).SetInt(n.Int)
+	exp := n.Exp
+	if exp < 0 {
+		exp = -exp
+	}
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+	if n.Exp < 0 {
+		return r.Quo(r, scale)
+	}
+	return r.Mul(r, scale)
+}
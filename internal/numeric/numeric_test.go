@@ -0,0 +1,38 @@
+package numeric
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   pgtype.Numeric
+		want *big.Rat
+	}{
+		{"decimals", pgtype.Numeric{Int: big.NewInt(1250), Exp: -2, Valid: true}, big.NewRat(25, 2)},
+		{"negative", pgtype.Numeric{Int: big.NewInt(-5), Exp: -1, Valid: true}, big.NewRat(-1, 2)},
+		{"integer", pgtype.Numeric{Int: big.NewInt(42), Valid: true}, big.NewRat(42, 1)},
+		{"positive exponent", pgtype.Numeric{Int: big.NewInt(3), Exp: 2, Valid: true}, big.NewRat(300, 1)},
+		{"null", pgtype.Numeric{}, nil},
+		{"nan", pgtype.Numeric{NaN: true, Valid: true}, nil},
+		{"infinity", pgtype.Numeric{InfinityModifier: pgtype.Infinity, Valid: true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Rat(tt.in)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("Rat() = %s, want nil", got.RatString())
+				}
+				return
+			}
+			if got == nil || got.Cmp(tt.want) != 0 {
+				t.Errorf("Rat() = %v, want %s", got, tt.want.RatString())
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Package payee recognizes the merchant or person a transaction is with from
+// its bank description. Descriptions carry a lot of noise around the name —
+// card processor prefixes, store numbers, dates and locations — so they are
+// normalized before being compared with the names and aliases of the user's
+// payees:
+//
+//	SQ *BLUE BOTTLE 0423 SAN FRAN    Blue Bottle
+//	CHECKCARD 0423 SHELL OIL 5744    Shell Oil
+//	STARBUCKS STORE 1234             Starbucks
+//
+// A payee may also have RE2 patterns, matched against the raw description,
+// for descriptions that normalize to something else entirely.
+package payee
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"budgetctl-go/internal/database/gensql"
+)
+
+// processorPrefix matches the prefixes card processors and banks put before
+// the merchant name. It is stripped repeatedly, as in "POS SQ *CAFE".
+var processorPrefix = regexp.MustCompile(`(?i)^(?:` +
+	`(?:sq|tst|sp|pp|paypal|in|apl|google|amzn mktp|amazon mktpl?)\s*\*` +
+	`|(?:pos|debit card|debit|checkcard|check card|visa|recurring|ach)(?: (?:debit|purchase|payment))?\b` +
+	`|purchase authorized on\b` +
+	`)[\s*]*`)
+
+// fillerWords are dropped from the end of a normalized name; they usually
+// precede a store number.
+var fillerWords = map[string]bool{
+	"store": true,
+	"str":   true,
+	"no":    true,
+	"inc":   true,
+	"llc":   true,
+}
+
+// Normalize returns the merchant name in a transaction description, or ""
+// if there is none.
+func Normalize(description string) string {
+	s := strings.TrimSpace(description)
+	for {
+		loc := processorPrefix.FindStringIndex(s)
+		if loc == nil || loc[1] == 0 {
+			break
+		}
+		s = s[loc[1]:]
+	}
+
+	var words []string
+	for _, token := range strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '*'
+	}) {
+		token = strings.Trim(token, ",;:#")
+		letters, digits := countRunes(token)
+		if len(words) == 0 {
+			// Dates and reference numbers before the name.
+			if letters == 0 || digits > letters {
+				continue
+			}
+		} else if digits > 0 {
+			// A store number or date; what follows is the location.
+			break
+		} else if letters == 0 && token != "&" {
+			continue
+		}
+		words = append(words, token)
+	}
+	for len(words) > 0 && fillerWords[strings.ToLower(words[len(words)-1])] {
+		words = words[:len(words)-1]
+	}
+	for i, w := range words {
+		words[i] = titleCase(w)
+	}
+	return strings.Join(words, " ")
+}
+
+func countRunes(s string) (letters, digits int) {
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case unicode.IsDigit(r):
+			digits++
+		}
+	}
+	return letters, digits
+}
+
+// titleCase capitalizes words written in upper case only, leaving names such
+// as "YouTube" alone.
+func titleCase(word string) string {
+	if strings.ToUpper(word) != word {
+		return word
+	}
+	runes := []rune(strings.ToLower(word))
+	for i, r := range runes {
+		if i == 0 || runes[i-1] == '-' {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+// Key returns the form names, aliases and descriptions are compared in.
+func Key(name string) string {
+	if n := Normalize(name); n != "" {
+		return strings.ToLower(n)
+	}
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// CompilePatterns validates the patterns of a payee.
+func CompilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		compiled[i] = re
+	}
+	return compiled, nil
+}
+
+// Matcher finds the payee of a description among a user's payees. It is not
+// safe for concurrent use.
+type Matcher struct {
+	patterns []pattern
+	names    map[string]gensql.Payee
+}
+
+type pattern struct {
+	re    *regexp.Regexp
+	payee gensql.Payee
+}
+
+// NewMatcher returns a matcher for the given payees. Patterns are tried in
+// the order of the payees, before any names.
+func NewMatcher(payees []gensql.Payee) *Matcher {
+	m := &Matcher{names: map[string]gensql.Payee{}}
+	for _, p := range payees {
+		m.Add(p)
+	}
+	return m
+}
+
+// Add makes a payee known to the matcher, e.g. one created for an earlier
+// description. Invalid patterns are ignored.
+func (m *Matcher) Add(p gensql.Payee) {
+	for _, s := range p.Patterns {
+		if re, err := regexp.Compile(s); err == nil {
+			m.patterns = append(m.patterns, pattern{re: re, payee: p})
+		}
+	}
+	for _, name := range append([]string{p.Name}, p.Aliases...) {
+		if key := Key(name); key != "" {
+			if _, ok := m.names[key]; !ok {
+				m.names[key] = p
+			}
+		}
+	}
+}
+
+// Match returns the payee of a description.
+func (m *Matcher) Match(description string) (gensql.Payee, bool) {
+	for _, p := range m.patterns {
+		if p.re.MatchString(description) {
+			return p.payee, true
+		}
+	}
+	if key := Key(description); key != "" {
+		p, ok := m.names[key]
+		return p, ok
+	}
+	return gensql.Payee{}, false
+}
@@ -0,0 +1,57 @@
+package payee
+
+import (
+	"testing"
+
+	"budgetctl-go/internal/database/gensql"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"SQ *BLUE BOTTLE 0423 SAN FRAN":             "Blue Bottle",
+		"TST* SHAKE SHACK 1234":                     "Shake Shack",
+		"CHECKCARD 0423 SHELL OIL 5744":             "Shell Oil",
+		"POS DEBIT SQ *CAFE NERO":                   "Cafe Nero",
+		"PURCHASE AUTHORIZED ON 04/23 TRADER JOE'S": "Trader Joe's",
+		"STARBUCKS STORE 1234":                      "Starbucks",
+		"AMAZON.COM*AB12CD34E":                      "Amazon.com",
+		"7-ELEVEN 12345":                            "7-Eleven",
+		"PAYPAL *GOOGLE YouTube":                    "Google YouTube",
+		"BARNES & NOBLE #2231":                      "Barnes & Noble",
+		"Rent":                                      "Rent",
+		"04/23 #1234":                               "",
+	}
+	for description, want := range tests {
+		if got := Normalize(description); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", description, got, want)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	m := NewMatcher([]gensql.Payee{
+		{ID: 1, Name: "Blue Bottle Coffee", Aliases: []string{"BLUE BOTTLE"}},
+		{ID: 2, Name: "Amazon", Patterns: []string{`(?i)^(amzn|amazon)`}},
+		{ID: 3, Name: "Broken", Patterns: []string{"("}},
+	})
+
+	tests := map[string]int64{
+		"SQ *BLUE BOTTLE 0423 SAN FRAN": 1,
+		"blue bottle coffee":            1,
+		"AMZN MKTP US*2K4":              2,
+		"AMAZON.COM*AB12CD34E":          2,
+		"Broken":                        3,
+		"SHELL OIL 5744":                0,
+	}
+	for description, want := range tests {
+		p, ok := m.Match(description)
+		if ok != (want != 0) || p.ID != want {
+			t.Errorf("Match(%q) = %d, %v; want %d", description, p.ID, ok, want)
+		}
+	}
+
+	m.Add(gensql.Payee{ID: 4, Name: "Shell", Aliases: []string{"Shell Oil"}})
+	if p, ok := m.Match("SHELL OIL 5744"); !ok || p.ID != 4 {
+		t.Errorf("added payee not matched: %d, %v", p.ID, ok)
+	}
+}
@@ -0,0 +1,153 @@
+// Package receipt validates uploaded receipt files and renders thumbnails of
+// image receipts.
+package receipt
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// Accepted receipt content types.
+const (
+	JPEG = "image/jpeg"
+	PNG  = "image/png"
+	PDF  = "application/pdf"
+	HEIC = "image/heic"
+)
+
+// ErrUnsupported is returned for files that are not a JPEG, PNG, PDF or HEIC.
+var ErrUnsupported = errors.New("unsupported file type, expected JPEG, PNG, PDF or HEIC")
+
+// heicBrands are the ISO base media file brands of HEIC and HEIF images.
+var heicBrands = map[string]bool{
+	"heic": true, "heix": true, "heim": true, "heis": true,
+	"hevc": true, "hevx": true, "mif1": true, "msf1": true,
+}
+
+// Sniff returns the content type of a file from its first bytes, ignoring
+// whatever the client claimed.
+func Sniff(head []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(head, []byte("\xff\xd8\xff")):
+		return JPEG, nil
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return PNG, nil
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return PDF, nil
+	case len(head) >= 12 && string(head[4:8]) == "ftyp" && heicBrands[string(head[8:12])]:
+		return HEIC, nil
+	}
+	return "", ErrUnsupported
+}
+
+// Extension returns the file extension for a content type returned by Sniff.
+func Extension(contentType string) string {
+	switch contentType {
+	case JPEG:
+		return ".jpg"
+	case PNG:
+		return ".png"
+	case PDF:
+		return ".pdf"
+	case HEIC:
+		return ".heic"
+	}
+	return ""
+}
+
+// CanThumbnail reports whether Thumbnail supports the content type. PDF and
+// HEIC receipts have no thumbnail.
+func CanThumbnail(contentType string) bool {
+	return contentType == JPEG || contentType == PNG
+}
+
+// NoThumbnailReason explains why a file of the content type has no
+// thumbnail, or returns "" if it gets one. HEIC images are stored as
+// uploaded; the standard library has no HEVC decoder to render them with.
+func NoThumbnailReason(contentType string) string {
+	switch {
+	case CanThumbnail(contentType):
+		return ""
+	case contentType == PDF:
+		return "PDF files are not rendered"
+	case contentType == HEIC:
+		return "HEIC images cannot be decoded by the server"
+	}
+	return "unsupported file type"
+}
+
+// maxPixels bounds the decoded size of an image, so a small file declaring
+// huge dimensions cannot exhaust memory.
+const maxPixels = 50_000_000
+
+// Thumbnail decodes a JPEG or PNG image and returns a JPEG scaled down to fit
+// within size×size pixels. Smaller images are not enlarged.
+func Thumbnail(data []byte, size int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, errors.New("image too large for a thumbnail")
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scale(src, w, h), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scale resizes src to w×h by averaging the source pixels covered by each
+// destination pixel. Transparent areas are composed onto white.
+func scale(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					// Premultiplied colour plus the white background showing
+					// through the transparent part.
+					white := 0xffff - uint64(pa)
+					r += uint64(pr) + white
+					g += uint64(pg) + white
+					bl += uint64(pb) + white
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,103 @@
+package receipt
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		head string
+		want string
+	}{
+		{"\xff\xd8\xff\xe0\x00\x10JFIF", JPEG},
+		{"\x89PNG\r\n\x1a\n\x00\x00", PNG},
+		{"%PDF-1.7\n", PDF},
+		{"\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", HEIC},
+		{"\x00\x00\x00\x1cftypmif1\x00\x00\x00\x00", HEIC},
+	}
+	for _, tt := range tests {
+		got, err := Sniff([]byte(tt.head))
+		if err != nil || got != tt.want {
+			t.Errorf("Sniff(%q) = %q, %v, want %q", tt.head, got, err, tt.want)
+		}
+	}
+
+	for _, head := range []string{"", "GIF89a", "<html>", "\x00\x00\x00\x18ftypisom"} {
+		if got, err := Sniff([]byte(head)); err != ErrUnsupported {
+			t.Errorf("Sniff(%q) = %q, %v, want ErrUnsupported", head, got, err)
+		}
+	}
+}
+
+func TestNoThumbnailReason(t *testing.T) {
+	for _, contentType := range []string{JPEG, PNG} {
+		if reason := NoThumbnailReason(contentType); reason != "" {
+			t.Errorf("NoThumbnailReason(%q) = %q, want none", contentType, reason)
+		}
+	}
+	for _, contentType := range []string{PDF, HEIC} {
+		if NoThumbnailReason(contentType) == "" {
+			t.Errorf("NoThumbnailReason(%q) is empty, want a reason", contentType)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 800; x++ {
+			if x < 400 {
+				src.Set(x, y, color.NRGBA{R: 255, A: 255})
+			} else {
+				src.Set(x, y, color.NRGBA{A: 0}) // transparent, shown as white
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	thumb, err := Thumbnail(buf.Bytes(), 200)
+	if err != nil {
+		t.Fatalf("Thumbnail: %v", err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+		t.Errorf("thumbnail is %dx%d, want 200x100", b.Dx(), b.Dy())
+	}
+
+	near := func(c color.Color, r, g, b uint32) bool {
+		cr, cg, cb, _ := c.RGBA()
+		d := func(a, b uint32) bool { return a>>8 > b+24 || a>>8+24 < b }
+		return !d(cr, r) && !d(cg, g) && !d(cb, b)
+	}
+	if c := img.At(20, 50); !near(c, 255, 0, 0) {
+		t.Errorf("left half is %v, want red", c)
+	}
+	if c := img.At(180, 50); !near(c, 255, 255, 255) {
+		t.Errorf("right half is %v, want white", c)
+	}
+}
+
+func TestThumbnailKeepsSmallImages(t *testing.T) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 30)), nil)
+
+	thumb, err := Thumbnail(buf.Bytes(), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil || cfg.Width != 40 || cfg.Height != 30 {
+		t.Errorf("got %dx%d, %v, want 40x30", cfg.Width, cfg.Height, err)
+	}
+}
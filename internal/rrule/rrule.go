@@ -0,0 +1,484 @@
+// Package rrule evaluates recurrence rules in the RRULE syntax of RFC 5545,
+// for example:
+//
+//	FREQ=MONTHLY;BYMONTHDAY=1                     rent on the 1st
+//	FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1 last business day
+//	FREQ=WEEKLY;INTERVAL=2;BYDAY=FR               every other Friday
+//
+// Occurrences are calendar dates; rules with BYHOUR, BYMINUTE, BYSECOND,
+// BYWEEKNO or BYYEARDAY are not supported. Weeks start on Monday.
+package rrule
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the FREQ part of a rule.
+type Frequency int
+
+const (
+	Daily Frequency = iota
+	Weekly
+	Monthly
+	Yearly
+)
+
+var frequencyNames = []string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}
+
+func (f Frequency) String() string {
+	return frequencyNames[f]
+}
+
+// WeekdayNum is an element of BYDAY: a weekday, optionally restricted to the
+// Nth (or, if negative, Nth from last) one of the month or year.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return weekdayNames[w.Day]
+	}
+	return strconv.Itoa(w.N) + weekdayNames[w.Day]
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	// Count limits the number of occurrences; 0 means unlimited.
+	Count int
+	// Until is the last possible occurrence date; zero means unlimited.
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+}
+
+// maxEmptyYears stops the search for the next occurrence of a rule that can
+// never match again, such as FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30. The
+// Gregorian calendar repeats every 400 years, so a rule that still matches
+// does so within that time, however rare its dates.
+const maxEmptyYears = 400
+
+// Parse parses a rule such as "FREQ=MONTHLY;BYMONTHDAY=-1". A leading
+// "RRULE:" is allowed.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("empty rule")
+	}
+
+	r := &Rule{Interval: 1}
+	seen := map[string]bool{}
+	freq := false
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s given more than once", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			i := slices.Index(frequencyNames, value)
+			if i < 0 {
+				return nil, fmt.Errorf("unsupported FREQ %q, expected DAILY, WEEKLY, MONTHLY or YEARLY", value)
+			}
+			r.Freq, freq = Frequency(i), true
+		case "INTERVAL":
+			r.Interval, err = parseInt(name, value, 1, 1000)
+		case "COUNT":
+			r.Count, err = parseInt(name, value, 1, 10000)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseList(name, value, -31, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseList(name, value, 1, 12)
+			for _, m := range months {
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			r.BySetPos, err = parseList(name, value, -366, 366)
+		case "WKST":
+			if value != "MO" {
+				err = fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("unsupported rule part %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !freq {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot be combined")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return nil, fmt.Errorf("BYDAY=%s needs FREQ=MONTHLY or YEARLY", d)
+		}
+		if d.N != 0 && r.Freq == Yearly && len(r.ByMonth) == 0 && (d.N > 53 || d.N < -53) {
+			return nil, fmt.Errorf("BYDAY=%s is out of range", d)
+		}
+		if d.N != 0 && r.Freq == Monthly && (d.N > 5 || d.N < -5) {
+			return nil, fmt.Errorf("BYDAY=%s is out of range", d)
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return nil, fmt.Errorf("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	if len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+		return nil, fmt.Errorf("BYSETPOS needs another BY rule part")
+	}
+	return r, nil
+}
+
+func parseInt(name, value string, min, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return n, nil
+}
+
+func parseList(name, value string, min, max int) ([]int, error) {
+	var out []int
+	for _, v := range strings.Split(value, ",") {
+		n, err := parseInt(name, v, min, max)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid %s value %q", name, v)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102", "20060102T150405Z", "20060102T150405"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return date(t), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", value)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var out []WeekdayNum
+	for _, v := range strings.Split(value, ",") {
+		if len(v) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY value %q", v)
+		}
+		day := slices.Index(weekdayNames, v[len(v)-2:])
+		if day < 0 {
+			return nil, fmt.Errorf("invalid BYDAY value %q", v)
+		}
+		w := WeekdayNum{Day: time.Weekday(day)}
+		if prefix := v[:len(v)-2]; prefix != "" {
+			n, err := strconv.Atoi(strings.TrimPrefix(prefix, "+"))
+			if err != nil || n == 0 {
+				return nil, fmt.Errorf("invalid BYDAY value %q", v)
+			}
+			w.N = n
+		}
+		out = append(out, w)
+	}
+	return out, nil
+}
+
+// String formats the rule in RRULE syntax without the "RRULE:" prefix.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	if len(r.ByMonth) > 0 {
+		var months []string
+		for _, m := range r.ByMonth {
+			months = append(months, strconv.Itoa(int(m)))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, d := range r.ByDay {
+			days = append(days, d.String())
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
+
+// All returns the dates matching the rule on or after start, in ascending
+// order. start also anchors INTERVAL and supplies the day when the rule does
+// not give one; unlike DTSTART in RFC 5545 it is not an occurrence unless it
+// matches.
+func (r *Rule) All(start time.Time) iter.Seq[time.Time] {
+	start = date(start)
+	return func(yield func(time.Time) bool) {
+		n := 0
+		giveUp := start.AddDate(maxEmptyYears, 0, 0)
+		for period := 0; ; period++ {
+			candidates := r.period(start, period)
+			if len(candidates) == 0 {
+				if r.periodStart(start, period).After(giveUp) {
+					return
+				}
+				continue
+			}
+			giveUp = candidates[len(candidates)-1].AddDate(maxEmptyYears, 0, 0)
+			for _, d := range candidates {
+				if d.Before(start) {
+					continue
+				}
+				if !r.Until.IsZero() && d.After(r.Until) {
+					return
+				}
+				if r.Count > 0 && n >= r.Count {
+					return
+				}
+				n++
+				if !yield(d) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Between returns the occurrences from from to to, inclusive.
+func (r *Rule) Between(start, from, to time.Time) []time.Time {
+	from, to = date(from), date(to)
+	var out []time.Time
+	for d := range r.All(start) {
+		if d.After(to) {
+			break
+		}
+		if !d.Before(from) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// period returns the sorted candidate dates of the nth period after the one
+// containing start, with BYSETPOS applied.
+func (r *Rule) period(start time.Time, n int) []time.Time {
+	step := n * r.Interval
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		d := start.AddDate(0, 0, step)
+		if r.matchMonth(d) && r.matchMonthDay(d) && r.matchWeekday(d) {
+			days = []time.Time{d}
+		}
+	case Weekly:
+		monday := start.AddDate(0, 0, -(int(start.Weekday())+6)%7+7*step)
+		for i := range 7 {
+			d := monday.AddDate(0, 0, i)
+			if !r.matchMonth(d) {
+				continue
+			}
+			if len(r.ByDay) > 0 && r.matchWeekday(d) || len(r.ByDay) == 0 && d.Weekday() == start.Weekday() {
+				days = append(days, d)
+			}
+		}
+	case Monthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchMonth(first) {
+			days = r.expandMonth(start, first)
+		}
+	case Yearly:
+		year := start.Year() + step
+		switch {
+		case len(r.ByMonth) > 0:
+			for _, m := range sortedMonths(r.ByMonth) {
+				days = append(days, r.expandMonth(start, time.Date(year, m, 1, 0, 0, 0, 0, time.UTC))...)
+			}
+		case len(r.ByMonthDay) > 0:
+			for m := time.January; m <= time.December; m++ {
+				days = append(days, r.expandMonth(start, time.Date(year, m, 1, 0, 0, 0, 0, time.UTC))...)
+			}
+		case len(r.ByDay) > 0:
+			first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+			days = r.expandWeekdays(first, first.AddDate(1, 0, 0))
+		default:
+			if d := time.Date(year, start.Month(), start.Day(), 0, 0, 0, 0, time.UTC); d.Day() == start.Day() {
+				days = []time.Time{d}
+			}
+		}
+	}
+	return r.setPos(days)
+}
+
+// periodStart returns the first day of the nth period after the one
+// containing start.
+func (r *Rule) periodStart(start time.Time, n int) time.Time {
+	step := n * r.Interval
+	switch r.Freq {
+	case Weekly:
+		return start.AddDate(0, 0, -(int(start.Weekday())+6)%7+7*step)
+	case Monthly:
+		return time.Date(start.Year(), start.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+	case Yearly:
+		return time.Date(start.Year()+step, time.January, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return start.AddDate(0, 0, step)
+	}
+}
+
+// expandMonth returns the days of the month beginning at first selected by
+// BYMONTHDAY and BYDAY, or the day of start's month if neither is given.
+func (r *Rule) expandMonth(start, first time.Time) []time.Time {
+	next := first.AddDate(0, 1, 0)
+	switch {
+	case len(r.ByMonthDay) > 0:
+		var weekdays []time.Time
+		if len(r.ByDay) > 0 {
+			weekdays = r.expandWeekdays(first, next)
+		}
+		var days []time.Time
+		for d := first; d.Before(next); d = d.AddDate(0, 0, 1) {
+			if r.matchMonthDay(d) && (len(r.ByDay) == 0 || slices.Contains(weekdays, d)) {
+				days = append(days, d)
+			}
+		}
+		return days
+	case len(r.ByDay) > 0:
+		return r.expandWeekdays(first, next)
+	default:
+		// Months without the day, such as the 31st, are skipped.
+		if d := first.AddDate(0, 0, start.Day()-1); d.Month() == first.Month() {
+			return []time.Time{d}
+		}
+		return nil
+	}
+}
+
+// expandWeekdays returns the days from first up to end selected by BYDAY,
+// with ordinals counted within that range.
+func (r *Rule) expandWeekdays(first, end time.Time) []time.Time {
+	var days []time.Time
+	for _, w := range r.ByDay {
+		var matches []time.Time
+		for d := first; d.Before(end); d = d.AddDate(0, 0, 1) {
+			if d.Weekday() == w.Day {
+				matches = append(matches, d)
+			}
+		}
+		switch {
+		case w.N == 0:
+			days = append(days, matches...)
+		case w.N > 0 && w.N <= len(matches):
+			days = append(days, matches[w.N-1])
+		case w.N < 0 && -w.N <= len(matches):
+			days = append(days, matches[len(matches)+w.N])
+		}
+	}
+	slices.SortFunc(days, time.Time.Compare)
+	return slices.Compact(days)
+}
+
+func (r *Rule) setPos(days []time.Time) []time.Time {
+	slices.SortFunc(days, time.Time.Compare)
+	days = slices.Compact(days)
+	if len(r.BySetPos) == 0 {
+		return days
+	}
+	var out []time.Time
+	for _, pos := range r.BySetPos {
+		switch {
+		case pos > 0 && pos <= len(days):
+			out = append(out, days[pos-1])
+		case pos < 0 && -pos <= len(days):
+			out = append(out, days[len(days)+pos])
+		}
+	}
+	slices.SortFunc(out, time.Time.Compare)
+	return slices.Compact(out)
+}
+
+func (r *Rule) matchMonth(d time.Time) bool {
+	return len(r.ByMonth) == 0 || slices.Contains(r.ByMonth, d.Month())
+}
+
+func (r *Rule) matchMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysInMonth := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, md := range r.ByMonthDay {
+		if md == d.Day() || md < 0 && daysInMonth+md+1 == d.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeekday checks BYDAY for rules where it has no ordinals.
+func (r *Rule) matchWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, w := range r.ByDay {
+		if w.Day == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedMonths(months []time.Month) []time.Month {
+	out := slices.Clone(months)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// date truncates t to its calendar date in UTC.
+func date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,160 @@
+package rrule
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dates(ts []time.Time) string {
+	s := make([]string, len(ts))
+	for i, t := range ts {
+		s[i] = t.Format("2006-01-02")
+	}
+	return strings.Join(s, " ")
+}
+
+func first(t *testing.T, rule, start string, n int) string {
+	t.Helper()
+	r, err := Parse(rule)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", rule, err)
+	}
+	var out []time.Time
+	for d := range r.All(day(start)) {
+		out = append(out, d)
+		if len(out) == n {
+			break
+		}
+	}
+	return dates(out)
+}
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name, rule, start string
+		n                 int
+		want              string
+	}{
+		{"monthly on the start day", "FREQ=MONTHLY", "2025-01-15", 3,
+			"2025-01-15 2025-02-15 2025-03-15"},
+		{"monthly on the 31st skips short months", "FREQ=MONTHLY", "2025-01-31", 3,
+			"2025-01-31 2025-03-31 2025-05-31"},
+		{"monthly on day N", "FREQ=MONTHLY;BYMONTHDAY=1", "2025-01-15", 3,
+			"2025-02-01 2025-03-01 2025-04-01"},
+		{"last day of the month", "FREQ=MONTHLY;BYMONTHDAY=-1", "2024-01-01", 3,
+			"2024-01-31 2024-02-29 2024-03-31"},
+		{"last business day", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "2025-05-01", 4,
+			"2025-05-30 2025-06-30 2025-07-31 2025-08-29"},
+		{"first business day", "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=1", "2025-03-01", 3,
+			"2025-03-03 2025-04-01 2025-05-01"},
+		{"every 2 weeks", "FREQ=WEEKLY;INTERVAL=2", "2025-01-03", 3,
+			"2025-01-03 2025-01-17 2025-01-31"},
+		{"every other Friday from a Monday", "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", "2025-01-06", 3,
+			"2025-01-10 2025-01-24 2025-02-07"},
+		{"twice a week", "FREQ=WEEKLY;BYDAY=TU,TH", "2025-01-01", 4,
+			"2025-01-02 2025-01-07 2025-01-09 2025-01-14"},
+		{"second Tuesday", "FREQ=MONTHLY;BYDAY=2TU", "2025-01-01", 3,
+			"2025-01-14 2025-02-11 2025-03-11"},
+		{"last Friday", "FREQ=MONTHLY;BYDAY=-1FR", "2025-01-01", 2,
+			"2025-01-31 2025-02-28"},
+		{"Friday the 13th", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", "2025-01-01", 2,
+			"2025-06-13 2026-02-13"},
+		{"quarterly", "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=15", "2025-02-01", 3,
+			"2025-02-15 2025-05-15 2025-08-15"},
+		{"yearly on the start day", "FREQ=YEARLY", "2024-02-29", 2,
+			"2024-02-29 2028-02-29"},
+		{"yearly in given months", "FREQ=YEARLY;BYMONTH=3,9;BYMONTHDAY=1", "2025-01-01", 3,
+			"2025-03-01 2025-09-01 2026-03-01"},
+		{"first Monday of the year", "FREQ=YEARLY;BYDAY=1MO", "2025-01-01", 2,
+			"2025-01-06 2026-01-05"},
+		{"weekdays", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", "2025-01-03", 3,
+			"2025-01-03 2025-01-06 2025-01-07"},
+		{"count", "FREQ=DAILY;INTERVAL=10;COUNT=2", "2025-01-01", 5,
+			"2025-01-01 2025-01-11"},
+		{"until", "FREQ=WEEKLY;UNTIL=20250115", "2025-01-01", 5,
+			"2025-01-01 2025-01-08 2025-01-15"},
+		{"never again", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "2025-01-01", 1,
+			""},
+		{"leap days", "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29", "2025-01-01", 3,
+			"2028-02-29 2032-02-29 2036-02-29"},
+		{"leap day on a monday", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29;BYDAY=MO", "2025-01-01", 2,
+			"2044-02-29 2072-02-29"},
+		{"never again daily", "FREQ=DAILY;BYMONTH=4;BYMONTHDAY=31", "2025-01-01", 1,
+			""},
+	}
+	for _, tt := range tests {
+		if got := first(t, tt.rule, tt.start, tt.n); got != tt.want {
+			t.Errorf("%s: %s from %s = %q, want %q", tt.name, tt.rule, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	r, err := Parse("FREQ=MONTHLY;BYMONTHDAY=1,15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := r.Between(day("2025-01-01"), day("2025-03-01"), day("2025-04-01"))
+	if want := "2025-03-01 2025-03-15 2025-04-01"; dates(got) != want {
+		t.Errorf("Between = %q, want %q", dates(got), want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=3;UNTIL=20250101",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYSETPOS=-1",
+		"FREQ=MONTHLY;BYHOUR=9",
+		"FREQ=MONTHLY;FREQ=WEEKLY",
+		"FREQ=MONTHLY;WKST=SU",
+	} {
+		if _, err := Parse(rule); err == nil {
+			t.Errorf("Parse(%q) succeeded", rule)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	for _, rule := range []string{
+		"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+		"FREQ=WEEKLY;INTERVAL=2;UNTIL=20251231;BYDAY=FR",
+		"FREQ=YEARLY;COUNT=5;BYMONTH=3,9;BYMONTHDAY=1,-1",
+		"FREQ=MONTHLY;BYDAY=2TU,-1FR",
+	} {
+		r, err := Parse(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.String(); got != rule {
+			t.Errorf("String() = %q, want %q", got, rule)
+		}
+	}
+
+	r, _ := Parse("freq=monthly;bymonthday=1;interval=1")
+	if got := r.String(); got != "FREQ=MONTHLY;BYMONTHDAY=1" {
+		t.Errorf("normalized rule = %q", got)
+	}
+	if !slices.Equal(r.ByMonthDay, []int{1}) {
+		t.Errorf("ByMonthDay = %v", r.ByMonthDay)
+	}
+}
@@ -0,0 +1,168 @@
+// Package rules evaluates the user-defined rules that categorize
+// transactions. A rule matches transactions on any combination of a
+// description pattern, an amount range, the account, the currency and the
+// type, and sets the category, notes or status and adds tags, for example:
+//
+//	description ~ (?i)^netflix    set category Subscriptions, add tag tv
+//	account = Amex, amount > 500  set status pending, add tag review
+//
+// Rules apply in priority order. A field set by a rule is left alone by the
+// rules after it, while the tags of every matching rule are added.
+package rules
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/numeric"
+)
+
+// Rule is a compiled rule.
+type Rule struct {
+	ID   int64
+	Name string
+
+	description *regexp.Regexp
+	minAmount   *big.Rat
+	maxAmount   *big.Rat
+	account     *string
+	currency    *string
+	typ         *string
+
+	setCategory *string
+	addTags     []string
+	setNotes    *string
+	setStatus   *string
+}
+
+// Transaction holds the fields of a transaction that rules read and write.
+type Transaction struct {
+	Description string
+	Amount      *big.Rat
+	Account     string
+	Currency    string
+	Type        string
+
+	Category string
+	Tags     []string
+	Notes    *string
+	Status   string
+}
+
+// Compile validates a stored rule and prepares it for matching. The
+// description pattern uses RE2 syntax and matches anywhere in the
+// description unless anchored.
+func Compile(r gensql.Rule) (*Rule, error) {
+	rule := &Rule{
+		ID:          r.ID,
+		Name:        r.Name,
+		account:     r.Account,
+		currency:    r.Currency,
+		typ:         r.Type,
+		setCategory: r.SetCategory,
+		addTags:     r.AddTags,
+		setNotes:    r.SetNotes,
+		setStatus:   r.SetStatus,
+	}
+	if r.DescriptionPattern != nil {
+		re, err := regexp.Compile(*r.DescriptionPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid description pattern: %w", err)
+		}
+		rule.description = re
+	}
+	rule.minAmount = numeric.Rat(r.MinAmount)
+	rule.maxAmount = numeric.Rat(r.MaxAmount)
+
+	if rule.description == nil && rule.minAmount == nil && rule.maxAmount == nil &&
+		rule.account == nil && rule.currency == nil && rule.typ == nil {
+		return nil, fmt.Errorf("rule needs at least one condition")
+	}
+	if rule.setCategory == nil && len(rule.addTags) == 0 && rule.setNotes == nil && rule.setStatus == nil {
+		return nil, fmt.Errorf("rule needs at least one action")
+	}
+	if rule.minAmount != nil && rule.maxAmount != nil && rule.minAmount.Cmp(rule.maxAmount) > 0 {
+		return nil, fmt.Errorf("minimum amount is greater than the maximum")
+	}
+	// Only locking a reconciliation reconciles transactions.
+	if rule.setStatus != nil && *rule.setStatus != "pending" && *rule.setStatus != "cleared" {
+		return nil, fmt.Errorf("status to set must be pending or cleared, not %q", *rule.setStatus)
+	}
+	return rule, nil
+}
+
+// Matches reports whether t meets every condition of the rule. The amount
+// range is inclusive.
+func (r *Rule) Matches(t Transaction) bool {
+	if r.description != nil && !r.description.MatchString(t.Description) {
+		return false
+	}
+	if r.minAmount != nil && (t.Amount == nil || t.Amount.Cmp(r.minAmount) < 0) {
+		return false
+	}
+	if r.maxAmount != nil && (t.Amount == nil || t.Amount.Cmp(r.maxAmount) > 0) {
+		return false
+	}
+	if r.account != nil && *r.account != t.Account {
+		return false
+	}
+	if r.currency != nil && *r.currency != t.Currency {
+		return false
+	}
+	if r.typ != nil && *r.typ != t.Type {
+		return false
+	}
+	return true
+}
+
+// Apply runs rules, which must be in priority order, on t and returns the
+// IDs of the ones that matched. The status of a reconciled transaction is
+// never changed.
+func Apply(rules []*Rule, t *Transaction) []int64 {
+	var matched []int64
+	var categorySet, notesSet bool
+	statusSet := t.Status == "reconciled"
+	for _, r := range rules {
+		if !r.Matches(*t) {
+			continue
+		}
+		matched = append(matched, r.ID)
+		if r.setCategory != nil && !categorySet {
+			t.Category, categorySet = *r.setCategory, true
+		}
+		if r.setNotes != nil && !notesSet {
+			notes := *r.setNotes
+			t.Notes, notesSet = &notes, true
+		}
+		if r.setStatus != nil && !statusSet {
+			t.Status, statusSet = *r.setStatus, true
+		}
+		for _, tag := range r.addTags {
+			if !slices.Contains(t.Tags, tag) {
+				t.Tags = append(t.Tags, tag)
+			}
+		}
+	}
+	return matched
+}
+
+// ApplyToParams runs rules on a transaction about to be created.
+func ApplyToParams(rules []*Rule, p *gensql.CreateTransactionParams) []int64 {
+	t := Transaction{
+		Description: p.Description,
+		Amount:      numeric.Rat(p.Amount),
+		Account:     p.Account,
+		Currency:    p.Currency,
+		Type:        p.Type,
+		Category:    p.Category,
+		Tags:        slices.Clone(p.Tags),
+		Notes:       p.Notes,
+		Status:      p.Status,
+	}
+	matched := Apply(rules, &t)
+	p.Category, p.Tags, p.Notes, p.Status = t.Category, t.Tags, t.Notes, t.Status
+	return matched
+}
@@ -0,0 +1,139 @@
+package rules
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func amount(cents int64) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(cents), Exp: -2, Valid: true}
+}
+
+func compile(t *testing.T, r gensql.Rule) *Rule {
+	t.Helper()
+	rule, err := Compile(r)
+	if err != nil {
+		t.Fatalf("Compile(%s): %v", r.Name, err)
+	}
+	return rule
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []gensql.Rule{
+		{Name: "no condition", SetCategory: ptr("Food")},
+		{Name: "no action", Account: ptr("Checking")},
+		{Name: "bad pattern", DescriptionPattern: ptr("(netflix"), SetCategory: ptr("TV")},
+		{Name: "empty range", MinAmount: amount(5000), MaxAmount: amount(1000), SetCategory: ptr("Food")},
+		{Name: "reconciles", Account: ptr("Checking"), SetStatus: ptr("reconciled")},
+		{Name: "unknown status", Account: ptr("Checking"), SetStatus: ptr("done")},
+	}
+	for _, r := range tests {
+		if _, err := Compile(r); err == nil {
+			t.Errorf("%s: expected an error", r.Name)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	rule := compile(t, gensql.Rule{
+		Name:               "groceries",
+		DescriptionPattern: ptr(`(?i)\bwhole ?foods\b`),
+		MinAmount:          amount(1000),
+		MaxAmount:          amount(20000),
+		Account:            ptr("Checking"),
+		Type:               ptr("expense"),
+		SetCategory:        ptr("Groceries"),
+	})
+	base := Transaction{
+		Description: "WHOLEFOODS MKT #123",
+		Amount:      big.NewRat(4250, 100),
+		Account:     "Checking",
+		Currency:    "USD",
+		Type:        "expense",
+	}
+	if !rule.Matches(base) {
+		t.Fatalf("expected %+v to match", base)
+	}
+
+	tests := map[string]func(*Transaction){
+		"description": func(t *Transaction) { t.Description = "Trader Joe's" },
+		"below range": func(t *Transaction) { t.Amount = big.NewRat(999, 100) },
+		"above range": func(t *Transaction) { t.Amount = big.NewRat(20001, 100) },
+		"account":     func(t *Transaction) { t.Account = "Savings" },
+		"type":        func(t *Transaction) { t.Type = "income" },
+	}
+	for name, change := range tests {
+		tx := base
+		change(&tx)
+		if rule.Matches(tx) {
+			t.Errorf("%s: expected no match", name)
+		}
+	}
+
+	edge := base
+	edge.Amount = big.NewRat(200, 1)
+	if !rule.Matches(edge) {
+		t.Error("amount range should be inclusive")
+	}
+}
+
+func TestApplyPriority(t *testing.T) {
+	list := []*Rule{
+		compile(t, gensql.Rule{ID: 1, Name: "netflix", DescriptionPattern: ptr("(?i)netflix"), SetCategory: ptr("TV"), AddTags: []string{"subscription"}}),
+		compile(t, gensql.Rule{ID: 2, Name: "card", Account: ptr("Amex"), SetCategory: ptr("Credit card"), SetNotes: ptr("check statement"), AddTags: []string{"amex", "subscription"}}),
+		compile(t, gensql.Rule{ID: 3, Name: "other", Account: ptr("Checking"), SetStatus: ptr("cleared")}),
+	}
+	tx := Transaction{Description: "NETFLIX.COM", Account: "Amex", Category: "Uncategorized", Tags: []string{"online"}, Status: "pending"}
+
+	matched := Apply(list, &tx)
+	if !reflect.DeepEqual(matched, []int64{1, 2}) {
+		t.Errorf("matched %v, want [1 2]", matched)
+	}
+	if tx.Category != "TV" {
+		t.Errorf("category %q; the first matching rule should win", tx.Category)
+	}
+	if tx.Notes == nil || *tx.Notes != "check statement" {
+		t.Errorf("notes %v", tx.Notes)
+	}
+	if !reflect.DeepEqual(tx.Tags, []string{"online", "subscription", "amex"}) {
+		t.Errorf("tags %v", tx.Tags)
+	}
+	if tx.Status != "pending" {
+		t.Errorf("status %q", tx.Status)
+	}
+}
+
+func TestApplyKeepsReconciledStatus(t *testing.T) {
+	list := []*Rule{compile(t, gensql.Rule{ID: 1, Name: "clear", Account: ptr("Checking"), SetStatus: ptr("cleared"), SetCategory: ptr("Bills")})}
+	tx := Transaction{Account: "Checking", Status: "reconciled"}
+
+	Apply(list, &tx)
+	if tx.Status != "reconciled" || tx.Category != "Bills" {
+		t.Errorf("status %q, category %q", tx.Status, tx.Category)
+	}
+}
+
+func TestApplyToParams(t *testing.T) {
+	list := []*Rule{compile(t, gensql.Rule{ID: 1, Name: "rent", MinAmount: amount(100000), Type: ptr("expense"), SetCategory: ptr("Housing"), AddTags: []string{"rent"}})}
+	tags := []string{"home"}
+	params := gensql.CreateTransactionParams{Amount: amount(150000), Type: "expense", Category: "Uncategorized", Tags: tags}
+
+	if matched := ApplyToParams(list, &params); !reflect.DeepEqual(matched, []int64{1}) {
+		t.Errorf("matched %v", matched)
+	}
+	if params.Category != "Housing" || !reflect.DeepEqual(params.Tags, []string{"home", "rent"}) {
+		t.Errorf("category %q, tags %v", params.Category, params.Tags)
+	}
+	if len(tags) != 1 {
+		t.Errorf("the caller's tags were modified: %v", tags)
+	}
+}
@@ -0,0 +1,466 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/receipt"
+	"budgetctl-go/internal/storage"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxAttachmentBytes limits the size of uploaded attachments.
+	maxAttachmentBytes = 10 << 20
+	// attachmentURLTTL is how long signed download URLs stay valid.
+	attachmentURLTTL = 15 * time.Minute
+	// thumbnailSize is the longest side of image thumbnails.
+	thumbnailSize = 320
+	// maxFilenameLength limits stored attachment names, in characters.
+	maxFilenameLength = 255
+)
+
+type Attachment struct {
+	ID                   int64     `json:"id"`
+	TransactionID        int64     `json:"transaction_id"`
+	Filename             string    `json:"filename"`
+	ContentType          string    `json:"content_type"`
+	Size                 int64     `json:"size"`
+	Checksum             *string   `json:"checksum" doc:"SHA-256 of the file, hex encoded"`
+	CreatedAt            time.Time `json:"created_at"`
+	URL                  string    `json:"url" doc:"Signed download URL"`
+	ThumbnailURL         *string   `json:"thumbnail_url" doc:"Signed URL of a JPEG thumbnail, for JPEG and PNG images. Null for PDF and HEIC files, with the reason in thumbnail_unavailable."`
+	ThumbnailUnavailable string    `json:"thumbnail_unavailable,omitempty" doc:"Why thumbnail_url is null"`
+	ExpiresAt            time.Time `json:"expires_at" doc:"When the signed URLs stop working"`
+}
+
+type ListAttachmentsRequest struct {
+	TransactionID int64 `path:"id" doc:"Transaction ID"`
+}
+
+type ListAttachmentsResponse struct {
+	Body []Attachment
+}
+
+type UploadAttachmentRequest struct {
+	TransactionID int64 `path:"id" doc:"Transaction ID"`
+	RawBody       huma.MultipartFormFiles[struct {
+		File     huma.FormFile `form:"file" contentType:"image/jpeg,image/png,application/pdf,image/heic,image/heif,application/octet-stream" required:"true" doc:"JPEG, PNG, PDF or HEIC file"`
+		Filename string        `form:"filename" doc:"Name to store the file under; defaults to the uploaded file name"`
+	}]
+}
+
+type AttachmentRequest struct {
+	TransactionID int64 `path:"id" doc:"Transaction ID"`
+	AttachmentID  int64 `path:"attachment_id" doc:"Attachment ID"`
+}
+
+type RenameAttachmentRequest struct {
+	TransactionID int64 `path:"id" doc:"Transaction ID"`
+	AttachmentID  int64 `path:"attachment_id" doc:"Attachment ID"`
+	Body          struct {
+		Filename string `json:"filename" minLength:"1" maxLength:"255"`
+	}
+}
+
+type AttachmentResponse struct {
+	Body *Attachment
+}
+
+func RegisterAttachmentRoutes(api huma.API, db database.Service, store storage.Storage) {
+	// List Attachments
+	huma.Register(api, huma.Operation{
+		OperationID: "list-attachments",
+		Method:      http.MethodGet,
+		Path:        "/transactions/{id}/attachments",
+		Summary:     "List Attachments",
+		Description: fmt.Sprintf("Lists the files attached to a transaction with signed download URLs valid for %s.", attachmentURLTTL),
+		Tags:        []string{"Attachments"},
+	}, func(ctx context.Context, input *ListAttachmentsRequest) (*ListAttachmentsResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		if _, err := queries.GetTransactionByID(ctx, gensql.GetTransactionByIDParams{
+			ID:     input.TransactionID,
+			UserID: user.ID,
+		}); err != nil {
+			return nil, huma.Error404NotFound("Transaction not found", err)
+		}
+
+		attachments, err := queries.ListAttachments(ctx, gensql.ListAttachmentsParams{
+			TransactionID: input.TransactionID,
+			UserID:        user.ID,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch attachments", err)
+		}
+
+		body := []Attachment{}
+		for _, a := range attachments {
+			signed, err := signAttachment(ctx, store, a)
+			if err != nil {
+				return nil, huma.Error500InternalServerError("Failed to sign attachment URL", err)
+			}
+			body = append(body, *signed)
+		}
+		return &ListAttachmentsResponse{Body: body}, nil
+	})
+
+	// Upload Attachment
+	huma.Register(api, huma.Operation{
+		OperationID:   "upload-attachment",
+		Method:        http.MethodPost,
+		Path:          "/transactions/{id}/attachments",
+		Summary:       "Upload Attachment",
+		Description:   "Attaches a JPEG, PNG, PDF or HEIC file to the transaction. The file type is detected from its contents. JPEG and PNG files get a thumbnail; PDF and HEIC files do not, as the server does not render them. A file already uploaded for another transaction is stored only once; attaching the same file twice to one transaction is a conflict.",
+		Tags:          []string{"Attachments"},
+		MaxBodyBytes:  maxAttachmentBytes + 1<<20,
+		DefaultStatus: http.StatusCreated,
+	}, func(ctx context.Context, input *UploadAttachmentRequest) (*AttachmentResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		form := input.RawBody.Data()
+		body, err := storeAttachment(ctx, db, store, user.ID, input.TransactionID, form.File, form.Filename)
+		if err != nil {
+			return nil, err
+		}
+		return &AttachmentResponse{Body: body}, nil
+	})
+
+	// Get Attachment
+	huma.Register(api, huma.Operation{
+		OperationID: "get-attachment",
+		Method:      http.MethodGet,
+		Path:        "/transactions/{id}/attachments/{attachment_id}",
+		Summary:     "Get Attachment",
+		Tags:        []string{"Attachments"},
+	}, func(ctx context.Context, input *AttachmentRequest) (*AttachmentResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		a, err := db.GetQueries().GetAttachment(ctx, gensql.GetAttachmentParams{
+			ID:            input.AttachmentID,
+			TransactionID: input.TransactionID,
+			UserID:        user.ID,
+		})
+		if err != nil {
+			return nil, huma.Error404NotFound("Attachment not found", err)
+		}
+
+		body, err := signAttachment(ctx, store, a)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to sign attachment URL", err)
+		}
+		return &AttachmentResponse{Body: body}, nil
+	})
+
+	// Rename Attachment
+	huma.Register(api, huma.Operation{
+		OperationID: "rename-attachment",
+		Method:      http.MethodPatch,
+		Path:        "/transactions/{id}/attachments/{attachment_id}",
+		Summary:     "Rename Attachment",
+		Tags:        []string{"Attachments"},
+	}, func(ctx context.Context, input *RenameAttachmentRequest) (*AttachmentResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		a, err := db.GetQueries().RenameAttachment(ctx, gensql.RenameAttachmentParams{
+			ID:            input.AttachmentID,
+			TransactionID: input.TransactionID,
+			UserID:        user.ID,
+			Filename:      attachmentFilename(input.Body.Filename, ""),
+		})
+		if err != nil {
+			return nil, huma.Error404NotFound("Attachment not found", err)
+		}
+
+		body, err := signAttachment(ctx, store, a)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to sign attachment URL", err)
+		}
+		return &AttachmentResponse{Body: body}, nil
+	})
+
+	// Delete Attachment
+	huma.Register(api, huma.Operation{
+		OperationID:   "delete-attachment",
+		Method:        http.MethodDelete,
+		Path:          "/transactions/{id}/attachments/{attachment_id}",
+		Summary:       "Delete Attachment",
+		Description:   "Removes the attachment. The stored file is deleted once no other attachment uses it.",
+		Tags:          []string{"Attachments"},
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *AttachmentRequest) (*struct{}, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, removeAttachment(ctx, db, store, user.ID, input.TransactionID, input.AttachmentID)
+	})
+}
+
+// storeAttachment attaches an uploaded file to one of the user's
+// transactions. The file is stored unless the user uploaded it before; name
+// defaults to the uploaded file name.
+func storeAttachment(ctx context.Context, db database.Service, store storage.Storage, userID, transactionID int64, file huma.FormFile, name string) (*Attachment, error) {
+	queries := db.GetQueries()
+	tx, err := queries.GetTransactionByID(ctx, gensql.GetTransactionByIDParams{
+		ID:     transactionID,
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, huma.Error404NotFound("Transaction not found", err)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAttachmentBytes+1))
+	if err != nil {
+		return nil, huma.Error400BadRequest("Failed to read file", err)
+	}
+	if len(data) > maxAttachmentBytes {
+		return nil, huma.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("Attachments are limited to %d MB", maxAttachmentBytes>>20))
+	}
+	contentType, err := receipt.Sniff(data[:min(len(data), 512)])
+	if err != nil {
+		return nil, huma.Error415UnsupportedMediaType(err.Error())
+	}
+	var thumbnail []byte
+	if receipt.CanThumbnail(contentType) {
+		if thumbnail, err = receipt.Thumbnail(data, thumbnailSize); err != nil {
+			return nil, huma.Error422UnprocessableEntity("Image could not be decoded", err)
+		}
+	}
+
+	if name == "" {
+		name = file.Filename
+	}
+	filename := attachmentFilename(name, receipt.Extension(contentType))
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	key := fmt.Sprintf("attachments/%d/%s%s", userID, checksum, receipt.Extension(contentType))
+	var thumbnailKey *string
+	if thumbnail != nil {
+		k := fmt.Sprintf("attachments/%d/%s.thumb.jpg", userID, checksum)
+		thumbnailKey = &k
+	}
+
+	var created gensql.Attachment
+	uploaded := false
+	err = db.WithTx(ctx, func(pgTx pgx.Tx) error {
+		q := queries.WithTx(pgTx)
+		for _, k := range []*string{&key, thumbnailKey} {
+			if k == nil {
+				continue
+			}
+			if err := q.LockStorageKey(ctx, *k); err != nil {
+				return err
+			}
+		}
+
+		existing, err := q.FindAttachmentByChecksum(ctx, gensql.FindAttachmentByChecksumParams{
+			UserID:   userID,
+			Checksum: &checksum,
+		})
+		switch {
+		case err == nil:
+			key, thumbnailKey = existing.StorageKey, existing.ThumbnailKey
+		case errors.Is(err, pgx.ErrNoRows):
+			uploaded = true
+			if err := store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+				return err
+			}
+			if thumbnailKey != nil {
+				if err := store.Put(ctx, *thumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), receipt.JPEG); err != nil {
+					return err
+				}
+			}
+		default:
+			return err
+		}
+
+		created, err = q.CreateAttachment(ctx, gensql.CreateAttachmentParams{
+			UserID:        userID,
+			TransactionID: tx.ID,
+			Filename:      filename,
+			ContentType:   contentType,
+			Size:          int64(len(data)),
+			Checksum:      &checksum,
+			StorageKey:    key,
+			ThumbnailKey:  thumbnailKey,
+		})
+		return err
+	})
+	if err != nil {
+		if uploaded {
+			queueStorageDeletion(queries, &key, thumbnailKey)
+		}
+		if _, ok := pgError(err, pgUniqueViolation); ok {
+			return nil, huma.Error409Conflict("This file is already attached to the transaction")
+		}
+		return nil, huma.Error500InternalServerError("Failed to store attachment", err)
+	}
+
+	body, err := signAttachment(ctx, store, created)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to sign attachment URL", err)
+	}
+	return body, nil
+}
+
+// removeAttachment deletes an attachment and, unless another attachment uses
+// it, its stored file.
+func removeAttachment(ctx context.Context, db database.Service, store storage.Storage, userID, transactionID, attachmentID int64) error {
+	a, err := db.GetQueries().DeleteAttachment(ctx, gensql.DeleteAttachmentParams{
+		ID:            attachmentID,
+		TransactionID: transactionID,
+		UserID:        userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return huma.Error404NotFound("Attachment not found")
+		}
+		return huma.Error500InternalServerError("Failed to delete attachment", err)
+	}
+
+	// The deletion was queued by a trigger; purge it right away rather than
+	// waiting for the next sweep.
+	for _, key := range []*string{&a.StorageKey, a.ThumbnailKey} {
+		if key == nil {
+			continue
+		}
+		if err := purgeStorageKey(ctx, db, store, *key); err != nil {
+			log.Printf("purge stored object %s: %v", *key, err)
+		}
+	}
+	return nil
+}
+
+// PurgeStorage deletes the stored objects queued for deletion when their
+// last attachment was removed, including by deleting its transaction.
+func PurgeStorage(ctx context.Context, db database.Service, store storage.Storage) error {
+	const batch = 100
+	for {
+		keys, err := db.GetQueries().ListStorageDeletions(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := purgeStorageKey(ctx, db, store, key); err != nil {
+				return fmt.Errorf("purge %s: %w", key, err)
+			}
+		}
+		if len(keys) < batch {
+			return nil
+		}
+	}
+}
+
+// purgeStorageKey deletes a queued object unless an attachment uses it again.
+// The lock keeps a concurrent upload of the same file from reusing the object
+// while it is being deleted.
+func purgeStorageKey(ctx context.Context, db database.Service, store storage.Storage, key string) error {
+	return db.WithTx(ctx, func(tx pgx.Tx) error {
+		q := db.GetQueries().WithTx(tx)
+		if err := q.LockStorageKey(ctx, key); err != nil {
+			return err
+		}
+		inUse, err := q.StorageKeyInUse(ctx, key)
+		if err != nil {
+			return err
+		}
+		if !inUse {
+			if err := store.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+		return q.DeleteStorageDeletion(ctx, key)
+	})
+}
+
+// queueStorageDeletion schedules objects uploaded by a failed request for
+// deletion.
+func queueStorageDeletion(queries *gensql.Queries, keys ...*string) {
+	for _, key := range keys {
+		if key == nil {
+			continue
+		}
+		if err := queries.QueueStorageDeletion(context.Background(), *key); err != nil {
+			log.Printf("queue deletion of stored object %s: %v", *key, err)
+		}
+	}
+}
+
+func signAttachment(ctx context.Context, store storage.Storage, a gensql.Attachment) (*Attachment, error) {
+	out := &Attachment{
+		ID:            a.ID,
+		TransactionID: a.TransactionID,
+		Filename:      a.Filename,
+		ContentType:   a.ContentType,
+		Size:          a.Size,
+		Checksum:      a.Checksum,
+		CreatedAt:     a.CreatedAt.Time,
+		ExpiresAt:     time.Now().Add(attachmentURLTTL),
+	}
+
+	var err error
+	if out.URL, err = store.SignedURL(ctx, a.StorageKey, attachmentURLTTL); err != nil {
+		return nil, err
+	}
+	if a.ThumbnailKey != nil {
+		thumbnail, err := store.SignedURL(ctx, *a.ThumbnailKey, attachmentURLTTL)
+		if err != nil {
+			return nil, err
+		}
+		out.ThumbnailURL = &thumbnail
+	} else {
+		out.ThumbnailUnavailable = receipt.NoThumbnailReason(a.ContentType)
+	}
+	return out, nil
+}
+
+// attachmentFilename reduces a client-supplied name to its base name without
+// control characters, falling back to "attachment" plus ext.
+func attachmentFilename(name, ext string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		name = "attachment" + ext
+	}
+	if utf8.RuneCountInString(name) > maxFilenameLength {
+		name = string([]rune(name)[:maxFilenameLength])
+	}
+	return name
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAttachmentFilename(t *testing.T) {
+	tests := []struct {
+		name, ext, want string
+	}{
+		{"invoice.pdf", ".pdf", "invoice.pdf"},
+		{"../../etc/passwd", "", "passwd"},
+		{`C:\Users\me\Warranty card.jpg`, ".jpg", "Warranty card.jpg"},
+		{"  bad\x00name\n.png ", ".png", "badname.png"},
+		{"", ".heic", "attachment.heic"},
+		{"dir/", ".pdf", "dir"},
+		{"/", ".pdf", "attachment.pdf"},
+	}
+	for _, tt := range tests {
+		if got := attachmentFilename(tt.name, tt.ext); got != tt.want {
+			t.Errorf("attachmentFilename(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if got := attachmentFilename(strings.Repeat("é", 300), ""); len([]rune(got)) != maxFilenameLength {
+		t.Errorf("long name kept %d characters", len([]rune(got)))
+	}
+}
@@ -0,0 +1,331 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/rules"
+	"budgetctl-go/internal/txquery"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxBatchMatches caps how many transactions a single filter-based change may touch.
+const maxBatchMatches = 10000
+
+// errDryRun rolls back a batch after all operations have been evaluated.
+var errDryRun = errors.New("dry run")
+
+// errBatchFailed rolls back a batch in which at least one operation failed.
+var errBatchFailed = errors.New("batch failed")
+
+type BatchOperation struct {
+	Op     string                          `json:"op" enum:"create,update,delete" doc:"Operation to perform"`
+	ID     int64                           `json:"id,omitempty" doc:"Transaction ID for update and delete"`
+	Create *gensql.CreateTransactionParams `json:"create,omitempty" doc:"Transaction to create"`
+	Update *gensql.UpdateTransactionParams `json:"update,omitempty" doc:"Replacement values for update"`
+}
+
+type BatchApply struct {
+	Query       string   `json:"query" minLength:"1" doc:"Structured query selecting the transactions to change, e.g. category:uncategorized after:2025-01-01"`
+	SetCategory *string  `json:"set_category,omitempty" doc:"Category to set"`
+	SetStatus   *string  `json:"set_status,omitempty" doc:"Status to set"`
+	AddTags     []string `json:"add_tags,omitempty" doc:"Tags to add"`
+	RemoveTags  []string `json:"remove_tags,omitempty" doc:"Tags to remove"`
+}
+
+type BatchTransactionsRequest struct {
+	DryRun bool `query:"dry_run" doc:"Evaluate every operation and roll back instead of saving"`
+	Body   struct {
+		Operations []BatchOperation `json:"operations,omitempty" maxItems:"1000" doc:"Individual create, update and delete operations"`
+		Apply      *BatchApply      `json:"apply,omitempty" doc:"Change applied to every transaction matching a query"`
+	}
+}
+
+type BatchOperationResult struct {
+	Index       int                 `json:"index"`
+	Op          string              `json:"op"`
+	ID          int64               `json:"id,omitempty"`
+	OK          bool                `json:"ok"`
+	Error       string              `json:"error,omitempty"`
+	Transaction *gensql.Transaction `json:"transaction,omitempty"`
+}
+
+type BatchApplyResult struct {
+	Matched      int64                `json:"matched"`
+	Transactions []gensql.Transaction `json:"transactions"`
+	Error        string               `json:"error,omitempty"`
+}
+
+type BatchTransactionsResponse struct {
+	Body struct {
+		DryRun    bool                   `json:"dry_run"`
+		Committed bool                   `json:"committed"`
+		Results   []BatchOperationResult `json:"results"`
+		Apply     *BatchApplyResult      `json:"apply,omitempty"`
+	}
+}
+
+func RegisterBatchRoutes(api huma.API, db database.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "batch-transactions",
+		Method:      http.MethodPost,
+		Path:        "/transactions:batch",
+		Summary:     "Batch Transactions",
+		Description: "Runs create, update and delete operations and an optional query-based change in a single database transaction. If any operation fails nothing is saved.",
+		Tags:        []string{"Transactions"},
+		Middlewares: huma.Middlewares{exactPath(api, "/transactions:batch")},
+	}, func(ctx context.Context, input *BatchTransactionsRequest) (*BatchTransactionsResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(input.Body.Operations) == 0 && input.Body.Apply == nil {
+			return nil, huma.Error400BadRequest("Batch must contain operations or an apply change")
+		}
+
+		resp := &BatchTransactionsResponse{}
+		resp.Body.DryRun = input.DryRun
+		resp.Body.Results = []BatchOperationResult{}
+
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+			failed := false
+
+			// Report amount changes that no longer match a transaction's
+			// splits on the operation making them rather than at commit.
+			if err := database.CheckSplitTotalsImmediately(ctx, tx); err != nil {
+				return err
+			}
+
+			enabled, err := enabledRules(ctx, queries, user.ID)
+			if err != nil {
+				return err
+			}
+			payees, err := newPayeeLinker(ctx, queries, user.ID, true)
+			if err != nil {
+				return err
+			}
+			for i, op := range input.Body.Operations {
+				result := runBatchOperation(ctx, tx, queries, user.ID, enabled, payees, i, op)
+				if !result.OK {
+					// Payees created by the operation were rolled back with it.
+					payees.forget()
+				}
+				failed = failed || !result.OK
+				resp.Body.Results = append(resp.Body.Results, result)
+			}
+
+			if input.Body.Apply != nil {
+				applyResult := applyBatchChange(ctx, queries, user.ID, *input.Body.Apply)
+				failed = failed || applyResult.Error != ""
+				resp.Body.Apply = applyResult
+			}
+
+			if failed {
+				return errBatchFailed
+			}
+			if input.DryRun {
+				return errDryRun
+			}
+			return nil
+		})
+
+		switch {
+		case err == nil:
+			resp.Body.Committed = true
+		case errors.Is(err, errDryRun), errors.Is(err, errBatchFailed):
+		default:
+			return nil, huma.Error500InternalServerError("Failed to run batch", err)
+		}
+
+		return resp, nil
+	})
+}
+
+// runBatchOperation executes a single operation inside a savepoint so that a
+// failure does not abort the surrounding transaction and later operations can
+// still report their own results. Created transactions go through the given
+// rules; created and updated ones are linked to payees.
+func runBatchOperation(ctx context.Context, tx pgx.Tx, queries *gensql.Queries, userID int64, enabled []*rules.Rule, payees *payeeLinker, index int, op BatchOperation) BatchOperationResult {
+	result := BatchOperationResult{Index: index, Op: op.Op, ID: op.ID}
+	if err := op.validate(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer savepoint.Rollback(ctx)
+
+	q := queries.WithTx(savepoint)
+	switch op.Op {
+	case "create":
+		params := *op.Create
+		applyCreateDefaults(&params, userID)
+		rules.ApplyToParams(enabled, &params)
+		if err := payees.link(ctx, &params); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		transaction, err := q.CreateTransaction(ctx, params)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.ID = transaction.ID
+		result.Transaction = &transaction
+	case "update":
+		params := *op.Update
+		params.ID = op.ID
+		params.UserID = userID
+		if params.Status == "reconciled" {
+			current, err := q.GetTransactionByID(ctx, gensql.GetTransactionByIDParams{ID: op.ID, UserID: userID})
+			if err != nil {
+				result.Error = batchErrorMessage(err)
+				return result
+			}
+			if err := checkStatusChange(current.Status, params.Status); err != nil {
+				result.Error = err.Error()
+				return result
+			}
+		}
+		if err := payees.linkUpdate(ctx, &params); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		transaction, err := q.UpdateTransaction(ctx, params)
+		if err != nil {
+			result.Error = batchErrorMessage(err)
+			return result
+		}
+		result.Transaction = &transaction
+	case "delete":
+		if _, err := q.GetTransactionByID(ctx, gensql.GetTransactionByIDParams{ID: op.ID, UserID: userID}); err != nil {
+			result.Error = batchErrorMessage(err)
+			return result
+		}
+		if _, err := q.DeleteTransaction(ctx, gensql.DeleteTransactionParams{ID: op.ID, UserID: userID}); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+
+	if err := savepoint.Commit(ctx); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.OK = true
+	return result
+}
+
+// validate checks that an operation carries what its kind needs.
+func (op BatchOperation) validate() error {
+	switch op.Op {
+	case "create":
+		if op.Create == nil {
+			return errors.New("create operation requires a create body")
+		}
+		return checkStatusChange("", op.Create.Status)
+	case "update":
+		if op.ID == 0 || op.Update == nil {
+			return errors.New("update operation requires an id and an update body")
+		}
+	case "delete":
+		if op.ID == 0 {
+			return errors.New("delete operation requires an id")
+		}
+	default:
+		return fmt.Errorf("unknown operation %q", op.Op)
+	}
+	return nil
+}
+
+// validate checks that a query-based change changes something.
+func (apply BatchApply) validate() error {
+	if apply.SetCategory == nil && apply.SetStatus == nil && len(apply.AddTags) == 0 && len(apply.RemoveTags) == 0 {
+		return errors.New("apply requires at least one change")
+	}
+	if apply.SetStatus != nil {
+		return checkStatusChange("", *apply.SetStatus)
+	}
+	return nil
+}
+
+// applyBatchChange applies a single change to every transaction matching the query.
+func applyBatchChange(ctx context.Context, queries *gensql.Queries, userID int64, apply BatchApply) *BatchApplyResult {
+	result := &BatchApplyResult{Transactions: []gensql.Transaction{}}
+
+	if err := apply.validate(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	params := gensql.ListTransactionsWithFiltersParams{UserID: userID}
+	if err := txquery.Apply(apply.Query, &params); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	total, err := queries.CountTransactions(ctx, countParamsFor(params))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Matched = total
+	if total == 0 {
+		return result
+	}
+	if total > maxBatchMatches {
+		result.Error = fmt.Sprintf("query matches %d transactions, at most %d can be changed at once", total, maxBatchMatches)
+		return result
+	}
+
+	params.Limit = int32(total)
+	matches, err := queries.ListTransactionsWithFilters(ctx, params)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	ids := make([]int64, 0, len(matches))
+	for _, m := range matches {
+		ids = append(ids, m.ID)
+	}
+
+	updated, err := queries.BulkUpdateTransactions(ctx, gensql.BulkUpdateTransactionsParams{
+		Category:   apply.SetCategory,
+		Status:     apply.SetStatus,
+		AddTags:    apply.AddTags,
+		RemoveTags: apply.RemoveTags,
+		UserID:     userID,
+		Ids:        ids,
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if updated != nil {
+		result.Transactions = updated
+	}
+	return result
+}
+
+func batchErrorMessage(err error) string {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "transaction not found"
+	}
+	return err.Error()
+}
@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestBatchOperationValidate(t *testing.T) {
+	create := &gensql.CreateTransactionParams{Description: "Coffee"}
+	update := &gensql.UpdateTransactionParams{Description: "Coffee"}
+
+	tests := []struct {
+		name string
+		op   BatchOperation
+		err  string
+	}{
+		{"create", BatchOperation{Op: "create", Create: create}, ""},
+		{"create without body", BatchOperation{Op: "create"}, "create operation requires a create body"},
+		{"create reconciled", BatchOperation{Op: "create", Create: &gensql.CreateTransactionParams{Description: "Coffee", Status: "reconciled"}}, `Status "reconciled" is only set by locking a reconciliation`},
+		{"update", BatchOperation{Op: "update", ID: 7, Update: update}, ""},
+		{"update without id", BatchOperation{Op: "update", Update: update}, "update operation requires an id and an update body"},
+		{"update without body", BatchOperation{Op: "update", ID: 7}, "update operation requires an id and an update body"},
+		{"delete", BatchOperation{Op: "delete", ID: 7}, ""},
+		{"delete without id", BatchOperation{Op: "delete"}, "delete operation requires an id"},
+		{"unknown", BatchOperation{Op: "upsert", ID: 7}, `unknown operation "upsert"`},
+	}
+	for _, tt := range tests {
+		err := tt.op.validate()
+		if got := fmt.Sprint(err); (tt.err == "" && err != nil) || (tt.err != "" && got != tt.err) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestBatchApplyValidate(t *testing.T) {
+	category := "Food"
+	cleared, reconciled := "cleared", "reconciled"
+	tests := []struct {
+		name  string
+		apply BatchApply
+		ok    bool
+	}{
+		{"no change", BatchApply{Query: "category:uncategorized"}, false},
+		{"empty tag lists", BatchApply{Query: "tag:old", AddTags: []string{}, RemoveTags: []string{}}, false},
+		{"set category", BatchApply{Query: "category:uncategorized", SetCategory: &category}, true},
+		{"remove tags", BatchApply{Query: "tag:old", RemoveTags: []string{"old"}}, true},
+		{"set status", BatchApply{Query: "account:checking", SetStatus: &cleared}, true},
+		{"set reconciled", BatchApply{Query: "account:checking", SetStatus: &reconciled}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.apply.validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: error %v", tt.name, err)
+		}
+	}
+}
+
+func TestBatchErrorMessage(t *testing.T) {
+	tests := map[error]string{
+		pgx.ErrNoRows:                           "transaction not found",
+		fmt.Errorf("update: %w", pgx.ErrNoRows): "transaction not found",
+		errors.New("connection reset"):          "connection reset",
+	}
+	for err, want := range tests {
+		if got := batchErrorMessage(err); got != want {
+			t.Errorf("batchErrorMessage(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
@@ -1,6 +1,20 @@
 package routes
 
-import "math"
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Postgres error codes handled by the routes.
+const (
+	pgUniqueViolation = "23505"
+	pgCheckViolation  = "23514"
+)
 
 // PaginationInput is a reusable pagination input struct that can be embedded in any handler
 type PaginationInput struct {
@@ -47,4 +61,37 @@ func NewPaginatedResponse[T any](data []T, total int64, page, perPage int) *Pagi
 			PerPage:     perPage,
 		},
 	}
-}
\ No newline at end of file
+}
+
+// exactPath is an operation middleware for custom-method paths such as
+// /transactions:batch. Echo treats ":" as the start of a path parameter, so
+// without it the route would also match any other suffix.
+func exactPath(api huma.API, path string) func(huma.Context, func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		if ctx.URL().Path != path {
+			huma.WriteErr(api, ctx, http.StatusNotFound, "Not Found")
+			return
+		}
+		next(ctx)
+	}
+}
+
+// Decimal is an amount in a request or response body. It is read from and
+// written as a JSON number without rounding through float64; a bare
+// pgtype.Numeric would be documented and validated as an object.
+type Decimal struct {
+	pgtype.Numeric
+}
+
+func (Decimal) Schema(huma.Registry) *huma.Schema {
+	return &huma.Schema{Type: huma.TypeNumber}
+}
+
+// pgError returns the Postgres error in err's chain if it has the given code.
+func pgError(err error, code string) (*pgconn.PgError, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == code {
+		return pgErr, true
+	}
+	return nil, false
+}
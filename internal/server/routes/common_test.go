@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+)
+
+func TestDecimalBody(t *testing.T) {
+	_, api := humatest.New(t)
+	type body struct {
+		Amount Decimal `json:"amount"`
+	}
+	huma.Post(api, "/echo", func(ctx context.Context, input *struct{ Body body }) (*struct{ Body body }, error) {
+		return &struct{ Body body }{input.Body}, nil
+	})
+
+	resp := api.Post("/echo", strings.NewReader(`{"amount": 12345678.91}`))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.Code, resp.Body.String())
+	}
+	if got := strings.TrimSpace(resp.Body.String()); !strings.Contains(got, `"amount":12345678.91`) {
+		t.Errorf("response %s", got)
+	}
+
+	if resp := api.Post("/echo", strings.NewReader(`{"amount": "12"}`)); resp.Code != http.StatusUnprocessableEntity {
+		t.Errorf("string amount: status %d", resp.Code)
+	}
+}
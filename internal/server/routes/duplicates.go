@@ -0,0 +1,204 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+type DuplicatePair struct {
+	Reason      string             `json:"reason" enum:"exact,possible" doc:"exact when the fingerprints match, otherwise possible"`
+	Similarity  float64            `json:"similarity" doc:"Similarity of the descriptions between 0 and 1"`
+	Transaction gensql.Transaction `json:"transaction"`
+	Duplicate   gensql.Transaction `json:"duplicate"`
+}
+
+type ListDuplicatesRequest struct {
+	PaginationInput
+}
+
+type ListDuplicatesResponse struct {
+	Body *PaginatedResponse[DuplicatePair]
+}
+
+type MergeTransactionsRequest struct {
+	ID   int64 `path:"id" doc:"Transaction to keep"`
+	Body struct {
+		DuplicateIDs []int64 `json:"duplicate_ids" minItems:"1" maxItems:"100" doc:"Transactions to merge into the kept one and delete"`
+	}
+}
+
+type MergeTransactionsResponse struct {
+	Body *gensql.Transaction
+}
+
+func RegisterDuplicateRoutes(api huma.API, db database.Service) {
+	// List Duplicates
+	huma.Register(api, huma.Operation{
+		OperationID: "list-duplicate-transactions",
+		Method:      http.MethodGet,
+		Path:        "/transactions/duplicates",
+		Summary:     "List Suspected Duplicates",
+		Description: "Lists pairs of transactions on the same account with the same amount and type booked within three days of each other. Pairs with different bank references are not reported.",
+		Tags:        []string{"Transactions"},
+	}, func(ctx context.Context, input *ListDuplicatesRequest) (*ListDuplicatesResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		limit, offset := input.ToLimitOffset()
+
+		candidates, err := queries.ListDuplicateCandidates(ctx, gensql.ListDuplicateCandidatesParams{
+			UserID: user.ID,
+			Limit:  limit,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch duplicates", err)
+		}
+
+		total, err := queries.CountDuplicateCandidates(ctx, user.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to count duplicates", err)
+		}
+
+		pairs := make([]DuplicatePair, 0, len(candidates))
+		for _, c := range candidates {
+			reason := "possible"
+			if c.Exact {
+				reason = "exact"
+			}
+			pairs = append(pairs, DuplicatePair{
+				Reason:      reason,
+				Similarity:  c.Similarity,
+				Transaction: c.Transaction,
+				Duplicate:   c.Transaction_2,
+			})
+		}
+
+		return &ListDuplicatesResponse{
+			Body: NewPaginatedResponse(pairs, total, input.Page, input.PerPage),
+		}, nil
+	})
+
+	// Merge Transactions
+	huma.Register(api, huma.Operation{
+		OperationID: "merge-transactions",
+		Method:      http.MethodPost,
+		Path:        "/transactions/{id}/merge",
+		Summary:     "Merge Duplicate Transactions",
+		Description: "Merges the given duplicates into the transaction and deletes them. Tags are combined, distinct notes are appended, the bank reference and receipt URL are taken from a duplicate when the kept transaction has none, and attachments the kept transaction does not already have are moved to it.",
+		Tags:        []string{"Transactions"},
+	}, func(ctx context.Context, input *MergeTransactionsRequest) (*MergeTransactionsResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if slices.Contains(input.Body.DuplicateIDs, input.ID) {
+			return nil, huma.Error400BadRequest("A transaction cannot be merged into itself")
+		}
+
+		var merged gensql.Transaction
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+
+			keep, err := queries.GetTransactionByID(ctx, gensql.GetTransactionByIDParams{ID: input.ID, UserID: user.ID})
+			if err != nil {
+				return err
+			}
+
+			duplicates := make([]gensql.Transaction, 0, len(input.Body.DuplicateIDs))
+			for _, id := range input.Body.DuplicateIDs {
+				dup, err := queries.GetTransactionByID(ctx, gensql.GetTransactionByIDParams{ID: id, UserID: user.ID})
+				if err != nil {
+					return err
+				}
+				duplicates = append(duplicates, dup)
+			}
+
+			if err := queries.MoveAttachments(ctx, gensql.MoveAttachmentsParams{
+				KeepID:       keep.ID,
+				UserID:       user.ID,
+				DuplicateIds: input.Body.DuplicateIDs,
+			}); err != nil {
+				return err
+			}
+
+			merged, err = queries.MergeTransactionDetails(ctx, mergeParams(keep, duplicates))
+			if err != nil {
+				return err
+			}
+
+			for _, dup := range duplicates {
+				if _, err := queries.DeleteTransaction(ctx, gensql.DeleteTransactionParams{ID: dup.ID, UserID: user.ID}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, huma.Error404NotFound("Transaction not found", err)
+		}
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to merge transactions", err)
+		}
+
+		return &MergeTransactionsResponse{Body: &merged}, nil
+	})
+}
+
+// mergeParams combines the tags and notes of keep and its duplicates and
+// picks the first bank reference and receipt found among the duplicates.
+func mergeParams(keep gensql.Transaction, duplicates []gensql.Transaction) gensql.MergeTransactionDetailsParams {
+	params := gensql.MergeTransactionDetailsParams{
+		ID:     keep.ID,
+		UserID: keep.UserID,
+		Tags:   append([]string{}, keep.Tags...),
+		Notes:  keep.Notes,
+	}
+
+	var notes []string
+	if keep.Notes != nil && strings.TrimSpace(*keep.Notes) != "" {
+		notes = append(notes, *keep.Notes)
+	}
+
+	for _, dup := range duplicates {
+		for _, tag := range dup.Tags {
+			if !slices.Contains(params.Tags, tag) {
+				params.Tags = append(params.Tags, tag)
+			}
+		}
+		if dup.Notes != nil && strings.TrimSpace(*dup.Notes) != "" && !slices.Contains(notes, *dup.Notes) {
+			notes = append(notes, *dup.Notes)
+		}
+		if params.ExternalID == nil {
+			params.ExternalID = dup.ExternalID
+		}
+		if dup.HasReceipt {
+			params.HasReceipt = true
+		}
+		if params.ReceiptUrl == nil {
+			params.ReceiptUrl = dup.ReceiptUrl
+		}
+	}
+
+	if len(notes) > 0 {
+		joined := strings.Join(notes, "\n\n")
+		params.Notes = &joined
+	}
+	return params
+}
@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"reflect"
+	"testing"
+
+	"budgetctl-go/internal/database/gensql"
+)
+
+func TestMergeParamsCombinesTagsAndNotes(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	keep := gensql.Transaction{ID: 1, UserID: 7, Tags: []string{"food"}, Notes: str("lunch")}
+	duplicates := []gensql.Transaction{
+		{ID: 2, Tags: []string{"food", "work"}, Notes: str("lunch"), ExternalID: str("FIT-1")},
+		{ID: 3, Tags: []string{"client"}, Notes: str("with Alex"), HasReceipt: true, ReceiptUrl: str("/r/3.jpg")},
+	}
+
+	params := mergeParams(keep, duplicates)
+
+	if params.ID != 1 || params.UserID != 7 {
+		t.Fatalf("unexpected target %d/%d", params.ID, params.UserID)
+	}
+	if !reflect.DeepEqual(params.Tags, []string{"food", "work", "client"}) {
+		t.Errorf("tags = %v", params.Tags)
+	}
+	if params.Notes == nil || *params.Notes != "lunch\n\nwith Alex" {
+		t.Errorf("notes = %v", params.Notes)
+	}
+	if params.ExternalID == nil || *params.ExternalID != "FIT-1" {
+		t.Errorf("external id = %v", params.ExternalID)
+	}
+	if !params.HasReceipt || params.ReceiptUrl == nil || *params.ReceiptUrl != "/r/3.jpg" {
+		t.Errorf("receipt = %v %v", params.HasReceipt, params.ReceiptUrl)
+	}
+	if len(keep.Tags) != 1 {
+		t.Errorf("kept transaction's tags were modified: %v", keep.Tags)
+	}
+}
@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// IfMatchInput makes a write conditional on the version of the resource it
+// changes.
+type IfMatchInput struct {
+	IfMatch []string `header:"If-Match" doc:"Only apply the change while the resource still has one of these ETags, as returned when reading it; * matches any version. A stale ETag fails with 412 Precondition Failed and the current resource."`
+}
+
+// Check fails with 412 Precondition Failed and the current transaction
+// unless the request is unconditional or one of its ETags matches.
+func (in IfMatchInput) Check(current gensql.Transaction) error {
+	if len(in.IfMatch) == 0 {
+		return nil
+	}
+	etag := transactionETag(current)
+	for _, match := range in.IfMatch {
+		// If-Match uses strong comparison, so weak ETags never match.
+		if match = strings.TrimSpace(match); match == "*" || match == etag {
+			return nil
+		}
+	}
+	return huma.ErrorWithHeaders(&preconditionFailedError{current: current}, http.Header{"ETag": {etag}})
+}
+
+// transactionETag returns the strong ETag of the current version of a
+// transaction.
+func transactionETag(t gensql.Transaction) string {
+	return `"` + strconv.FormatInt(t.Version, 10) + `"`
+}
+
+// preconditionFailedError answers a write with a stale If-Match. Its body is
+// the current representation of the resource rather than an error model, so
+// the client can merge its change and retry with the new ETag.
+type preconditionFailedError struct {
+	current any
+}
+
+func (e *preconditionFailedError) Error() string {
+	return http.StatusText(http.StatusPreconditionFailed)
+}
+
+func (e *preconditionFailedError) GetStatus() int {
+	return http.StatusPreconditionFailed
+}
+
+func (e *preconditionFailedError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.current)
+}
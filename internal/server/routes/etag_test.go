@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+)
+
+func TestIfMatch(t *testing.T) {
+	_, api := humatest.New(t)
+	current := gensql.Transaction{ID: 7, Description: "Rent", Version: 3}
+	huma.Patch(api, "/transactions/7", func(ctx context.Context, input *IfMatchInput) (*struct{}, error) {
+		return nil, input.Check(current)
+	})
+
+	for _, ifMatch := range []string{"", `"3"`, `"2", "3"`, "*"} {
+		var args []any
+		if ifMatch != "" {
+			args = append(args, "If-Match: "+ifMatch)
+		}
+		if resp := api.Patch("/transactions/7", args...); resp.Code != http.StatusNoContent {
+			t.Errorf("If-Match %s: status %d: %s", ifMatch, resp.Code, resp.Body.String())
+		}
+	}
+
+	for _, ifMatch := range []string{`"2"`, `W/"3"`} {
+		resp := api.Patch("/transactions/7", "If-Match: "+ifMatch)
+		if resp.Code != http.StatusPreconditionFailed {
+			t.Errorf("If-Match %s: status %d", ifMatch, resp.Code)
+			continue
+		}
+		if etag := resp.Header().Get("ETag"); etag != `"3"` {
+			t.Errorf("If-Match %s: ETag %s", ifMatch, etag)
+		}
+		if body := resp.Body.String(); !strings.Contains(body, `"Description":"Rent"`) || !strings.Contains(body, `"Version":3`) {
+			t.Errorf("If-Match %s: body %s", ifMatch, body)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/exporter"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const (
+	// exportFlushInterval is the number of rows written between flushes, so
+	// that clients see progress on long exports.
+	exportFlushInterval = 500
+	// exportWriteTimeout replaces the server's write timeout for exports,
+	// which can take longer than it. Each flush extends the deadline, so
+	// only a client that stops reading is cut off.
+	exportWriteTimeout = 30 * time.Second
+)
+
+type ExportTransactionsRequest struct {
+	TransactionFilterInput
+	Sort       []string `query:"sort" doc:"Comma-separated sort keys, as for listing transactions. Defaults to -relevance when searching, otherwise -date"`
+	Format     string   `query:"format" enum:"csv,xlsx,ndjson,beancount,hledger" default:"csv" doc:"Output format. Beancount and hledger journals are always in date order and contain every field they can represent"`
+	Columns    []string `query:"columns" doc:"Comma-separated columns to export, in order (id|date|description|amount|signed_amount|type|category|account|currency|status|tags|notes|has_receipt|receipt_url|external_id|import_batch_id|created_at|updated_at). Defaults to date,description,amount,type,category,account,currency,status,tags,notes"`
+	Locale     string   `query:"locale" doc:"BCP 47 locale for number and date formatting in CSV and XLSX, e.g. de-DE. Defaults to plain 1234.50 and YYYY-MM-DD"`
+	DateFormat string   `query:"date_format" doc:"Date format overriding the locale, e.g. DD.MM.YYYY"`
+	Delimiter  string   `query:"delimiter" doc:"CSV field separator. Defaults to a comma, or a semicolon for locales with a decimal comma"`
+}
+
+func RegisterExportRoutes(api huma.API, db database.Service) {
+	// Export Transactions
+	huma.Register(api, huma.Operation{
+		OperationID: "export-transactions",
+		Method:      http.MethodGet,
+		Path:        "/transactions/export",
+		Summary:     "Export Transactions",
+		Description: "Streams every transaction matching the filters as CSV, XLSX, NDJSON or a Beancount or hledger journal. Rows are written as they are read from the database.",
+		Tags:        []string{"Transactions"},
+	}, func(ctx context.Context, input *ExportTransactionsRequest) (*huma.StreamResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		sort, err := parseTransactionSort(input.Sort)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
+		params, err := input.ToFilterParams(user.ID)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
+		exp, err := exporter.New(input.Format, exporter.Options{
+			Columns:    input.Columns,
+			Locale:     input.Locale,
+			DateFormat: input.DateFormat,
+			Delimiter:  input.Delimiter,
+		})
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
+		params.Sort = sort
+		params.Limit = math.MaxInt32
+		params.Offset = 0
+		if exp.Chronological() {
+			params.Sort = []string{"date", "created_at"}
+		} else if params.Search != nil && len(params.Sort) == 0 {
+			params.Sort = []string{"-relevance"}
+		}
+
+		filename := fmt.Sprintf("transactions-%s.%s", time.Now().Format("20060102"), exp.Extension())
+
+		return &huma.StreamResponse{
+			Body: func(hctx huma.Context) {
+				hctx.SetHeader("Content-Type", exp.ContentType())
+				hctx.SetHeader("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+				body := hctx.BodyWriter()
+				var rc *http.ResponseController
+				if rw, ok := body.(http.ResponseWriter); ok {
+					rc = http.NewResponseController(rw)
+				}
+				extendDeadline := func() {
+					if rc == nil {
+						return
+					}
+					if err := rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil {
+						log.Printf("export: %v", err)
+					}
+				}
+				extendDeadline()
+
+				w, err := exp.Start(body)
+				if err != nil {
+					log.Printf("export: %v", err)
+					return
+				}
+
+				n := 0
+				err = db.StreamTransactionsWithFilters(hctx.Context(), params, func(row gensql.ListTransactionsWithFiltersRow) error {
+					if err := w.Write(&row); err != nil {
+						return err
+					}
+					n++
+					if rc != nil && n%exportFlushInterval == 0 {
+						if err := rc.Flush(); err != nil {
+							return err
+						}
+						extendDeadline()
+					}
+					return nil
+				})
+				if err != nil {
+					// The status line has already been sent, so the client
+					// only sees a truncated file.
+					log.Printf("export of user %d failed after %d rows: %v", user.ID, n, err)
+					return
+				}
+				if err := w.Close(); err != nil {
+					log.Printf("export: %v", err)
+				}
+			},
+		}, nil
+	})
+}
@@ -0,0 +1,157 @@
+package routes
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/txquery"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TransactionFilterInput holds the transaction filters shared by every
+// endpoint that selects transactions by query parameters.
+type TransactionFilterInput struct {
+	Q                 string    `query:"q" doc:"Structured query, e.g. category:food amount>20 tag:trip after:2025-01-01 -tag:work \"coffee shop\". Combined with the other filters"`
+	Search            string    `query:"search" doc:"Full-text search in description, notes, tags, category and account, tolerant of typos. Supports quoted phrases, OR and -exclusions"`
+	DateFrom          string    `query:"date_from" doc:"Filter by date from (YYYY-MM-DD)"`
+	DateTo            string    `query:"date_to" doc:"Filter by date to (YYYY-MM-DD)"`
+	Categories        []string  `query:"category" doc:"Filter by categories"`
+	ExcludeCategories []string  `query:"exclude_category" doc:"Exclude transactions with any line in these categories"`
+	Type              string    `query:"type" doc:"Filter by type (income|expense|transfer|all)"`
+	MinAmount         string    `query:"min_amount" doc:"Minimum amount filter, e.g. 20 or 12.50"`
+	MaxAmount         string    `query:"max_amount" doc:"Maximum amount filter, e.g. 20 or 12.50"`
+	Tags              []string  `query:"tag" doc:"Filter by tags"`
+	ExcludeTags       []string  `query:"exclude_tag" doc:"Exclude transactions with any line carrying one of these tags"`
+	Statuses          []string  `query:"status" doc:"Filter by statuses"`
+	Accounts          []string  `query:"account" doc:"Filter by accounts"`
+	Currencies        []string  `query:"currency" doc:"Filter by currencies"`
+	HasReceipt        string    `query:"has_receipt" enum:"true,false" doc:"Filter by whether a receipt is attached"`
+	CreatedSince      time.Time `query:"created_since" doc:"Only transactions created at or after this time (RFC 3339)"`
+	UpdatedSince      time.Time `query:"updated_since" doc:"Only transactions updated at or after this time (RFC 3339)"`
+}
+
+// ToFilterParams converts the filter input into list query parameters for the given user.
+// Sorting and pagination are left for the caller to fill in.
+func (f TransactionFilterInput) ToFilterParams(userID int64) (gensql.ListTransactionsWithFiltersParams, error) {
+	params := gensql.ListTransactionsWithFiltersParams{
+		UserID: userID,
+	}
+	if f.Search != "" {
+		params.Search = &f.Search
+	}
+
+	// Parse date filters
+	if f.DateFrom != "" {
+		if d, err := time.Parse("2006-01-02", f.DateFrom); err == nil {
+			params.DateFrom = pgtype.Date{Time: d, Valid: true}
+		}
+	}
+	if f.DateTo != "" {
+		if d, err := time.Parse("2006-01-02", f.DateTo); err == nil {
+			params.DateTo = pgtype.Date{Time: d, Valid: true}
+		}
+	}
+	if !f.CreatedSince.IsZero() {
+		params.CreatedSince = pgtype.Timestamptz{Time: f.CreatedSince, Valid: true}
+	}
+	if !f.UpdatedSince.IsZero() {
+		params.UpdatedSince = pgtype.Timestamptz{Time: f.UpdatedSince, Valid: true}
+	}
+
+	// Parse array filters
+	if len(f.Categories) > 0 {
+		params.Categories = f.Categories
+	}
+	if len(f.ExcludeCategories) > 0 {
+		params.ExcludeCategories = f.ExcludeCategories
+	}
+	if f.Type != "" && f.Type != "all" {
+		params.Type = &f.Type
+	}
+	if len(f.Tags) > 0 {
+		params.Tags = f.Tags
+	}
+	if len(f.ExcludeTags) > 0 {
+		params.ExcludeTags = f.ExcludeTags
+	}
+	if len(f.Statuses) > 0 {
+		params.Statuses = f.Statuses
+	}
+	if len(f.Accounts) > 0 {
+		params.Accounts = f.Accounts
+	}
+	if len(f.Currencies) > 0 {
+		params.Currencies = f.Currencies
+	}
+
+	if f.HasReceipt != "" {
+		hasReceipt, err := strconv.ParseBool(f.HasReceipt)
+		if err != nil {
+			return params, fmt.Errorf("invalid has_receipt value %q", f.HasReceipt)
+		}
+		params.HasReceipt = &hasReceipt
+	}
+
+	// Parse amount filters
+	if f.MinAmount != "" {
+		n, err := parseAmountFilter(f.MinAmount)
+		if err != nil {
+			return params, fmt.Errorf("invalid min_amount value %q", f.MinAmount)
+		}
+		params.MinAmount = n
+	}
+	if f.MaxAmount != "" {
+		n, err := parseAmountFilter(f.MaxAmount)
+		if err != nil {
+			return params, fmt.Errorf("invalid max_amount value %q", f.MaxAmount)
+		}
+		params.MaxAmount = n
+	}
+
+	// Structured query terms are applied last so they refine the plain filters
+	if f.Q != "" {
+		if err := txquery.Apply(f.Q, &params); err != nil {
+			return params, err
+		}
+	}
+
+	return params, nil
+}
+
+// parseAmountFilter parses a decimal amount such as "12.50" exactly.
+func parseAmountFilter(s string) (pgtype.Numeric, error) {
+	var n pgtype.Numeric
+	if err := n.Scan(s); err != nil {
+		return pgtype.Numeric{}, err
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		return pgtype.Numeric{}, fmt.Errorf("%q is not a finite number", s)
+	}
+	return n, nil
+}
+
+// countParamsFor returns the count query parameters matching a list query.
+func countParamsFor(params gensql.ListTransactionsWithFiltersParams) gensql.CountTransactionsParams {
+	return gensql.CountTransactionsParams{
+		UserID:            params.UserID,
+		Search:            params.Search,
+		DateFrom:          params.DateFrom,
+		DateTo:            params.DateTo,
+		Categories:        params.Categories,
+		Type:              params.Type,
+		MinAmount:         params.MinAmount,
+		MaxAmount:         params.MaxAmount,
+		Tags:              params.Tags,
+		ExcludeCategories: params.ExcludeCategories,
+		ExcludeTags:       params.ExcludeTags,
+		Statuses:          params.Statuses,
+		Accounts:          params.Accounts,
+		Currencies:        params.Currencies,
+		HasReceipt:        params.HasReceipt,
+		CreatedSince:      params.CreatedSince,
+		UpdatedSince:      params.UpdatedSince,
+	}
+}
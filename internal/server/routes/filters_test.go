@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"budgetctl-go/internal/numeric"
+)
+
+func TestToFilterParamsAmounts(t *testing.T) {
+	params, err := TransactionFilterInput{MinAmount: "12.50", MaxAmount: "300"}.ToFilterParams(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !params.MinAmount.Valid || numeric.Rat(params.MinAmount).Cmp(big.NewRat(25, 2)) != 0 {
+		t.Errorf("min amount %+v, want 12.50", params.MinAmount)
+	}
+	if !params.MaxAmount.Valid || numeric.Rat(params.MaxAmount).Cmp(big.NewRat(300, 1)) != 0 {
+		t.Errorf("max amount %+v, want 300", params.MaxAmount)
+	}
+
+	params, err = TransactionFilterInput{}.ToFilterParams(1)
+	if err != nil || params.MinAmount.Valid || params.MaxAmount.Valid {
+		t.Errorf("no amount filters: %+v, %+v, %v", params.MinAmount, params.MaxAmount, err)
+	}
+
+	for _, bad := range []TransactionFilterInput{{MinAmount: "abc"}, {MaxAmount: "1,5"}, {MinAmount: "NaN"}, {MaxAmount: "Infinity"}} {
+		if _, err := bad.ToFilterParams(1); err == nil {
+			t.Errorf("%+v: expected an error", bad)
+		}
+	}
+}
+
+func TestParseTransactionSort(t *testing.T) {
+	got, err := parseTransactionSort([]string{"-date", " +amount ", "", "description"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "-date,amount,description" {
+		t.Errorf("sort = %v", got)
+	}
+
+	for _, keys := range [][]string{{"--date"}, {"+-amount"}, {"-+amount"}, {"++date"}, {"payee"}, {"date", "-date"}} {
+		if _, err := parseTransactionSort(keys); err == nil {
+			t.Errorf("parseTransactionSort(%q) succeeded, want an error", keys)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header.
+const maxIdempotencyKeyLength = 255
+
+// Idempotency is an API middleware that makes POST requests sent with an
+// Idempotency-Key header safe to retry. The first request with a key runs
+// normally and its response is stored along with a hash of the request;
+// retries within window replay that response with an Idempotent-Replayed
+// header instead of running again. Reusing a key for a different request
+// fails with 422, and a retry while the first request is still running fails
+// with 409. Responses with a 5xx status are not stored, so that the request
+// can be retried.
+func Idempotency(api huma.API, db database.Service, window time.Duration) func(huma.Context, func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		key := ctx.Header("Idempotency-Key")
+		if ctx.Method() != http.MethodPost || key == "" {
+			next(ctx)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			huma.WriteErr(api, ctx, http.StatusBadRequest, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+			return
+		}
+
+		user, err := getUserFromContext(ctx.Context())
+		if err != nil {
+			huma.WriteErr(api, ctx, http.StatusUnauthorized, "Not authenticated", err)
+			return
+		}
+
+		rec, requestHash, err := recordRequest(ctx)
+		if err != nil {
+			huma.WriteErr(api, ctx, http.StatusBadRequest, "Failed to read request body", err)
+			return
+		}
+
+		// The response is stored even if the client goes away meanwhile.
+		dbCtx := context.WithoutCancel(ctx.Context())
+		queries := db.GetQueries()
+		_, err = queries.ClaimIdempotencyKey(dbCtx, gensql.ClaimIdempotencyKeyParams{
+			UserID:      user.ID,
+			Key:         key,
+			RequestHash: requestHash,
+			Cutoff:      pgtype.Timestamptz{Time: time.Now().Add(-window), Valid: true},
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			replayResponse(api, ctx, queries, user.ID, key, requestHash)
+			return
+		}
+		if err != nil {
+			huma.WriteErr(api, ctx, http.StatusInternalServerError, "Failed to store idempotency key", err)
+			return
+		}
+
+		next(rec)
+
+		if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+			if err := queries.ReleaseIdempotencyKey(dbCtx, gensql.ReleaseIdempotencyKeyParams{UserID: user.ID, Key: key}); err != nil {
+				log.Printf("release idempotency key: %v", err)
+			}
+			return
+		}
+		headers, err := json.Marshal(rec.headers)
+		if err == nil {
+			status := int32(rec.status)
+			err = queries.SaveIdempotentResponse(dbCtx, gensql.SaveIdempotentResponseParams{
+				UserID:  user.ID,
+				Key:     key,
+				Status:  &status,
+				Headers: headers,
+				Body:    rec.body.Bytes(),
+			})
+		}
+		if err != nil {
+			log.Printf("save idempotent response: %v", err)
+			if err := queries.ReleaseIdempotencyKey(dbCtx, gensql.ReleaseIdempotencyKeyParams{UserID: user.ID, Key: key}); err != nil {
+				log.Printf("release idempotency key: %v", err)
+			}
+		}
+	}
+}
+
+// replayResponse answers a request whose key was claimed before with the
+// stored response.
+func replayResponse(api huma.API, ctx huma.Context, queries *gensql.Queries, userID int64, key, requestHash string) {
+	stored, err := queries.GetIdempotencyKey(ctx.Context(), gensql.GetIdempotencyKeyParams{UserID: userID, Key: key})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// The first request failed and released the key just now.
+		huma.WriteErr(api, ctx, http.StatusConflict, "A request with this Idempotency-Key was still being processed; retry it")
+		return
+	case err != nil:
+		huma.WriteErr(api, ctx, http.StatusInternalServerError, "Failed to load idempotency key", err)
+		return
+	case stored.RequestHash != requestHash:
+		huma.WriteErr(api, ctx, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+		return
+	case stored.Status == nil:
+		huma.WriteErr(api, ctx, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+		return
+	}
+
+	var headers http.Header
+	if err := json.Unmarshal(stored.Headers, &headers); err != nil {
+		huma.WriteErr(api, ctx, http.StatusInternalServerError, "Failed to load stored response", err)
+		return
+	}
+	for name, values := range headers {
+		for _, v := range values {
+			ctx.AppendHeader(name, v)
+		}
+	}
+	ctx.SetHeader("Idempotent-Replayed", "true")
+	ctx.SetStatus(int(*stored.Status))
+	ctx.BodyWriter().Write(stored.Body)
+}
+
+// PurgeIdempotencyKeys deletes the keys of all users claimed longer than
+// window ago.
+func PurgeIdempotencyKeys(ctx context.Context, db database.Service, window time.Duration, now time.Time) (int64, error) {
+	return db.GetQueries().DeleteExpiredIdempotencyKeys(ctx, pgtype.Timestamptz{Time: now.Add(-window), Valid: true})
+}
+
+// humaContext lets recordingContext embed huma.Context, whose Context method
+// would clash with the field name.
+type humaContext = huma.Context
+
+// recordingContext passes a request on with its already read body and
+// records the response written for it.
+type recordingContext struct {
+	humaContext
+	// requestBody replaces the request body; nil for multipart forms, which
+	// are parsed before the body is hashed.
+	requestBody io.Reader
+	status      int
+	headers     http.Header
+	body        bytes.Buffer
+}
+
+func (c *recordingContext) Unwrap() huma.Context {
+	return c.humaContext
+}
+
+func (c *recordingContext) BodyReader() io.Reader {
+	if c.requestBody != nil {
+		return c.requestBody
+	}
+	return c.humaContext.BodyReader()
+}
+
+func (c *recordingContext) SetStatus(code int) {
+	c.status = code
+	c.humaContext.SetStatus(code)
+}
+
+func (c *recordingContext) SetHeader(name, value string) {
+	c.headers.Set(name, value)
+	c.humaContext.SetHeader(name, value)
+}
+
+func (c *recordingContext) AppendHeader(name, value string) {
+	c.headers.Add(name, value)
+	c.humaContext.AppendHeader(name, value)
+}
+
+func (c *recordingContext) BodyWriter() io.Writer {
+	return io.MultiWriter(c.humaContext.BodyWriter(), &c.body)
+}
+
+// recordRequest reads the request and returns a context that hands the body
+// on to the handler and records the response, along with a hash of the
+// method, URL and body.
+func recordRequest(ctx huma.Context) (*recordingContext, string, error) {
+	rec := &recordingContext{humaContext: ctx, headers: http.Header{}}
+	h := sha256.New()
+	u := ctx.URL()
+	fmt.Fprintf(h, "%s %s\n", ctx.Method(), u.RequestURI())
+
+	if strings.HasPrefix(ctx.Header("Content-Type"), "multipart/form-data") {
+		if err := hashMultipartForm(ctx, h); err != nil {
+			return nil, "", err
+		}
+		return rec, hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	// Bodies over the operation's limit are passed on to be rejected.
+	r := ctx.BodyReader()
+	if limit := ctx.Operation().MaxBodyBytes; limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	h.Write(body)
+	rec.requestBody = bytes.NewReader(body)
+	return rec, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashMultipartForm adds the fields and files of a multipart form to h. The
+// parsed form is kept for the handler.
+func hashMultipartForm(ctx huma.Context, h hash.Hash) error {
+	form, err := ctx.GetMultipartForm()
+	if err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(form.Value)) {
+		fmt.Fprintf(h, "%q=%q\n", name, form.Value[name])
+	}
+	for _, name := range slices.Sorted(maps.Keys(form.File)) {
+		for _, fh := range form.File[name] {
+			fmt.Fprintf(h, "%q=%q %d\n", name, fh.Filename, fh.Size)
+			f, err := fh.Open()
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
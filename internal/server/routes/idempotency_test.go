@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+)
+
+func TestRecordRequest(t *testing.T) {
+	_, api := humatest.New(t)
+	var rec *recordingContext
+	var hashes []string
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		var requestHash string
+		var err error
+		rec, requestHash, err = recordRequest(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, requestHash)
+		next(rec)
+	})
+
+	type echoInput struct {
+		Body struct {
+			Description string `json:"description"`
+		}
+	}
+	type echoOutput struct {
+		Location string `header:"Location"`
+		Body     struct {
+			Description string `json:"description"`
+		}
+	}
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		Path:          "/transactions",
+		DefaultStatus: http.StatusCreated,
+	}, func(ctx context.Context, input *echoInput) (*echoOutput, error) {
+		out := &echoOutput{Location: "/transactions/1"}
+		out.Body.Description = input.Body.Description
+		return out, nil
+	})
+
+	resp := api.Post("/transactions", map[string]any{"description": "Rent"})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", resp.Code, resp.Body.String())
+	}
+	if rec.status != http.StatusCreated {
+		t.Errorf("recorded status %d", rec.status)
+	}
+	if loc := rec.headers.Get("Location"); loc != "/transactions/1" {
+		t.Errorf("recorded Location %q", loc)
+	}
+	if got, want := rec.body.String(), resp.Body.String(); got != want {
+		t.Errorf("recorded body %q, want %q", got, want)
+	}
+
+	api.Post("/transactions", map[string]any{"description": "Rent"})
+	api.Post("/transactions", map[string]any{"description": "Groceries"})
+	api.Post("/transactions?dry_run=true", map[string]any{"description": "Rent"})
+	if hashes[0] != hashes[1] {
+		t.Error("same request hashed differently")
+	}
+	if hashes[0] == hashes[2] {
+		t.Error("different body hashed the same")
+	}
+	if hashes[0] == hashes[3] {
+		t.Error("different URL hashed the same")
+	}
+}
@@ -0,0 +1,688 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/importer"
+	"budgetctl-go/internal/numeric"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxImportFileBytes limits the size of uploaded statement files.
+const maxImportFileBytes = 10 << 20
+
+// ImportQuery holds the query parameters shared by every import endpoint.
+type ImportQuery struct {
+	Preview     bool `query:"preview" doc:"Parse and validate the file without saving anything"`
+	SkipInvalid bool `query:"skip_invalid" doc:"Import the valid rows even if some rows have errors"`
+}
+
+type ImportResult struct {
+	Preview            bool           `json:"preview"`
+	BatchID            *int64         `json:"batch_id,omitempty" doc:"Import batch the committed rows are tagged with"`
+	Total              int            `json:"total"`
+	Valid              int            `json:"valid"`
+	Invalid            int            `json:"invalid"`
+	Imported           int            `json:"imported"`
+	Duplicates         int            `json:"duplicates" doc:"Exact duplicates of existing transactions, which are skipped"`
+	PossibleDuplicates int            `json:"possible_duplicates" doc:"Imported rows that resemble an existing transaction"`
+	AutoCategorized    int            `json:"auto_categorized" doc:"Uncategorized rows given a category learned from earlier transactions"`
+	NeedsReview        int            `json:"needs_review" doc:"Uncategorized rows whose suggested category was not filled in, because it was not confident enough or the merchant is new"`
+	Rows               []importer.Row `json:"rows"`
+}
+
+type ImportResponse struct {
+	Body *ImportResult
+}
+
+type CSVImportRequest struct {
+	ImportQuery
+	RawBody huma.MultipartFormFiles[struct {
+		File    huma.FormFile `form:"file" contentType:"text/csv,text/plain,application/octet-stream" required:"true" doc:"CSV file"`
+		Bank    string        `form:"bank" doc:"Use the column mapping saved for this bank"`
+		Options string        `form:"options" doc:"CSV options as JSON; takes precedence over the saved mapping"`
+	}]
+}
+
+type OFXImportRequest struct {
+	ImportQuery
+	RawBody huma.MultipartFormFiles[struct {
+		File huma.FormFile `form:"file" contentType:"application/x-ofx,application/vnd.intu.qfx,application/xml,text/plain,application/octet-stream" required:"true" doc:"OFX or QFX statement"`
+	}]
+}
+
+type QIFImportRequest struct {
+	ImportQuery
+	RawBody huma.MultipartFormFiles[struct {
+		File       huma.FormFile `form:"file" contentType:"application/qif,application/x-qif,text/plain,application/octet-stream" required:"true" doc:"QIF file"`
+		DateFormat string        `form:"date_format" doc:"Date format such as DD/MM/YYYY. Defaults to M/D/YYYY"`
+		Encoding   string        `form:"encoding" doc:"Text encoding, defaults to utf-8"`
+		Currency   string        `form:"currency" doc:"Currency of the imported transactions"`
+		Account    string        `form:"account" doc:"Account for transactions outside an !Account block"`
+	}]
+}
+
+type CAMT053ImportRequest struct {
+	ImportQuery
+	RawBody huma.MultipartFormFiles[struct {
+		File huma.FormFile `form:"file" contentType:"application/xml,text/xml,application/octet-stream" required:"true" doc:"camt.053 XML statement"`
+	}]
+}
+
+type MT940ImportRequest struct {
+	ImportQuery
+	RawBody huma.MultipartFormFiles[struct {
+		File     huma.FormFile `form:"file" contentType:"text/plain,application/octet-stream" required:"true" doc:"MT940 statement"`
+		Encoding string        `form:"encoding" doc:"Text encoding, defaults to utf-8. Many banks use iso-8859-1"`
+	}]
+}
+
+type LedgerImportRequest struct {
+	ImportQuery
+	RawBody huma.MultipartFormFiles[struct {
+		File huma.FormFile `form:"file" contentType:"text/plain,application/octet-stream" required:"true" doc:"Journal file"`
+	}]
+}
+
+type CSVMapping struct {
+	Bank      string              `json:"bank"`
+	Options   importer.CSVOptions `json:"options"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+type CSVMappingRequest struct {
+	Bank string `path:"bank" doc:"Bank name"`
+}
+
+type PutCSVMappingRequest struct {
+	Bank string `path:"bank" doc:"Bank name"`
+	Body importer.CSVOptions
+}
+
+type CSVMappingResponse struct {
+	Body *CSVMapping
+}
+
+type ListCSVMappingsResponse struct {
+	Body []CSVMapping
+}
+
+func RegisterImportRoutes(api huma.API, db database.Service) {
+	// Import CSV
+	huma.Register(api, huma.Operation{
+		OperationID:  "import-csv",
+		Method:       http.MethodPost,
+		Path:         "/imports/csv",
+		Summary:      "Import CSV",
+		Description:  "Parses a CSV export using inline options or a saved bank mapping. With preview=true the parsed rows and their validation errors are returned without saving.",
+		Tags:         []string{"Imports"},
+		MaxBodyBytes: maxImportFileBytes,
+	}, func(ctx context.Context, input *CSVImportRequest) (*ImportResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		form := input.RawBody.Data()
+		var opts importer.CSVOptions
+		switch {
+		case form.Options != "":
+			if err := json.Unmarshal([]byte(form.Options), &opts); err != nil {
+				return nil, huma.Error400BadRequest("Invalid CSV options", err)
+			}
+		case form.Bank != "":
+			mapping, err := db.GetQueries().GetImportMapping(ctx, gensql.GetImportMappingParams{
+				UserID: user.ID,
+				Bank:   form.Bank,
+			})
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return nil, huma.Error404NotFound("No saved mapping for bank " + form.Bank)
+				}
+				return nil, huma.Error500InternalServerError("Failed to load mapping", err)
+			}
+			if err := json.Unmarshal(mapping.Options, &opts); err != nil {
+				return nil, huma.Error500InternalServerError("Saved mapping is corrupt", err)
+			}
+		default:
+			return nil, huma.Error400BadRequest("Either options or bank is required")
+		}
+
+		rows, err := importer.ParseCSV(form.File, opts)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity("Failed to parse CSV", err)
+		}
+
+		return runImport(ctx, db, user.ID, "csv", form.File.Filename, rows, input.ImportQuery)
+	})
+
+	// Import OFX
+	huma.Register(api, huma.Operation{
+		OperationID:  "import-ofx",
+		Method:       http.MethodPost,
+		Path:         "/imports/ofx",
+		Summary:      "Import OFX",
+		Description:  "Imports an OFX 1.x, OFX 2.x or QFX bank or credit card statement. The bank's FITID is kept as the transaction's external ID.",
+		Tags:         []string{"Imports"},
+		MaxBodyBytes: maxImportFileBytes,
+	}, func(ctx context.Context, input *OFXImportRequest) (*ImportResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		form := input.RawBody.Data()
+		rows, err := importer.ParseOFX(form.File)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity("Failed to parse OFX", err)
+		}
+
+		return runImport(ctx, db, user.ID, "ofx", form.File.Filename, rows, input.ImportQuery)
+	})
+
+	// Import QIF
+	huma.Register(api, huma.Operation{
+		OperationID:  "import-qif",
+		Method:       http.MethodPost,
+		Path:         "/imports/qif",
+		Summary:      "Import QIF",
+		Description:  "Imports the bank, credit card and cash sections of a QIF export. Subcategories and classes become tags and split lines are kept in the notes.",
+		Tags:         []string{"Imports"},
+		MaxBodyBytes: maxImportFileBytes,
+	}, func(ctx context.Context, input *QIFImportRequest) (*ImportResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		form := input.RawBody.Data()
+		rows, err := importer.ParseQIF(form.File, importer.QIFOptions{
+			DateFormat:      form.DateFormat,
+			Encoding:        form.Encoding,
+			DefaultCurrency: form.Currency,
+			DefaultAccount:  form.Account,
+		})
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity("Failed to parse QIF", err)
+		}
+
+		return runImport(ctx, db, user.ID, "qif", form.File.Filename, rows, input.ImportQuery)
+	})
+
+	// Import camt.053
+	huma.Register(api, huma.Operation{
+		OperationID:  "import-camt053",
+		Method:       http.MethodPost,
+		Path:         "/imports/camt053",
+		Summary:      "Import camt.053",
+		Description:  "Imports an ISO 20022 camt.053 bank statement. The counterparty becomes the description and the bank's entry reference is kept as the external ID.",
+		Tags:         []string{"Imports"},
+		MaxBodyBytes: maxImportFileBytes,
+	}, func(ctx context.Context, input *CAMT053ImportRequest) (*ImportResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		form := input.RawBody.Data()
+		rows, err := importer.ParseCAMT053(form.File)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity("Failed to parse camt.053", err)
+		}
+
+		return runImport(ctx, db, user.ID, "camt053", form.File.Filename, rows, input.ImportQuery)
+	})
+
+	// Import MT940
+	huma.Register(api, huma.Operation{
+		OperationID:  "import-mt940",
+		Method:       http.MethodPost,
+		Path:         "/imports/mt940",
+		Summary:      "Import MT940",
+		Description:  "Imports a SWIFT MT940 statement. The :86: information supplies the counterparty and remittance text and the bank reference is kept as the external ID.",
+		Tags:         []string{"Imports"},
+		MaxBodyBytes: maxImportFileBytes,
+	}, func(ctx context.Context, input *MT940ImportRequest) (*ImportResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		form := input.RawBody.Data()
+		rows, err := importer.ParseMT940(form.File, form.Encoding)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity("Failed to parse MT940", err)
+		}
+
+		return runImport(ctx, db, user.ID, "mt940", form.File.Filename, rows, input.ImportQuery)
+	})
+
+	// Import Beancount
+	huma.Register(api, huma.Operation{
+		OperationID:  "import-beancount",
+		Method:       http.MethodPost,
+		Path:         "/imports/beancount",
+		Summary:      "Import Beancount",
+		Description:  "Imports the transactions of a Beancount ledger. The Assets or Liabilities posting gives the account and the other posting the category; names, notes, status, tags and external IDs written by the Beancount export are restored.",
+		Tags:         []string{"Imports"},
+		MaxBodyBytes: maxImportFileBytes,
+	}, func(ctx context.Context, input *LedgerImportRequest) (*ImportResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		form := input.RawBody.Data()
+		rows, err := importer.ParseBeancount(form.File)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity("Failed to parse Beancount ledger", err)
+		}
+
+		return runImport(ctx, db, user.ID, "beancount", form.File.Filename, rows, input.ImportQuery)
+	})
+
+	// Import hledger
+	huma.Register(api, huma.Operation{
+		OperationID:  "import-hledger",
+		Method:       http.MethodPost,
+		Path:         "/imports/hledger",
+		Summary:      "Import hledger",
+		Description:  "Imports the transactions of an hledger or Ledger journal. The assets or liabilities posting gives the account and the other posting the category; names, notes, status, tags and external IDs written by the hledger export are restored.",
+		Tags:         []string{"Imports"},
+		MaxBodyBytes: maxImportFileBytes,
+	}, func(ctx context.Context, input *LedgerImportRequest) (*ImportResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		form := input.RawBody.Data()
+		rows, err := importer.ParseHledger(form.File)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity("Failed to parse hledger journal", err)
+		}
+
+		return runImport(ctx, db, user.ID, "hledger", form.File.Filename, rows, input.ImportQuery)
+	})
+
+	// List CSV Mappings
+	huma.Register(api, huma.Operation{
+		OperationID: "list-csv-mappings",
+		Method:      http.MethodGet,
+		Path:        "/imports/csv/mappings",
+		Summary:     "List CSV Mappings",
+		Tags:        []string{"Imports"},
+	}, func(ctx context.Context, input *struct{}) (*ListCSVMappingsResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		mappings, err := db.GetQueries().ListImportMappings(ctx, user.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch mappings", err)
+		}
+
+		body := []CSVMapping{}
+		for _, m := range mappings {
+			mapping, err := toCSVMapping(m)
+			if err != nil {
+				return nil, huma.Error500InternalServerError("Saved mapping is corrupt", err)
+			}
+			body = append(body, *mapping)
+		}
+
+		return &ListCSVMappingsResponse{Body: body}, nil
+	})
+
+	// Get CSV Mapping
+	huma.Register(api, huma.Operation{
+		OperationID: "get-csv-mapping",
+		Method:      http.MethodGet,
+		Path:        "/imports/csv/mappings/{bank}",
+		Summary:     "Get CSV Mapping",
+		Tags:        []string{"Imports"},
+	}, func(ctx context.Context, input *CSVMappingRequest) (*CSVMappingResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := db.GetQueries().GetImportMapping(ctx, gensql.GetImportMappingParams{
+			UserID: user.ID,
+			Bank:   input.Bank,
+		})
+		if err != nil {
+			return nil, huma.Error404NotFound("Mapping not found", err)
+		}
+
+		mapping, err := toCSVMapping(m)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Saved mapping is corrupt", err)
+		}
+		return &CSVMappingResponse{Body: mapping}, nil
+	})
+
+	// Save CSV Mapping
+	huma.Register(api, huma.Operation{
+		OperationID: "put-csv-mapping",
+		Method:      http.MethodPut,
+		Path:        "/imports/csv/mappings/{bank}",
+		Summary:     "Save CSV Mapping",
+		Tags:        []string{"Imports"},
+	}, func(ctx context.Context, input *PutCSVMappingRequest) (*CSVMappingResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		options, err := json.Marshal(input.Body)
+		if err != nil {
+			return nil, huma.Error400BadRequest("Invalid CSV options", err)
+		}
+
+		m, err := db.GetQueries().UpsertImportMapping(ctx, gensql.UpsertImportMappingParams{
+			UserID:  user.ID,
+			Bank:    input.Bank,
+			Options: options,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to save mapping", err)
+		}
+
+		mapping, err := toCSVMapping(m)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Saved mapping is corrupt", err)
+		}
+		return &CSVMappingResponse{Body: mapping}, nil
+	})
+
+	// Delete CSV Mapping
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-csv-mapping",
+		Method:      http.MethodDelete,
+		Path:        "/imports/csv/mappings/{bank}",
+		Summary:     "Delete CSV Mapping",
+		Tags:        []string{"Imports"},
+	}, func(ctx context.Context, input *CSVMappingRequest) (*struct{}, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		err = db.GetQueries().DeleteImportMapping(ctx, gensql.DeleteImportMappingParams{
+			UserID: user.ID,
+			Bank:   input.Bank,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to delete mapping", err)
+		}
+
+		return nil, nil
+	})
+}
+
+// runImport summarises parsed rows, marks duplicates of existing transactions
+// and, unless previewing, inserts the valid non-duplicate rows in a single
+// database transaction tagged with a new import batch.
+func runImport(ctx context.Context, db database.Service, userID int64, source, filename string, rows []importer.Row, query ImportQuery) (*ImportResponse, error) {
+	result := &ImportResult{
+		Preview: query.Preview,
+		Total:   len(rows),
+		Rows:    rows,
+	}
+	for _, row := range rows {
+		if row.Valid() {
+			result.Valid++
+		} else {
+			result.Invalid++
+		}
+	}
+
+	// Rules run before the preview so that it shows the values to be saved.
+	// Transfer legs are saved as they were exported.
+	var valid []*gensql.CreateTransactionParams
+	for i := range rows {
+		if rows[i].Valid() {
+			applyCreateDefaults(&rows[i].Transaction, userID)
+			if rows[i].Transfer == "" {
+				valid = append(valid, &rows[i].Transaction)
+			}
+		}
+	}
+	if err := applyRulesOnCreate(ctx, db.GetQueries(), userID, valid...); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to apply rules", err)
+	}
+	// Payees known before the import categorize rows too; new ones are only
+	// created when the rows are saved.
+	preview, err := newPayeeLinker(ctx, db.GetQueries(), userID, false)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch payees", err)
+	}
+	for _, params := range valid {
+		if err := preview.link(ctx, params); err != nil {
+			return nil, huma.Error500InternalServerError("Failed to link payees", err)
+		}
+	}
+	// Rows no rule or payee categorized get a category learned from earlier
+	// ones.
+	if err := suggestImportCategories(ctx, db.GetQueries(), userID, rows, result); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to suggest categories", err)
+	}
+
+	if query.Preview {
+		if err := markDuplicates(ctx, db.GetQueries(), userID, rows, result); err != nil {
+			return nil, huma.Error500InternalServerError("Failed to check for duplicates", err)
+		}
+		return &ImportResponse{Body: result}, nil
+	}
+	if result.Invalid > 0 && !query.SkipInvalid {
+		return nil, huma.Error422UnprocessableEntity("File contains invalid rows; fix them or retry with skip_invalid=true")
+	}
+	if result.Valid == 0 {
+		return nil, huma.Error422UnprocessableEntity("File contains no valid rows")
+	}
+
+	err = db.WithTx(ctx, func(tx pgx.Tx) error {
+		queries := db.GetQueries().WithTx(tx)
+		if err := queries.SetRevisionActorID(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := markDuplicates(ctx, queries, userID, rows, result); err != nil {
+			return err
+		}
+		if result.Valid == result.Duplicates {
+			return nil
+		}
+
+		batch, err := queries.CreateImportBatch(ctx, gensql.CreateImportBatchParams{
+			UserID:   userID,
+			Source:   source,
+			Filename: filename,
+			RowCount: int32(result.Valid - result.Duplicates),
+		})
+		if err != nil {
+			return err
+		}
+		result.BatchID = &batch.ID
+
+		payees, err := newPayeeLinker(ctx, queries, userID, true)
+		if err != nil {
+			return err
+		}
+		for i := range rows {
+			if !rows[i].Valid() || rows[i].Duplicate == importer.DuplicateExact || rows[i].Transfer != "" {
+				continue
+			}
+
+			params := rows[i].Transaction
+			applyCreateDefaults(&params, userID)
+			params.ImportBatchID = &batch.ID
+			if err := payees.link(ctx, &params); err != nil {
+				return err
+			}
+			if _, err := queries.CreateTransaction(ctx, params); err != nil {
+				return err
+			}
+			result.Imported++
+		}
+		return importTransfers(ctx, queries, userID, batch.ID, rows, result)
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to import transactions", err)
+	}
+
+	return &ImportResponse{Body: result}, nil
+}
+
+// importTransfers saves each pair of valid transfer legs in rows as a new
+// transfer. A transfer with an already imported leg is skipped whole.
+func importTransfers(ctx context.Context, queries *gensql.Queries, userID, batchID int64, rows []importer.Row, result *ImportResult) error {
+	outgoing := map[string]*importer.Row{}
+	for i := range rows {
+		if rows[i].Valid() && rows[i].TransferDirection == "out" {
+			outgoing[rows[i].Transfer] = &rows[i]
+		}
+	}
+	for i := range rows {
+		to := &rows[i]
+		from, ok := outgoing[to.Transfer]
+		if !to.Valid() || to.TransferDirection != "in" || !ok {
+			continue
+		}
+		if from.Duplicate == importer.DuplicateExact || to.Duplicate == importer.DuplicateExact {
+			continue
+		}
+
+		_, rate, err := transferAmounts(numeric.Rat(from.Transaction.Amount), from.Transaction.Currency == to.Transaction.Currency, &Decimal{to.Transaction.Amount}, nil)
+		if err != nil {
+			return err
+		}
+		transfer, err := queries.CreateTransfer(ctx, gensql.CreateTransferParams{UserID: userID, Rate: rate})
+		if err != nil {
+			return err
+		}
+		for _, leg := range []*importer.Row{from, to} {
+			t := leg.Transaction
+			if _, err := queries.CreateTransferLeg(ctx, gensql.CreateTransferLegParams{
+				UserID:            userID,
+				TransferID:        &transfer.ID,
+				TransferDirection: &leg.TransferDirection,
+				Amount:            t.Amount,
+				Description:       t.Description,
+				Category:          t.Category,
+				Currency:          t.Currency,
+				Status:            t.Status,
+				Account:           t.Account,
+				Tags:              t.Tags,
+				Notes:             t.Notes,
+				Date:              t.Date,
+				ImportBatchID:     &batchID,
+				ExternalID:        t.ExternalID,
+			}); err != nil {
+				return err
+			}
+			result.Imported++
+		}
+	}
+	return nil
+}
+
+// markDuplicates compares the valid rows with the user's transactions. A row
+// is an exact duplicate when its bank reference is already known or when an
+// existing transaction has the same fingerprint; rows repeated within the file
+// only count as duplicates as often as they already exist. Rows resembling an
+// existing transaction are flagged as possible duplicates.
+func markDuplicates(ctx context.Context, queries *gensql.Queries, userID int64, rows []importer.Row, result *ImportResult) error {
+	seenReferences := map[string]bool{}
+	seenFingerprints := map[string]int64{}
+
+	for i := range rows {
+		row := &rows[i]
+		if !row.Valid() {
+			continue
+		}
+		t := row.Transaction
+
+		if t.ExternalID != nil {
+			key := t.Account + "\x00" + *t.ExternalID
+			if seenReferences[key] {
+				row.Duplicate = importer.DuplicateExact
+				result.Duplicates++
+				continue
+			}
+			seenReferences[key] = true
+
+			id, err := queries.FindTransactionByExternalID(ctx, gensql.FindTransactionByExternalIDParams{
+				UserID:     userID,
+				Account:    t.Account,
+				ExternalID: t.ExternalID,
+			})
+			if err == nil {
+				row.Duplicate, row.DuplicateOf = importer.DuplicateExact, &id
+				result.Duplicates++
+				continue
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return err
+			}
+		}
+
+		match, err := queries.MatchFingerprint(ctx, gensql.MatchFingerprintParams{
+			Date:        t.Date,
+			Amount:      t.Amount,
+			Type:        t.Type,
+			Description: t.Description,
+			Account:     t.Account,
+			UserID:      userID,
+			ExternalID:  t.ExternalID,
+		})
+		if err != nil {
+			return err
+		}
+		seenFingerprints[match.Fingerprint]++
+		if seenFingerprints[match.Fingerprint] <= match.Existing {
+			row.Duplicate, row.DuplicateOf = importer.DuplicateExact, &match.FirstID
+			result.Duplicates++
+			continue
+		}
+
+		id, err := queries.FindNearDuplicate(ctx, gensql.FindNearDuplicateParams{
+			UserID:      userID,
+			Account:     t.Account,
+			Type:        t.Type,
+			Amount:      t.Amount,
+			Date:        t.Date,
+			ExternalID:  t.ExternalID,
+			Description: t.Description,
+		})
+		switch {
+		case err == nil:
+			row.Duplicate, row.DuplicateOf = importer.DuplicatePossible, &id
+			result.PossibleDuplicates++
+		case !errors.Is(err, pgx.ErrNoRows):
+			return err
+		}
+	}
+	return nil
+}
+
+func toCSVMapping(m gensql.ImportMapping) (*CSVMapping, error) {
+	mapping := &CSVMapping{
+		Bank:      m.Bank,
+		UpdatedAt: m.UpdatedAt.Time,
+	}
+	if err := json.Unmarshal(m.Options, &mapping.Options); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
@@ -0,0 +1,624 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/importer"
+	"budgetctl-go/internal/payee"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+type PayeeInput struct {
+	Name            string   `json:"name" minLength:"1"`
+	Aliases         []string `json:"aliases,omitempty" doc:"Other names of the payee; descriptions are compared with them after normalization"`
+	Patterns        []string `json:"patterns,omitempty" doc:"RE2 regular expressions matched anywhere in raw descriptions, e.g. (?i)^amzn"`
+	DefaultCategory *string  `json:"default_category,omitempty" minLength:"1" doc:"Category given to uncategorized transactions linked to the payee"`
+}
+
+type PayeeRequest struct {
+	ID int64 `path:"id" doc:"Payee ID"`
+}
+
+type ListPayeesRequest struct {
+	PaginationInput
+}
+
+type ListPayeesResponse struct {
+	Body *PaginatedResponse[gensql.Payee]
+}
+
+type CreatePayeeRequest struct {
+	Body PayeeInput
+}
+
+type UpdatePayeeRequest struct {
+	ID   int64 `path:"id" doc:"Payee ID"`
+	Body PayeeInput
+}
+
+type PayeeResponse struct {
+	Body *gensql.Payee
+}
+
+type MergePayeesRequest struct {
+	ID   int64 `path:"id" doc:"Payee to merge into"`
+	Body struct {
+		PayeeIDs []int64 `json:"payee_ids" minItems:"1" doc:"Payees to merge; they are deleted"`
+	}
+}
+
+type MergePayeesResponse struct {
+	Body struct {
+		Payee        gensql.Payee `json:"payee"`
+		Merged       int          `json:"merged" doc:"Payees merged into this one"`
+		Transactions int64        `json:"transactions" doc:"Transactions moved to this payee"`
+	}
+}
+
+type SetPayeeDefaultCategoryRequest struct {
+	ID   int64 `path:"id" doc:"Payee ID"`
+	Body struct {
+		Category        *string `json:"category" minLength:"1" doc:"Default category, or null to remove it"`
+		ApplyToExisting bool    `json:"apply_to_existing,omitempty" doc:"Also categorize the payee's uncategorized transactions"`
+	}
+}
+
+type SetPayeeDefaultCategoryResponse struct {
+	Body struct {
+		Payee   gensql.Payee `json:"payee"`
+		Updated int64        `json:"updated" doc:"Existing transactions given the category"`
+	}
+}
+
+type LinkPayeesRequest struct {
+	After int64 `query:"after" doc:"Only link transactions with a greater ID, e.g. the last_id of the previous call"`
+}
+
+type LinkPayeesResponse struct {
+	Body struct {
+		Scanned   int   `json:"scanned" doc:"Unlinked transactions looked at"`
+		Linked    int   `json:"linked"`
+		LastID    int64 `json:"last_id" doc:"ID of the last transaction looked at; pass it as after to continue"`
+		Remaining int64 `json:"remaining" doc:"Unlinked transactions after last_id"`
+	}
+}
+
+func RegisterPayeeRoutes(api huma.API, db database.Service) {
+	// List Payees
+	huma.Register(api, huma.Operation{
+		OperationID: "list-payees",
+		Method:      http.MethodGet,
+		Path:        "/payees",
+		Summary:     "List Payees",
+		Description: "Lists payees by name.",
+		Tags:        []string{"Payees"},
+	}, func(ctx context.Context, input *ListPayeesRequest) (*ListPayeesResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		limit, offset := input.ToLimitOffset()
+		payees, err := queries.ListPayees(ctx, gensql.ListPayeesParams{
+			UserID: user.ID,
+			Limit:  limit,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch payees", err)
+		}
+		total, err := queries.CountPayees(ctx, user.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to count payees", err)
+		}
+
+		return &ListPayeesResponse{
+			Body: NewPaginatedResponse(payees, total, input.Page, input.PerPage),
+		}, nil
+	})
+
+	// Create Payee
+	huma.Register(api, huma.Operation{
+		OperationID: "create-payee",
+		Method:      http.MethodPost,
+		Path:        "/payees",
+		Summary:     "Create Payee",
+		Description: "Creates a payee. Payees are also created automatically, named after the normalized description, for created and imported transactions no payee matches.",
+		Tags:        []string{"Payees"},
+	}, func(ctx context.Context, input *CreatePayeeRequest) (*PayeeResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := input.Body.normalize()
+		if err != nil {
+			return nil, err
+		}
+
+		created, err := db.GetQueries().CreatePayee(ctx, gensql.CreatePayeeParams{
+			UserID:          user.ID,
+			Name:            p.Name,
+			Aliases:         p.Aliases,
+			Patterns:        p.Patterns,
+			DefaultCategory: p.DefaultCategory,
+		})
+		if err != nil {
+			if _, ok := pgError(err, pgUniqueViolation); ok {
+				return nil, huma.Error409Conflict("A payee with this name already exists")
+			}
+			return nil, huma.Error500InternalServerError("Failed to create payee", err)
+		}
+		return &PayeeResponse{Body: &created}, nil
+	})
+
+	// Get Payee
+	huma.Register(api, huma.Operation{
+		OperationID: "get-payee",
+		Method:      http.MethodGet,
+		Path:        "/payees/{id}",
+		Summary:     "Get Payee",
+		Tags:        []string{"Payees"},
+	}, func(ctx context.Context, input *PayeeRequest) (*PayeeResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := db.GetQueries().GetPayee(ctx, gensql.GetPayeeParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error404NotFound("Payee not found", err)
+		}
+		return &PayeeResponse{Body: &p}, nil
+	})
+
+	// Update Payee
+	huma.Register(api, huma.Operation{
+		OperationID: "update-payee",
+		Method:      http.MethodPut,
+		Path:        "/payees/{id}",
+		Summary:     "Update Payee",
+		Description: "Replaces the payee. Transactions already linked to it stay linked.",
+		Tags:        []string{"Payees"},
+	}, func(ctx context.Context, input *UpdatePayeeRequest) (*PayeeResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := input.Body.normalize()
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := db.GetQueries().UpdatePayee(ctx, gensql.UpdatePayeeParams{
+			ID:              input.ID,
+			UserID:          user.ID,
+			Name:            p.Name,
+			Aliases:         p.Aliases,
+			Patterns:        p.Patterns,
+			DefaultCategory: p.DefaultCategory,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, huma.Error404NotFound("Payee not found", err)
+			}
+			if _, ok := pgError(err, pgUniqueViolation); ok {
+				return nil, huma.Error409Conflict("A payee with this name already exists")
+			}
+			return nil, huma.Error500InternalServerError("Failed to update payee", err)
+		}
+		return &PayeeResponse{Body: &updated}, nil
+	})
+
+	// Delete Payee
+	huma.Register(api, huma.Operation{
+		OperationID:   "delete-payee",
+		Method:        http.MethodDelete,
+		Path:          "/payees/{id}",
+		Summary:       "Delete Payee",
+		Description:   "Deletes the payee. Its transactions are unlinked; merge it into another payee to keep them linked.",
+		Tags:          []string{"Payees"},
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *PayeeRequest) (*struct{}, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		deleted, err := db.GetQueries().DeletePayee(ctx, gensql.DeletePayeeParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to delete payee", err)
+		}
+		if deleted == 0 {
+			return nil, huma.Error404NotFound("Payee not found")
+		}
+		return nil, nil
+	})
+
+	// Merge Payees
+	huma.Register(api, huma.Operation{
+		OperationID: "merge-payees",
+		Method:      http.MethodPost,
+		Path:        "/payees/{id}/merge",
+		Summary:     "Merge Payees",
+		Description: "Merges payees into this one: their transactions move to it, their names and aliases become its aliases and their patterns are added to its own. It keeps its default category, or takes the first merged payee's if it has none. The merged payees are deleted.",
+		Tags:        []string{"Payees"},
+	}, func(ctx context.Context, input *MergePayeesRequest) (*MergePayeesResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		sources := slices.Compact(slices.Sorted(slices.Values(input.Body.PayeeIDs)))
+		if slices.Contains(sources, input.ID) {
+			return nil, huma.Error422UnprocessableEntity("A payee cannot be merged into itself")
+		}
+
+		resp := &MergePayeesResponse{}
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+
+			locked, err := queries.LockPayees(ctx, gensql.LockPayeesParams{
+				UserID: user.ID,
+				Ids:    append([]int64{input.ID}, sources...),
+			})
+			if err != nil {
+				return err
+			}
+			i := slices.IndexFunc(locked, func(p gensql.Payee) bool { return p.ID == input.ID })
+			if i < 0 {
+				return huma.Error404NotFound("Payee not found")
+			}
+			target := locked[i]
+			merged := slices.Delete(locked, i, i+1)
+			for _, id := range sources {
+				if !slices.ContainsFunc(merged, func(p gensql.Payee) bool { return p.ID == id }) {
+					return huma.Error404NotFound(fmt.Sprintf("Payee %d not found", id))
+				}
+			}
+
+			moved, err := queries.ReassignPayeeTransactions(ctx, gensql.ReassignPayeeTransactionsParams{
+				PayeeID: &input.ID,
+				UserID:  user.ID,
+				Ids:     sources,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := queries.DeletePayees(ctx, gensql.DeletePayeesParams{UserID: user.ID, Ids: sources}); err != nil {
+				return err
+			}
+
+			m := mergePayees(target, merged)
+			updated, err := queries.UpdatePayee(ctx, gensql.UpdatePayeeParams{
+				ID:              target.ID,
+				UserID:          user.ID,
+				Name:            m.Name,
+				Aliases:         m.Aliases,
+				Patterns:        m.Patterns,
+				DefaultCategory: m.DefaultCategory,
+			})
+			if err != nil {
+				return err
+			}
+			resp.Body.Payee = updated
+			resp.Body.Merged = len(merged)
+			resp.Body.Transactions = moved
+			return nil
+		})
+		if err != nil {
+			if se := huma.StatusError(nil); errors.As(err, &se) {
+				return nil, err
+			}
+			return nil, huma.Error500InternalServerError("Failed to merge payees", err)
+		}
+		return resp, nil
+	})
+
+	// Set Payee Default Category
+	huma.Register(api, huma.Operation{
+		OperationID: "set-payee-default-category",
+		Method:      http.MethodPut,
+		Path:        "/payees/{id}/default-category",
+		Summary:     "Set Payee Default Category",
+		Description: "Sets the category given to transactions linked to the payee when they are created or imported uncategorized. Categorization rules take precedence.",
+		Tags:        []string{"Payees"},
+	}, func(ctx context.Context, input *SetPayeeDefaultCategoryRequest) (*SetPayeeDefaultCategoryResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if input.Body.ApplyToExisting && input.Body.Category == nil {
+			return nil, huma.Error422UnprocessableEntity("apply_to_existing requires a category")
+		}
+
+		resp := &SetPayeeDefaultCategoryResponse{}
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+
+			p, err := queries.SetPayeeDefaultCategory(ctx, gensql.SetPayeeDefaultCategoryParams{
+				ID:              input.ID,
+				UserID:          user.ID,
+				DefaultCategory: input.Body.Category,
+			})
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return huma.Error404NotFound("Payee not found")
+				}
+				return err
+			}
+			resp.Body.Payee = p
+
+			if input.Body.ApplyToExisting {
+				resp.Body.Updated, err = queries.CategorizePayeeTransactions(ctx, gensql.CategorizePayeeTransactionsParams{
+					Category:      *input.Body.Category,
+					UserID:        user.ID,
+					PayeeID:       &p.ID,
+					Uncategorized: importer.DefaultCategory,
+				})
+			}
+			return err
+		})
+		if err != nil {
+			if se := huma.StatusError(nil); errors.As(err, &se) {
+				return nil, err
+			}
+			return nil, huma.Error500InternalServerError("Failed to set default category", err)
+		}
+		return resp, nil
+	})
+
+	// Link Payees
+	huma.Register(api, huma.Operation{
+		OperationID: "link-payees",
+		Method:      http.MethodPost,
+		Path:        "/payees/link",
+		Summary:     "Link Payees",
+		Description: fmt.Sprintf("Links existing transactions without a payee to one, creating payees as new transactions would, and gives uncategorized ones the payee's default category. At most %d transactions are looked at per call; continue from last_id while remaining is not 0.", maxBatchMatches),
+		Tags:        []string{"Payees"},
+	}, func(ctx context.Context, input *LinkPayeesRequest) (*LinkPayeesResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := &LinkPayeesResponse{}
+		resp.Body.LastID = input.After
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+
+			linker, err := newPayeeLinker(ctx, queries, user.ID, true)
+			if err != nil {
+				return err
+			}
+			unlinked, err := queries.ListUnlinkedTransactions(ctx, gensql.ListUnlinkedTransactionsParams{
+				UserID: user.ID,
+				After:  input.After,
+				Limit:  maxBatchMatches,
+			})
+			if err != nil {
+				return err
+			}
+			for _, t := range unlinked {
+				resp.Body.Scanned++
+				resp.Body.LastID = t.ID
+
+				params := gensql.CreateTransactionParams{Description: t.Description, Category: t.Category}
+				if err := linker.link(ctx, &params); err != nil {
+					return err
+				}
+				if params.PayeeID == nil {
+					continue
+				}
+				if err := queries.LinkTransactionPayee(ctx, gensql.LinkTransactionPayeeParams{
+					ID:       t.ID,
+					UserID:   user.ID,
+					PayeeID:  params.PayeeID,
+					Category: params.Category,
+				}); err != nil {
+					return err
+				}
+				resp.Body.Linked++
+			}
+
+			resp.Body.Remaining, err = queries.CountUnlinkedTransactions(ctx, gensql.CountUnlinkedTransactionsParams{
+				UserID: user.ID,
+				After:  resp.Body.LastID,
+			})
+			return err
+		})
+		if err != nil {
+			if pgErr, ok := pgError(err, pgCheckViolation); ok {
+				return nil, huma.Error422UnprocessableEntity(checkViolationMessage(pgErr))
+			}
+			return nil, huma.Error500InternalServerError("Failed to link payees", err)
+		}
+		return resp, nil
+	})
+}
+
+// normalize validates the payee and fills in defaults. The result is not
+// stored; it carries the values to save.
+func (in PayeeInput) normalize() (gensql.Payee, error) {
+	p := gensql.Payee{
+		Name:            strings.TrimSpace(in.Name),
+		Aliases:         in.Aliases,
+		Patterns:        in.Patterns,
+		DefaultCategory: in.DefaultCategory,
+	}
+	if p.Name == "" {
+		return gensql.Payee{}, huma.Error422UnprocessableEntity("Payee name cannot be blank")
+	}
+	if p.Aliases == nil {
+		p.Aliases = []string{}
+	}
+	if p.Patterns == nil {
+		p.Patterns = []string{}
+	}
+	if _, err := payee.CompilePatterns(p.Patterns); err != nil {
+		return gensql.Payee{}, huma.Error422UnprocessableEntity("Invalid payee: " + err.Error())
+	}
+	return p, nil
+}
+
+// mergePayees returns the target payee with the names, aliases, patterns and
+// default category of the merged ones added. Aliases matching the same
+// descriptions as the name or an earlier alias are dropped.
+func mergePayees(target gensql.Payee, merged []gensql.Payee) gensql.Payee {
+	seen := map[string]bool{payee.Key(target.Name): true}
+	aliases := []string{}
+	addAlias := func(alias string) {
+		if key := payee.Key(alias); !seen[key] {
+			seen[key] = true
+			aliases = append(aliases, alias)
+		}
+	}
+	for _, a := range target.Aliases {
+		addAlias(a)
+	}
+	patterns := slices.Clone(target.Patterns)
+	for _, m := range merged {
+		addAlias(m.Name)
+		for _, a := range m.Aliases {
+			addAlias(a)
+		}
+		for _, p := range m.Patterns {
+			if !slices.Contains(patterns, p) {
+				patterns = append(patterns, p)
+			}
+		}
+		if target.DefaultCategory == nil {
+			target.DefaultCategory = m.DefaultCategory
+		}
+	}
+	target.Aliases = aliases
+	target.Patterns = patterns
+	if target.Patterns == nil {
+		target.Patterns = []string{}
+	}
+	return target
+}
+
+// payeeLinker links transactions about to be created or updated to the
+// user's payees.
+type payeeLinker struct {
+	queries *gensql.Queries
+	userID  int64
+	matcher *payee.Matcher
+	// create makes the linker create payees for descriptions none matches;
+	// created holds them by key, as they are not part of the matcher.
+	create  bool
+	created map[string]gensql.Payee
+}
+
+func newPayeeLinker(ctx context.Context, queries *gensql.Queries, userID int64, create bool) (*payeeLinker, error) {
+	payees, err := queries.ListAllPayees(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &payeeLinker{
+		queries: queries,
+		userID:  userID,
+		matcher: payee.NewMatcher(payees),
+		create:  create,
+		created: map[string]gensql.Payee{},
+	}, nil
+}
+
+// link sets the payee of a transaction the client did not choose one for,
+// creating one named after the normalized description if the linker creates
+// payees. A chosen payee has to be one of the user's. Uncategorized
+// transactions get the payee's default category.
+func (l *payeeLinker) link(ctx context.Context, params *gensql.CreateTransactionParams) error {
+	var p gensql.Payee
+	if params.PayeeID != nil {
+		var err error
+		if p, err = l.chosen(ctx, *params.PayeeID); err != nil {
+			return err
+		}
+	} else {
+		var ok bool
+		if p, ok = l.matcher.Match(params.Description); !ok {
+			name := payee.Normalize(params.Description)
+			if !l.create || name == "" {
+				return nil
+			}
+			key := payee.Key(name)
+			if p, ok = l.created[key]; !ok {
+				var err error
+				p, err = l.queries.EnsurePayee(ctx, gensql.EnsurePayeeParams{UserID: l.userID, Name: name})
+				if err != nil {
+					return err
+				}
+				l.created[key] = p
+			}
+		}
+	}
+
+	params.PayeeID = &p.ID
+	if p.DefaultCategory != nil && (params.Category == "" || params.Category == importer.DefaultCategory) {
+		params.Category = *p.DefaultCategory
+	}
+	return nil
+}
+
+// linkUpdate links a transaction about to be updated like link does, so a
+// changed description is linked again unless the client chose a payee.
+func (l *payeeLinker) linkUpdate(ctx context.Context, params *gensql.UpdateTransactionParams) error {
+	p := gensql.CreateTransactionParams{Description: params.Description, Category: params.Category, PayeeID: params.PayeeID}
+	if err := l.link(ctx, &p); err != nil {
+		return err
+	}
+	params.PayeeID, params.Category = p.PayeeID, p.Category
+	return nil
+}
+
+// chosen returns the payee a client set on a transaction.
+func (l *payeeLinker) chosen(ctx context.Context, id int64) (gensql.Payee, error) {
+	p, err := l.queries.GetPayee(ctx, gensql.GetPayeeParams{ID: id, UserID: l.userID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return gensql.Payee{}, huma.Error422UnprocessableEntity(fmt.Sprintf("Payee %d not found", id))
+	}
+	return p, err
+}
+
+// forget drops the payees the linker created, for when they were rolled
+// back; they are looked up again when needed.
+func (l *payeeLinker) forget() {
+	clear(l.created)
+}
+
+// linkPayeesOnCreate links transactions about to be created to the user's
+// payees, creating payees as needed.
+func linkPayeesOnCreate(ctx context.Context, queries *gensql.Queries, userID int64, params ...*gensql.CreateTransactionParams) error {
+	linker, err := newPayeeLinker(ctx, queries, userID, true)
+	if err != nil {
+		return err
+	}
+	for _, p := range params {
+		if err := linker.link(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
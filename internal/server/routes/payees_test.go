@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"slices"
+	"testing"
+
+	"budgetctl-go/internal/database/gensql"
+)
+
+func TestMergePayees(t *testing.T) {
+	food := "Food"
+	coffee := "Coffee"
+	target := gensql.Payee{ID: 1, Name: "Blue Bottle", Aliases: []string{"Blue Bottle Coffee"}, Patterns: []string{"(?i)bluebottle"}}
+	merged := mergePayees(target, []gensql.Payee{
+		{ID: 2, Name: "BLUE BOTTLE 0423", Aliases: []string{"BB Cafe"}, Patterns: []string{"(?i)bluebottle", "^BBC"}, DefaultCategory: &coffee},
+		{ID: 3, Name: "Blue bottle coffee", DefaultCategory: &food},
+	})
+
+	if merged.ID != 1 || merged.Name != "Blue Bottle" {
+		t.Errorf("merged into %d %q", merged.ID, merged.Name)
+	}
+	// "BLUE BOTTLE 0423" normalizes to the name and "Blue bottle coffee" to
+	// an existing alias.
+	if !slices.Equal(merged.Aliases, []string{"Blue Bottle Coffee", "BB Cafe"}) {
+		t.Errorf("aliases %q", merged.Aliases)
+	}
+	if !slices.Equal(merged.Patterns, []string{"(?i)bluebottle", "^BBC"}) {
+		t.Errorf("patterns %q", merged.Patterns)
+	}
+	if merged.DefaultCategory == nil || *merged.DefaultCategory != "Coffee" {
+		t.Errorf("default category %v", merged.DefaultCategory)
+	}
+	if len(target.Aliases) != 1 {
+		t.Errorf("the target's aliases were modified: %q", target.Aliases)
+	}
+}
@@ -0,0 +1,148 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/storage"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// The receipt routes predate attachments. A transaction's receipt is its
+// first attachment, and uploading a receipt adds an attachment.
+
+type Receipt struct {
+	AttachmentID         int64     `json:"attachment_id"`
+	ContentType          string    `json:"content_type"`
+	Size                 int64     `json:"size"`
+	URL                  string    `json:"url" doc:"Signed download URL"`
+	ThumbnailURL         *string   `json:"thumbnail_url" doc:"Signed URL of a JPEG thumbnail, for JPEG and PNG receipts. Null for PDF and HEIC receipts, with the reason in thumbnail_unavailable."`
+	ThumbnailUnavailable string    `json:"thumbnail_unavailable,omitempty" doc:"Why thumbnail_url is null"`
+	ExpiresAt            time.Time `json:"expires_at" doc:"When the signed URLs stop working"`
+}
+
+type UploadReceiptRequest struct {
+	ID      int64 `path:"id" doc:"Transaction ID"`
+	RawBody huma.MultipartFormFiles[struct {
+		File huma.FormFile `form:"file" contentType:"image/jpeg,image/png,application/pdf,image/heic,image/heif,application/octet-stream" required:"true" doc:"Receipt as JPEG, PNG, PDF or HEIC"`
+	}]
+}
+
+type ReceiptRequest struct {
+	ID int64 `path:"id" doc:"Transaction ID"`
+}
+
+type ReceiptResponse struct {
+	Body *Receipt
+}
+
+func RegisterReceiptRoutes(api huma.API, db database.Service, store storage.Storage) {
+	// Upload Receipt
+	huma.Register(api, huma.Operation{
+		OperationID:   "upload-receipt",
+		Method:        http.MethodPost,
+		Path:          "/transactions/{id}/receipt",
+		Summary:       "Upload Receipt",
+		Description:   "Attaches a JPEG, PNG, PDF or HEIC receipt to the transaction, as uploading an attachment does. The file type is detected from its contents. JPEG and PNG receipts get a thumbnail. HEIC receipts are stored as uploaded but get no thumbnail, as the server cannot decode them.",
+		Tags:          []string{"Transactions"},
+		MaxBodyBytes:  maxAttachmentBytes + 1<<20,
+		DefaultStatus: http.StatusCreated,
+	}, func(ctx context.Context, input *UploadReceiptRequest) (*ReceiptResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		a, err := storeAttachment(ctx, db, store, user.ID, input.ID, input.RawBody.Data().File, "")
+		if err != nil {
+			return nil, err
+		}
+		return &ReceiptResponse{Body: receiptFromAttachment(a)}, nil
+	})
+
+	// Get Receipt
+	huma.Register(api, huma.Operation{
+		OperationID: "get-receipt",
+		Method:      http.MethodGet,
+		Path:        "/transactions/{id}/receipt",
+		Summary:     "Get Receipt",
+		Description: fmt.Sprintf("Returns signed URLs for downloading the transaction's first attachment, valid for %s.", attachmentURLTTL),
+		Tags:        []string{"Transactions"},
+	}, func(ctx context.Context, input *ReceiptRequest) (*ReceiptResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		first, err := firstAttachment(ctx, db.GetQueries(), user.ID, input.ID)
+		if err != nil {
+			return nil, err
+		}
+		a, err := signAttachment(ctx, store, first)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to sign receipt URL", err)
+		}
+		return &ReceiptResponse{Body: receiptFromAttachment(a)}, nil
+	})
+
+	// Delete Receipt
+	huma.Register(api, huma.Operation{
+		OperationID:   "delete-receipt",
+		Method:        http.MethodDelete,
+		Path:          "/transactions/{id}/receipt",
+		Summary:       "Delete Receipt",
+		Description:   "Removes the transaction's first attachment.",
+		Tags:          []string{"Transactions"},
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *ReceiptRequest) (*struct{}, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		first, err := firstAttachment(ctx, db.GetQueries(), user.ID, input.ID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, removeAttachment(ctx, db, store, user.ID, input.ID, first.ID)
+	})
+}
+
+// firstAttachment returns the attachment serving as a transaction's receipt.
+func firstAttachment(ctx context.Context, queries *gensql.Queries, userID, transactionID int64) (gensql.Attachment, error) {
+	if _, err := queries.GetTransactionByID(ctx, gensql.GetTransactionByIDParams{
+		ID:     transactionID,
+		UserID: userID,
+	}); err != nil {
+		return gensql.Attachment{}, huma.Error404NotFound("Transaction not found", err)
+	}
+
+	attachments, err := queries.ListAttachments(ctx, gensql.ListAttachmentsParams{
+		TransactionID: transactionID,
+		UserID:        userID,
+	})
+	if err != nil {
+		return gensql.Attachment{}, huma.Error500InternalServerError("Failed to fetch attachments", err)
+	}
+	if len(attachments) == 0 {
+		return gensql.Attachment{}, huma.Error404NotFound("Transaction has no uploaded receipt")
+	}
+	return attachments[0], nil
+}
+
+func receiptFromAttachment(a *Attachment) *Receipt {
+	return &Receipt{
+		AttachmentID:         a.ID,
+		ContentType:          a.ContentType,
+		Size:                 a.Size,
+		URL:                  a.URL,
+		ThumbnailURL:         a.ThumbnailURL,
+		ThumbnailUnavailable: a.ThumbnailUnavailable,
+		ExpiresAt:            a.ExpiresAt,
+	}
+}
@@ -0,0 +1,451 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/numeric"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ReconciliationStatement struct {
+	StatementDate string  `json:"statement_date" format:"date" doc:"Last day covered by the statement"`
+	EndingBalance Decimal `json:"ending_balance" doc:"Balance at the end of the statement"`
+}
+
+// ReconciliationDetail is a reconciliation with the balance of its cleared
+// transactions and the transactions left to match.
+type ReconciliationDetail struct {
+	Reconciliation gensql.Reconciliation `json:"reconciliation"`
+	OpeningBalance Decimal               `json:"opening_balance" doc:"Balance of the transactions reconciled by earlier statements"`
+	ClearedBalance Decimal               `json:"cleared_balance" doc:"Opening balance plus the transactions cleared up to the statement date"`
+	Difference     Decimal               `json:"difference" doc:"Ending balance minus cleared balance; the reconciliation can be locked once it is 0"`
+	Transactions   []gensql.Transaction  `json:"transactions" doc:"Unreconciled transactions up to the statement date, cleared ones being matched; once locked, the transactions it reconciled"`
+}
+
+type ListReconciliationsRequest struct {
+	Account string `query:"account" doc:"Only reconciliations of this account"`
+	PaginationInput
+}
+
+type ListReconciliationsResponse struct {
+	Body *PaginatedResponse[gensql.Reconciliation]
+}
+
+type ReconciliationRequest struct {
+	ID int64 `path:"id" doc:"Reconciliation ID"`
+}
+
+type CreateReconciliationRequest struct {
+	Body struct {
+		Account  string `json:"account" doc:"Account the statement is for"`
+		Currency string `json:"currency,omitempty" doc:"USD by default"`
+		ReconciliationStatement
+	}
+}
+
+type UpdateReconciliationRequest struct {
+	ID   int64 `path:"id" doc:"Reconciliation ID"`
+	Body ReconciliationStatement
+}
+
+type MatchTransactionsRequest struct {
+	ID   int64 `path:"id" doc:"Reconciliation ID"`
+	Body struct {
+		TransactionIDs []int64 `json:"transaction_ids" minItems:"1" maxItems:"1000" doc:"Unreconciled transactions of the account dated up to the statement date"`
+	}
+}
+
+type ReconciliationResponse struct {
+	Body *ReconciliationDetail
+}
+
+func RegisterReconciliationRoutes(api huma.API, db database.Service) {
+	// List Reconciliations
+	huma.Register(api, huma.Operation{
+		OperationID: "list-reconciliations",
+		Method:      http.MethodGet,
+		Path:        "/reconciliations",
+		Summary:     "List Reconciliations",
+		Description: "Lists reconciliations, latest statement first.",
+		Tags:        []string{"Reconciliation"},
+	}, func(ctx context.Context, input *ListReconciliationsRequest) (*ListReconciliationsResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var account *string
+		if input.Account != "" {
+			account = &input.Account
+		}
+		queries := db.GetQueries()
+		limit, offset := input.ToLimitOffset()
+		reconciliations, err := queries.ListReconciliations(ctx, gensql.ListReconciliationsParams{
+			UserID:  user.ID,
+			Account: account,
+			Limit:   limit,
+			Offset:  offset,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch reconciliations", err)
+		}
+		total, err := queries.CountReconciliations(ctx, gensql.CountReconciliationsParams{
+			UserID:  user.ID,
+			Account: account,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to count reconciliations", err)
+		}
+		if reconciliations == nil {
+			reconciliations = []gensql.Reconciliation{}
+		}
+
+		return &ListReconciliationsResponse{
+			Body: NewPaginatedResponse(reconciliations, total, input.Page, input.PerPage),
+		}, nil
+	})
+
+	// Create Reconciliation
+	huma.Register(api, huma.Operation{
+		OperationID: "create-reconciliation",
+		Method:      http.MethodPost,
+		Path:        "/reconciliations",
+		Summary:     "Create Reconciliation",
+		Description: "Starts reconciling an account against a bank statement. The response lists the unreconciled transactions up to the statement date and the difference between the statement's ending balance and the cleared balance. An account has at most one open reconciliation per currency.",
+		Tags:        []string{"Reconciliation"},
+	}, func(ctx context.Context, input *CreateReconciliationRequest) (*ReconciliationResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		statementDate, err := input.Body.date()
+		if err != nil {
+			return nil, err
+		}
+		currency := input.Body.Currency
+		if currency == "" {
+			currency = "USD"
+		}
+
+		queries := db.GetQueries()
+		reconciliation, err := queries.CreateReconciliation(ctx, gensql.CreateReconciliationParams{
+			UserID:        user.ID,
+			Account:       input.Body.Account,
+			Currency:      currency,
+			StatementDate: statementDate,
+			EndingBalance: input.Body.EndingBalance.Numeric,
+		})
+		if err != nil {
+			if _, ok := pgError(err, pgUniqueViolation); ok {
+				return nil, huma.Error409Conflict("The account already has an open reconciliation in this currency")
+			}
+			return nil, huma.Error500InternalServerError("Failed to create reconciliation", err)
+		}
+
+		detail, err := reconciliationDetail(ctx, queries, reconciliation)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch reconciliation", err)
+		}
+		return &ReconciliationResponse{Body: detail}, nil
+	})
+
+	// Get Reconciliation
+	huma.Register(api, huma.Operation{
+		OperationID: "get-reconciliation",
+		Method:      http.MethodGet,
+		Path:        "/reconciliations/{id}",
+		Summary:     "Get Reconciliation",
+		Tags:        []string{"Reconciliation"},
+	}, func(ctx context.Context, input *ReconciliationRequest) (*ReconciliationResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		reconciliation, err := queries.GetReconciliation(ctx, gensql.GetReconciliationParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error404NotFound("Reconciliation not found", err)
+		}
+		detail, err := reconciliationDetail(ctx, queries, reconciliation)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch reconciliation", err)
+		}
+		return &ReconciliationResponse{Body: detail}, nil
+	})
+
+	// Update Reconciliation
+	huma.Register(api, huma.Operation{
+		OperationID: "update-reconciliation",
+		Method:      http.MethodPut,
+		Path:        "/reconciliations/{id}",
+		Summary:     "Update Reconciliation",
+		Description: "Corrects the statement date or ending balance of an open reconciliation.",
+		Tags:        []string{"Reconciliation"},
+	}, func(ctx context.Context, input *UpdateReconciliationRequest) (*ReconciliationResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		statementDate, err := input.Body.date()
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		reconciliation, err := queries.UpdateReconciliation(ctx, gensql.UpdateReconciliationParams{
+			ID:            input.ID,
+			UserID:        user.ID,
+			StatementDate: statementDate,
+			EndingBalance: input.Body.EndingBalance.Numeric,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, huma.Error404NotFound("Open reconciliation not found", err)
+		}
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to update reconciliation", err)
+		}
+
+		detail, err := reconciliationDetail(ctx, queries, reconciliation)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch reconciliation", err)
+		}
+		return &ReconciliationResponse{Body: detail}, nil
+	})
+
+	// Delete Reconciliation
+	huma.Register(api, huma.Operation{
+		OperationID:   "delete-reconciliation",
+		Method:        http.MethodDelete,
+		Path:          "/reconciliations/{id}",
+		Summary:       "Delete Reconciliation",
+		Description:   "Abandons an open reconciliation. Transactions matched in it stay cleared. Locked reconciliations cannot be deleted.",
+		Tags:          []string{"Reconciliation"},
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *ReconciliationRequest) (*struct{}, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		deleted, err := db.GetQueries().DeleteReconciliation(ctx, gensql.DeleteReconciliationParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to delete reconciliation", err)
+		}
+		if deleted == 0 {
+			return nil, huma.Error404NotFound("Open reconciliation not found")
+		}
+		return nil, nil
+	})
+
+	// Match Transactions
+	huma.Register(api, huma.Operation{
+		OperationID: "match-reconciliation-transactions",
+		Method:      http.MethodPost,
+		Path:        "/reconciliations/{id}/match",
+		Summary:     "Match Transactions",
+		Description: "Marks transactions that appear on the statement as cleared, counting them toward the cleared balance.",
+		Tags:        []string{"Reconciliation"},
+	}, func(ctx context.Context, input *MatchTransactionsRequest) (*ReconciliationResponse, error) {
+		return setReconciliationMatches(ctx, db, input, "cleared")
+	})
+
+	// Unmatch Transactions
+	huma.Register(api, huma.Operation{
+		OperationID: "unmatch-reconciliation-transactions",
+		Method:      http.MethodPost,
+		Path:        "/reconciliations/{id}/unmatch",
+		Summary:     "Unmatch Transactions",
+		Description: "Sets matched transactions back to pending.",
+		Tags:        []string{"Reconciliation"},
+	}, func(ctx context.Context, input *MatchTransactionsRequest) (*ReconciliationResponse, error) {
+		return setReconciliationMatches(ctx, db, input, "pending")
+	})
+
+	// Lock Reconciliation
+	huma.Register(api, huma.Operation{
+		OperationID: "lock-reconciliation",
+		Method:      http.MethodPost,
+		Path:        "/reconciliations/{id}/lock",
+		Summary:     "Lock Reconciliation",
+		Description: "Finishes a reconciliation whose difference is 0. Its cleared transactions become reconciled, after which their date, amount, type, currency, account and status can no longer change and they cannot be deleted.",
+		Tags:        []string{"Reconciliation"},
+	}, func(ctx context.Context, input *ReconciliationRequest) (*ReconciliationResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var detail *ReconciliationDetail
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+			reconciliation, err := queries.LockReconciliation(ctx, gensql.LockReconciliationParams{ID: input.ID, UserID: user.ID})
+			if err != nil {
+				return err
+			}
+			if reconciliation.LockedAt.Valid {
+				return huma.Error409Conflict("Reconciliation is already locked")
+			}
+			balances, err := queries.GetReconciliationBalances(ctx, gensql.GetReconciliationBalancesParams{ID: input.ID, UserID: user.ID})
+			if err != nil {
+				return err
+			}
+			if d := numeric.Rat(balances.Difference); d == nil || d.Sign() != 0 {
+				return huma.Error422UnprocessableEntity(fmt.Sprintf(
+					"Cleared balance %s differs from the ending balance %s by %s",
+					formatNumeric(balances.ClearedBalance), formatNumeric(reconciliation.EndingBalance), formatNumeric(balances.Difference),
+				))
+			}
+			if _, err := queries.ReconcileTransactions(ctx, gensql.ReconcileTransactionsParams{ID: input.ID, UserID: user.ID}); err != nil {
+				return err
+			}
+			reconciliation, err = queries.CloseReconciliation(ctx, gensql.CloseReconciliationParams{ID: input.ID, UserID: user.ID})
+			if err != nil {
+				return err
+			}
+			detail, err = reconciliationDetail(ctx, queries, reconciliation)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, huma.Error404NotFound("Reconciliation not found", err)
+			}
+			if se := huma.StatusError(nil); errors.As(err, &se) {
+				return nil, err
+			}
+			return nil, huma.Error500InternalServerError("Failed to lock reconciliation", err)
+		}
+		return &ReconciliationResponse{Body: detail}, nil
+	})
+}
+
+func (s ReconciliationStatement) date() (pgtype.Date, error) {
+	d, err := time.Parse(time.DateOnly, s.StatementDate)
+	if err != nil {
+		return pgtype.Date{}, huma.Error422UnprocessableEntity("Invalid statement date", err)
+	}
+	return pgDate(d), nil
+}
+
+// setReconciliationMatches sets the status of transactions of an open
+// reconciliation. It fails without changing any if one of them is not an
+// unreconciled transaction of the statement.
+func setReconciliationMatches(ctx context.Context, db database.Service, input *MatchTransactionsRequest, status string) (*ReconciliationResponse, error) {
+	user, err := getUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var detail *ReconciliationDetail
+	err = db.WithTx(ctx, func(tx pgx.Tx) error {
+		queries := db.GetQueries().WithTx(tx)
+		if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+			return err
+		}
+		reconciliation, err := queries.LockReconciliation(ctx, gensql.LockReconciliationParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return err
+		}
+		if reconciliation.LockedAt.Valid {
+			return huma.Error409Conflict("Reconciliation is locked")
+		}
+		updated, err := queries.SetReconciliationMatches(ctx, gensql.SetReconciliationMatchesParams{
+			Status:           status,
+			ReconciliationID: input.ID,
+			UserID:           user.ID,
+			Ids:              input.Body.TransactionIDs,
+		})
+		if err != nil {
+			return err
+		}
+		if missing := missingTransactionIDs(input.Body.TransactionIDs, updated); len(missing) > 0 {
+			return huma.Error422UnprocessableEntity("Not unreconciled transactions of the statement: " + strings.Join(missing, ", "))
+		}
+		detail, err = reconciliationDetail(ctx, queries, reconciliation)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, huma.Error404NotFound("Reconciliation not found", err)
+		}
+		if se := huma.StatusError(nil); errors.As(err, &se) {
+			return nil, err
+		}
+		return nil, huma.Error500InternalServerError("Failed to match transactions", err)
+	}
+	return &ReconciliationResponse{Body: detail}, nil
+}
+
+// missingTransactionIDs lists the requested IDs that are not among the
+// transactions, in request order.
+func missingTransactionIDs(ids []int64, transactions []gensql.Transaction) []string {
+	var missing []string
+	for _, id := range ids {
+		if !slices.ContainsFunc(transactions, func(t gensql.Transaction) bool { return t.ID == id }) {
+			missing = append(missing, fmt.Sprint(id))
+		}
+	}
+	return missing
+}
+
+func reconciliationDetail(ctx context.Context, queries *gensql.Queries, reconciliation gensql.Reconciliation) (*ReconciliationDetail, error) {
+	balances, err := queries.GetReconciliationBalances(ctx, gensql.GetReconciliationBalancesParams{
+		ID:     reconciliation.ID,
+		UserID: reconciliation.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := queries.ListReconciliationTransactions(ctx, gensql.ListReconciliationTransactionsParams{
+		ID:     reconciliation.ID,
+		UserID: reconciliation.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if transactions == nil {
+		transactions = []gensql.Transaction{}
+	}
+	return &ReconciliationDetail{
+		Reconciliation: reconciliation,
+		OpeningBalance: Decimal{balances.OpeningBalance},
+		ClearedBalance: Decimal{balances.ClearedBalance},
+		Difference:     Decimal{balances.Difference},
+		Transactions:   transactions,
+	}, nil
+}
+
+// formatNumeric formats n as its JSON number.
+func formatNumeric(n pgtype.Numeric) string {
+	b, err := n.MarshalJSON()
+	if err != nil {
+		return "?"
+	}
+	return string(b)
+}
+
+// checkStatusChange rejects setting a transaction's status to reconciled.
+// Transactions are only reconciled by locking a reconciliation, which also
+// protects them; a transaction that already has the status may keep it.
+func checkStatusChange(from, to string) error {
+	if to == "reconciled" && from != "reconciled" {
+		return huma.Error422UnprocessableEntity(`Status "reconciled" is only set by locking a reconciliation`)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"slices"
+	"testing"
+
+	"budgetctl-go/internal/database/gensql"
+)
+
+func TestMissingTransactionIDs(t *testing.T) {
+	updated := []gensql.Transaction{{ID: 4}, {ID: 2}}
+
+	if missing := missingTransactionIDs([]int64{2, 4}, updated); len(missing) != 0 {
+		t.Errorf("all updated: missing %v", missing)
+	}
+	if missing := missingTransactionIDs([]int64{9, 2, 7, 4}, updated); !slices.Equal(missing, []string{"9", "7"}) {
+		t.Errorf("missing %v, want [9 7]", missing)
+	}
+}
+
+func TestCheckStatusChange(t *testing.T) {
+	tests := []struct {
+		from, to string
+		ok       bool
+	}{
+		{"", "pending", true},
+		{"pending", "cleared", true},
+		{"reconciled", "reconciled", true},
+		{"", "reconciled", false},
+		{"cleared", "reconciled", false},
+	}
+	for _, tt := range tests {
+		if err := checkStatusChange(tt.from, tt.to); (err == nil) != tt.ok {
+			t.Errorf("%q to %q: error %v", tt.from, tt.to, err)
+		}
+	}
+}
@@ -0,0 +1,773 @@
+package routes
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/rrule"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// upcomingDays is how far ahead upcoming occurrences are listed by
+	// default.
+	upcomingDays = 30
+	// maxOccurrenceRange bounds the date range occurrences are listed for.
+	maxOccurrenceRange = 366 * 24 * time.Hour
+	// maxRecurringCatchUp bounds how many occurrences of a template one run
+	// of the generator creates; the rest follow in the next run.
+	maxRecurringCatchUp = 1000
+)
+
+type RecurringTemplate struct {
+	Rule        string   `json:"rule" minLength:"1" doc:"RFC 5545 recurrence rule, e.g. FREQ=MONTHLY;BYMONTHDAY=1 or FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1 for the last business day"`
+	StartDate   string   `json:"start_date" format:"date" doc:"First day an occurrence can fall on; also the anchor of INTERVAL"`
+	Amount      Decimal  `json:"amount"`
+	Description string   `json:"description" minLength:"1"`
+	Category    string   `json:"category" minLength:"1"`
+	Type        string   `json:"type" enum:"income,expense"`
+	Currency    string   `json:"currency,omitempty" doc:"USD by default"`
+	Account     string   `json:"account,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Notes       *string  `json:"notes,omitempty"`
+	Active      *bool    `json:"active,omitempty" doc:"Inactive templates create no transactions; true by default. Occurrences that came due while inactive are not created on reactivation."`
+}
+
+type Occurrence struct {
+	RecurringID   int64    `json:"recurring_id"`
+	Occurrence    string   `json:"occurrence" doc:"Scheduled date"`
+	Date          string   `json:"date" doc:"Date of the transaction, differing from occurrence when moved"`
+	Amount        Decimal  `json:"amount"`
+	Description   string   `json:"description"`
+	Category      string   `json:"category"`
+	Type          string   `json:"type"`
+	Currency      string   `json:"currency"`
+	Account       string   `json:"account"`
+	Tags          []string `json:"tags"`
+	Notes         *string  `json:"notes,omitempty"`
+	Skipped       bool     `json:"skipped"`
+	Modified      bool     `json:"modified" doc:"Whether the occurrence differs from the template"`
+	TransactionID *int64   `json:"transaction_id,omitempty" doc:"Transaction created for the occurrence"`
+}
+
+type RecurringRequest struct {
+	ID int64 `path:"id" doc:"Recurring template ID"`
+}
+
+type CreateRecurringRequest struct {
+	Body RecurringTemplate
+}
+
+type UpdateRecurringRequest struct {
+	ID   int64 `path:"id" doc:"Recurring template ID"`
+	Body RecurringTemplate
+}
+
+type RecurringResponse struct {
+	Body *gensql.RecurringTransaction
+}
+
+type ListRecurringResponse struct {
+	Body []gensql.RecurringTransaction
+}
+
+type OccurrenceRangeInput struct {
+	From string `query:"from" format:"date" doc:"First date, today by default"`
+	To   string `query:"to" format:"date" doc:"Last date, 30 days after from by default"`
+}
+
+type ListOccurrencesRequest struct {
+	ID int64 `path:"id" doc:"Recurring template ID"`
+	OccurrenceRangeInput
+}
+
+type ListUpcomingRequest struct {
+	OccurrenceRangeInput
+}
+
+type OccurrencesResponse struct {
+	Body []Occurrence
+}
+
+type OccurrenceRequest struct {
+	ID         int64  `path:"id" doc:"Recurring template ID"`
+	Occurrence string `path:"date" format:"date" doc:"Scheduled date of the occurrence"`
+}
+
+type ModifyOccurrenceRequest struct {
+	OccurrenceRequest
+	Body struct {
+		Skip        bool     `json:"skip,omitempty" doc:"Create no transaction for this occurrence"`
+		Date        *string  `json:"date,omitempty" format:"date" doc:"Date of the transaction instead of the scheduled date"`
+		Amount      *Decimal `json:"amount,omitempty"`
+		Description *string  `json:"description,omitempty" minLength:"1"`
+		Category    *string  `json:"category,omitempty" minLength:"1"`
+		Notes       *string  `json:"notes,omitempty"`
+	}
+}
+
+type OccurrenceResponse struct {
+	Body *Occurrence
+}
+
+func RegisterRecurringRoutes(api huma.API, db database.Service) {
+	// List Recurring Templates
+	huma.Register(api, huma.Operation{
+		OperationID: "list-recurring",
+		Method:      http.MethodGet,
+		Path:        "/recurring",
+		Summary:     "List Recurring Templates",
+		Tags:        []string{"Recurring"},
+	}, func(ctx context.Context, input *struct{}) (*ListRecurringResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		templates, err := db.GetQueries().ListRecurring(ctx, user.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch recurring templates", err)
+		}
+		if templates == nil {
+			templates = []gensql.RecurringTransaction{}
+		}
+		return &ListRecurringResponse{Body: templates}, nil
+	})
+
+	// Create Recurring Template
+	huma.Register(api, huma.Operation{
+		OperationID: "create-recurring",
+		Method:      http.MethodPost,
+		Path:        "/recurring",
+		Summary:     "Create Recurring Template",
+		Description: "Creates a template for transactions that repeat on the dates of a recurrence rule. A pending transaction is created from the template when each occurrence comes due, including occurrences since a start date in the past.",
+		Tags:        []string{"Recurring"},
+	}, func(ctx context.Context, input *CreateRecurringRequest) (*RecurringResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := input.Body.normalize()
+		if err != nil {
+			return nil, err
+		}
+
+		template, err := db.GetQueries().CreateRecurring(ctx, gensql.CreateRecurringParams{
+			UserID:      user.ID,
+			Rrule:       t.Rule,
+			StartDate:   pgDate(t.startDate),
+			Amount:      t.Amount.Numeric,
+			Description: t.Description,
+			Category:    t.Category,
+			Type:        t.Type,
+			Currency:    t.Currency,
+			Account:     t.Account,
+			Tags:        t.Tags,
+			Notes:       t.Notes,
+			Active:      *t.Active,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to create recurring template", err)
+		}
+		return &RecurringResponse{Body: &template}, nil
+	})
+
+	// Get Recurring Template
+	huma.Register(api, huma.Operation{
+		OperationID: "get-recurring",
+		Method:      http.MethodGet,
+		Path:        "/recurring/{id}",
+		Summary:     "Get Recurring Template",
+		Tags:        []string{"Recurring"},
+	}, func(ctx context.Context, input *RecurringRequest) (*RecurringResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		template, err := db.GetQueries().GetRecurring(ctx, gensql.GetRecurringParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error404NotFound("Recurring template not found", err)
+		}
+		return &RecurringResponse{Body: &template}, nil
+	})
+
+	// Update Recurring Template
+	huma.Register(api, huma.Operation{
+		OperationID: "update-recurring",
+		Method:      http.MethodPut,
+		Path:        "/recurring/{id}",
+		Summary:     "Update Recurring Template",
+		Description: "Replaces the template. Transactions already created from it are left as they are.",
+		Tags:        []string{"Recurring"},
+	}, func(ctx context.Context, input *UpdateRecurringRequest) (*RecurringResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := input.Body.normalize()
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		current, err := queries.GetRecurring(ctx, gensql.GetRecurringParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error404NotFound("Recurring template not found", err)
+		}
+
+		params := gensql.UpdateRecurringParams{
+			ID:          input.ID,
+			UserID:      user.ID,
+			Rrule:       t.Rule,
+			StartDate:   pgDate(t.startDate),
+			Amount:      t.Amount.Numeric,
+			Description: t.Description,
+			Category:    t.Category,
+			Type:        t.Type,
+			Currency:    t.Currency,
+			Account:     t.Account,
+			Tags:        t.Tags,
+			Notes:       t.Notes,
+			Active:      *t.Active,
+		}
+		// A paused template resumes with today's occurrence rather than
+		// catching up on the ones it was paused for.
+		if !current.Active && *t.Active {
+			params.GeneratedThrough = pgDate(today(time.Now()).AddDate(0, 0, -1))
+		}
+
+		template, err := queries.UpdateRecurring(ctx, params)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, huma.Error404NotFound("Recurring template not found", err)
+			}
+			return nil, huma.Error500InternalServerError("Failed to update recurring template", err)
+		}
+		return &RecurringResponse{Body: &template}, nil
+	})
+
+	// Delete Recurring Template
+	huma.Register(api, huma.Operation{
+		OperationID:   "delete-recurring",
+		Method:        http.MethodDelete,
+		Path:          "/recurring/{id}",
+		Summary:       "Delete Recurring Template",
+		Description:   "Deletes the template. Transactions already created from it are kept.",
+		Tags:          []string{"Recurring"},
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *RecurringRequest) (*struct{}, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		deleted, err := db.GetQueries().DeleteRecurring(ctx, gensql.DeleteRecurringParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to delete recurring template", err)
+		}
+		if deleted == 0 {
+			return nil, huma.Error404NotFound("Recurring template not found")
+		}
+		return nil, nil
+	})
+
+	// List Upcoming Occurrences
+	huma.Register(api, huma.Operation{
+		OperationID: "list-upcoming-occurrences",
+		Method:      http.MethodGet,
+		Path:        "/recurring/upcoming",
+		Summary:     "List Upcoming Occurrences",
+		Description: "Lists the occurrences of all active templates in a date range by date, with skipped and modified occurrences marked.",
+		Tags:        []string{"Recurring"},
+	}, func(ctx context.Context, input *ListUpcomingRequest) (*OccurrencesResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		from, to, err := input.dateRange()
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		templates, err := queries.ListActiveRecurring(ctx, user.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch recurring templates", err)
+		}
+		out, err := occurrences(ctx, queries, user.ID, templates, from, to)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to list occurrences", err)
+		}
+		return &OccurrencesResponse{Body: out}, nil
+	})
+
+	// List Occurrences
+	huma.Register(api, huma.Operation{
+		OperationID: "list-occurrences",
+		Method:      http.MethodGet,
+		Path:        "/recurring/{id}/occurrences",
+		Summary:     "List Occurrences",
+		Description: "Lists the occurrences of a template in a date range, with skipped and modified occurrences marked.",
+		Tags:        []string{"Recurring"},
+	}, func(ctx context.Context, input *ListOccurrencesRequest) (*OccurrencesResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		from, to, err := input.dateRange()
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		template, err := queries.GetRecurring(ctx, gensql.GetRecurringParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error404NotFound("Recurring template not found", err)
+		}
+		out, err := occurrences(ctx, queries, user.ID, []gensql.RecurringTransaction{template}, from, to)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to list occurrences", err)
+		}
+		return &OccurrencesResponse{Body: out}, nil
+	})
+
+	// Modify Occurrence
+	huma.Register(api, huma.Operation{
+		OperationID: "modify-occurrence",
+		Method:      http.MethodPut,
+		Path:        "/recurring/{id}/occurrences/{date}",
+		Summary:     "Skip or Modify Occurrence",
+		Description: "Skips a single occurrence of a template or overrides its date, amount, description, category or notes. Replaces any earlier change to the occurrence. Occurrences whose transaction was already created cannot be changed; edit the transaction instead.",
+		Tags:        []string{"Recurring"},
+	}, func(ctx context.Context, input *ModifyOccurrenceRequest) (*OccurrenceResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		body := input.Body
+		exception := gensql.UpsertRecurringExceptionParams{
+			RecurringID: input.ID,
+			Skip:        body.Skip,
+			Description: body.Description,
+			Category:    body.Category,
+			Notes:       body.Notes,
+		}
+		if body.Date != nil {
+			d, err := time.Parse(time.DateOnly, *body.Date)
+			if err != nil {
+				return nil, huma.Error422UnprocessableEntity("Invalid date", err)
+			}
+			exception.Date = pgDate(d)
+		}
+		if body.Amount != nil {
+			exception.Amount = body.Amount.Numeric
+		}
+
+		var out *Occurrence
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			template, occurrence, err := input.find(ctx, queries, user.ID)
+			if err != nil {
+				return err
+			}
+			exception.Occurrence = pgDate(occurrence)
+			if _, err := queries.UpsertRecurringException(ctx, exception); err != nil {
+				return err
+			}
+			out, err = occurrenceOf(ctx, queries, user.ID, template, occurrence)
+			return err
+		})
+		if err != nil {
+			if se := huma.StatusError(nil); errors.As(err, &se) {
+				return nil, se
+			}
+			return nil, huma.Error500InternalServerError("Failed to modify occurrence", err)
+		}
+		return &OccurrenceResponse{Body: out}, nil
+	})
+
+	// Restore Occurrence
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-occurrence",
+		Method:      http.MethodDelete,
+		Path:        "/recurring/{id}/occurrences/{date}",
+		Summary:     "Restore Occurrence",
+		Description: "Undoes skipping or modifying an occurrence, so it is created from the template again.",
+		Tags:        []string{"Recurring"},
+	}, func(ctx context.Context, input *OccurrenceRequest) (*OccurrenceResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var out *Occurrence
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			template, occurrence, err := input.find(ctx, queries, user.ID)
+			if err != nil {
+				return err
+			}
+			deleted, err := queries.DeleteRecurringException(ctx, gensql.DeleteRecurringExceptionParams{
+				RecurringID: input.ID,
+				Occurrence:  pgDate(occurrence),
+			})
+			if err != nil {
+				return err
+			}
+			if deleted == 0 {
+				return huma.Error404NotFound("Occurrence is neither skipped nor modified")
+			}
+			out, err = occurrenceOf(ctx, queries, user.ID, template, occurrence)
+			return err
+		})
+		if err != nil {
+			if se := huma.StatusError(nil); errors.As(err, &se) {
+				return nil, se
+			}
+			return nil, huma.Error500InternalServerError("Failed to restore occurrence", err)
+		}
+		return &OccurrenceResponse{Body: out}, nil
+	})
+}
+
+// recurringTemplate is a validated template with its defaults filled in.
+type recurringTemplate struct {
+	RecurringTemplate
+	startDate time.Time
+}
+
+// normalize validates the template and fills in its defaults. The rule is
+// stored in a canonical form.
+func (t RecurringTemplate) normalize() (recurringTemplate, error) {
+	rule, err := rrule.Parse(t.Rule)
+	if err != nil {
+		return recurringTemplate{}, huma.Error422UnprocessableEntity("Invalid recurrence rule: " + err.Error())
+	}
+	start, err := time.Parse(time.DateOnly, t.StartDate)
+	if err != nil {
+		return recurringTemplate{}, huma.Error422UnprocessableEntity("Invalid start date", err)
+	}
+
+	t.Rule = rule.String()
+	if t.Currency == "" {
+		t.Currency = "USD"
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	if t.Active == nil {
+		active := true
+		t.Active = &active
+	}
+	return recurringTemplate{RecurringTemplate: t, startDate: start}, nil
+}
+
+// dateRange returns the requested range, by default the next upcomingDays
+// days.
+func (r OccurrenceRangeInput) dateRange() (time.Time, time.Time, error) {
+	from := today(time.Now())
+	if r.From != "" {
+		d, err := time.Parse(time.DateOnly, r.From)
+		if err != nil {
+			return time.Time{}, time.Time{}, huma.Error400BadRequest("Invalid from date", err)
+		}
+		from = d
+	}
+	to := from.AddDate(0, 0, upcomingDays)
+	if r.To != "" {
+		d, err := time.Parse(time.DateOnly, r.To)
+		if err != nil {
+			return time.Time{}, time.Time{}, huma.Error400BadRequest("Invalid to date", err)
+		}
+		to = d
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, huma.Error400BadRequest("to must not be before from")
+	}
+	if to.Sub(from) > maxOccurrenceRange {
+		return time.Time{}, time.Time{}, huma.Error400BadRequest("The range must not exceed a year")
+	}
+	return from, to, nil
+}
+
+// find returns the template and the date of the occurrence, failing unless
+// the rule schedules one on that date and its transaction does not exist yet.
+func (r OccurrenceRequest) find(ctx context.Context, queries *gensql.Queries, userID int64) (gensql.RecurringTransaction, time.Time, error) {
+	template, err := queries.GetRecurring(ctx, gensql.GetRecurringParams{ID: r.ID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return template, time.Time{}, huma.Error404NotFound("Recurring template not found", err)
+		}
+		return template, time.Time{}, err
+	}
+	occurrence, err := time.Parse(time.DateOnly, r.Occurrence)
+	if err != nil {
+		return template, time.Time{}, huma.Error400BadRequest("Invalid occurrence date", err)
+	}
+	rule, err := rrule.Parse(template.Rrule)
+	if err != nil {
+		return template, time.Time{}, err
+	}
+	if len(rule.Between(template.StartDate.Time, occurrence, occurrence)) == 0 {
+		return template, time.Time{}, huma.Error404NotFound("The template has no occurrence on " + r.Occurrence)
+	}
+
+	created, err := queries.ListRecurringTransactions(ctx, gensql.ListRecurringTransactionsParams{
+		UserID:       userID,
+		RecurringIds: []int64{r.ID},
+		DateFrom:     pgDate(occurrence),
+		DateTo:       pgDate(occurrence),
+	})
+	if err != nil {
+		return template, time.Time{}, err
+	}
+	if len(created) > 0 {
+		return template, time.Time{}, huma.Error409Conflict(fmt.Sprintf("The occurrence was already created as transaction %d; edit the transaction instead", created[0].ID))
+	}
+	return template, occurrence, nil
+}
+
+// occurrenceOf returns a single occurrence of a template.
+func occurrenceOf(ctx context.Context, queries *gensql.Queries, userID int64, template gensql.RecurringTransaction, date time.Time) (*Occurrence, error) {
+	out, err := occurrences(ctx, queries, userID, []gensql.RecurringTransaction{template}, date, date)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("template %d has no occurrence on %s", template.ID, date.Format(time.DateOnly))
+	}
+	return &out[0], nil
+}
+
+// occurrences lists the occurrences of templates from from to to, ordered by
+// date, with their exceptions applied and the transactions created for them.
+func occurrences(ctx context.Context, queries *gensql.Queries, userID int64, templates []gensql.RecurringTransaction, from, to time.Time) ([]Occurrence, error) {
+	ids := make([]int64, len(templates))
+	for i, t := range templates {
+		ids[i] = t.ID
+	}
+
+	type key struct {
+		id   int64
+		date time.Time
+	}
+	exceptions, err := queries.ListRecurringExceptions(ctx, gensql.ListRecurringExceptionsParams{
+		UserID:       userID,
+		RecurringIds: ids,
+		DateFrom:     pgDate(from),
+		DateTo:       pgDate(to),
+	})
+	if err != nil {
+		return nil, err
+	}
+	exceptionOf := make(map[key]gensql.RecurringException, len(exceptions))
+	for _, e := range exceptions {
+		exceptionOf[key{e.RecurringID, e.Occurrence.Time}] = e
+	}
+	created, err := queries.ListRecurringTransactions(ctx, gensql.ListRecurringTransactionsParams{
+		UserID:       userID,
+		RecurringIds: ids,
+		DateFrom:     pgDate(from),
+		DateTo:       pgDate(to),
+	})
+	if err != nil {
+		return nil, err
+	}
+	transactionOf := make(map[key]int64, len(created))
+	for _, c := range created {
+		transactionOf[key{*c.RecurringID, c.RecurringOccurrence.Time}] = c.ID
+	}
+
+	out := []Occurrence{}
+	for _, t := range templates {
+		rule, err := rrule.Parse(t.Rrule)
+		if err != nil {
+			return nil, fmt.Errorf("template %d: %w", t.ID, err)
+		}
+		for _, date := range rule.Between(t.StartDate.Time, from, to) {
+			o := Occurrence{
+				RecurringID: t.ID,
+				Occurrence:  date.Format(time.DateOnly),
+				Date:        date.Format(time.DateOnly),
+				Amount:      Decimal{t.Amount},
+				Description: t.Description,
+				Category:    t.Category,
+				Type:        t.Type,
+				Currency:    t.Currency,
+				Account:     t.Account,
+				Tags:        t.Tags,
+				Notes:       t.Notes,
+			}
+			if e, ok := exceptionOf[key{t.ID, date}]; ok {
+				o.apply(e)
+			}
+			if id, ok := transactionOf[key{t.ID, date}]; ok {
+				o.TransactionID = &id
+			}
+			out = append(out, o)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b Occurrence) int {
+		return cmp.Compare(a.Occurrence, b.Occurrence)
+	})
+	return out, nil
+}
+
+// apply overrides the occurrence with the values of an exception.
+func (o *Occurrence) apply(e gensql.RecurringException) {
+	o.Skipped = e.Skip
+	o.Modified = true
+	if e.Date.Valid {
+		o.Date = e.Date.Time.Format(time.DateOnly)
+	}
+	if e.Amount.Valid {
+		o.Amount = Decimal{e.Amount}
+	}
+	if e.Description != nil {
+		o.Description = *e.Description
+	}
+	if e.Category != nil {
+		o.Category = *e.Category
+	}
+	if e.Notes != nil {
+		o.Notes = e.Notes
+	}
+}
+
+// GenerateRecurringTransactions creates a pending transaction for every
+// occurrence of an active template that has come due by now, catching up on
+// occurrences missed while the generator was not running. A template is
+// locked while its transactions are created, and an occurrence is never
+// created twice.
+func GenerateRecurringTransactions(ctx context.Context, db database.Service, now time.Time) error {
+	day := today(now)
+	ids, err := db.GetQueries().ListDueRecurringIDs(ctx, pgDate(day))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, id := range ids {
+		if err := generateRecurring(ctx, db, id, day); err != nil {
+			errs = append(errs, fmt.Errorf("recurring template %d: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// generateRecurring creates the transactions of a template's occurrences
+// after the date it was last generated through, up to today.
+func generateRecurring(ctx context.Context, db database.Service, id int64, today time.Time) error {
+	return db.WithTx(ctx, func(tx pgx.Tx) error {
+		queries := db.GetQueries().WithTx(tx)
+		template, err := queries.LockRecurring(ctx, id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		// Another run may have generated it while we waited for the lock.
+		if !template.Active || (template.GeneratedThrough.Valid && !template.GeneratedThrough.Time.Before(today)) {
+			return nil
+		}
+
+		if err := queries.SetRevisionActor(ctx, "recurring"); err != nil {
+			return err
+		}
+		rule, err := rrule.Parse(template.Rrule)
+		if err != nil {
+			return err
+		}
+		from := template.StartDate.Time
+		if next := template.GeneratedThrough.Time.AddDate(0, 0, 1); template.GeneratedThrough.Valid && next.After(from) {
+			from = next
+		}
+		dates := rule.Between(template.StartDate.Time, from, today)
+		through := today
+		if len(dates) > maxRecurringCatchUp {
+			dates = dates[:maxRecurringCatchUp]
+			through = dates[len(dates)-1]
+		}
+
+		exceptions, err := queries.ListRecurringExceptions(ctx, gensql.ListRecurringExceptionsParams{
+			UserID:       template.UserID,
+			RecurringIds: []int64{template.ID},
+			DateFrom:     pgDate(from),
+			DateTo:       pgDate(through),
+		})
+		if err != nil {
+			return err
+		}
+		exceptionOf := make(map[time.Time]gensql.RecurringException, len(exceptions))
+		for _, e := range exceptions {
+			exceptionOf[e.Occurrence.Time] = e
+		}
+
+		for _, date := range dates {
+			o := Occurrence{
+				Date:        date.Format(time.DateOnly),
+				Amount:      Decimal{template.Amount},
+				Description: template.Description,
+				Category:    template.Category,
+				Notes:       template.Notes,
+			}
+			if e, ok := exceptionOf[date]; ok {
+				o.apply(e)
+			}
+			if o.Skipped {
+				continue
+			}
+			txDate, err := time.Parse(time.DateOnly, o.Date)
+			if err != nil {
+				return err
+			}
+			if _, err := queries.CreateRecurringTransaction(ctx, gensql.CreateRecurringTransactionParams{
+				UserID:              template.UserID,
+				RecurringID:         &template.ID,
+				RecurringOccurrence: pgDate(date),
+				Amount:              o.Amount.Numeric,
+				Description:         o.Description,
+				Category:            o.Category,
+				Type:                template.Type,
+				Currency:            template.Currency,
+				Account:             template.Account,
+				Tags:                template.Tags,
+				Notes:               o.Notes,
+				Date:                pgtype.Timestamptz{Time: txDate, Valid: true},
+			}); err != nil {
+				return err
+			}
+		}
+
+		return queries.SetRecurringGeneratedThrough(ctx, gensql.SetRecurringGeneratedThroughParams{
+			ID:               template.ID,
+			GeneratedThrough: pgDate(through),
+		})
+	})
+}
+
+// today returns the calendar date of t in UTC.
+func today(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func pgDate(t time.Time) pgtype.Date {
+	return pgtype.Date{Time: t, Valid: true}
+}
@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"testing"
+	"time"
+
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRecurringTemplateNormalize(t *testing.T) {
+	tmpl, err := RecurringTemplate{
+		Rule:      "RRULE:freq=monthly;interval=1;byday=mo,tu,we,th,fr;bysetpos=-1",
+		StartDate: "2025-01-01",
+	}.normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Rule != "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1" {
+		t.Errorf("rule = %q", tmpl.Rule)
+	}
+	if tmpl.Currency != "USD" || tmpl.Tags == nil || tmpl.Active == nil || !*tmpl.Active {
+		t.Errorf("defaults not filled in: %+v", tmpl.RecurringTemplate)
+	}
+
+	if _, err := (RecurringTemplate{Rule: "FREQ=HOURLY", StartDate: "2025-01-01"}).normalize(); err == nil {
+		t.Error("unsupported rule accepted")
+	}
+}
+
+func TestOccurrenceRange(t *testing.T) {
+	from, to, err := OccurrenceRangeInput{From: "2025-01-31"}.dateRange()
+	if err != nil || from.Format(time.DateOnly) != "2025-01-31" || to.Format(time.DateOnly) != "2025-03-02" {
+		t.Errorf("default range = %v %v %v", from, to, err)
+	}
+
+	for _, r := range []OccurrenceRangeInput{
+		{From: "2025-02-01", To: "2025-01-31"},
+		{From: "2025-01-01", To: "2026-01-03"},
+	} {
+		if _, _, err := r.dateRange(); err == nil {
+			t.Errorf("range %+v accepted", r)
+		}
+	}
+}
+
+func TestOccurrenceApply(t *testing.T) {
+	notes := "template"
+	o := Occurrence{Date: "2025-03-01", Amount: *decimal("1200.00"), Description: "Rent", Category: "Housing", Notes: &notes}
+
+	description := "Rent (late)"
+	o.apply(gensql.RecurringException{
+		Date:        pgtype.Date{Time: time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC), Valid: true},
+		Description: &description,
+	})
+	if !o.Modified || o.Skipped || o.Date != "2025-03-05" || o.Description != description {
+		t.Errorf("overrides not applied: %+v", o)
+	}
+	if numericString(o.Amount.Numeric) != "1200.00" || o.Category != "Housing" || *o.Notes != notes {
+		t.Errorf("template values replaced: %+v", o)
+	}
+
+	o.apply(gensql.RecurringException{Skip: true})
+	if !o.Skipped {
+		t.Error("skip not applied")
+	}
+}
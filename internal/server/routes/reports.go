@@ -0,0 +1,133 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type CategoryReportRequest struct {
+	TransactionFilterInput
+}
+
+type CategoryReportResponse struct {
+	Body []gensql.GetCategoryTotalsRow
+}
+
+type AccountBalancesRequest struct {
+	TransactionFilterInput
+}
+
+type AccountBalancesResponse struct {
+	Body []gensql.GetAccountBalancesRow
+}
+
+func RegisterReportRoutes(api huma.API, db database.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "category-report",
+		Method:      http.MethodGet,
+		Path:        "/reports/categories",
+		Summary:     "Category Report",
+		Description: "Totals the matching transactions per category, type and currency. Split transactions count each line under its own category with its own amount, and the category, amount and tag filters select lines rather than whole transactions.",
+		Tags:        []string{"Reports"},
+	}, func(ctx context.Context, input *CategoryReportRequest) (*CategoryReportResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		params, err := input.ToFilterParams(user.ID)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
+		totals, err := db.GetQueries().GetCategoryTotals(ctx, categoryTotalsParamsFor(params))
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to compute category totals", err)
+		}
+		if totals == nil {
+			totals = []gensql.GetCategoryTotalsRow{}
+		}
+		return &CategoryReportResponse{Body: totals}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "account-balances",
+		Method:      http.MethodGet,
+		Path:        "/reports/balances",
+		Summary:     "Account Balances",
+		Description: "Balance of every account per currency over the matching transactions: income minus expenses, plus transfers in and minus transfers out.",
+		Tags:        []string{"Reports"},
+	}, func(ctx context.Context, input *AccountBalancesRequest) (*AccountBalancesResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		params, err := input.ToFilterParams(user.ID)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
+		balances, err := db.GetQueries().GetAccountBalances(ctx, accountBalancesParamsFor(params))
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to compute account balances", err)
+		}
+		if balances == nil {
+			balances = []gensql.GetAccountBalancesRow{}
+		}
+		return &AccountBalancesResponse{Body: balances}, nil
+	})
+}
+
+// categoryTotalsParamsFor returns the category report parameters matching a
+// list query.
+func categoryTotalsParamsFor(params gensql.ListTransactionsWithFiltersParams) gensql.GetCategoryTotalsParams {
+	return gensql.GetCategoryTotalsParams{
+		UserID:            params.UserID,
+		Search:            params.Search,
+		DateFrom:          params.DateFrom,
+		DateTo:            params.DateTo,
+		Categories:        params.Categories,
+		Type:              params.Type,
+		MinAmount:         params.MinAmount,
+		MaxAmount:         params.MaxAmount,
+		Tags:              params.Tags,
+		ExcludeCategories: params.ExcludeCategories,
+		ExcludeTags:       params.ExcludeTags,
+		Statuses:          params.Statuses,
+		Accounts:          params.Accounts,
+		Currencies:        params.Currencies,
+		HasReceipt:        params.HasReceipt,
+		CreatedSince:      params.CreatedSince,
+		UpdatedSince:      params.UpdatedSince,
+	}
+}
+
+// accountBalancesParamsFor returns the account balance parameters matching a
+// list query.
+func accountBalancesParamsFor(params gensql.ListTransactionsWithFiltersParams) gensql.GetAccountBalancesParams {
+	return gensql.GetAccountBalancesParams{
+		UserID:            params.UserID,
+		Search:            params.Search,
+		DateFrom:          params.DateFrom,
+		DateTo:            params.DateTo,
+		Categories:        params.Categories,
+		Type:              params.Type,
+		MinAmount:         params.MinAmount,
+		MaxAmount:         params.MaxAmount,
+		Tags:              params.Tags,
+		ExcludeCategories: params.ExcludeCategories,
+		ExcludeTags:       params.ExcludeTags,
+		Statuses:          params.Statuses,
+		Accounts:          params.Accounts,
+		Currencies:        params.Currencies,
+		HasReceipt:        params.HasReceipt,
+		CreatedSince:      params.CreatedSince,
+		UpdatedSince:      params.UpdatedSince,
+	}
+}
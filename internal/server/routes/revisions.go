@@ -0,0 +1,186 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// revisionFields are the transaction fields recorded by revisions, in the
+// order their changes are listed. They match transaction_revision_values()
+// in the database.
+var revisionFields = []string{
+	"date", "amount", "description", "category", "type", "currency",
+	"status", "account", "tags", "notes", "payee_id",
+}
+
+type Revision struct {
+	ID        int64         `json:"id"`
+	Action    string        `json:"action" enum:"create,update,delete,restore"`
+	Actor     string        `json:"actor" doc:"\"user\" for changes made by a user, otherwise the server job that made them, e.g. \"recurring\""`
+	ActorID   *int64        `json:"actor_id,omitempty" doc:"User who made the change"`
+	CreatedAt time.Time     `json:"created_at"`
+	Changes   []FieldChange `json:"changes" doc:"Fields that changed; every set field for a create"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+type TransactionHistoryRequest struct {
+	ID int64 `path:"id" doc:"Transaction ID"`
+}
+
+type TransactionHistoryResponse struct {
+	Body []Revision
+}
+
+type RevertTransactionRequest struct {
+	ID         int64 `path:"id" doc:"Transaction ID"`
+	RevisionID int64 `path:"revision_id" doc:"Revision to go back to"`
+}
+
+type RevertTransactionResponse struct {
+	Body *gensql.Transaction
+}
+
+func RegisterRevisionRoutes(api huma.API, db database.Service) {
+	// Transaction History
+	huma.Register(api, huma.Operation{
+		OperationID: "transaction-history",
+		Method:      http.MethodGet,
+		Path:        "/transactions/{id}/history",
+		Summary:     "Transaction History",
+		Description: "Lists every create, update, delete and restore of a transaction, oldest first, with the fields each one changed.",
+		Tags:        []string{"Transactions"},
+	}, func(ctx context.Context, input *TransactionHistoryRequest) (*TransactionHistoryResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions, err := db.GetQueries().ListTransactionRevisions(ctx, gensql.ListTransactionRevisionsParams{
+			TransactionID: input.ID,
+			UserID:        user.ID,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch history", err)
+		}
+		if len(revisions) == 0 {
+			return nil, huma.Error404NotFound("Transaction not found")
+		}
+
+		out := make([]Revision, len(revisions))
+		for i, r := range revisions {
+			changes, err := diffRevision(r.OldValues, r.NewValues)
+			if err != nil {
+				return nil, huma.Error500InternalServerError("Failed to read history", err)
+			}
+			out[i] = Revision{
+				ID:        r.ID,
+				Action:    r.Action,
+				Actor:     r.Actor,
+				ActorID:   r.ActorID,
+				CreatedAt: r.CreatedAt.Time,
+				Changes:   changes,
+			}
+		}
+		return &TransactionHistoryResponse{Body: out}, nil
+	})
+
+	// Revert Transaction
+	huma.Register(api, huma.Operation{
+		OperationID: "revert-transaction",
+		Method:      http.MethodPost,
+		Path:        "/transactions/{id}/history/{revision_id}/revert",
+		Summary:     "Revert Transaction",
+		Description: "Sets the transaction back to how it was right after an earlier revision. The revert is itself recorded in the history, so it can be undone the same way. Transactions in the trash have to be restored first.",
+		Tags:        []string{"Transactions"},
+	}, func(ctx context.Context, input *RevertTransactionRequest) (*RevertTransactionResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var transaction gensql.Transaction
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+			transaction, err = queries.RevertTransaction(ctx, gensql.RevertTransactionParams{
+				RevisionID: input.RevisionID,
+				ID:         input.ID,
+				UserID:     user.ID,
+			})
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, huma.Error404NotFound("Revision not found", err)
+			}
+			if pgErr, ok := pgError(err, pgCheckViolation); ok {
+				return nil, huma.Error422UnprocessableEntity(checkViolationMessage(pgErr))
+			}
+			return nil, huma.Error500InternalServerError("Failed to revert transaction", err)
+		}
+		return &RevertTransactionResponse{Body: &transaction}, nil
+	})
+}
+
+// diffRevision lists the fields whose recorded values differ between old and
+// new. Without old values, as for a create, every field set in new is listed.
+func diffRevision(oldValues, newValues []byte) ([]FieldChange, error) {
+	var before, after map[string]json.RawMessage
+	if oldValues != nil {
+		if err := json.Unmarshal(oldValues, &before); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(newValues, &after); err != nil {
+		return nil, err
+	}
+
+	changes := []FieldChange{}
+	for _, field := range revisionFields {
+		o, n := before[field], after[field]
+		if bytes.Equal(o, n) || (o == nil && isJSONNull(n)) {
+			continue
+		}
+		change := FieldChange{Field: field}
+		if err := decodeJSONValue(o, &change.Old); err != nil {
+			return nil, err
+		}
+		if err := decodeJSONValue(n, &change.New); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func isJSONNull(v json.RawMessage) bool {
+	return v == nil || bytes.Equal(v, []byte("null"))
+}
+
+// decodeJSONValue decodes v into dst, keeping numbers exact. A missing
+// value decodes to nil.
+func decodeJSONValue(v json.RawMessage, dst *any) error {
+	if v == nil {
+		return nil
+	}
+	d := json.NewDecoder(bytes.NewReader(v))
+	d.UseNumber()
+	return d.Decode(dst)
+}
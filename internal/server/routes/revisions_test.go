@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiffRevision(t *testing.T) {
+	created := `{"date": "2025-03-01T00:00:00+00:00", "tags": [], "type": "expense", "notes": null, "amount": 12.50, "status": "pending", "account": "", "category": "Food", "currency": "USD", "description": "Lunch"}`
+	updated := `{"date": "2025-03-01T00:00:00+00:00", "tags": ["work"], "type": "expense", "notes": null, "amount": 12.50, "status": "cleared", "account": "", "category": "Food", "currency": "USD", "description": "Lunch"}`
+
+	changes, err := diffRevision(nil, []byte(created))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 9 || changes[0].Field != "date" || changes[0].Old != nil {
+		t.Errorf("create changes = %+v", changes)
+	}
+	if changes[1].Field != "amount" || changes[1].New != json.Number("12.50") {
+		t.Errorf("amount change = %+v", changes[1])
+	}
+
+	changes, err = diffRevision([]byte(created), []byte(updated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(changes)
+	want := `[{"field":"status","old":"pending","new":"cleared"},{"field":"tags","old":[],"new":["work"]}]`
+	if string(got) != want {
+		t.Errorf("update changes = %s, want %s", got, want)
+	}
+
+	withPayee := `{"date": "2025-03-01T00:00:00+00:00", "tags": ["work"], "type": "expense", "notes": null, "amount": 12.50, "status": "cleared", "account": "", "category": "Food", "currency": "USD", "description": "Lunch", "payee_id": 7}`
+	changes, err = diffRevision([]byte(updated), []byte(withPayee))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := json.Marshal(changes); string(got) != `[{"field":"payee_id","old":null,"new":7}]` {
+		t.Errorf("payee changes = %s", got)
+	}
+
+	if changes, err := diffRevision([]byte(updated), []byte(updated)); err != nil || len(changes) != 0 {
+		t.Errorf("unchanged = %+v, %v", changes, err)
+	}
+}
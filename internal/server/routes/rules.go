@@ -0,0 +1,562 @@
+package routes
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/numeric"
+	"budgetctl-go/internal/rules"
+	"budgetctl-go/internal/txquery"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+type RuleInput struct {
+	Name               string   `json:"name" minLength:"1"`
+	Priority           int32    `json:"priority,omitempty" doc:"Rules with lower priorities apply first and win when rules set the same field; 0 by default"`
+	Enabled            *bool    `json:"enabled,omitempty" doc:"true by default"`
+	DescriptionPattern *string  `json:"description_pattern,omitempty" minLength:"1" doc:"RE2 regular expression matched anywhere in the description, e.g. (?i)^netflix"`
+	MinAmount          *Decimal `json:"min_amount,omitempty" doc:"Smallest matching amount, inclusive"`
+	MaxAmount          *Decimal `json:"max_amount,omitempty" doc:"Largest matching amount, inclusive"`
+	Account            *string  `json:"account,omitempty"`
+	Currency           *string  `json:"currency,omitempty"`
+	Type               *string  `json:"type,omitempty" enum:"income,expense,transfer"`
+	SetCategory        *string  `json:"set_category,omitempty" minLength:"1" doc:"Category to set"`
+	AddTags            []string `json:"add_tags,omitempty" doc:"Tags to add"`
+	SetNotes           *string  `json:"set_notes,omitempty" doc:"Notes to set"`
+	SetStatus          *string  `json:"set_status,omitempty" enum:"pending,cleared" doc:"Status to set; reconciled transactions keep theirs"`
+}
+
+// RuleSample is a transaction to test rules against.
+type RuleSample struct {
+	Description string   `json:"description"`
+	Amount      Decimal  `json:"amount"`
+	Account     string   `json:"account,omitempty"`
+	Currency    string   `json:"currency,omitempty" doc:"USD by default"`
+	Type        string   `json:"type,omitempty" enum:"income,expense,transfer" doc:"expense by default"`
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Notes       *string  `json:"notes,omitempty"`
+	Status      string   `json:"status,omitempty" doc:"pending by default"`
+}
+
+type RuleTestResult struct {
+	MatchedRules []int64  `json:"matched_rules" doc:"Rules that matched, in the order they applied"`
+	Category     string   `json:"category"`
+	Tags         []string `json:"tags"`
+	Notes        *string  `json:"notes,omitempty"`
+	Status       string   `json:"status"`
+}
+
+type RuleChange struct {
+	TransactionID int64         `json:"transaction_id"`
+	MatchedRules  []int64       `json:"matched_rules"`
+	Changes       []FieldChange `json:"changes"`
+}
+
+type RuleRequest struct {
+	ID int64 `path:"id" doc:"Rule ID"`
+}
+
+type CreateRuleRequest struct {
+	Body RuleInput
+}
+
+type UpdateRuleRequest struct {
+	ID   int64 `path:"id" doc:"Rule ID"`
+	Body RuleInput
+}
+
+type RuleResponse struct {
+	Body *gensql.Rule
+}
+
+type ListRulesResponse struct {
+	Body []gensql.Rule
+}
+
+type TestRulesRequest struct {
+	Body RuleSample
+}
+
+type TestRuleRequest struct {
+	ID   int64 `path:"id" doc:"Rule ID"`
+	Body RuleSample
+}
+
+type RuleTestResponse struct {
+	Body *RuleTestResult
+}
+
+type ApplyRulesRequest struct {
+	Preview bool `query:"preview" doc:"List the changes without saving them"`
+	Body    struct {
+		Query   string  `json:"query,omitempty" doc:"Structured query selecting the transactions, e.g. category:uncategorized after:2025-01-01; all transactions by default"`
+		RuleIDs []int64 `json:"rule_ids,omitempty" doc:"Only apply these rules, even if disabled; all enabled rules by default"`
+	}
+}
+
+type ApplyRulesResponse struct {
+	Body struct {
+		Preview bool         `json:"preview"`
+		Matched int64        `json:"matched" doc:"Transactions selected by the query"`
+		Changed int          `json:"changed" doc:"Transactions the rules changed"`
+		Changes []RuleChange `json:"changes"`
+	}
+}
+
+func RegisterRuleRoutes(api huma.API, db database.Service) {
+	// List Rules
+	huma.Register(api, huma.Operation{
+		OperationID: "list-rules",
+		Method:      http.MethodGet,
+		Path:        "/rules",
+		Summary:     "List Rules",
+		Description: "Lists rules in the order they apply.",
+		Tags:        []string{"Rules"},
+	}, func(ctx context.Context, input *struct{}) (*ListRulesResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := db.GetQueries().ListRules(ctx, user.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch rules", err)
+		}
+		if list == nil {
+			list = []gensql.Rule{}
+		}
+		return &ListRulesResponse{Body: list}, nil
+	})
+
+	// Create Rule
+	huma.Register(api, huma.Operation{
+		OperationID: "create-rule",
+		Method:      http.MethodPost,
+		Path:        "/rules",
+		Summary:     "Create Rule",
+		Description: "Creates a rule that sets the category, notes or status of matching transactions and adds tags to them. Enabled rules run on every created and imported transaction; a rule needs at least one condition and one action.",
+		Tags:        []string{"Rules"},
+	}, func(ctx context.Context, input *CreateRuleRequest) (*RuleResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := input.Body.normalize()
+		if err != nil {
+			return nil, err
+		}
+
+		rule, err := db.GetQueries().CreateRule(ctx, gensql.CreateRuleParams{
+			UserID:             user.ID,
+			Name:               r.Name,
+			Priority:           r.Priority,
+			Enabled:            r.Enabled,
+			DescriptionPattern: r.DescriptionPattern,
+			MinAmount:          r.MinAmount,
+			MaxAmount:          r.MaxAmount,
+			Account:            r.Account,
+			Currency:           r.Currency,
+			Type:               r.Type,
+			SetCategory:        r.SetCategory,
+			AddTags:            r.AddTags,
+			SetNotes:           r.SetNotes,
+			SetStatus:          r.SetStatus,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to create rule", err)
+		}
+		return &RuleResponse{Body: &rule}, nil
+	})
+
+	// Get Rule
+	huma.Register(api, huma.Operation{
+		OperationID: "get-rule",
+		Method:      http.MethodGet,
+		Path:        "/rules/{id}",
+		Summary:     "Get Rule",
+		Tags:        []string{"Rules"},
+	}, func(ctx context.Context, input *RuleRequest) (*RuleResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		rule, err := db.GetQueries().GetRule(ctx, gensql.GetRuleParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error404NotFound("Rule not found", err)
+		}
+		return &RuleResponse{Body: &rule}, nil
+	})
+
+	// Update Rule
+	huma.Register(api, huma.Operation{
+		OperationID: "update-rule",
+		Method:      http.MethodPut,
+		Path:        "/rules/{id}",
+		Summary:     "Update Rule",
+		Description: "Replaces the rule. Transactions it already changed are left as they are; re-apply rules to update them.",
+		Tags:        []string{"Rules"},
+	}, func(ctx context.Context, input *UpdateRuleRequest) (*RuleResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := input.Body.normalize()
+		if err != nil {
+			return nil, err
+		}
+
+		rule, err := db.GetQueries().UpdateRule(ctx, gensql.UpdateRuleParams{
+			ID:                 input.ID,
+			UserID:             user.ID,
+			Name:               r.Name,
+			Priority:           r.Priority,
+			Enabled:            r.Enabled,
+			DescriptionPattern: r.DescriptionPattern,
+			MinAmount:          r.MinAmount,
+			MaxAmount:          r.MaxAmount,
+			Account:            r.Account,
+			Currency:           r.Currency,
+			Type:               r.Type,
+			SetCategory:        r.SetCategory,
+			AddTags:            r.AddTags,
+			SetNotes:           r.SetNotes,
+			SetStatus:          r.SetStatus,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, huma.Error404NotFound("Rule not found", err)
+			}
+			return nil, huma.Error500InternalServerError("Failed to update rule", err)
+		}
+		return &RuleResponse{Body: &rule}, nil
+	})
+
+	// Delete Rule
+	huma.Register(api, huma.Operation{
+		OperationID:   "delete-rule",
+		Method:        http.MethodDelete,
+		Path:          "/rules/{id}",
+		Summary:       "Delete Rule",
+		Description:   "Deletes the rule. Transactions it already changed are left as they are.",
+		Tags:          []string{"Rules"},
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *RuleRequest) (*struct{}, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		deleted, err := db.GetQueries().DeleteRule(ctx, gensql.DeleteRuleParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to delete rule", err)
+		}
+		if deleted == 0 {
+			return nil, huma.Error404NotFound("Rule not found")
+		}
+		return nil, nil
+	})
+
+	// Test Rules
+	huma.Register(api, huma.Operation{
+		OperationID: "test-rules",
+		Method:      http.MethodPost,
+		Path:        "/rules/test",
+		Summary:     "Test Rules",
+		Description: "Runs the enabled rules on a sample transaction, as they would run when it is created, without saving anything.",
+		Tags:        []string{"Rules"},
+	}, func(ctx context.Context, input *TestRulesRequest) (*RuleTestResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		enabled, err := enabledRules(ctx, db.GetQueries(), user.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch rules", err)
+		}
+		return &RuleTestResponse{Body: input.Body.test(enabled)}, nil
+	})
+
+	// Test Rule
+	huma.Register(api, huma.Operation{
+		OperationID: "test-rule",
+		Method:      http.MethodPost,
+		Path:        "/rules/{id}/test",
+		Summary:     "Test Rule",
+		Description: "Runs a single rule, even if disabled, on a sample transaction without saving anything.",
+		Tags:        []string{"Rules"},
+	}, func(ctx context.Context, input *TestRuleRequest) (*RuleTestResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		stored, err := db.GetQueries().GetRule(ctx, gensql.GetRuleParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error404NotFound("Rule not found", err)
+		}
+		rule, err := rules.Compile(stored)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity(err.Error())
+		}
+		return &RuleTestResponse{Body: input.Body.test([]*rules.Rule{rule})}, nil
+	})
+
+	// Apply Rules
+	huma.Register(api, huma.Operation{
+		OperationID: "apply-rules",
+		Method:      http.MethodPost,
+		Path:        "/rules/apply",
+		Summary:     "Apply Rules",
+		Description: "Re-applies rules to existing transactions, listing the fields each change sets. With preview=true nothing is saved.",
+		Tags:        []string{"Rules"},
+	}, func(ctx context.Context, input *ApplyRulesRequest) (*ApplyRulesResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		params := gensql.ListTransactionsWithFiltersParams{UserID: user.ID}
+		if err := txquery.Apply(input.Body.Query, &params); err != nil {
+			return nil, huma.Error422UnprocessableEntity(err.Error())
+		}
+
+		resp := &ApplyRulesResponse{}
+		resp.Body.Preview = input.Preview
+		resp.Body.Changes = []RuleChange{}
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+			selected, err := selectRules(ctx, queries, user.ID, input.Body.RuleIDs)
+			if err != nil {
+				return err
+			}
+
+			total, err := queries.CountTransactions(ctx, countParamsFor(params))
+			if err != nil {
+				return err
+			}
+			resp.Body.Matched = total
+			if total == 0 {
+				return nil
+			}
+			if total > maxBatchMatches {
+				return huma.Error422UnprocessableEntity(fmt.Sprintf("query matches %d transactions, at most %d can be changed at once", total, maxBatchMatches))
+			}
+			params.Limit = int32(total)
+			matches, err := queries.ListTransactionsWithFilters(ctx, params)
+			if err != nil {
+				return err
+			}
+
+			for _, m := range matches {
+				t := rules.Transaction{
+					Description: m.Description,
+					Amount:      numeric.Rat(m.Amount),
+					Account:     m.Account,
+					Currency:    m.Currency,
+					Type:        m.Type,
+					Category:    m.Category,
+					Tags:        slices.Clone(m.Tags),
+					Notes:       m.Notes,
+					Status:      m.Status,
+				}
+				matched := rules.Apply(selected, &t)
+				changes := ruleChanges(m, t)
+				if len(changes) == 0 {
+					continue
+				}
+				resp.Body.Changes = append(resp.Body.Changes, RuleChange{
+					TransactionID: m.ID,
+					MatchedRules:  matched,
+					Changes:       changes,
+				})
+				if input.Preview {
+					continue
+				}
+				if _, err := queries.ApplyRuleChanges(ctx, gensql.ApplyRuleChangesParams{
+					ID:       m.ID,
+					UserID:   user.ID,
+					Category: t.Category,
+					Tags:     t.Tags,
+					Notes:    t.Notes,
+					Status:   t.Status,
+				}); err != nil {
+					return err
+				}
+			}
+			resp.Body.Changed = len(resp.Body.Changes)
+			return nil
+		})
+		if err != nil {
+			if se := huma.StatusError(nil); errors.As(err, &se) {
+				return nil, err
+			}
+			if pgErr, ok := pgError(err, pgCheckViolation); ok {
+				return nil, huma.Error422UnprocessableEntity(checkViolationMessage(pgErr))
+			}
+			return nil, huma.Error500InternalServerError("Failed to apply rules", err)
+		}
+		return resp, nil
+	})
+}
+
+// normalize validates the rule and fills in defaults. The result is not
+// stored; it carries the values to save.
+func (in RuleInput) normalize() (gensql.Rule, error) {
+	r := gensql.Rule{
+		Name:               in.Name,
+		Priority:           in.Priority,
+		Enabled:            in.Enabled == nil || *in.Enabled,
+		DescriptionPattern: in.DescriptionPattern,
+		Account:            in.Account,
+		Currency:           in.Currency,
+		Type:               in.Type,
+		SetCategory:        in.SetCategory,
+		AddTags:            in.AddTags,
+		SetNotes:           in.SetNotes,
+		SetStatus:          in.SetStatus,
+	}
+	if in.MinAmount != nil {
+		r.MinAmount = in.MinAmount.Numeric
+	}
+	if in.MaxAmount != nil {
+		r.MaxAmount = in.MaxAmount.Numeric
+	}
+	if r.AddTags == nil {
+		r.AddTags = []string{}
+	}
+	if _, err := rules.Compile(r); err != nil {
+		return gensql.Rule{}, huma.Error422UnprocessableEntity("Invalid rule: " + err.Error())
+	}
+	return r, nil
+}
+
+// test runs rules on the sample.
+func (s RuleSample) test(list []*rules.Rule) *RuleTestResult {
+	t := rules.Transaction{
+		Description: s.Description,
+		Amount:      numeric.Rat(s.Amount.Numeric),
+		Account:     s.Account,
+		Currency:    cmp.Or(s.Currency, "USD"),
+		Type:        cmp.Or(s.Type, "expense"),
+		Category:    s.Category,
+		Tags:        slices.Clone(s.Tags),
+		Notes:       s.Notes,
+		Status:      cmp.Or(s.Status, "pending"),
+	}
+	matched := rules.Apply(list, &t)
+	if matched == nil {
+		matched = []int64{}
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	return &RuleTestResult{
+		MatchedRules: matched,
+		Category:     t.Category,
+		Tags:         t.Tags,
+		Notes:        t.Notes,
+		Status:       t.Status,
+	}
+}
+
+// enabledRules loads the user's enabled rules in the order they apply.
+// Rules that no longer compile are skipped.
+func enabledRules(ctx context.Context, queries *gensql.Queries, userID int64) ([]*rules.Rule, error) {
+	stored, err := queries.ListEnabledRules(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return compileRules(stored), nil
+}
+
+// selectRules loads the given rules in the order they apply, or all enabled
+// rules if ids is empty.
+func selectRules(ctx context.Context, queries *gensql.Queries, userID int64, ids []int64) ([]*rules.Rule, error) {
+	if len(ids) == 0 {
+		return enabledRules(ctx, queries, userID)
+	}
+	stored, err := queries.ListRules(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var selected []gensql.Rule
+	for _, id := range ids {
+		i := slices.IndexFunc(stored, func(r gensql.Rule) bool { return r.ID == id })
+		if i < 0 {
+			return nil, huma.Error404NotFound(fmt.Sprintf("Rule %d not found", id))
+		}
+		selected = append(selected, stored[i])
+	}
+	slices.SortFunc(selected, func(a, b gensql.Rule) int {
+		return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(a.ID, b.ID))
+	})
+	return compileRules(slices.CompactFunc(selected, func(a, b gensql.Rule) bool { return a.ID == b.ID })), nil
+}
+
+func compileRules(stored []gensql.Rule) []*rules.Rule {
+	compiled := make([]*rules.Rule, 0, len(stored))
+	for _, r := range stored {
+		rule, err := rules.Compile(r)
+		if err != nil {
+			log.Printf("skipping rule %d: %v", r.ID, err)
+			continue
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled
+}
+
+// applyRulesOnCreate runs the user's enabled rules on transactions about to
+// be created.
+func applyRulesOnCreate(ctx context.Context, queries *gensql.Queries, userID int64, params ...*gensql.CreateTransactionParams) error {
+	enabled, err := enabledRules(ctx, queries, userID)
+	if err != nil || len(enabled) == 0 {
+		return err
+	}
+	for _, p := range params {
+		rules.ApplyToParams(enabled, p)
+	}
+	return nil
+}
+
+// ruleChanges lists the fields rules changed on a transaction.
+func ruleChanges(before gensql.ListTransactionsWithFiltersRow, after rules.Transaction) []FieldChange {
+	var changes []FieldChange
+	if before.Category != after.Category {
+		changes = append(changes, FieldChange{Field: "category", Old: before.Category, New: after.Category})
+	}
+	if before.Status != after.Status {
+		changes = append(changes, FieldChange{Field: "status", Old: before.Status, New: after.Status})
+	}
+	if !slices.Equal(before.Tags, after.Tags) {
+		changes = append(changes, FieldChange{Field: "tags", Old: before.Tags, New: after.Tags})
+	}
+	if !equalPtr(before.Notes, after.Notes) {
+		changes = append(changes, FieldChange{Field: "notes", Old: before.Notes, New: after.Notes})
+	}
+	return changes
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
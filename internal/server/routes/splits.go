@@ -0,0 +1,154 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+type Split struct {
+	Amount   Decimal  `json:"amount" doc:"Part of the transaction amount; the amounts of all splits must add up to it"`
+	Category string   `json:"category" minLength:"1"`
+	Tags     []string `json:"tags,omitempty" doc:"Tags of this line, in addition to the transaction's"`
+	Notes    *string  `json:"notes,omitempty"`
+}
+
+type ListSplitsRequest struct {
+	TransactionID int64 `path:"id" doc:"Transaction ID"`
+}
+
+type ReplaceSplitsRequest struct {
+	TransactionID int64 `path:"id" doc:"Transaction ID"`
+	IfMatchInput
+	Body struct {
+		Splits []Split `json:"splits" maxItems:"100" doc:"New split lines in order; empty to stop splitting the transaction"`
+	}
+}
+
+type SplitsResponse struct {
+	ETag string `header:"ETag" doc:"ETag of the transaction"`
+	Body []gensql.TransactionSplit
+}
+
+func RegisterSplitRoutes(api huma.API, db database.Service) {
+	// List Splits
+	huma.Register(api, huma.Operation{
+		OperationID: "list-splits",
+		Method:      http.MethodGet,
+		Path:        "/transactions/{id}/splits",
+		Summary:     "List Splits",
+		Description: "Lists the lines a transaction is split into. Unsplit transactions have none.",
+		Tags:        []string{"Transactions"},
+	}, func(ctx context.Context, input *ListSplitsRequest) (*SplitsResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		transaction, err := queries.GetTransactionByID(ctx, gensql.GetTransactionByIDParams{
+			ID:     input.TransactionID,
+			UserID: user.ID,
+		})
+		if err != nil {
+			return nil, huma.Error404NotFound("Transaction not found", err)
+		}
+
+		splits, err := queries.ListSplits(ctx, gensql.ListSplitsParams{
+			TransactionID: input.TransactionID,
+			UserID:        user.ID,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch splits", err)
+		}
+		if splits == nil {
+			splits = []gensql.TransactionSplit{}
+		}
+		return &SplitsResponse{ETag: transactionETag(transaction), Body: splits}, nil
+	})
+
+	// Replace Splits
+	huma.Register(api, huma.Operation{
+		OperationID: "replace-splits",
+		Method:      http.MethodPut,
+		Path:        "/transactions/{id}/splits",
+		Summary:     "Replace Splits",
+		Description: "Replaces the lines a transaction is split into. Each line has its own amount, category, tags and note, and the amounts must add up to the transaction amount. Category reports and filters count the lines instead of the transaction.",
+		Tags:        []string{"Transactions"},
+	}, func(ctx context.Context, input *ReplaceSplitsRequest) (*SplitsResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(input.Body.Splits) == 1 {
+			return nil, huma.Error422UnprocessableEntity("A transaction must be split into at least two lines")
+		}
+
+		splits := []gensql.TransactionSplit{}
+		var transaction gensql.Transaction
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+
+			current, err := queries.LockTransaction(ctx, gensql.LockTransactionParams{
+				ID:     input.TransactionID,
+				UserID: user.ID,
+			})
+			if err != nil {
+				return err
+			}
+			if err := input.Check(current); err != nil {
+				return err
+			}
+
+			if err := queries.DeleteSplits(ctx, input.TransactionID); err != nil {
+				return err
+			}
+			for i, s := range input.Body.Splits {
+				tags := s.Tags
+				if tags == nil {
+					tags = []string{}
+				}
+				split, err := queries.CreateSplit(ctx, gensql.CreateSplitParams{
+					TransactionID: input.TransactionID,
+					Position:      int32(i),
+					Amount:        s.Amount.Numeric,
+					Category:      s.Category,
+					Tags:          tags,
+					Notes:         s.Notes,
+				})
+				if err != nil {
+					return err
+				}
+				splits = append(splits, split)
+			}
+
+			// The splits are part of the transaction, so it gets a new version.
+			transaction, err = queries.TouchTransaction(ctx, input.TransactionID)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, huma.Error404NotFound("Transaction not found", err)
+			}
+			if se := huma.StatusError(nil); errors.As(err, &se) {
+				return nil, err
+			}
+			if pgErr, ok := pgError(err, pgCheckViolation); ok {
+				return nil, huma.Error422UnprocessableEntity(checkViolationMessage(pgErr))
+			}
+			return nil, huma.Error500InternalServerError("Failed to save splits", err)
+		}
+
+		return &SplitsResponse{ETag: transactionETag(transaction), Body: splits}, nil
+	})
+}
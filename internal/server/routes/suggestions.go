@@ -0,0 +1,163 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"budgetctl-go/internal/classifier"
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/importer"
+	"budgetctl-go/internal/numeric"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const (
+	// maxTrainingExamples bounds how many of the latest categorized
+	// transactions category suggestions are learned from.
+	maxTrainingExamples = 5000
+	// minTrainingExamples is how many categorized transactions a user needs
+	// before categories are suggested at all.
+	minTrainingExamples = 20
+	// autoCategorizeConfidence is the confidence from which imports fill in
+	// a suggested category rather than flagging the row for review.
+	autoCategorizeConfidence = 0.8
+	// reviewTag marks imported transactions whose suggested category was
+	// not confident enough to fill in.
+	reviewTag = "needs-review"
+)
+
+type SuggestCategoryRequest struct {
+	Body struct {
+		Description string   `json:"description" minLength:"1"`
+		Amount      *Decimal `json:"amount,omitempty"`
+		Account     string   `json:"account,omitempty"`
+		Type        string   `json:"type,omitempty" enum:"income,expense" doc:"expense by default"`
+		Limit       int      `json:"limit,omitempty" minimum:"1" maximum:"20" doc:"Number of categories to suggest, 3 by default"`
+	}
+}
+
+type SuggestCategoryResponse struct {
+	Body struct {
+		TrainedOn   int                     `json:"trained_on" doc:"Number of categorized transactions the suggestions were learned from"`
+		Suggestions []classifier.Suggestion `json:"suggestions" doc:"Most likely category first; empty until enough transactions are categorized"`
+	}
+}
+
+func RegisterSuggestionRoutes(api huma.API, db database.Service) {
+	// Suggest Category
+	huma.Register(api, huma.Operation{
+		OperationID: "suggest-category",
+		Method:      http.MethodPost,
+		Path:        "/transactions/suggest-category",
+		Summary:     "Suggest Category",
+		Description: "Suggests categories for a transaction, learned from the description, amount, account and type of the latest categorized transactions. Confidences are estimated probabilities over all known categories.",
+		Tags:        []string{"Transactions"},
+	}, func(ctx context.Context, input *SuggestCategoryRequest) (*SuggestCategoryResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		model, err := trainCategoryModel(ctx, db.GetQueries(), user.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to learn categories", err)
+		}
+
+		body := input.Body
+		if body.Type == "" {
+			body.Type = "expense"
+		}
+		if body.Limit == 0 {
+			body.Limit = 3
+		}
+		var amount Decimal
+		if body.Amount != nil {
+			amount = *body.Amount
+		}
+
+		resp := &SuggestCategoryResponse{}
+		resp.Body.TrainedOn = model.Examples()
+		resp.Body.Suggestions = []classifier.Suggestion{}
+		if model.Examples() >= minTrainingExamples {
+			features := classifier.Features(body.Description, numeric.Rat(amount.Numeric), body.Account, body.Type)
+			resp.Body.Suggestions = append(resp.Body.Suggestions, model.Suggest(features, body.Limit)...)
+		}
+		return resp, nil
+	})
+}
+
+// trainCategoryModel learns the user's categories from their latest
+// categorized transactions.
+func trainCategoryModel(ctx context.Context, queries *gensql.Queries, userID int64) (*classifier.Model, error) {
+	rows, err := queries.ListCategorizedTransactions(ctx, gensql.ListCategorizedTransactionsParams{
+		UserID:        userID,
+		Uncategorized: importer.DefaultCategory,
+		Limit:         maxTrainingExamples,
+	})
+	if err != nil {
+		return nil, err
+	}
+	examples := make([]classifier.Example, len(rows))
+	for i, r := range rows {
+		examples[i] = classifier.Example{
+			Features: classifier.Features(r.Description, numeric.Rat(r.Amount), r.Account, r.Type),
+			Category: r.Category,
+		}
+	}
+	return classifier.Train(examples), nil
+}
+
+// suggestImportCategories suggests categories for the valid rows that are
+// still uncategorized. Confident suggestions are filled in; the other rows
+// are tagged for review.
+func suggestImportCategories(ctx context.Context, queries *gensql.Queries, userID int64, rows []importer.Row, result *ImportResult) error {
+	if !slices.ContainsFunc(rows, uncategorizedRow) {
+		return nil
+	}
+	model, err := trainCategoryModel(ctx, queries, userID)
+	if err != nil {
+		return err
+	}
+	if model.Examples() < minTrainingExamples {
+		return nil
+	}
+	categorizeImportRows(model, rows, result)
+	return nil
+}
+
+// categorizeImportRows fills in confident suggestions for the uncategorized
+// rows and tags the others for review. A suggestion is only filled in when
+// the description shares a word with earlier transactions; the amount,
+// account and type alone are not enough to recognize a merchant.
+func categorizeImportRows(model *classifier.Model, rows []importer.Row, result *ImportResult) {
+	for i := range rows {
+		if !uncategorizedRow(rows[i]) {
+			continue
+		}
+		t := &rows[i].Transaction
+		features := classifier.Features(t.Description, numeric.Rat(t.Amount), t.Account, t.Type)
+		suggestions := model.Suggest(features, 1)
+		if len(suggestions) == 0 {
+			continue
+		}
+		rows[i].SuggestedCategory = suggestions[0].Category
+		rows[i].Confidence = suggestions[0].Confidence
+		if suggestions[0].Confidence >= autoCategorizeConfidence && model.KnowsDescription(features) {
+			t.Category = suggestions[0].Category
+			result.AutoCategorized++
+			continue
+		}
+		rows[i].NeedsReview = true
+		if !slices.Contains(t.Tags, reviewTag) {
+			t.Tags = append(t.Tags, reviewTag)
+		}
+		result.NeedsReview++
+	}
+}
+
+func uncategorizedRow(r importer.Row) bool {
+	return r.Valid() && r.Transfer == "" && r.Transaction.Category == importer.DefaultCategory
+}
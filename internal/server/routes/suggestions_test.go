@@ -0,0 +1,64 @@
+package routes
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+
+	"budgetctl-go/internal/classifier"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/importer"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCategorizeImportRows(t *testing.T) {
+	var examples []classifier.Example
+	for range 20 {
+		examples = append(examples, classifier.Example{
+			Features: classifier.Features("WHOLE FOODS MARKET", big.NewRat(70, 1), "Checking", "expense"),
+			Category: "Groceries",
+		})
+	}
+	for range 5 {
+		examples = append(examples, classifier.Example{
+			Features: classifier.Features("SQ *BLUE BOTTLE", big.NewRat(5, 1), "Amex", "expense"),
+			Category: "Coffee",
+		})
+	}
+	model := classifier.Train(examples)
+
+	row := func(description string) importer.Row {
+		return importer.Row{Transaction: gensql.CreateTransactionParams{
+			Description: description,
+			Amount:      pgtype.Numeric{Int: big.NewInt(-7000), Exp: -2, Valid: true},
+			Account:     "Checking",
+			Type:        "expense",
+			Category:    importer.DefaultCategory,
+			Tags:        []string{},
+		}}
+	}
+	rows := []importer.Row{row("WHOLE FOODS #123"), row("ACME HARDWARE")}
+	result := &ImportResult{}
+	categorizeImportRows(model, rows, result)
+
+	if got := rows[0].Transaction.Category; got != "Groceries" || rows[0].NeedsReview {
+		t.Errorf("known merchant: category %q, needs review %v", got, rows[0].NeedsReview)
+	}
+
+	// Amount, account and type all point to Groceries, but nothing is known
+	// about the merchant itself.
+	unknown := rows[1]
+	if unknown.SuggestedCategory != "Groceries" || unknown.Confidence < autoCategorizeConfidence {
+		t.Fatalf("unknown merchant: suggested %q with confidence %f", unknown.SuggestedCategory, unknown.Confidence)
+	}
+	if unknown.Transaction.Category != importer.DefaultCategory || !unknown.NeedsReview {
+		t.Errorf("unknown merchant: category %q, needs review %v", unknown.Transaction.Category, unknown.NeedsReview)
+	}
+	if !slices.Contains(unknown.Transaction.Tags, reviewTag) {
+		t.Errorf("unknown merchant: tags %q", unknown.Transaction.Tags)
+	}
+	if result.AutoCategorized != 1 || result.NeedsReview != 1 {
+		t.Errorf("auto categorized %d, needs review %d", result.AutoCategorized, result.NeedsReview)
+	}
+}
@@ -2,13 +2,17 @@ package routes
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"budgetctl-go/internal/database"
 	"budgetctl-go/internal/database/gensql"
 
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -16,18 +20,12 @@ import (
 
 type ListTransactionsRequest struct {
 	PaginationInput
-	Search     string   `query:"search" doc:"Search in description, tags, notes"`
-	DateFrom   string   `query:"date_from" doc:"Filter by date from (YYYY-MM-DD)"`
-	DateTo     string   `query:"date_to" doc:"Filter by date to (YYYY-MM-DD)"`
-	Categories []string `query:"category" doc:"Filter by categories"`
-	Type       string   `query:"type" doc:"Filter by type (income|expense|all)"`
-	MinAmount  float64  `query:"min_amount" doc:"Minimum amount filter"`
-	MaxAmount  float64  `query:"max_amount" doc:"Maximum amount filter"`
-	Tags       []string `query:"tag" doc:"Filter by tags"`
+	TransactionFilterInput
+	Sort []string `query:"sort" doc:"Comma-separated sort keys, prefix with - for descending (date|amount|description|category|created_at|updated_at|relevance). Defaults to -relevance when searching, otherwise -date"`
 }
 
 type ListTransactionsResponse struct {
-	Body *PaginatedResponse[gensql.Transaction]
+	Body *PaginatedResponse[gensql.ListTransactionsWithFiltersRow]
 }
 
 type GetTransactionRequest struct {
@@ -35,6 +33,7 @@ type GetTransactionRequest struct {
 }
 
 type GetTransactionResponse struct {
+	ETag string `header:"ETag"`
 	Body *gensql.Transaction
 }
 
@@ -43,20 +42,24 @@ type CreateTransactionRequest struct {
 }
 
 type CreateTransactionResponse struct {
+	ETag string `header:"ETag"`
 	Body *gensql.Transaction
 }
 
 type UpdateTransactionRequest struct {
 	ID   int64                           `path:"id" doc:"Transaction ID"`
+	IfMatchInput
 	Body gensql.UpdateTransactionParams
 }
 
 type UpdateTransactionResponse struct {
+	ETag string `header:"ETag"`
 	Body *gensql.Transaction
 }
 
 type DeleteTransactionRequest struct {
 	ID int64 `path:"id" doc:"Transaction ID"`
+	IfMatchInput
 }
 
 type GetCategoriesResponse struct {
@@ -86,45 +89,21 @@ func RegisterTransactionRoutes(api huma.API, db database.Service) {
 		queries := db.GetQueries()
 		limit, offset := input.ToLimitOffset()
 
-		// Build filter parameters
-		params := gensql.ListTransactionsWithFiltersParams{
-			UserID:  user.ID,
-			Column2: input.Search,
-			Limit:   limit,
-			Offset:  offset,
-		}
-
-		// Parse date filters
-		if input.DateFrom != "" {
-			if d, err := time.Parse("2006-01-02", input.DateFrom); err == nil {
-				params.Column3 = pgtype.Date{Time: d, Valid: true}
-			}
-		}
-		if input.DateTo != "" {
-			if d, err := time.Parse("2006-01-02", input.DateTo); err == nil {
-				params.Column4 = pgtype.Date{Time: d, Valid: true}
-			}
-		}
-
-		// Parse array filters
-		if len(input.Categories) > 0 {
-			params.Column5 = input.Categories
-		}
-		if input.Type != "" && input.Type != "all" {
-			params.Column6 = input.Type
-		}
-		if len(input.Tags) > 0 {
-			params.Column9 = input.Tags
+		sort, err := parseTransactionSort(input.Sort)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
 		}
 
-		// Parse amount filters
-		if input.MinAmount > 0 {
-			params.Column7 = pgtype.Numeric{Valid: true}
-			params.Column7.Scan(input.MinAmount)
+		// Build filter parameters
+		params, err := input.ToFilterParams(user.ID)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
 		}
-		if input.MaxAmount > 0 {
-			params.Column8 = pgtype.Numeric{Valid: true}
-			params.Column8.Scan(input.MaxAmount)
+		params.Sort = sort
+		params.Limit = limit
+		params.Offset = offset
+		if params.Search != nil && len(params.Sort) == 0 {
+			params.Sort = []string{"-relevance"}
 		}
 
 		// Fetch data
@@ -134,19 +113,7 @@ func RegisterTransactionRoutes(api huma.API, db database.Service) {
 		}
 
 		// Fetch count for pagination
-		countParams := gensql.CountTransactionsParams{
-			UserID:  user.ID,
-			Column2: params.Column2,
-			Column3: params.Column3,
-			Column4: params.Column4,
-			Column5: params.Column5,
-			Column6: params.Column6,
-			Column7: params.Column7,
-			Column8: params.Column8,
-			Column9: params.Column9,
-		}
-
-		total, err := queries.CountTransactions(ctx, countParams)
+		total, err := queries.CountTransactions(ctx, countParamsFor(params))
 		if err != nil {
 			return nil, huma.Error500InternalServerError("Failed to count transactions", err)
 		}
@@ -178,7 +145,7 @@ func RegisterTransactionRoutes(api huma.API, db database.Service) {
 			return nil, huma.Error404NotFound("Transaction not found", err)
 		}
 
-		return &GetTransactionResponse{Body: &transaction}, nil
+		return &GetTransactionResponse{ETag: transactionETag(transaction), Body: &transaction}, nil
 	})
 
 	// Create Transaction
@@ -196,27 +163,38 @@ func RegisterTransactionRoutes(api huma.API, db database.Service) {
 
 		// Set required fields and defaults
 		params := input.Body
-		params.UserID = user.ID
-		if params.Currency == "" {
-			params.Currency = "USD"
-		}
-		if params.Status == "" {
-			params.Status = "pending"
-		}
-		if params.Account == "" {
-			params.Account = ""
-		}
-		if !params.Date.Valid {
-			params.Date = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		applyCreateDefaults(&params, user.ID)
+		if err := checkStatusChange("", params.Status); err != nil {
+			return nil, err
 		}
 
 		queries := db.GetQueries()
-		transaction, err := queries.CreateTransaction(ctx, params)
+		if err := applyRulesOnCreate(ctx, queries, user.ID, &params); err != nil {
+			return nil, huma.Error500InternalServerError("Failed to apply rules", err)
+		}
+		if err := linkPayeesOnCreate(ctx, queries, user.ID, &params); err != nil {
+			if se := huma.StatusError(nil); errors.As(err, &se) {
+				return nil, err
+			}
+			return nil, huma.Error500InternalServerError("Failed to link payee", err)
+		}
+		var transaction gensql.Transaction
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+			transaction, err = queries.CreateTransaction(ctx, params)
+			return err
+		})
 		if err != nil {
+			if pgErr, ok := pgError(err, pgCheckViolation); ok {
+				return nil, huma.Error422UnprocessableEntity(checkViolationMessage(pgErr))
+			}
 			return nil, huma.Error500InternalServerError("Failed to create transaction", err)
 		}
 
-		return &CreateTransactionResponse{Body: &transaction}, nil
+		return &CreateTransactionResponse{ETag: transactionETag(transaction), Body: &transaction}, nil
 	})
 
 	// Update Transaction
@@ -237,13 +215,46 @@ func RegisterTransactionRoutes(api huma.API, db database.Service) {
 		params.ID = input.ID
 		params.UserID = user.ID
 
-		queries := db.GetQueries()
-		transaction, err := queries.UpdateTransaction(ctx, params)
+		var transaction gensql.Transaction
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+			current, err := queries.LockTransaction(ctx, gensql.LockTransactionParams{ID: params.ID, UserID: params.UserID})
+			if err != nil {
+				return err
+			}
+			if err := input.Check(current); err != nil {
+				return err
+			}
+			if err := checkStatusChange(current.Status, params.Status); err != nil {
+				return err
+			}
+			payees, err := newPayeeLinker(ctx, queries, user.ID, true)
+			if err != nil {
+				return err
+			}
+			if err := payees.linkUpdate(ctx, &params); err != nil {
+				return err
+			}
+			transaction, err = queries.UpdateTransaction(ctx, params)
+			return err
+		})
 		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, huma.Error404NotFound("Transaction not found", err)
+			}
+			if se := huma.StatusError(nil); errors.As(err, &se) {
+				return nil, err
+			}
+			if pgErr, ok := pgError(err, pgCheckViolation); ok {
+				return nil, huma.Error422UnprocessableEntity(checkViolationMessage(pgErr))
+			}
 			return nil, huma.Error500InternalServerError("Failed to update transaction", err)
 		}
 
-		return &UpdateTransactionResponse{Body: &transaction}, nil
+		return &UpdateTransactionResponse{ETag: transactionETag(transaction), Body: &transaction}, nil
 	})
 
 	// Delete Transaction
@@ -252,6 +263,7 @@ func RegisterTransactionRoutes(api huma.API, db database.Service) {
 		Method:      http.MethodDelete,
 		Path:        "/transactions/{id}",
 		Summary:     "Delete Transaction",
+		Description: "Moves the transaction to the trash, where it can be restored until it is purged. Deleting a transfer leg moves both legs.",
 		Tags:        []string{"Transactions"},
 	}, func(ctx context.Context, input *DeleteTransactionRequest) (*struct{}, error) {
 		user, err := getUserFromContext(ctx)
@@ -259,12 +271,34 @@ func RegisterTransactionRoutes(api huma.API, db database.Service) {
 			return nil, err
 		}
 
-		queries := db.GetQueries()
-		err = queries.DeleteTransaction(ctx, gensql.DeleteTransactionParams{
-			ID:     input.ID,
-			UserID: user.ID,
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+			current, err := queries.LockTransaction(ctx, gensql.LockTransactionParams{ID: input.ID, UserID: user.ID})
+			if err != nil {
+				return err
+			}
+			if err := input.Check(current); err != nil {
+				return err
+			}
+			_, err = queries.DeleteTransaction(ctx, gensql.DeleteTransactionParams{
+				ID:     input.ID,
+				UserID: user.ID,
+			})
+			return err
 		})
 		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, huma.Error404NotFound("Transaction not found", err)
+			}
+			if se := huma.StatusError(nil); errors.As(err, &se) {
+				return nil, err
+			}
+			if pgErr, ok := pgError(err, pgCheckViolation); ok {
+				return nil, huma.Error422UnprocessableEntity(checkViolationMessage(pgErr))
+			}
 			return nil, huma.Error500InternalServerError("Failed to delete transaction", err)
 		}
 
@@ -318,6 +352,71 @@ func RegisterTransactionRoutes(api huma.API, db database.Service) {
 
 // Helper functions
 
+// applyCreateDefaults sets the owner and fills in defaults for fields left empty by the client.
+// Import batches are only ever assigned by the importer.
+func applyCreateDefaults(params *gensql.CreateTransactionParams, userID int64) {
+	params.UserID = userID
+	params.ImportBatchID = nil
+	if params.Currency == "" {
+		params.Currency = "USD"
+	}
+	if params.Status == "" {
+		params.Status = "pending"
+	}
+	if !params.Date.Valid {
+		params.Date = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+}
+
+// transactionSortFields whitelists the fields accepted by the sort query parameter.
+var transactionSortFields = map[string]bool{
+	"date":        true,
+	"amount":      true,
+	"description": true,
+	"category":    true,
+	"created_at":  true,
+	"updated_at":  true,
+	"relevance":   true,
+}
+
+// maxTransactionSortKeys matches the number of sort positions handled by ListTransactionsWithFilters.
+const maxTransactionSortKeys = 3
+
+// parseTransactionSort validates sort keys such as "-date" or "amount" and
+// returns them in the "<field>" / "-<field>" form expected by the query.
+func parseTransactionSort(keys []string) ([]string, error) {
+	sort := []string{}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+
+		field, desc := strings.CutPrefix(key, "-")
+		if !desc {
+			field, _ = strings.CutPrefix(key, "+")
+		}
+		if !transactionSortFields[field] {
+			return nil, fmt.Errorf("unknown sort field %q", key)
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("duplicate sort field %q", field)
+		}
+		seen[field] = true
+
+		if desc {
+			field = "-" + field
+		}
+		sort = append(sort, field)
+	}
+
+	if len(sort) > maxTransactionSortKeys {
+		return nil, fmt.Errorf("at most %d sort fields are supported", maxTransactionSortKeys)
+	}
+	return sort, nil
+}
+
 func getUserFromContext(ctx context.Context) (*gensql.User, error) {
 	// Since we're using Huma with Echo adapter, we need to extract the user
 	// from the Echo context that was set by the auth middleware
@@ -0,0 +1,345 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"time"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+	"budgetctl-go/internal/numeric"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// transferCategory is the category of both legs of a transfer.
+	transferCategory = "Transfer"
+	// rateDecimals is the precision of exchange rates derived from a
+	// destination amount.
+	rateDecimals = 12
+)
+
+type Transfer struct {
+	ID        int64              `json:"id"`
+	Rate      Decimal            `json:"rate" doc:"Destination amount per unit of the source amount"`
+	CreatedAt time.Time          `json:"created_at"`
+	From      gensql.Transaction `json:"from" doc:"Leg on the source account"`
+	To        gensql.Transaction `json:"to" doc:"Leg on the destination account"`
+}
+
+type CreateTransferRequest struct {
+	Body struct {
+		FromAccount string    `json:"from_account" minLength:"1" doc:"Account the money leaves"`
+		ToAccount   string    `json:"to_account" minLength:"1" doc:"Account the money arrives in"`
+		Amount      Decimal   `json:"amount" doc:"Amount leaving the source account"`
+		Currency    string    `json:"currency,omitempty" doc:"Currency of the source account, USD by default"`
+		ToCurrency  string    `json:"to_currency,omitempty" doc:"Currency of the destination account, the source currency by default"`
+		ToAmount    *Decimal  `json:"to_amount,omitempty" doc:"Amount arriving in the destination account; required with a different currency unless rate is given"`
+		Rate        *Decimal  `json:"rate,omitempty" doc:"Destination amount per unit of the source amount, for a different currency"`
+		Description string    `json:"description" minLength:"1"`
+		Date        time.Time `json:"date,omitempty" doc:"Defaults to now"`
+		Status      string    `json:"status,omitempty" doc:"Status of both legs, pending by default"`
+		Tags        []string  `json:"tags,omitempty"`
+		Notes       *string   `json:"notes,omitempty"`
+	}
+}
+
+type ListTransfersRequest struct {
+	PaginationInput
+}
+
+type ListTransfersResponse struct {
+	Body *PaginatedResponse[Transfer]
+}
+
+type TransferRequest struct {
+	ID int64 `path:"id" doc:"Transfer ID"`
+}
+
+type TransferResponse struct {
+	Body *Transfer
+}
+
+func RegisterTransferRoutes(api huma.API, db database.Service) {
+	// List Transfers
+	huma.Register(api, huma.Operation{
+		OperationID: "list-transfers",
+		Method:      http.MethodGet,
+		Path:        "/transfers",
+		Summary:     "List Transfers",
+		Tags:        []string{"Transfers"},
+	}, func(ctx context.Context, input *ListTransfersRequest) (*ListTransfersResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		limit, offset := input.ToLimitOffset()
+		transfers, err := queries.ListTransfers(ctx, gensql.ListTransfersParams{
+			UserID: user.ID,
+			Limit:  limit,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch transfers", err)
+		}
+		total, err := queries.CountTransfers(ctx, user.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to count transfers", err)
+		}
+
+		out, err := withTransferLegs(ctx, queries, user.ID, transfers)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch transfer legs", err)
+		}
+		return &ListTransfersResponse{
+			Body: NewPaginatedResponse(out, total, input.Page, input.PerPage),
+		}, nil
+	})
+
+	// Get Transfer
+	huma.Register(api, huma.Operation{
+		OperationID: "get-transfer",
+		Method:      http.MethodGet,
+		Path:        "/transfers/{id}",
+		Summary:     "Get Transfer",
+		Tags:        []string{"Transfers"},
+	}, func(ctx context.Context, input *TransferRequest) (*TransferResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		transfer, err := queries.GetTransfer(ctx, gensql.GetTransferParams{ID: input.ID, UserID: user.ID})
+		if err != nil {
+			return nil, huma.Error404NotFound("Transfer not found", err)
+		}
+		out, err := withTransferLegs(ctx, queries, user.ID, []gensql.Transfer{transfer})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch transfer legs", err)
+		}
+		return &TransferResponse{Body: &out[0]}, nil
+	})
+
+	// Create Transfer
+	huma.Register(api, huma.Operation{
+		OperationID:   "create-transfer",
+		Method:        http.MethodPost,
+		Path:          "/transfers",
+		Summary:       "Create Transfer",
+		Description:   "Records money moving between two accounts as a linked pair of transactions of type transfer, which count toward account balances but not toward income or spending. Editing the description, date, notes, tags or amount of either leg through the transaction endpoints updates the other leg too; in different currencies a new source amount is converted at the transfer's rate and a new destination amount changes the rate. Deleting either leg deletes the transfer.",
+		Tags:          []string{"Transfers"},
+		DefaultStatus: http.StatusCreated,
+	}, func(ctx context.Context, input *CreateTransferRequest) (*TransferResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		body := input.Body
+		if body.Currency == "" {
+			body.Currency = "USD"
+		}
+		if body.ToCurrency == "" {
+			body.ToCurrency = body.Currency
+		}
+		if body.Status == "" {
+			body.Status = "pending"
+		}
+		if body.Date.IsZero() {
+			body.Date = time.Now()
+		}
+		if body.Tags == nil {
+			body.Tags = []string{}
+		}
+		if err := checkStatusChange("", body.Status); err != nil {
+			return nil, err
+		}
+		if body.FromAccount == body.ToAccount && body.Currency == body.ToCurrency {
+			return nil, huma.Error422UnprocessableEntity("A transfer needs two different accounts")
+		}
+
+		amount := numeric.Rat(body.Amount.Numeric)
+		if amount == nil || amount.Sign() <= 0 {
+			return nil, huma.Error422UnprocessableEntity("amount must be greater than zero")
+		}
+		toAmount, rate, err := transferAmounts(amount, body.Currency == body.ToCurrency, body.ToAmount, body.Rate)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity(err.Error())
+		}
+
+		var out Transfer
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+
+			transfer, err := queries.CreateTransfer(ctx, gensql.CreateTransferParams{UserID: user.ID, Rate: rate})
+			if err != nil {
+				return err
+			}
+
+			leg := func(direction, account, currency string, amount pgtype.Numeric) (gensql.Transaction, error) {
+				return queries.CreateTransferLeg(ctx, gensql.CreateTransferLegParams{
+					UserID:            user.ID,
+					TransferID:        &transfer.ID,
+					TransferDirection: &direction,
+					Amount:            amount,
+					Description:       body.Description,
+					Category:          transferCategory,
+					Currency:          currency,
+					Status:            body.Status,
+					Account:           account,
+					Tags:              body.Tags,
+					Notes:             body.Notes,
+					Date:              pgtype.Timestamptz{Time: body.Date, Valid: true},
+				})
+			}
+			from, err := leg("out", body.FromAccount, body.Currency, roundNumeric(amount, 2))
+			if err != nil {
+				return err
+			}
+			to, err := leg("in", body.ToAccount, body.ToCurrency, toAmount)
+			if err != nil {
+				return err
+			}
+
+			out = Transfer{
+				ID:        transfer.ID,
+				Rate:      Decimal{transfer.Rate},
+				CreatedAt: transfer.CreatedAt.Time,
+				From:      from,
+				To:        to,
+			}
+			return nil
+		})
+		if err != nil {
+			if pgErr, ok := pgError(err, pgCheckViolation); ok {
+				return nil, huma.Error422UnprocessableEntity(checkViolationMessage(pgErr))
+			}
+			return nil, huma.Error500InternalServerError("Failed to create transfer", err)
+		}
+
+		return &TransferResponse{Body: &out}, nil
+	})
+
+	// Delete Transfer
+	huma.Register(api, huma.Operation{
+		OperationID:   "delete-transfer",
+		Method:        http.MethodDelete,
+		Path:          "/transfers/{id}",
+		Summary:       "Delete Transfer",
+		Description:   "Moves both legs of the transfer to the trash, from where either leg restores or purges the whole transfer.",
+		Tags:          []string{"Transfers"},
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *TransferRequest) (*struct{}, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var deleted int64
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+			deleted, err = queries.DeleteTransfer(ctx, gensql.DeleteTransferParams{TransferID: &input.ID, UserID: user.ID})
+			return err
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to delete transfer", err)
+		}
+		if deleted == 0 {
+			return nil, huma.Error404NotFound("Transfer not found")
+		}
+		return nil, nil
+	})
+}
+
+// withTransferLegs pairs transfers with their source and destination legs.
+func withTransferLegs(ctx context.Context, queries *gensql.Queries, userID int64, transfers []gensql.Transfer) ([]Transfer, error) {
+	ids := make([]int64, len(transfers))
+	for i, t := range transfers {
+		ids[i] = t.ID
+	}
+	legs, err := queries.ListTransferLegs(ctx, gensql.ListTransferLegsParams{UserID: userID, TransferIds: ids})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[int64]*Transfer{}
+	out := make([]Transfer, len(transfers))
+	for i, t := range transfers {
+		out[i] = Transfer{ID: t.ID, Rate: Decimal{t.Rate}, CreatedAt: t.CreatedAt.Time}
+		byID[t.ID] = &out[i]
+	}
+	for _, leg := range legs {
+		t := byID[*leg.TransferID]
+		if *leg.TransferDirection == "out" {
+			t.From = leg
+		} else {
+			t.To = leg
+		}
+	}
+	return out, nil
+}
+
+// transferAmounts returns the destination amount and exchange rate of a
+// transfer of amount. In one currency both legs have the same amount;
+// otherwise the destination amount is given directly or converted at rate.
+func transferAmounts(amount *big.Rat, sameCurrency bool, toAmount, rate *Decimal) (pgtype.Numeric, pgtype.Numeric, error) {
+	one := pgtype.Numeric{Int: big.NewInt(1), Valid: true}
+	if sameCurrency {
+		if rate != nil || (toAmount != nil && numeric.Rat(toAmount.Numeric).Cmp(amount) != 0) {
+			return pgtype.Numeric{}, pgtype.Numeric{}, errors.New("to_amount and rate only apply to transfers between currencies")
+		}
+		return roundNumeric(amount, 2), one, nil
+	}
+
+	switch {
+	case toAmount != nil && rate != nil:
+		return pgtype.Numeric{}, pgtype.Numeric{}, errors.New("give either to_amount or rate, not both")
+	case toAmount != nil:
+		to := numeric.Rat(toAmount.Numeric)
+		if to == nil || to.Sign() <= 0 {
+			return pgtype.Numeric{}, pgtype.Numeric{}, errors.New("to_amount must be greater than zero")
+		}
+		return roundNumeric(to, 2), roundNumeric(new(big.Rat).Quo(to, amount), rateDecimals), nil
+	case rate != nil:
+		r := numeric.Rat(rate.Numeric)
+		if r == nil || r.Sign() <= 0 {
+			return pgtype.Numeric{}, pgtype.Numeric{}, errors.New("rate must be greater than zero")
+		}
+		return roundNumeric(new(big.Rat).Mul(amount, r), 2), rate.Numeric, nil
+	}
+	return pgtype.Numeric{}, pgtype.Numeric{}, errors.New("a transfer between currencies needs to_amount or rate")
+}
+
+// roundNumeric rounds r half away from zero to the given number of decimals.
+func roundNumeric(r *big.Rat, decimals int) pgtype.Numeric {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(scale))
+	q, m := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if new(big.Int).Mul(m.Abs(m), big.NewInt(2)).Cmp(scaled.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(scaled.Sign())))
+	}
+	return pgtype.Numeric{Int: q, Exp: int32(-decimals), Valid: true}
+}
+
+// checkViolationMessage explains a violated check constraint.
+func checkViolationMessage(err *pgconn.PgError) string {
+	if err.ConstraintName == "transactions_transfer_check" {
+		return "Transfers are created with POST /transfers and their legs keep the transfer type"
+	}
+	return err.Message
+}
@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"math/big"
+	"testing"
+
+	"budgetctl-go/internal/numeric"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func decimal(s string) *Decimal {
+	var d Decimal
+	if err := d.Scan(s); err != nil {
+		panic(err)
+	}
+	return &d
+}
+
+func numericString(n pgtype.Numeric) string {
+	return numeric.Rat(n).FloatString(int(-n.Exp))
+}
+
+func TestTransferAmounts(t *testing.T) {
+	amount := big.NewRat(10000, 100) // 100.00
+
+	to, rate, err := transferAmounts(amount, true, nil, nil)
+	if err != nil || numericString(to) != "100.00" || numericString(rate) != "1" {
+		t.Errorf("same currency: %s %s %v", numericString(to), numericString(rate), err)
+	}
+
+	to, rate, err = transferAmounts(amount, false, nil, decimal("0.9137"))
+	if err != nil || numericString(to) != "91.37" || numericString(rate) != "0.9137" {
+		t.Errorf("rate: %s %s %v", numericString(to), numericString(rate), err)
+	}
+
+	to, rate, err = transferAmounts(big.NewRat(3, 1), false, decimal("1"), nil)
+	if err != nil || numericString(to) != "1.00" || numericString(rate) != "0.333333333333" {
+		t.Errorf("to_amount: %s %s %v", numericString(to), numericString(rate), err)
+	}
+
+	for name, args := range map[string][2]*Decimal{
+		"neither":        {nil, nil},
+		"both":           {decimal("91.37"), decimal("0.9137")},
+		"negative rate":  {nil, decimal("-1")},
+		"zero to_amount": {decimal("0"), nil},
+	} {
+		if _, _, err := transferAmounts(amount, false, args[0], args[1]); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, _, err := transferAmounts(amount, true, decimal("90"), nil); err == nil {
+		t.Error("same currency with a different to_amount: expected an error")
+	}
+}
+
+func TestRoundNumeric(t *testing.T) {
+	tests := []struct {
+		in   *big.Rat
+		want string
+	}{
+		{big.NewRat(1005, 1000), "1.01"},
+		{big.NewRat(-1005, 1000), "-1.01"},
+		{big.NewRat(1004, 1000), "1.00"},
+		{big.NewRat(2, 3), "0.67"},
+		{big.NewRat(-2, 3), "-0.67"},
+	}
+	for _, tt := range tests {
+		if got := numericString(roundNumeric(tt.in, 2)); got != tt.want {
+			t.Errorf("roundNumeric(%s) = %s, want %s", tt.in.RatString(), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"budgetctl-go/internal/database"
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ListTrashRequest struct {
+	PaginationInput
+}
+
+type ListTrashResponse struct {
+	Body *PaginatedResponse[gensql.Transaction]
+}
+
+type TrashedTransactionRequest struct {
+	ID int64 `path:"id" doc:"Transaction ID"`
+}
+
+type RestoreTransactionResponse struct {
+	Body *gensql.Transaction
+}
+
+type EmptyTrashResponse struct {
+	Body struct {
+		Purged int64 `json:"purged" doc:"Number of transactions deleted for good"`
+	}
+}
+
+func RegisterTrashRoutes(api huma.API, db database.Service) {
+	// List Trash
+	huma.Register(api, huma.Operation{
+		OperationID: "list-trash",
+		Method:      http.MethodGet,
+		Path:        "/transactions/trash",
+		Summary:     "List Trash",
+		Description: "Lists deleted transactions, most recently deleted first. They are purged automatically once the retention period has passed.",
+		Tags:        []string{"Trash"},
+	}, func(ctx context.Context, input *ListTrashRequest) (*ListTrashResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		queries := db.GetQueries()
+		limit, offset := input.ToLimitOffset()
+		transactions, err := queries.ListTrash(ctx, gensql.ListTrashParams{
+			UserID: user.ID,
+			Limit:  limit,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch trash", err)
+		}
+		total, err := queries.CountTrash(ctx, user.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to count trash", err)
+		}
+		if transactions == nil {
+			transactions = []gensql.Transaction{}
+		}
+
+		return &ListTrashResponse{
+			Body: NewPaginatedResponse(transactions, total, input.Page, input.PerPage),
+		}, nil
+	})
+
+	// Restore Transaction
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-transaction",
+		Method:      http.MethodPost,
+		Path:        "/transactions/trash/{id}/restore",
+		Summary:     "Restore Transaction",
+		Description: "Takes a deleted transaction out of the trash. Restoring a transfer leg restores both legs.",
+		Tags:        []string{"Trash"},
+	}, func(ctx context.Context, input *TrashedTransactionRequest) (*RestoreTransactionResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var transaction gensql.Transaction
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			queries := db.GetQueries().WithTx(tx)
+			if err := queries.SetRevisionActorID(ctx, user.ID); err != nil {
+				return err
+			}
+			restored, err := queries.RestoreTransaction(ctx, gensql.RestoreTransactionParams{
+				ID:     input.ID,
+				UserID: user.ID,
+			})
+			if err != nil {
+				return err
+			}
+			if restored == 0 {
+				return pgx.ErrNoRows
+			}
+			transaction, err = queries.GetTransactionByID(ctx, gensql.GetTransactionByIDParams{
+				ID:     input.ID,
+				UserID: user.ID,
+			})
+			return err
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, huma.Error404NotFound("Transaction not found in trash", err)
+		}
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to restore transaction", err)
+		}
+
+		return &RestoreTransactionResponse{Body: &transaction}, nil
+	})
+
+	// Purge Transaction
+	huma.Register(api, huma.Operation{
+		OperationID:   "purge-transaction",
+		Method:        http.MethodDelete,
+		Path:          "/transactions/trash/{id}",
+		Summary:       "Purge Transaction",
+		Description:   "Deletes a transaction in the trash for good, along with its splits and attachments. Purging a transfer leg purges both legs.",
+		Tags:          []string{"Trash"},
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *TrashedTransactionRequest) (*struct{}, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		purged, err := db.GetQueries().PurgeTransaction(ctx, gensql.PurgeTransactionParams{
+			ID:     input.ID,
+			UserID: user.ID,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to purge transaction", err)
+		}
+		if purged == 0 {
+			return nil, huma.Error404NotFound("Transaction not found in trash")
+		}
+		return nil, nil
+	})
+
+	// Empty Trash
+	huma.Register(api, huma.Operation{
+		OperationID: "empty-trash",
+		Method:      http.MethodDelete,
+		Path:        "/transactions/trash",
+		Summary:     "Empty Trash",
+		Description: "Deletes every transaction in the trash for good.",
+		Tags:        []string{"Trash"},
+	}, func(ctx context.Context, input *struct{}) (*EmptyTrashResponse, error) {
+		user, err := getUserFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		purged, err := db.GetQueries().EmptyTrash(ctx, user.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to empty trash", err)
+		}
+
+		resp := &EmptyTrashResponse{}
+		resp.Body.Purged = purged
+		return resp, nil
+	})
+}
+
+// PurgeTrash deletes the transactions of all users that have been in the
+// trash for longer than retention.
+func PurgeTrash(ctx context.Context, db database.Service, retention time.Duration, now time.Time) (int64, error) {
+	return db.GetQueries().PurgeExpiredTrash(ctx, pgtype.Timestamptz{Time: now.Add(-retention), Valid: true})
+}
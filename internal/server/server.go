@@ -1,13 +1,17 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"budgetctl-go/internal/database"
 	"budgetctl-go/internal/server/routes"
+	"budgetctl-go/internal/storage"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humaecho"
@@ -21,16 +25,41 @@ import (
 )
 
 type Server struct {
-	port int
-	db   database.Service
+	port    int
+	db      database.Service
+	storage storage.Storage
+	// trashRetention is how long deleted transactions stay in the trash.
+	trashRetention time.Duration
+	// idempotencyWindow is how long responses to requests with an
+	// Idempotency-Key are kept for replay.
+	idempotencyWindow time.Duration
 }
 
 func NewServer() *http.Server {
 	port := 8080
+	files, err := storage.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	retention, err := trashRetention()
+	if err != nil {
+		log.Fatal(err)
+	}
+	window, err := idempotencyWindow()
+	if err != nil {
+		log.Fatal(err)
+	}
 	NewServer := &Server{
-		port: port,
-		db:   database.New(),
+		port:              port,
+		db:                database.New(),
+		storage:           files,
+		trashRetention:    retention,
+		idempotencyWindow: window,
 	}
+	go NewServer.purgeStorage()
+	go NewServer.purgeTrash()
+	go NewServer.purgeIdempotencyKeys()
+	go NewServer.generateRecurring()
 
 	store := sessions.NewCookieStore([]byte("secret_key"))
   gothic.Store = store
@@ -62,12 +91,119 @@ func (s *Server) RegisterRoutes() http.Handler {
 
 	config := huma.DefaultConfig("BudgetCtl API", "1.0.0")
 	api := humaecho.New(e, config)
+	// Registered first, as middleware only applies to later operations.
+	api.UseMiddleware(routes.Idempotency(api, s.db, s.idempotencyWindow))
 
 	routes.RegisterHealth(api, s.db)
 	routes.RegisterHello(api)
 
 	routes.RegisterAuthRoutes(e, s.db)
 	routes.RegisterTransactionRoutes(api, s.db)
+	routes.RegisterTrashRoutes(api, s.db)
+	routes.RegisterRevisionRoutes(api, s.db)
+	routes.RegisterSplitRoutes(api, s.db)
+	routes.RegisterTransferRoutes(api, s.db)
+	routes.RegisterRecurringRoutes(api, s.db)
+	routes.RegisterReconciliationRoutes(api, s.db)
+	routes.RegisterRuleRoutes(api, s.db)
+	routes.RegisterSuggestionRoutes(api, s.db)
+	routes.RegisterPayeeRoutes(api, s.db)
+	routes.RegisterBatchRoutes(api, s.db)
+	routes.RegisterImportRoutes(api, s.db)
+	routes.RegisterDuplicateRoutes(api, s.db)
+	routes.RegisterExportRoutes(api, s.db)
+	routes.RegisterReportRoutes(api, s.db)
+	routes.RegisterAttachmentRoutes(api, s.db, s.storage)
+	routes.RegisterReceiptRoutes(api, s.db, s.storage)
+
+	// The local storage backend serves signed downloads itself.
+	if local, ok := s.storage.(*storage.Local); ok {
+		e.GET(storage.LocalPathPrefix+"*", echo.WrapHandler(http.StripPrefix(storage.LocalPathPrefix, local)))
+	}
 
 	return e
 }
+
+// storagePurgeInterval is how often files of deleted attachments are removed
+// from storage.
+const storagePurgeInterval = 5 * time.Minute
+
+// purgeStorage periodically deletes stored files no attachment uses anymore.
+func (s *Server) purgeStorage() {
+	for range time.Tick(storagePurgeInterval) {
+		if err := routes.PurgeStorage(context.Background(), s.db, s.storage); err != nil {
+			log.Printf("purge storage: %v", err)
+		}
+	}
+}
+
+// recurringInterval is how often transactions are created for occurrences of
+// recurring templates that came due.
+const recurringInterval = time.Hour
+
+// generateRecurring creates the due recurring transactions at startup,
+// catching up on any missed while the server was down, and then periodically.
+func (s *Server) generateRecurring() {
+	for {
+		if err := routes.GenerateRecurringTransactions(context.Background(), s.db, time.Now()); err != nil {
+			log.Printf("generate recurring transactions: %v", err)
+		}
+		time.Sleep(recurringInterval)
+	}
+}
+
+// trashPurgeInterval is how often transactions past the trash retention
+// period are deleted.
+const trashPurgeInterval = time.Hour
+
+// trashRetention reads the number of days deleted transactions are kept in
+// the trash from TRASH_RETENTION_DAYS, 30 by default.
+func trashRetention() (time.Duration, error) {
+	days := 30
+	if v := os.Getenv("TRASH_RETENTION_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid TRASH_RETENTION_DAYS %q", v)
+		}
+		days = n
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// purgeTrash periodically deletes transactions that have been in the trash
+// for longer than the retention period.
+func (s *Server) purgeTrash() {
+	for range time.Tick(trashPurgeInterval) {
+		if _, err := routes.PurgeTrash(context.Background(), s.db, s.trashRetention, time.Now()); err != nil {
+			log.Printf("purge trash: %v", err)
+		}
+	}
+}
+
+// idempotencyPurgeInterval is how often expired idempotency keys are
+// deleted.
+const idempotencyPurgeInterval = time.Hour
+
+// idempotencyWindow reads the number of hours an Idempotency-Key can be
+// retried from IDEMPOTENCY_KEY_TTL_HOURS, 24 by default.
+func idempotencyWindow() (time.Duration, error) {
+	hours := 24
+	if v := os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid IDEMPOTENCY_KEY_TTL_HOURS %q", v)
+		}
+		hours = n
+	}
+	return time.Duration(hours) * time.Hour, nil
+}
+
+// purgeIdempotencyKeys periodically deletes idempotency keys that can no
+// longer be retried.
+func (s *Server) purgeIdempotencyKeys() {
+	for range time.Tick(idempotencyPurgeInterval) {
+		if _, err := routes.PurgeIdempotencyKeys(context.Background(), s.db, s.idempotencyWindow, time.Now()); err != nil {
+			log.Printf("purge idempotency keys: %v", err)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// Package txquery parses the transaction search syntax accepted by the q
+// query parameter, for example:
+//
+//	category:food amount>20 tag:trip after:2025-01-01 -tag:work "coffee shop"
+//
+// Field terms become structured filters and everything else is passed on as
+// free-text search.
+package txquery
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"unicode"
+
+	"budgetctl-go/internal/database/gensql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ParseError reports a problem with the query together with the 1-based
+// character position where it was found.
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("query error at position %d: %s", e.Pos, e.Msg)
+}
+
+const dateLayout = "2006-01-02"
+
+// term is a single whitespace-separated element of the query.
+type term struct {
+	pos    int
+	negate bool
+	key    string // empty for free-text terms
+	op     string // ":", "=", ">", ">=", "<", "<="
+	value  string
+	quoted bool
+}
+
+// Apply parses input and merges the resulting filters into params. List
+// filters are appended to any already present, single-value filters replace
+// them, and free text is appended to the search string.
+func Apply(input string, params *gensql.ListTransactionsWithFiltersParams) error {
+	terms, err := tokenize(input)
+	if err != nil {
+		return err
+	}
+
+	var search []string
+	for _, t := range terms {
+		if t.key == "" {
+			search = append(search, t.searchText())
+			continue
+		}
+		if err := applyField(t, params); err != nil {
+			return err
+		}
+	}
+
+	if len(search) > 0 {
+		text := strings.Join(search, " ")
+		if params.Search != nil && *params.Search != "" {
+			text = *params.Search + " " + text
+		}
+		params.Search = &text
+	}
+	return nil
+}
+
+func applyField(t term, params *gensql.ListTransactionsWithFiltersParams) error {
+	if t.value == "" {
+		return t.errorf("missing value for %q", t.key)
+	}
+
+	switch t.key {
+	case "category", "cat":
+		if err := t.requireOp(":", "="); err != nil {
+			return err
+		}
+		if t.negate {
+			params.ExcludeCategories = append(params.ExcludeCategories, t.values()...)
+		} else {
+			params.Categories = append(params.Categories, t.values()...)
+		}
+	case "tag":
+		if err := t.requireOp(":", "="); err != nil {
+			return err
+		}
+		if t.negate {
+			params.ExcludeTags = append(params.ExcludeTags, t.values()...)
+		} else {
+			params.Tags = append(params.Tags, t.values()...)
+		}
+	case "status", "account", "currency":
+		if err := t.requireOp(":", "="); err != nil {
+			return err
+		}
+		if t.negate {
+			return t.errorf("%q cannot be negated", t.key)
+		}
+		switch t.key {
+		case "status":
+			params.Statuses = append(params.Statuses, t.values()...)
+		case "account":
+			params.Accounts = append(params.Accounts, t.values()...)
+		case "currency":
+			params.Currencies = append(params.Currencies, t.values()...)
+		}
+	case "type":
+		if err := t.requireOp(":", "="); err != nil {
+			return err
+		}
+		if t.negate {
+			return t.errorf("%q cannot be negated", t.key)
+		}
+		if t.value != "income" && t.value != "expense" && t.value != "transfer" {
+			return t.errorf("type must be income, expense or transfer, got %q", t.value)
+		}
+		value := t.value
+		params.Type = &value
+	case "has":
+		if err := t.requireOp(":"); err != nil {
+			return err
+		}
+		if t.value != "receipt" {
+			return t.errorf("unknown has: value %q", t.value)
+		}
+		hasReceipt := !t.negate
+		params.HasReceipt = &hasReceipt
+	case "amount":
+		return applyAmount(t, params)
+	case "date", "after", "before":
+		return applyDate(t, params)
+	default:
+		return t.errorf("unknown field %q", t.key)
+	}
+	return nil
+}
+
+// applyAmount handles amount comparisons. Amounts are stored with two decimal
+// places, so strict comparisons are turned into inclusive bounds one cent away.
+func applyAmount(t term, params *gensql.ListTransactionsWithFiltersParams) error {
+	if t.negate {
+		return t.errorf("%q cannot be negated", t.key)
+	}
+
+	cents, ok := parseCents(t.value)
+	if !ok {
+		return t.errorf("invalid amount %q", t.value)
+	}
+
+	switch t.op {
+	case ":", "=":
+		params.MinAmount = centsToNumeric(cents)
+		params.MaxAmount = centsToNumeric(cents)
+	case ">":
+		params.MinAmount = centsToNumeric(cents + 1)
+	case ">=":
+		params.MinAmount = centsToNumeric(cents)
+	case "<":
+		params.MaxAmount = centsToNumeric(cents - 1)
+	case "<=":
+		params.MaxAmount = centsToNumeric(cents)
+	}
+	return nil
+}
+
+// applyDate handles date:, after: and before:. after and before are exclusive.
+func applyDate(t term, params *gensql.ListTransactionsWithFiltersParams) error {
+	if t.negate {
+		return t.errorf("%q cannot be negated", t.key)
+	}
+
+	d, err := time.Parse(dateLayout, t.value)
+	if err != nil {
+		return t.errorf("invalid date %q, expected YYYY-MM-DD", t.value)
+	}
+
+	op := t.op
+	switch t.key {
+	case "after":
+		if err := t.requireOp(":"); err != nil {
+			return err
+		}
+		op = ">"
+	case "before":
+		if err := t.requireOp(":"); err != nil {
+			return err
+		}
+		op = "<"
+	}
+
+	switch op {
+	case ":", "=":
+		params.DateFrom = pgtype.Date{Time: d, Valid: true}
+		params.DateTo = pgtype.Date{Time: d, Valid: true}
+	case ">":
+		params.DateFrom = pgtype.Date{Time: d.AddDate(0, 0, 1), Valid: true}
+	case ">=":
+		params.DateFrom = pgtype.Date{Time: d, Valid: true}
+	case "<":
+		params.DateTo = pgtype.Date{Time: d.AddDate(0, 0, -1), Valid: true}
+	case "<=":
+		params.DateTo = pgtype.Date{Time: d, Valid: true}
+	}
+	return nil
+}
+
+func (t term) requireOp(ops ...string) error {
+	for _, op := range ops {
+		if t.op == op {
+			return nil
+		}
+	}
+	return t.errorf("operator %q is not supported for %q", t.op, t.key)
+}
+
+func (t term) errorf(format string, args ...any) error {
+	return &ParseError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// values splits comma-separated values such as category:food,groceries.
+// Quoted values are taken literally.
+func (t term) values() []string {
+	if t.quoted {
+		return []string{t.value}
+	}
+
+	var out []string
+	for _, v := range strings.Split(t.value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// searchText renders a free-text term in websearch_to_tsquery syntax.
+func (t term) searchText() string {
+	text := t.value
+	if t.quoted {
+		text = `"` + text + `"`
+	}
+	if t.negate {
+		text = "-" + text
+	}
+	return text
+}
+
+func tokenize(input string) ([]term, error) {
+	runes := []rune(input)
+	var terms []term
+
+	i := 0
+	for i < len(runes) {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		t := term{pos: i + 1}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			t.negate = true
+			i++
+		}
+
+		if runes[i] == '"' {
+			value, next, err := readQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			t.value, t.quoted = value, true
+			terms = append(terms, t)
+			i = next
+			continue
+		}
+
+		// Read a possible field name.
+		start := i
+		for i < len(runes) && (unicode.IsLetter(runes[i]) || runes[i] == '_') {
+			i++
+		}
+		key := strings.ToLower(string(runes[start:i]))
+
+		op := readOperator(runes, i)
+		if key == "" || op == "" {
+			// Plain word: rewind and read up to the next space.
+			i = start
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			t.value = string(runes[start:i])
+			terms = append(terms, t)
+			continue
+		}
+
+		t.key, t.op = key, op
+		i += len([]rune(op))
+
+		if i < len(runes) && runes[i] == '"' {
+			value, next, err := readQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			t.value, t.quoted = value, true
+			i = next
+		} else {
+			valueStart := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			t.value = string(runes[valueStart:i])
+		}
+		terms = append(terms, t)
+	}
+
+	return terms, nil
+}
+
+// readQuoted reads a double-quoted string starting at runes[start] and
+// returns its contents and the index just past the closing quote.
+func readQuoted(runes []rune, start int) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				b.WriteRune(runes[i])
+			}
+		case '"':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+	return "", 0, &ParseError{Pos: start + 1, Msg: "unterminated quoted string"}
+}
+
+func readOperator(runes []rune, i int) string {
+	if i >= len(runes) {
+		return ""
+	}
+	switch runes[i] {
+	case ':', '=':
+		return string(runes[i])
+	case '>', '<':
+		if i+1 < len(runes) && runes[i+1] == '=' {
+			return string(runes[i : i+2])
+		}
+		return string(runes[i])
+	}
+	return ""
+}
+
+// parseCents parses a decimal amount with at most two fractional digits.
+func parseCents(s string) (int64, bool) {
+	if strings.ContainsAny(s, "/eE") {
+		return 0, false
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, false
+	}
+	r.Mul(r, big.NewRat(100, 1))
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	return r.Num().Int64(), true
+}
+
+func centsToNumeric(cents int64) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(cents), Exp: -2, Valid: true}
+}
@@ -0,0 +1,137 @@
+package txquery
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"budgetctl-go/internal/database/gensql"
+)
+
+func TestApplyExample(t *testing.T) {
+	var params gensql.ListTransactionsWithFiltersParams
+	err := Apply(`category:food amount>20 tag:trip after:2025-01-01 -tag:work "coffee shop"`, &params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(params.Categories, []string{"food"}) {
+		t.Errorf("categories = %v", params.Categories)
+	}
+	if !reflect.DeepEqual(params.Tags, []string{"trip"}) {
+		t.Errorf("tags = %v", params.Tags)
+	}
+	if !reflect.DeepEqual(params.ExcludeTags, []string{"work"}) {
+		t.Errorf("exclude tags = %v", params.ExcludeTags)
+	}
+	if got := params.MinAmount.Int.Int64(); !params.MinAmount.Valid || got != 2001 || params.MinAmount.Exp != -2 {
+		t.Errorf("min amount = %v (exp %d)", got, params.MinAmount.Exp)
+	}
+	if params.MaxAmount.Valid {
+		t.Errorf("max amount should not be set")
+	}
+	want := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	if !params.DateFrom.Valid || !params.DateFrom.Time.Equal(want) {
+		t.Errorf("date from = %v", params.DateFrom.Time)
+	}
+	if params.Search == nil || *params.Search != `"coffee shop"` {
+		t.Errorf("search = %v", params.Search)
+	}
+}
+
+func TestApplyFields(t *testing.T) {
+	tests := []struct {
+		query string
+		check func(p gensql.ListTransactionsWithFiltersParams) bool
+	}{
+		{"category:food,groceries", func(p gensql.ListTransactionsWithFiltersParams) bool {
+			return reflect.DeepEqual(p.Categories, []string{"food", "groceries"})
+		}},
+		{`-category:"home office"`, func(p gensql.ListTransactionsWithFiltersParams) bool {
+			return reflect.DeepEqual(p.ExcludeCategories, []string{"home office"})
+		}},
+		{"status:pending account:checking currency:EUR", func(p gensql.ListTransactionsWithFiltersParams) bool {
+			return reflect.DeepEqual(p.Statuses, []string{"pending"}) &&
+				reflect.DeepEqual(p.Accounts, []string{"checking"}) &&
+				reflect.DeepEqual(p.Currencies, []string{"EUR"})
+		}},
+		{"type:income", func(p gensql.ListTransactionsWithFiltersParams) bool {
+			return p.Type != nil && *p.Type == "income"
+		}},
+		{"type:transfer", func(p gensql.ListTransactionsWithFiltersParams) bool {
+			return p.Type != nil && *p.Type == "transfer"
+		}},
+		{"-has:receipt", func(p gensql.ListTransactionsWithFiltersParams) bool {
+			return p.HasReceipt != nil && !*p.HasReceipt
+		}},
+		{"amount:12.50", func(p gensql.ListTransactionsWithFiltersParams) bool {
+			return p.MinAmount.Int.Int64() == 1250 && p.MaxAmount.Int.Int64() == 1250
+		}},
+		{"amount<=99.99 amount>=10", func(p gensql.ListTransactionsWithFiltersParams) bool {
+			return p.MinAmount.Int.Int64() == 1000 && p.MaxAmount.Int.Int64() == 9999
+		}},
+		{"before:2025-03-01 date>=2025-02-01", func(p gensql.ListTransactionsWithFiltersParams) bool {
+			return p.DateTo.Time.Equal(time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)) &&
+				p.DateFrom.Time.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+		}},
+		{"coffee -decaf", func(p gensql.ListTransactionsWithFiltersParams) bool {
+			return p.Search != nil && *p.Search == "coffee -decaf"
+		}},
+	}
+
+	for _, tt := range tests {
+		var params gensql.ListTransactionsWithFiltersParams
+		if err := Apply(tt.query, &params); err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.query, err)
+			continue
+		}
+		if !tt.check(params) {
+			t.Errorf("%q: unexpected params %+v", tt.query, params)
+		}
+	}
+}
+
+func TestApplyMergesSearch(t *testing.T) {
+	search := "rent"
+	params := gensql.ListTransactionsWithFiltersParams{Search: &search, Tags: []string{"home"}}
+	if err := Apply("march tag:monthly", &params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *params.Search != "rent march" {
+		t.Errorf("search = %q", *params.Search)
+	}
+	if !reflect.DeepEqual(params.Tags, []string{"home", "monthly"}) {
+		t.Errorf("tags = %v", params.Tags)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{"colour:red", 1},
+		{"food amount>abc", 6},
+		{"after:01/02/2025", 1},
+		{`tag:trip "coffee`, 10},
+		{"category:", 1},
+		{"-status:pending", 1},
+		{"amount>12.345", 1},
+		{"type:refund", 1},
+		{"tag>trip", 1},
+	}
+
+	for _, tt := range tests {
+		var params gensql.ListTransactionsWithFiltersParams
+		err := Apply(tt.query, &params)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: expected ParseError, got %v", tt.query, err)
+			continue
+		}
+		if perr.Pos != tt.pos {
+			t.Errorf("%q: error position = %d, want %d (%v)", tt.query, perr.Pos, tt.pos, perr)
+		}
+	}
+}